@@ -0,0 +1,123 @@
+package main
+
+import (
+	"net/http"
+
+	"mindforge/internal/database"
+
+	"github.com/gin-gonic/gin"
+)
+
+type createAPIKeyRequest struct {
+	Name string `json:"name" binding:"required,min=1,max=100"`
+}
+
+type apiKeyResponse struct {
+	ID         int     `json:"id"`
+	Name       string  `json:"name"`
+	KeyPrefix  string  `json:"key_prefix"`
+	LastUsedAt *string `json:"last_used_at"`
+	CreatedAt  string  `json:"created_at"`
+	// Key возвращается только при создании ключа
+	Key string `json:"key,omitempty"`
+}
+
+func newAPIKeyResponse(key *database.APIKey) apiKeyResponse {
+	response := apiKeyResponse{
+		ID:        key.ID,
+		Name:      key.Name,
+		KeyPrefix: key.KeyPrefix,
+		CreatedAt: key.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+	if key.LastUsedAt != nil {
+		lastUsedAt := key.LastUsedAt.Format("2006-01-02T15:04:05Z07:00")
+		response.LastUsedAt = &lastUsedAt
+	}
+	return response
+}
+
+// handleCreateAPIKey создает API ключ для OpenAI-совместимого API
+// Значение ключа возвращается только в этом ответе
+func (app *application) handleCreateAPIKey(c *gin.Context) {
+	userID, apiErr := getUserIDFromContext(c)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	var req createAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		validationErrorResponse(c, err)
+		return
+	}
+
+	key, err := database.GenerateAPIKey()
+	if err != nil {
+		app.logger.Error("Error generating api key", "error", err, "user_id", userID)
+		internalErrorResponse(c, err)
+		return
+	}
+
+	apiKey, err := app.models.APIKeys.Create(userID, req.Name, key)
+	if err != nil {
+		app.logger.Error("Error creating api key", "error", err, "user_id", userID)
+		internalErrorResponse(c, err)
+		return
+	}
+
+	response := newAPIKeyResponse(apiKey)
+	response.Key = key
+
+	c.JSON(http.StatusCreated, response)
+}
+
+// handleGetAPIKeys возвращает API ключи пользователя (без значений ключей)
+func (app *application) handleGetAPIKeys(c *gin.Context) {
+	userID, apiErr := getUserIDFromContext(c)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	keys, err := app.models.APIKeys.GetByUserID(userID)
+	if err != nil {
+		app.logger.Error("Error getting api keys", "error", err, "user_id", userID)
+		internalErrorResponse(c, err)
+		return
+	}
+
+	response := make([]apiKeyResponse, len(keys))
+	for i, key := range keys {
+		response[i] = newAPIKeyResponse(key)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// handleDeleteAPIKey отзывает API ключ
+func (app *application) handleDeleteAPIKey(c *gin.Context) {
+	userID, apiErr := getUserIDFromContext(c)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	keyID, apiErr := getIDFromParam(c, "id", "api key", "INVALID_API_KEY_ID")
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	if err := app.models.APIKeys.Delete(keyID, userID); err != nil {
+		errorResponse(c, &APIError{
+			Status:  http.StatusNotFound,
+			Message: "api key not found",
+			Code:    "API_KEY_NOT_FOUND",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "api key deleted successfully",
+	})
+}
@@ -2,6 +2,9 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
 	"net/http"
 	"strings"
 	"time"
@@ -9,37 +12,136 @@ import (
 	"mindforge/internal/ai"
 	"mindforge/internal/database"
 	"mindforge/internal/env"
+	"mindforge/internal/realtime"
 
 	"github.com/gin-gonic/gin"
 )
 
+// createChatRequest - ai_model обязателен, если не задана персона; по умолчанию берется модель персоны
 type createChatRequest struct {
-	AIModel string `json:"ai_model" binding:"required"`
+	AIModel        string             `json:"ai_model" binding:"max=100"`
+	PersonaID      *int               `json:"persona_id"`
+	FolderID       *int               `json:"folder_id"`
+	ResponseFormat *ai.ResponseFormat `json:"response_format"`
 }
 
 type chatResponse struct {
-	ID        int    `json:"id"`
-	UserID    int    `json:"user_id"`
-	AIModel   string `json:"ai_model"`
-	Title     string `json:"title"`
-	CreatedAt string `json:"created_at"`
-	UpdatedAt string `json:"updated_at"`
+	ID             int             `json:"id"`
+	UserID         int             `json:"user_id"`
+	AIModel        string          `json:"ai_model"`
+	Title          string          `json:"title"`
+	ResponseFormat json.RawMessage `json:"response_format,omitempty"`
+	PersonaID      *int            `json:"persona_id,omitempty"`
+	FolderID       *int            `json:"folder_id"`
+	TagIDs         []int           `json:"tag_ids"`
+	Archived       bool            `json:"archived"`
+	Pinned         bool            `json:"pinned"`
+	DeletedAt      *string         `json:"deleted_at,omitempty"`
+	CreatedAt      string          `json:"created_at"`
+	UpdatedAt      string          `json:"updated_at"`
+
+	// Происхождение ответвления: исходный чат и сообщение или публичная ссылка
+	ForkedFromChatID    *int `json:"forked_from_chat_id,omitempty"`
+	ForkedFromMessageID *int `json:"forked_from_message_id,omitempty"`
+	ForkedFromShareID   *int `json:"forked_from_share_id,omitempty"`
+}
+
+// newChatResponse формирует ответ API для чата
+func newChatResponse(chat *database.Chat) chatResponse {
+	response := chatResponse{
+		ID:             chat.ID,
+		UserID:         chat.UserID,
+		AIModel:        chat.AIModel,
+		Title:          chat.Title,
+		ResponseFormat: chat.ResponseFormat,
+		PersonaID:      chat.PersonaID,
+		FolderID:       chat.FolderID,
+		TagIDs:         chat.TagIDs,
+		Archived:       chat.IsArchived,
+		Pinned:         chat.IsPinned,
+		CreatedAt:      chat.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		UpdatedAt:      chat.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
+
+		ForkedFromChatID:    chat.ForkedFromChatID,
+		ForkedFromMessageID: chat.ForkedFromMessageID,
+		ForkedFromShareID:   chat.ForkedFromShareID,
+	}
+	if chat.DeletedAt != nil {
+		deletedAt := chat.DeletedAt.Format("2006-01-02T15:04:05Z07:00")
+		response.DeletedAt = &deletedAt
+	}
+	return response
+}
+
+type updateChatResponseFormatRequest struct {
+	// nil сбрасывает формат к обычному тексту
+	ResponseFormat *ai.ResponseFormat `json:"response_format"`
 }
 
 type updateChatTitleRequest struct {
 	Title string `json:"title" binding:"required,min=1,max=200"`
 }
 
+// updateChatRequest - частичное обновление чата: передаются только изменяемые поля
+// persona_id=0 отвязывает персону от чата, folder_id=0 переносит чат в корень
+type updateChatRequest struct {
+	Title     *string `json:"title" binding:"omitempty,min=1,max=200"`
+	AIModel   *string `json:"ai_model" binding:"omitempty,min=1,max=100"`
+	PersonaID *int    `json:"persona_id" binding:"omitempty,min=0"`
+	FolderID  *int    `json:"folder_id" binding:"omitempty,min=0"`
+	Archived  *bool   `json:"archived"`
+	Pinned    *bool   `json:"pinned"`
+}
+
+// createMessageRequest - текст сообщения либо шаблон промпта со значениями переменных
 type createMessageRequest struct {
-	Content string `json:"content" binding:"required,min=1,max=10000"`
+	Content         string            `json:"content" binding:"max=10000"`
+	TemplateID      *int              `json:"template_id"`
+	TemplateVersion *int              `json:"template_version"`
+	Variables       map[string]string `json:"variables"`
 }
 
 type messageResponse struct {
-	ID        int    `json:"id"`
-	ChatID    int    `json:"chat_id"`
-	Role      string `json:"role"`
-	Content   string `json:"content"`
-	CreatedAt string `json:"created_at"`
+	ID      int    `json:"id"`
+	ChatID  int    `json:"chat_id"`
+	Role    string `json:"role"`
+	Content string `json:"content"`
+	// ReasoningContent возвращается только по запросу (?include_reasoning=true)
+	ReasoningContent string          `json:"reasoning_content,omitempty"`
+	Metadata         json.RawMessage `json:"metadata,omitempty"`
+	Model            string          `json:"model,omitempty"`
+	ParentMessageID  *int            `json:"parent_message_id,omitempty"`
+	CandidateStatus  string          `json:"candidate_status,omitempty"`
+	ModerationStatus string          `json:"moderation_status,omitempty"`
+	CreatedAt        string          `json:"created_at"`
+}
+
+// newMessageResponse формирует ответ API для сообщения
+// Рассуждения модели скрыты по умолчанию и включаются флагом includeReasoning
+func newMessageResponse(msg *database.Message, includeReasoning bool) messageResponse {
+	response := messageResponse{
+		ID:               msg.ID,
+		ChatID:           msg.ChatID,
+		Role:             msg.Role,
+		Content:          msg.Content,
+		Metadata:         msg.Metadata,
+		Model:            msg.Model,
+		ParentMessageID:  msg.ParentMessageID,
+		CandidateStatus:  msg.CandidateStatus,
+		ModerationStatus: msg.ModerationStatus,
+		CreatedAt:        msg.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+	if includeReasoning {
+		response.ReasoningContent = msg.ReasoningContent
+	}
+	return response
+}
+
+// messageMetadata описывает содержимое поля metadata ответа ассистента
+type messageMetadata struct {
+	Citations []knowledgeCitation `json:"citations,omitempty"`
+	// PromptTemplate - шаблон, по которому составлено сообщение пользователя
+	PromptTemplate *promptTemplateRef `json:"prompt_template,omitempty"`
 }
 
 // handleCreateChat создает новый чат
@@ -57,6 +159,19 @@ func (app *application) handleCreateChat(c *gin.Context) {
 		return
 	}
 
+	// Персона задает модель чата по умолчанию; явно переданный ai_model важнее
+	// и проверяется ниже так же, как для чата без персоны
+	if req.PersonaID != nil {
+		persona, apiErr := app.validatePersonaOwnership(*req.PersonaID, userID.(int))
+		if apiErr != nil {
+			errorResponse(c, apiErr)
+			return
+		}
+		if req.AIModel == "" {
+			req.AIModel = persona.AIModel
+		}
+	}
+
 	// Проверяем, что ai_model не пустой
 	if req.AIModel == "" {
 		errorResponse(c, &APIError{
@@ -68,16 +183,7 @@ func (app *application) handleCreateChat(c *gin.Context) {
 	}
 
 	// Валидируем, что провайдер для указанной модели существует
-	providerName := strings.ToLower(req.AIModel)
-	if strings.Contains(providerName, "deepseek") || strings.Contains(providerName, "deep-seek") {
-		providerName = "deepseek" // DeepSeek напрямую
-	} else if strings.Contains(providerName, "gigachat") {
-		providerName = "gigachat" // GigaChat
-	} else if strings.Contains(providerName, "qwen") {
-		providerName = "qwen" // Qwen
-	} else {
-		providerName = "deepseek" // По умолчанию DeepSeek
-	}
+	providerName, _ := ai.ResolveModel(req.AIModel)
 
 	_, err := app.aiProviderFactory.Get(providerName)
 	if err != nil {
@@ -90,24 +196,40 @@ func (app *application) handleCreateChat(c *gin.Context) {
 		return
 	}
 
-	chat, err := app.models.Chats.Create(userID.(int), req.AIModel, "Новый чат")
+	if req.FolderID != nil {
+		if _, apiErr := app.validateFolderOwnership(*req.FolderID, userID.(int)); apiErr != nil {
+			errorResponse(c, apiErr)
+			return
+		}
+	}
+
+	responseFormat, apiErr := encodeResponseFormat(req.ResponseFormat)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	chat, err := app.models.Chats.Insert(&database.Chat{
+		UserID:         userID.(int),
+		AIModel:        req.AIModel,
+		Title:          "Новый чат",
+		ResponseFormat: responseFormat,
+		PersonaID:      req.PersonaID,
+		FolderID:       req.FolderID,
+	})
 	if err != nil {
 		app.logger.Error("Error creating chat", "error", err)
 		internalErrorResponse(c, err)
 		return
 	}
 
-	c.JSON(http.StatusCreated, chatResponse{
-		ID:        chat.ID,
-		UserID:    chat.UserID,
-		AIModel:   chat.AIModel,
-		Title:     chat.Title,
-		CreatedAt: chat.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
-		UpdatedAt: chat.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
-	})
+	app.publishChat(realtime.EventChatCreated, chat)
+
+	c.JSON(http.StatusCreated, newChatResponse(chat))
 }
 
-// handleGetChats получает список чатов пользователя
+// handleGetChats получает страницу чатов пользователя (keyset-пагинация: limit, before, after)
+// Фильтры: archived, pinned, trashed, folder_id, tag_id (см. parseChatFilter)
 func (app *application) handleGetChats(c *gin.Context) {
 	userID, exists := c.Get("userID")
 	if !exists {
@@ -115,23 +237,42 @@ func (app *application) handleGetChats(c *gin.Context) {
 		return
 	}
 
-	chats, err := app.models.Chats.GetByUserID(userID.(int))
+	page, apiErr := parsePageQuery(c, 50, 200)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	filter, apiErr := parseChatFilter(c)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	chats, hasMore, err := app.models.Chats.GetPage(userID.(int), filter, page)
 	if err != nil {
 		app.logger.Error("Error getting chats", "error", err, "user_id", userID)
 		internalErrorResponse(c, err)
 		return
 	}
 
-	response := make([]chatResponse, len(chats))
+	response := pageResponse[chatResponse]{Data: make([]chatResponse, len(chats))}
 	for i, chat := range chats {
-		response[i] = chatResponse{
-			ID:        chat.ID,
-			UserID:    chat.UserID,
-			AIModel:   chat.AIModel,
-			Title:     chat.Title,
-			CreatedAt: chat.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
-			UpdatedAt: chat.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		response.Data[i] = newChatResponse(chat)
+	}
+	if len(chats) > 0 {
+		// Чаты упорядочены от недавно обновленных к старым
+		response.setPageCursors(page, hasMore, chats[len(chats)-1].PageCursor(), chats[0].PageCursor())
+	}
+
+	if c.Query("include_total") == "true" {
+		total, err := app.models.Chats.CountByUserID(userID.(int), filter)
+		if err != nil {
+			app.logger.Error("Error counting chats", "error", err, "user_id", userID)
+			internalErrorResponse(c, err)
+			return
 		}
+		response.Total = &total
 	}
 
 	c.JSON(http.StatusOK, response)
@@ -176,14 +317,7 @@ func (app *application) handleGetChat(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, chatResponse{
-		ID:        chat.ID,
-		UserID:    chat.UserID,
-		AIModel:   chat.AIModel,
-		Title:     chat.Title,
-		CreatedAt: chat.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
-		UpdatedAt: chat.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
-	})
+	c.JSON(http.StatusOK, newChatResponse(chat))
 }
 
 // handleCreateMessage создает новое сообщение в чате
@@ -212,8 +346,43 @@ func (app *application) handleCreateMessage(c *gin.Context) {
 		return
 	}
 
+	if apiErr := app.checkTokenQuota(userID); apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	if apiErr := app.checkNoPendingCandidates(chatID); apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
 	// Валидация и очистка контента
 	content := strings.TrimSpace(req.Content)
+	var metadata messageMetadata
+	if req.TemplateID != nil {
+		if content != "" {
+			errorResponse(c, &APIError{
+				Status:  400,
+				Message: "content and template_id cannot be used together",
+				Code:    "VALIDATION_ERROR",
+			})
+			return
+		}
+
+		template, apiErr := app.validatePromptAccess(*req.TemplateID, userID)
+		if apiErr != nil {
+			errorResponse(c, apiErr)
+			return
+		}
+
+		rendered, ref, apiErr := app.renderPrompt(template, req.TemplateVersion, req.Variables)
+		if apiErr != nil {
+			errorResponse(c, apiErr)
+			return
+		}
+		content = rendered
+		metadata.PromptTemplate = &ref
+	}
 	if content == "" {
 		errorResponse(c, &APIError{
 			Status:  400,
@@ -233,8 +402,23 @@ func (app *application) handleCreateMessage(c *gin.Context) {
 		return
 	}
 
+	// Модерация до сохранения: заблокированное сообщение не попадает ни в историю, ни к провайдеру
+	if apiErr := app.moderateInput(c.Request.Context(), userID, &chatID, content); apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
 	// Создаем сообщение пользователя и сразу сохраняем в БД
-	userMessage, err := app.models.Messages.Create(chatID, "user", content)
+	userMessage := &database.Message{ChatID: chatID, Role: "user", Content: content}
+	if metadata.PromptTemplate != nil {
+		userMessage.Metadata, _ = json.Marshal(metadata)
+	}
+	// Проверка кандидатов повторяется атомарно со вставкой: сравнение могло начаться после первой проверки
+	userMessage, err := app.models.Messages.InsertNext(userMessage, nil)
+	if errors.Is(err, database.ErrCandidateSelectionRequired) {
+		errorResponse(c, ErrCandidateSelectionRequired)
+		return
+	}
 	if err != nil {
 		app.logger.Error("Error creating message", "error", err, "chat_id", chatID)
 		internalErrorResponse(c, err)
@@ -244,17 +428,19 @@ func (app *application) handleCreateMessage(c *gin.Context) {
 	// Обновляем время последнего обновления чата
 	app.models.Chats.UpdateUpdatedAt(chatID)
 
+	app.publishMessage(userID, userMessage)
+
+	// Потоковый режим: ответ AI передается клиенту через Server-Sent Events
+	if c.Query("stream") == "true" {
+		app.streamAIResponse(c, chat, userMessage)
+		return
+	}
+
 	// Возвращаем ответ пользователю сразу, не дожидаясь ответа AI
 	c.JSON(http.StatusCreated, gin.H{
-		"user_message": messageResponse{
-			ID:        userMessage.ID,
-			ChatID:    userMessage.ChatID,
-			Role:      userMessage.Role,
-			Content:   userMessage.Content,
-			CreatedAt: userMessage.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
-		},
-		"status":  "processing",
-		"message": "Your message has been sent. AI response will be saved automatically.",
+		"user_message": newMessageResponse(userMessage, false),
+		"status":       "processing",
+		"message":      "Your message has been sent. AI response will be saved automatically.",
 	})
 
 	// Обработка AI ответа в фоне (горутина)
@@ -269,33 +455,271 @@ func (app *application) handleCreateMessage(c *gin.Context) {
 				)
 			}
 		}()
-		app.processAIResponse(chatID, chat.AIModel, userMessage.ID)
+		app.processAIResponse(chat, userMessage.ID)
 	}()
 }
 
+// streamAIResponse генерирует ответ AI синхронно и передает его клиенту через SSE
+// События: user_message, reasoning (порции рассуждений), content (порции ответа), done, error
+// Ответ сохраняется в БД так же, как при фоновой обработке. Если включена модерация, порции не отправляются
+// до проверки ответа: прошедший проверку ответ приходит одной порцией, заблокированный - событием error (CONTENT_BLOCKED)
+func (app *application) streamAIResponse(c *gin.Context, chat *database.Chat, userMessage *database.Message) {
+	includeReasoning := c.Query("include_reasoning") == "true"
+
+	// Контекст запроса отменяется при отключении клиента, но ответ все равно должен сохраниться,
+	// поэтому генерация идет в собственном контексте с тем же лимитом, что и в фоне
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	startSSE(c, 2*time.Minute+10*time.Second)
+	writeSSE(c, "user_message", newMessageResponse(userMessage, false))
+
+	// Ответ, который еще не прошел модерацию, клиенту не показывается
+	buffered := app.moderator != nil
+
+	clientGone := false
+	onChunk := func(chunk ai.StreamChunk) error {
+		if clientGone || buffered {
+			return nil
+		}
+		if chunk.ReasoningDelta != "" {
+			if err := writeSSE(c, "reasoning", gin.H{"delta": chunk.ReasoningDelta}); err != nil {
+				clientGone = true
+			}
+		}
+		if chunk.ContentDelta != "" {
+			if err := writeSSE(c, "content", gin.H{"delta": chunk.ContentDelta}); err != nil {
+				clientGone = true
+			}
+		}
+		return nil
+	}
+
+	assistantMessage, err := app.generateAIResponseWithEvents(ctx, chat, userMessage.ID, onChunk)
+	if err != nil {
+		if errors.Is(err, ai.ErrInvalidStructuredOutput) {
+			writeSSE(c, "error", &APIError{
+				Status:  http.StatusBadGateway,
+				Message: "AI response does not match the chat response_format",
+				Code:    "INVALID_STRUCTURED_OUTPUT",
+			})
+			return
+		}
+		writeSSE(c, "error", &APIError{
+			Status:  http.StatusBadGateway,
+			Message: "failed to generate AI response",
+			Code:    "AI_GENERATION_FAILED",
+		})
+		return
+	}
+
+	if assistantMessage.ModerationStatus != "" {
+		writeSSE(c, "error", ErrContentBlocked)
+		return
+	}
+
+	// Ответ прошел модерацию: отправляем его целиком
+	if buffered {
+		if assistantMessage.ReasoningContent != "" {
+			writeSSE(c, "reasoning", gin.H{"delta": assistantMessage.ReasoningContent})
+		}
+		writeSSE(c, "content", gin.H{"delta": assistantMessage.Content})
+	}
+
+	writeSSE(c, "done", gin.H{
+		"assistant_message": newMessageResponse(assistantMessage, includeReasoning),
+	})
+}
+
 // processAIResponse обрабатывает ответ AI в фоне
 // ВАЖНО: Каждый чат имеет свой изолированный контекст:
 // - История сообщений получается только для конкретного chatID (WHERE chat_id = $1)
-// - Модель AI берется из самого чата (chat.AIModel), а не из запроса
+// - Модель AI берется из самого чата (chat.AIModel) или его персоны, а не из запроса
 // - Разные чаты и разные модели не смешиваются
 // - Каждый чат работает со своей собственной историей и своей моделью AI
-func (app *application) processAIResponse(chatID int, aiModel string, lastUserMessageID int) {
+func (app *application) processAIResponse(chat *database.Chat, lastUserMessageID int) {
 	// Создаем контекст с таймаутом (максимум 2 минуты на обработку)
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
 	defer cancel()
 
-	// Получаем историю сообщений для контекста (только для этого конкретного чата)
-	// SQL запрос: SELECT ... FROM messages WHERE chat_id = $1
-	// Это гарантирует, что каждый чат имеет свою изолированную историю
-	history, err := app.models.Messages.GetByChatID(chatID)
+	// Ошибки уже залогированы внутри generateAIResponse
+	app.generateAIResponseWithEvents(ctx, chat, lastUserMessageID, nil)
+}
+
+// generateAIResponseWithEvents генерирует ответ и сообщает о ходе генерации подключениям пользователя:
+// generation.status (started, затем completed или failed) и message.created для сохраненного ответа
+func (app *application) generateAIResponseWithEvents(ctx context.Context, chat *database.Chat, lastUserMessageID int, onChunk ai.StreamHandler) (*database.Message, error) {
+	app.publishGeneration(chat, realtime.GenerationStarted, nil, "")
+
+	assistantMessage, err := app.generateAIResponse(ctx, chat, lastUserMessageID, onChunk)
 	if err != nil {
-		app.logger.Error("Error getting message history", "error", err, "chat_id", chatID)
-		return
+		errMsg := "failed to generate AI response"
+		if errors.Is(err, ai.ErrInvalidStructuredOutput) {
+			errMsg = "AI response does not match the chat response_format"
+		}
+		app.publishGeneration(chat, realtime.GenerationFailed, nil, errMsg)
+		return nil, err
+	}
+
+	app.publishMessage(chat.UserID, assistantMessage)
+	app.publishGeneration(chat, realtime.GenerationCompleted, &assistantMessage.ID, "")
+	return assistantMessage, nil
+}
+
+// generateAIResponse собирает контекст чата, вызывает AI и сохраняет ответ ассистента
+// Если onChunk не nil, ответ запрашивается в потоковом режиме и каждая порция передается в onChunk
+func (app *application) generateAIResponse(ctx context.Context, chat *database.Chat, lastUserMessageID int, onChunk ai.StreamHandler) (*database.Message, error) {
+	chatID, aiModel := chat.ID, chat.AIModel
+	ctx = ai.WithRequestMeta(ctx, ai.RequestMeta{UserID: chat.UserID, ChatID: &chatID})
+
+	// Персона загружается при каждой генерации, поэтому изменения ее промпта и настроек сразу действуют на все ее чаты
+	// Модель всегда берется из чата: модель персоны лишь задает ее при создании чата или назначении персоны
+	persona := app.chatPersona(chat)
+
+	// Получаем AI провайдера на основе модели чата
+	// ВАЖНО: aiModel берется из самого чата (chat.AIModel), сохраненного в БД
+	// Это гарантирует, что каждый чат использует свою модель, даже если у пользователя несколько чатов с разными моделями
+	// Каждый чат может использовать свою модель (deepseek-chat, deepseek-reasoner, GigaChat, qwen и т.д.)
+	// Разные модели не смешиваются между чатами
+	providerName, model := ai.ResolveModel(aiModel)
+
+	provider, err := app.aiProviderFactory.Get(providerName)
+	if err != nil {
+		app.logger.Error("Error getting AI provider", "error", err, "provider", providerName)
+		return nil, err
+	}
+	if model == "" {
+		model = provider.GetDefaultModel()
+	}
+
+	// Лимит контекста зависит от модели: после смены модели чата история может не поместиться в ее окно
+	history, aiMessages, metadata, err := app.buildChatContext(ctx, chat, persona, lastUserMessageID, contextTokenLimit(model))
+	if err != nil {
+		return nil, err
+	}
+
+	app.logger.Debug("Sending request to AI with isolated context",
+		"chat_id", chatID,
+		"chat_ai_model", aiModel, // Модель, сохраненная в чате
+		"messages_count", len(aiMessages),
+		"provider", providerName,
+		"model", model,
+		"stream", onChunk != nil,
+		"context_isolation", "enabled", // Подтверждение изоляции
+	)
+
+	// Отправляем запрос к AI
+	aiReq := ai.ChatRequest{
+		Model:    model,
+		Messages: aiMessages,
+		Stream:   onChunk != nil,
+	}
+	applyPersona(&aiReq, persona)
+
+	// Структурированный формат ответа, заданный для чата
+	aiReq.ResponseFormat, err = chatResponseFormat(chat)
+	if err != nil {
+		app.logger.Error("Invalid chat response_format", "error", err, "chat_id", chatID)
+		return nil, err
+	}
+
+	var aiResp *ai.ChatResponse
+	switch {
+	case aiReq.ResponseFormat.IsJSON():
+		// Ответ нужно проверить целиком до отправки клиенту, поэтому JSON-режим не стримится:
+		// клиент получает проверенный ответ одной порцией
+		aiReq.Stream = false
+		aiResp, err = ai.ChatStructured(ctx, provider, aiReq)
+		if err == nil && onChunk != nil {
+			onChunk(ai.StreamChunk{ContentDelta: aiResp.Content})
+		}
+	case onChunk != nil:
+		aiResp, err = provider.ChatStream(ctx, aiReq, onChunk)
+	default:
+		aiResp, err = provider.Chat(ctx, aiReq)
+	}
+	if err != nil {
+		// Неудачные попытки JSON-режима тоже расходуют токены
+		if aiResp != nil {
+			app.recordUsage(chat.UserID, &chatID, database.UsageSourceChat, providerName, aiResp)
+		}
+		// Проверяем, не истек ли контекст
+		if ctx.Err() == context.DeadlineExceeded {
+			app.logger.Error("AI request timeout", "chat_id", chatID, "provider", providerName)
+		} else {
+			app.logger.Error("Error calling AI provider", "error", err, "chat_id", chatID, "provider", providerName)
+		}
+		return nil, err
+	}
+
+	app.recordUsage(chat.UserID, &chatID, database.UsageSourceChat, providerName, aiResp)
+
+	// Сохраняем ответ ассистента в БД (рассуждения хранятся отдельно от ответа)
+	assistant := &database.Message{
+		ChatID:           chatID,
+		Role:             "assistant",
+		Content:          aiResp.Content,
+		ReasoningContent: aiResp.ReasoningContent,
+		Model:            modelOrRequested(aiResp.Model, model),
+	}
+	if len(metadata.Citations) > 0 {
+		assistant.Metadata, _ = json.Marshal(metadata)
+	}
+
+	// Модерация ответа: заблокированный ответ сохраняется без текста и не попадает в контекст
+	verdict := app.moderate(ctx, database.ModerationStageOutput, aiResp.Content)
+	if verdict != nil {
+		blockModeratedMessage(assistant)
+	}
+
+	assistantMessage, err := app.models.Messages.Insert(assistant)
+	if err != nil {
+		app.logger.Error("Error creating assistant message", "error", err, "chat_id", chatID)
+		return nil, err
+	}
+	if verdict != nil {
+		app.recordModerationEvent(chat.UserID, &chatID, &assistantMessage.ID, database.ModerationStageOutput, verdict, aiResp.Content)
+	}
+
+	// Обновляем время последнего обновления чата
+	app.models.Chats.UpdateUpdatedAt(chatID)
+
+	app.logger.Info("AI response saved with isolated context",
+		"chat_id", chatID,
+		"chat_ai_model", aiModel, // Модель чата для подтверждения изоляции
+		"message_id", assistantMessage.ID,
+		"model", aiResp.Model,
+		"tokens", aiResp.Usage.TotalTokens,
+		"prompt_tokens", aiResp.Usage.PromptTokens,
+		"completion_tokens", aiResp.Usage.CompletionTokens,
+		"has_reasoning", aiResp.ReasoningContent != "",
+		"context_messages_count", len(history), // Количество сообщений в контексте этого чата
+	)
+
+	return assistantMessage, nil
+}
+
+// buildChatContext собирает контекст запроса к AI: системный промпт персоны, историю чата с учетом лимитов
+// и фрагменты из привязанных баз знаний (чата и персоны) для последнего сообщения пользователя
+func (app *application) buildChatContext(ctx context.Context, chat *database.Chat, persona *database.Persona, lastUserMessageID, maxContextTokens int) ([]*database.Message, []ai.Message, messageMetadata, error) {
+	chatID, aiModel := chat.ID, chat.AIModel
+	if persona != nil {
+		// Системный промпт персоны занимает часть лимита контекста
+		maxContextTokens -= len(persona.SystemPrompt) / 4
 	}
 
 	// Настройки контекста из переменных окружения
 	maxHistoryMessages := env.GetEnvInt("AI_MAX_CONTEXT_MESSAGES", 100)
-	maxContextTokens := env.GetEnvInt("AI_MAX_CONTEXT_TOKENS", 32000) // По умолчанию 32k токенов
+
+	// Получаем только последние N сообщений контекста (только для этого конкретного чата)
+	// SQL запрос: SELECT ... FROM messages WHERE chat_id = $1 ... LIMIT N
+	// Это гарантирует, что каждый чат имеет свою изолированную историю. Невыбранные ответы-кандидаты
+	// режима сравнения и заблокированные модерацией ответы в историю диалога не входят
+	history, err := app.models.Messages.GetContextTail(chatID, maxHistoryMessages)
+	if err != nil {
+		app.logger.Error("Error getting message history", "error", err, "chat_id", chatID)
+		return nil, nil, messageMetadata{}, err
+	}
 
 	originalCount := len(history)
 	app.logger.Debug("Processing AI response with isolated context",
@@ -307,27 +731,16 @@ func (app *application) processAIResponse(chatID int, aiModel string, lastUserMe
 		"context_isolation", "enabled", // Подтверждение изоляции контекста
 	)
 
-	// Ограничиваем количество сообщений в истории
-	if len(history) > maxHistoryMessages {
-		// Берем последние N сообщений (сохраняем контекст недавних сообщений)
-		history = history[len(history)-maxHistoryMessages:]
-		app.logger.Info("Message history truncated by count",
-			"chat_id", chatID,
-			"original_count", originalCount,
-			"truncated_count", len(history),
-		)
-	}
-
-	// Ограничиваем по токенам (приблизительная оценка: 1 токен ≈ 4 символа)
-	// Более точная оценка: примерно 0.75 токена на слово или 4 символа на токен
+	// Ограничиваем по токенам: точный подсчет, если провайдер его поддерживает, иначе оценка
+	tokenCounts := app.messageTokenCounts(ctx, aiModel, history)
 	estimatedTokens := 0
 	truncatedHistory := make([]*database.Message, 0, len(history))
 
 	// Идем с конца истории (последние сообщения важнее) и добавляем сообщения пока не превысим лимит
 	for i := len(history) - 1; i >= 0; i-- {
 		msg := history[i]
-		// Приблизительная оценка токенов: длина контента / 4 + накладные расходы на роль (~5 токенов)
-		msgTokens := len(msg.Content)/4 + 5
+		// Токены контента + накладные расходы на роль (~5 токенов)
+		msgTokens := tokenCounts[i] + 5
 
 		if estimatedTokens+msgTokens > maxContextTokens {
 			// Если добавление этого сообщения превысит лимит, останавливаемся
@@ -349,34 +762,9 @@ func (app *application) processAIResponse(chatID int, aiModel string, lastUserMe
 		history = truncatedHistory
 	}
 
-	// Получаем AI провайдера на основе модели чата
-	// ВАЖНО: aiModel берется из самого чата (chat.AIModel), сохраненного в БД
-	// Это гарантирует, что каждый чат использует свою модель, даже если у пользователя несколько чатов с разными моделями
-	providerName := strings.ToLower(aiModel)
-	if providerName == "" {
-		providerName = "deepseek" // По умолчанию DeepSeek
-	}
-
-	// Маппинг названий моделей на провайдеров
-	// Каждый чат может использовать свою модель (deepseek-chat, GigaChat, qwen и т.д.)
-	// Разные модели не смешиваются между чатами
-	if strings.Contains(providerName, "deepseek") || strings.Contains(providerName, "deep-seek") {
-		providerName = "deepseek" // DeepSeek напрямую
-	} else if strings.Contains(providerName, "gigachat") {
-		providerName = "gigachat" // GigaChat
-	} else if strings.Contains(providerName, "qwen") {
-		providerName = "qwen" // Qwen
-	} else {
-		providerName = "deepseek" // По умолчанию DeepSeek
-	}
-
-	provider, err := app.aiProviderFactory.Get(providerName)
-	if err != nil {
-		app.logger.Error("Error getting AI provider", "error", err, "provider", providerName)
-		return
-	}
-
 	// Конвертируем историю сообщений в формат для AI
+	// В контекст попадает только content: рассуждения (reasoning_content) прошлых ответов
+	// никогда не отправляются провайдеру повторно
 	aiMessages := make([]ai.Message, len(history))
 	for i, msg := range history {
 		aiMessages[i] = ai.Message{
@@ -385,55 +773,91 @@ func (app *application) processAIResponse(chatID int, aiModel string, lastUserMe
 		}
 	}
 
-	app.logger.Debug("Sending request to AI with isolated context",
-		"chat_id", chatID,
-		"chat_ai_model", aiModel, // Модель, сохраненная в чате
-		"messages_count", len(aiMessages),
-		"provider", providerName,
-		"model", provider.GetDefaultModel(),
-		"context_isolation", "enabled", // Подтверждение изоляции
-	)
+	// Если к чату привязаны базы знаний, добавляем найденные фрагменты в контекст
+	var metadata messageMetadata
+	var query string
+	for _, msg := range history {
+		if msg.ID == lastUserMessageID {
+			query = msg.Content
+		}
+	}
+	knowledgeMessage, citations, err := app.retrieveKnowledge(ctx, chatID, persona, query)
+	if err != nil {
+		// Ошибка поиска не должна блокировать ответ: отвечаем без базы знаний
+		app.logger.Warn("Error retrieving knowledge", "error", err, "chat_id", chatID)
+	} else if knowledgeMessage != nil {
+		aiMessages = append([]ai.Message{*knowledgeMessage}, aiMessages...)
+		metadata.Citations = citations
+		app.logger.Debug("Knowledge context added", "chat_id", chatID, "chunks", len(citations))
+	}
 
-	// Отправляем запрос к AI
-	aiReq := ai.ChatRequest{
-		Model:    provider.GetDefaultModel(),
-		Messages: aiMessages,
+	if persona != nil && persona.SystemPrompt != "" {
+		aiMessages = append([]ai.Message{{Role: "system", Content: persona.SystemPrompt}}, aiMessages...)
 	}
 
-	aiResp, err := provider.Chat(ctx, aiReq)
-	if err != nil {
-		// Проверяем, не истек ли контекст
-		if ctx.Err() == context.DeadlineExceeded {
-			app.logger.Error("AI request timeout", "chat_id", chatID, "provider", providerName)
-		} else {
-			app.logger.Error("Error calling AI provider", "error", err, "chat_id", chatID, "provider", providerName)
-		}
-		return
+	return history, aiMessages, metadata, nil
+}
+
+// messageTokenCounts возвращает количество токенов в каждом сообщении
+// Если провайдер модели поддерживает подсчет токенов (ai.TokenCounter, например GigaChat), используется
+// его токенизатор, иначе - приблизительная оценка: 1 токен ≈ 4 символа
+func (app *application) messageTokenCounts(ctx context.Context, aiModel string, messages []*database.Message) []int {
+	counts := make([]int, len(messages))
+	for i, msg := range messages {
+		counts[i] = len(msg.Content) / 4
+	}
+
+	providerName, model := ai.ResolveModel(aiModel)
+	provider, err := app.aiProviderFactory.Get(providerName)
+	if err != nil || len(messages) == 0 {
+		return counts
+	}
+	counter, ok := ai.As[ai.TokenCounter](provider)
+	if !ok {
+		return counts
 	}
 
-	// Сохраняем ответ ассистента в БД
-	assistantMessage, err := app.models.Messages.Create(chatID, "assistant", aiResp.Content)
+	texts := make([]string, len(messages))
+	for i, msg := range messages {
+		texts[i] = msg.Content
+	}
+
+	exact, err := counter.CountTokens(ctx, model, texts)
 	if err != nil {
-		app.logger.Error("Error creating assistant message", "error", err, "chat_id", chatID)
-		return
+		// Подсчет токенов не должен блокировать ответ: используем оценку
+		app.logger.Warn("Error counting tokens, using estimate", "error", err, "provider", providerName)
+		return counts
 	}
+	return exact
+}
 
-	// Обновляем время последнего обновления чата
-	app.models.Chats.UpdateUpdatedAt(chatID)
+// contextTokenLimit возвращает лимит токенов истории для модели
+// Берется меньшее из AI_MAX_CONTEXT_TOKENS и контекстного окна модели за вычетом запаса на ответ
+func contextTokenLimit(model string) int {
+	limit := env.GetEnvInt("AI_MAX_CONTEXT_TOKENS", 32000) // По умолчанию 32k токенов
 
-	app.logger.Info("AI response saved with isolated context",
-		"chat_id", chatID,
-		"chat_ai_model", aiModel, // Модель чата для подтверждения изоляции
-		"message_id", assistantMessage.ID,
-		"model", aiResp.Model,
-		"tokens", aiResp.Usage.TotalTokens,
-		"prompt_tokens", aiResp.Usage.PromptTokens,
-		"completion_tokens", aiResp.Usage.CompletionTokens,
-		"context_messages_count", len(history), // Количество сообщений в контексте этого чата
-	)
+	if info, ok := ai.LookupModel(model); ok && info.ContextWindow > 0 {
+		// Оставляем четверть окна под ответ модели и системные инструкции
+		limit = min(limit, info.ContextWindow*3/4)
+	}
+
+	return limit
+}
+
+// chatResponseFormat возвращает структурированный формат ответа чата или nil, если формат не задан
+func chatResponseFormat(chat *database.Chat) (*ai.ResponseFormat, error) {
+	if len(chat.ResponseFormat) == 0 {
+		return nil, nil
+	}
+
+	var format ai.ResponseFormat
+	if err := json.Unmarshal(chat.ResponseFormat, &format); err != nil {
+		return nil, err
+	}
+	return &format, nil
 }
 
-// handleGetMessages получает историю сообщений чата
+// handleGetMessages получает страницу истории сообщений чата (keyset-пагинация: limit, before, after)
 func (app *application) handleGetMessages(c *gin.Context) {
 	userID, apiErr := getUserIDFromContext(c)
 	if apiErr != nil {
@@ -453,28 +877,46 @@ func (app *application) handleGetMessages(c *gin.Context) {
 		return
 	}
 
-	messages, err := app.models.Messages.GetByChatID(chatID)
+	page, apiErr := parsePageQuery(c, 50, 200)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	messages, hasMore, err := app.models.Messages.GetPage(chatID, page)
 	if err != nil {
 		app.logger.Error("Error getting messages", "error", err, "chat_id", chatID)
 		internalErrorResponse(c, err)
 		return
 	}
 
-	response := make([]messageResponse, len(messages))
+	// Рассуждения reasoning-моделей скрыты, пока клиент явно их не запросит
+	includeReasoning := c.Query("include_reasoning") == "true"
+
+	response := pageResponse[messageResponse]{Data: make([]messageResponse, len(messages))}
 	for i, msg := range messages {
-		response[i] = messageResponse{
-			ID:        msg.ID,
-			ChatID:    msg.ChatID,
-			Role:      msg.Role,
-			Content:   msg.Content,
-			CreatedAt: msg.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		response.Data[i] = newMessageResponse(msg, includeReasoning)
+	}
+	if len(messages) > 0 {
+		// Сообщения упорядочены хронологически
+		response.setPageCursors(page, hasMore, messages[0].PageCursor(), messages[len(messages)-1].PageCursor())
+	}
+
+	if c.Query("include_total") == "true" {
+		total, err := app.models.Messages.CountByChatID(chatID)
+		if err != nil {
+			app.logger.Error("Error counting messages", "error", err, "chat_id", chatID)
+			internalErrorResponse(c, err)
+			return
 		}
+		response.Total = &total
 	}
 
 	c.JSON(http.StatusOK, response)
 }
 
-// handleDeleteChat удаляет чат
+// handleDeleteChat перемещает чат в корзину
+// Чат из корзины или с ?permanent=true удаляется окончательно вместе с сообщениями
 func (app *application) handleDeleteChat(c *gin.Context) {
 	userID, apiErr := getUserIDFromContext(c)
 	if apiErr != nil {
@@ -488,12 +930,27 @@ func (app *application) handleDeleteChat(c *gin.Context) {
 		return
 	}
 
-	_, apiErr = app.validateChatOwnership(c, chatID, userID)
+	chat, apiErr := app.findOwnedChat(chatID, userID)
 	if apiErr != nil {
 		errorResponse(c, apiErr)
 		return
 	}
 
+	if chat.DeletedAt == nil && c.Query("permanent") != "true" {
+		if err := app.models.Chats.MoveToTrash(chatID); err != nil {
+			app.logger.Error("Error moving chat to trash", "error", err, "chat_id", chatID)
+			internalErrorResponse(c, err)
+			return
+		}
+
+		app.realtime.Publish(userID, realtime.EventChatDeleted, chatID, chatDeletedEvent{Permanent: false})
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": "chat moved to trash",
+		})
+		return
+	}
+
 	// Удаляем сообщения и чат (CASCADE должен удалить сообщения автоматически)
 	if err := app.models.Chats.Delete(chatID); err != nil {
 		app.logger.Error("Error deleting chat", "error", err, "chat_id", chatID)
@@ -501,11 +958,76 @@ func (app *application) handleDeleteChat(c *gin.Context) {
 		return
 	}
 
+	app.realtime.Publish(userID, realtime.EventChatDeleted, chatID, chatDeletedEvent{Permanent: true})
+
 	c.JSON(http.StatusOK, gin.H{
 		"message": "chat deleted successfully",
 	})
 }
 
+// handleRestoreChat возвращает чат из корзины
+func (app *application) handleRestoreChat(c *gin.Context) {
+	userID, apiErr := getUserIDFromContext(c)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	chatID, apiErr := getChatIDFromParam(c)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	chat, apiErr := app.findOwnedChat(chatID, userID)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	if chat.DeletedAt == nil {
+		errorResponse(c, &APIError{
+			Status:  409,
+			Message: "chat is not in trash",
+			Code:    "CHAT_NOT_IN_TRASH",
+		})
+		return
+	}
+
+	if err := app.models.Chats.Restore(chatID); err != nil {
+		app.logger.Error("Error restoring chat", "error", err, "chat_id", chatID)
+		internalErrorResponse(c, err)
+		return
+	}
+
+	restored, err := app.models.Chats.GetByID(chatID)
+	if err != nil {
+		internalErrorResponse(c, err)
+		return
+	}
+
+	app.publishChat(realtime.EventChatUpdated, restored)
+
+	c.JSON(http.StatusOK, newChatResponse(restored))
+}
+
+// purgeTrashedChats периодически окончательно удаляет чаты, пролежавшие в корзине дольше retention
+func purgeTrashedChats(chats database.ChatModel, retention time.Duration, logger *slog.Logger) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		deleted, err := chats.PurgeTrashed(time.Now().Add(-retention))
+		if err != nil {
+			logger.Warn("Error purging chat trash", "error", err)
+			continue
+		}
+		if deleted > 0 {
+			logger.Info("Expired chats purged from trash", "deleted", deleted)
+		}
+	}
+}
+
 // handleUpdateChatTitle обновляет название чата
 func (app *application) handleUpdateChatTitle(c *gin.Context) {
 	userID, apiErr := getUserIDFromContext(c)
@@ -549,7 +1071,219 @@ func (app *application) handleUpdateChatTitle(c *gin.Context) {
 		return
 	}
 
+	app.publishChatUpdated(chatID)
+
 	c.JSON(http.StatusOK, gin.H{
 		"message": "chat title updated successfully",
 	})
 }
+
+// handleUpdateChat частично обновляет чат: название, модель, персону, папку, архив и закрепление
+// Смена модели действует со следующего ответа; история чата сохраняется и адаптируется
+// под ограничения новой модели при генерации ответа
+func (app *application) handleUpdateChat(c *gin.Context) {
+	userID, apiErr := getUserIDFromContext(c)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	chatID, apiErr := getChatIDFromParam(c)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	chat, apiErr := app.validateChatOwnership(c, chatID, userID)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	var req updateChatRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		validationErrorResponse(c, err)
+		return
+	}
+
+	if req.Title == nil && req.AIModel == nil && req.PersonaID == nil && req.FolderID == nil &&
+		req.Archived == nil && req.Pinned == nil {
+		errorResponse(c, &APIError{
+			Status:  400,
+			Message: "nothing to update: provide title, ai_model, persona_id, folder_id, archived or pinned",
+			Code:    "VALIDATION_ERROR",
+		})
+		return
+	}
+
+	// Все поля проверяются до записи: изменения применяются одним запросом, и ошибка в любом поле
+	// не оставляет чат измененным частично
+	var update database.ChatUpdate
+
+	if req.Title != nil {
+		title := strings.TrimSpace(*req.Title)
+		if title == "" {
+			errorResponse(c, &APIError{
+				Status:  400,
+				Message: "title cannot be empty",
+				Code:    "VALIDATION_ERROR",
+			})
+			return
+		}
+		update.Title = &title
+	}
+
+	if req.AIModel != nil {
+		aiModel := strings.TrimSpace(*req.AIModel)
+		if aiModel == "" {
+			errorResponse(c, &APIError{
+				Status:  400,
+				Message: "ai_model cannot be empty",
+				Code:    "VALIDATION_ERROR",
+			})
+			return
+		}
+		if aiModel != chat.AIModel {
+			if apiErr := app.validateAIModel(aiModel); apiErr != nil {
+				errorResponse(c, apiErr)
+				return
+			}
+			update.AIModel = &aiModel
+		}
+	}
+
+	if req.PersonaID != nil {
+		update.SetPersona = true
+		if *req.PersonaID != 0 {
+			persona, apiErr := app.validatePersonaOwnership(*req.PersonaID, userID)
+			if apiErr != nil {
+				errorResponse(c, apiErr)
+				return
+			}
+			update.PersonaID = &persona.ID
+
+			// Назначенная персона задает модель чата, если модель не передана явно
+			if req.AIModel == nil && persona.AIModel != chat.AIModel {
+				if apiErr := app.validateAIModel(persona.AIModel); apiErr != nil {
+					errorResponse(c, &APIError{
+						Status:  apiErr.Status,
+						Message: "persona ai_model is not available, pass ai_model explicitly",
+						Code:    apiErr.Code,
+					})
+					return
+				}
+				update.AIModel = &persona.AIModel
+			}
+		}
+	}
+
+	if req.FolderID != nil {
+		update.SetFolder = true
+		if *req.FolderID != 0 {
+			if _, apiErr := app.validateFolderOwnership(*req.FolderID, userID); apiErr != nil {
+				errorResponse(c, apiErr)
+				return
+			}
+			update.FolderID = req.FolderID
+		}
+	}
+
+	update.Archived = req.Archived
+	update.Pinned = req.Pinned
+
+	if err := app.models.Chats.Update(chatID, update); err != nil {
+		app.logger.Error("Error updating chat", "error", err, "chat_id", chatID)
+		internalErrorResponse(c, err)
+		return
+	}
+
+	if update.AIModel != nil {
+		app.logger.Info("Chat model changed",
+			"chat_id", chatID,
+			"from", chat.AIModel,
+			"to", *update.AIModel,
+		)
+	}
+
+	updated, err := app.models.Chats.GetByID(chatID)
+	if err != nil {
+		internalErrorResponse(c, err)
+		return
+	}
+
+	app.publishChat(realtime.EventChatUpdated, updated)
+
+	c.JSON(http.StatusOK, newChatResponse(updated))
+}
+
+// handleUpdateChatResponseFormat задает структурированный формат ответов чата
+func (app *application) handleUpdateChatResponseFormat(c *gin.Context) {
+	userID, apiErr := getUserIDFromContext(c)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	chatID, apiErr := getChatIDFromParam(c)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	_, apiErr = app.validateChatOwnership(c, chatID, userID)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	var req updateChatResponseFormatRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		validationErrorResponse(c, err)
+		return
+	}
+
+	responseFormat, apiErr := encodeResponseFormat(req.ResponseFormat)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	if err := app.models.Chats.UpdateResponseFormat(chatID, responseFormat); err != nil {
+		app.logger.Error("Error updating chat response format", "error", err, "chat_id", chatID)
+		internalErrorResponse(c, err)
+		return
+	}
+
+	app.publishChatUpdated(chatID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "chat response format updated successfully",
+	})
+}
+
+// encodeResponseFormat проверяет формат ответа и сериализует его для хранения в чате
+// Формат "text" равнозначен отсутствию формата
+func encodeResponseFormat(format *ai.ResponseFormat) (json.RawMessage, *APIError) {
+	if format == nil || format.Type == ai.ResponseFormatText {
+		return nil, nil
+	}
+
+	if err := format.Validate(); err != nil {
+		return nil, &APIError{
+			Status:  400,
+			Message: err.Error(),
+			Code:    "INVALID_RESPONSE_FORMAT",
+		}
+	}
+
+	data, err := json.Marshal(format)
+	if err != nil {
+		return nil, &APIError{
+			Status:  400,
+			Message: "invalid response_format",
+			Code:    "INVALID_RESPONSE_FORMAT",
+		}
+	}
+
+	return data, nil
+}
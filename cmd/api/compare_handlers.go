@@ -0,0 +1,363 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"mindforge/internal/ai"
+	"mindforge/internal/database"
+	"mindforge/internal/realtime"
+
+	"github.com/gin-gonic/gin"
+)
+
+type compareMessageRequest struct {
+	Content string   `json:"content" binding:"required,min=1,max=10000"`
+	Models  []string `json:"models" binding:"required,min=2,max=4,dive,required"`
+}
+
+type candidateUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+type candidateResponse struct {
+	Model     string           `json:"model"`
+	Provider  string           `json:"provider"`
+	Message   *messageResponse `json:"message,omitempty"`
+	LatencyMs int64            `json:"latency_ms"`
+	Usage     candidateUsage   `json:"usage"`
+	Error     string           `json:"error,omitempty"`
+}
+
+// handleCompareMessage отправляет сообщение пользователя сразу нескольким моделям
+// Ответы сохраняются как кандидаты (candidate_status = pending); продолжить диалог можно
+// после выбора одного из них через handleSelectCandidate
+func (app *application) handleCompareMessage(c *gin.Context) {
+	userID, apiErr := getUserIDFromContext(c)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	chatID, apiErr := getChatIDFromParam(c)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	chat, apiErr := app.validateChatOwnership(c, chatID, userID)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	var req compareMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		validationErrorResponse(c, err)
+		return
+	}
+
+	content := strings.TrimSpace(req.Content)
+	if content == "" {
+		errorResponse(c, &APIError{
+			Status:  400,
+			Message: "message content cannot be empty",
+			Code:    "VALIDATION_ERROR",
+		})
+		return
+	}
+
+	// Проверяем модели до сохранения сообщения, чтобы не оставлять в чате вопрос без ответов
+	// Принимаются только модели из GET /api/v1/models: неизвестная модель не подменяется моделью по умолчанию
+	listCtx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	models := make([]ai.ModelInfo, len(req.Models))
+	for i, model := range req.Models {
+		info, found := app.findAvailableModel(listCtx, strings.TrimSpace(model))
+		if !found {
+			cancel()
+			errorResponse(c, &APIError{
+				Status:  400,
+				Message: "invalid AI model: " + model,
+				Code:    "INVALID_AI_MODEL",
+			})
+			return
+		}
+		models[i] = info
+	}
+	cancel()
+
+	if apiErr := app.checkTokenQuota(userID); apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	if apiErr := app.checkNoPendingCandidates(chatID); apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	if apiErr := app.moderateInput(c.Request.Context(), userID, &chatID, content); apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	// Сообщение сохраняется вместе с отметкой о сравнении: до сохранения кандидатов в чат нельзя
+	// отправить другое сообщение или запустить еще одно сравнение
+	comparingUntil := time.Now().Add(2*time.Minute + 10*time.Second)
+	userMessage, err := app.models.Messages.InsertNext(&database.Message{ChatID: chatID, Role: "user", Content: content}, &comparingUntil)
+	if errors.Is(err, database.ErrCandidateSelectionRequired) {
+		errorResponse(c, ErrCandidateSelectionRequired)
+		return
+	}
+	if err != nil {
+		app.logger.Error("Error creating message", "error", err, "chat_id", chatID)
+		internalErrorResponse(c, err)
+		return
+	}
+
+	app.models.Chats.UpdateUpdatedAt(chatID)
+
+	app.publishMessage(userID, userMessage)
+
+	extendWriteDeadline(c, 2*time.Minute+10*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	ctx = ai.WithRequestMeta(ctx, ai.RequestMeta{UserID: userID, ChatID: &chatID})
+
+	// Контекст одинаков для всех моделей, поэтому собираем его один раз
+	// под наименьшее контекстное окно среди сравниваемых моделей
+	maxContextTokens := 0
+	for i, model := range models {
+		if limit := contextTokenLimit(model.ID); i == 0 || limit < maxContextTokens {
+			maxContextTokens = limit
+		}
+	}
+	// Системный промпт, базы знаний и параметры генерации персоны применяются ко всем сравниваемым моделям
+	persona := app.chatPersona(chat)
+	_, aiMessages, metadata, err := app.buildChatContext(ctx, chat, persona, userMessage.ID, maxContextTokens)
+	if err != nil {
+		app.models.Messages.FinishComparing(chatID)
+		internalErrorResponse(c, err)
+		return
+	}
+
+	responseFormat, err := chatResponseFormat(chat)
+	if err != nil {
+		app.logger.Error("Invalid chat response_format", "error", err, "chat_id", chatID)
+		app.models.Messages.FinishComparing(chatID)
+		internalErrorResponse(c, err)
+		return
+	}
+
+	app.publishGeneration(chat, realtime.GenerationStarted, nil, "")
+
+	candidates := make([]candidateResponse, len(models))
+	var wg sync.WaitGroup
+	for i, model := range models {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() {
+				if r := recover(); r != nil {
+					app.logger.Error("Panic in generateCandidate", "error", r, "chat_id", chatID, "model", model.ID)
+					candidates[i] = candidateResponse{Model: model.ID, Provider: model.Provider, Error: "failed to generate AI response"}
+				}
+			}()
+			candidates[i] = app.generateCandidate(ctx, chat, persona, userMessage, model, aiMessages, metadata, responseFormat)
+		}()
+	}
+	wg.Wait()
+
+	// Кандидаты сохранены: дальше диалог блокируют только они сами, пока один не выбран
+	app.models.Messages.FinishComparing(chatID)
+	app.models.Chats.UpdateUpdatedAt(chatID)
+
+	// Сравнение завершено, даже если часть моделей не ответила: ошибки моделей есть в ответах кандидатов
+	app.publishGeneration(chat, realtime.GenerationCompleted, nil, "")
+
+	c.JSON(http.StatusCreated, gin.H{
+		"user_message": newMessageResponse(userMessage, false),
+		"candidates":   candidates,
+	})
+}
+
+// generateCandidate получает ответ одной модели и сохраняет его как кандидата
+func (app *application) generateCandidate(ctx context.Context, chat *database.Chat, persona *database.Persona, userMessage *database.Message, info ai.ModelInfo, aiMessages []ai.Message, metadata messageMetadata, responseFormat *ai.ResponseFormat) candidateResponse {
+	providerName, model := info.Provider, info.ID
+	result := candidateResponse{Model: model, Provider: providerName}
+
+	provider, err := app.aiProviderFactory.Get(providerName)
+	if err != nil {
+		result.Error = "AI provider not available"
+		return result
+	}
+
+	aiReq := ai.ChatRequest{
+		Model:          model,
+		Messages:       aiMessages,
+		ResponseFormat: responseFormat,
+	}
+	applyPersona(&aiReq, persona)
+
+	start := time.Now()
+	aiResp, err := ai.ChatStructured(ctx, provider, aiReq)
+	result.LatencyMs = time.Since(start).Milliseconds()
+	if err != nil {
+		// Неудачные попытки JSON-режима тоже расходуют токены
+		if aiResp != nil {
+			app.recordUsage(chat.UserID, &chat.ID, database.UsageSourceChat, providerName, aiResp)
+		}
+		app.logger.Error("Error generating candidate", "error", err, "chat_id", chat.ID, "provider", providerName, "model", model)
+		result.Error = completionError(err).Message
+		return result
+	}
+
+	app.recordUsage(chat.UserID, &chat.ID, database.UsageSourceChat, providerName, aiResp)
+	result.Usage = candidateUsage{
+		PromptTokens:     aiResp.Usage.PromptTokens,
+		CompletionTokens: aiResp.Usage.CompletionTokens,
+		TotalTokens:      aiResp.Usage.TotalTokens,
+	}
+
+	parentID := userMessage.ID
+	candidate := &database.Message{
+		ChatID:           chat.ID,
+		Role:             "assistant",
+		Content:          aiResp.Content,
+		ReasoningContent: aiResp.ReasoningContent,
+		Model:            model,
+		ParentMessageID:  &parentID,
+		CandidateStatus:  database.CandidateStatusPending,
+	}
+	if len(metadata.Citations) > 0 {
+		candidate.Metadata, _ = json.Marshal(metadata)
+	}
+
+	// Заблокированный ответ сразу отклоняется: его нельзя выбрать, и он не должен ждать выбора
+	verdict := app.moderate(ctx, database.ModerationStageOutput, aiResp.Content)
+	if verdict != nil {
+		blockModeratedMessage(candidate)
+		candidate.CandidateStatus = database.CandidateStatusRejected
+	}
+
+	saved, err := app.models.Messages.Insert(candidate)
+	if err != nil {
+		app.logger.Error("Error saving candidate", "error", err, "chat_id", chat.ID, "model", model)
+		result.Error = "failed to save AI response"
+		return result
+	}
+	if verdict != nil {
+		app.recordModerationEvent(chat.UserID, &chat.ID, &saved.ID, database.ModerationStageOutput, verdict, aiResp.Content)
+	}
+
+	app.publishMessage(chat.UserID, saved)
+
+	response := newMessageResponse(saved, false)
+	result.Message = &response
+
+	app.logger.Info("Candidate response saved",
+		"chat_id", chat.ID,
+		"message_id", saved.ID,
+		"provider", providerName,
+		"model", model,
+		"latency_ms", result.LatencyMs,
+		"tokens", aiResp.Usage.TotalTokens,
+	)
+
+	return result
+}
+
+// handleSelectCandidate выбирает ответ-кандидат, с которым продолжится диалог
+func (app *application) handleSelectCandidate(c *gin.Context) {
+	userID, apiErr := getUserIDFromContext(c)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	chatID, apiErr := getChatIDFromParam(c)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	_, apiErr = app.validateChatOwnership(c, chatID, userID)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	messageID, apiErr := getIDFromParam(c, "message_id", "message", "INVALID_MESSAGE_ID")
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	message, err := app.models.Messages.GetByID(messageID)
+	if err != nil || message.ChatID != chatID {
+		errorResponse(c, &APIError{
+			Status:  http.StatusNotFound,
+			Message: "message not found",
+			Code:    "MESSAGE_NOT_FOUND",
+		})
+		return
+	}
+
+	if message.ParentMessageID == nil {
+		errorResponse(c, &APIError{
+			Status:  http.StatusBadRequest,
+			Message: "message is not a comparison candidate",
+			Code:    "NOT_A_CANDIDATE",
+		})
+		return
+	}
+
+	if message.ModerationStatus != "" {
+		errorResponse(c, &APIError{
+			Status:  http.StatusConflict,
+			Message: "candidate is blocked by moderation and cannot be selected",
+			Code:    "CANDIDATE_BLOCKED",
+		})
+		return
+	}
+
+	if err := app.models.Messages.SelectCandidate(messageID); err != nil {
+		app.logger.Error("Error selecting candidate", "error", err, "chat_id", chatID, "message_id", messageID)
+		internalErrorResponse(c, err)
+		return
+	}
+
+	app.models.Chats.UpdateUpdatedAt(chatID)
+
+	selected, err := app.models.Messages.GetByID(messageID)
+	if err != nil {
+		internalErrorResponse(c, err)
+		return
+	}
+
+	app.realtime.Publish(userID, realtime.EventCandidateSelected, chatID, newMessageResponse(selected, false))
+
+	c.JSON(http.StatusOK, gin.H{
+		"selected_message": newMessageResponse(selected, false),
+	})
+}
+
+// checkNoPendingCandidates не дает продолжить диалог, пока не выбран один из ответов-кандидатов
+func (app *application) checkNoPendingCandidates(chatID int) *APIError {
+	pending, err := app.models.Messages.HasPendingCandidates(chatID)
+	if err != nil {
+		app.logger.Error("Error checking pending candidates", "error", err, "chat_id", chatID)
+		return nil
+	}
+	if pending {
+		return ErrCandidateSelectionRequired
+	}
+	return nil
+}
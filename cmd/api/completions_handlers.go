@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"mindforge/internal/ai"
+	"mindforge/internal/database"
+
+	"github.com/gin-gonic/gin"
+)
+
+type completionMessage struct {
+	Role    string `json:"role" binding:"required,oneof=system user assistant"`
+	Content string `json:"content" binding:"required,min=1"`
+}
+
+type createCompletionRequest struct {
+	Model          string              `json:"model" binding:"required"`
+	Messages       []completionMessage `json:"messages" binding:"required,min=1,max=100,dive"`
+	ResponseFormat *ai.ResponseFormat  `json:"response_format"`
+	Temperature    *float64            `json:"temperature" binding:"omitempty,min=0,max=2"`
+	TopP           *float64            `json:"top_p" binding:"omitempty,gt=0,max=1"`
+	MaxTokens      int                 `json:"max_tokens" binding:"omitempty,min=1,max=32768"`
+	Stream         bool                `json:"stream"`
+	// NoCache - не использовать кэш ответов (аналог заголовка Cache-Control: no-cache)
+	NoCache bool `json:"no_cache"`
+}
+
+// handleCreateCompletion выполняет разовый запрос к модели без сохранения истории
+// Расход токенов учитывается так же, как в чатах, и входит в ту же дневную квоту.
+// Поддерживает response_format: при json_object/json_schema ответ проверяется перед отправкой
+func (app *application) handleCreateCompletion(c *gin.Context) {
+	userID, apiErr := getUserIDFromContext(c)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	var req createCompletionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		validationErrorResponse(c, err)
+		return
+	}
+
+	if err := req.ResponseFormat.Validate(); err != nil {
+		errorResponse(c, &APIError{
+			Status:  http.StatusBadRequest,
+			Message: err.Error(),
+			Code:    "INVALID_RESPONSE_FORMAT",
+		})
+		return
+	}
+
+	if apiErr := app.checkTokenQuota(userID); apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	if apiErr := app.validateAIModel(req.Model); apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	// Разовый запрос не подменяет неизвестную модель моделью провайдера по умолчанию
+	listCtx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	info, found := app.findAvailableModel(listCtx, req.Model)
+	cancel()
+	if !found {
+		app.logger.Warn("Unknown AI model", "model", req.Model)
+		errorResponse(c, ErrInvalidAIModel)
+		return
+	}
+	providerName, model := info.Provider, info.ID
+	provider, err := app.aiProviderFactory.Get(providerName)
+	if err != nil {
+		errorResponse(c, ErrInvalidAIModel)
+		return
+	}
+
+	messages := make([]ai.Message, len(req.Messages))
+	for i, msg := range req.Messages {
+		messages[i] = ai.Message{Role: msg.Role, Content: msg.Content}
+	}
+
+	if apiErr := app.moderateInput(c.Request.Context(), userID, nil, promptText(messages)); apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	aiReq := ai.ChatRequest{
+		Model:          model,
+		Messages:       messages,
+		ResponseFormat: req.ResponseFormat,
+		Temperature:    req.Temperature,
+		TopP:           req.TopP,
+		MaxTokens:      req.MaxTokens,
+		NoCache:        req.NoCache || noCacheRequested(c),
+	}
+
+	if req.Stream {
+		app.streamCompletion(c, userID, providerName, provider, aiReq)
+		return
+	}
+
+	extendWriteDeadline(c, 2*time.Minute+10*time.Second)
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Minute)
+	defer cancel()
+	ctx = ai.WithRequestMeta(ctx, ai.RequestMeta{UserID: userID})
+
+	aiResp, err := ai.ChatStructured(ctx, provider, aiReq)
+	if err != nil {
+		// Неудачные попытки JSON-режима тоже расходуют токены
+		if aiResp != nil {
+			app.recordUsage(userID, nil, database.UsageSourceCompletion, providerName, aiResp)
+		}
+		app.logger.Error("Error getting AI completion", "error", err, "provider", providerName, "user_id", userID)
+		errorResponse(c, completionError(err))
+		return
+	}
+
+	app.recordUsage(userID, nil, database.UsageSourceCompletion, providerName, aiResp)
+
+	if apiErr := app.moderateOutput(ctx, userID, aiResp.Content); apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	c.JSON(http.StatusOK, aiResp)
+}
+
+// streamCompletion передает ответ модели через SSE
+// События: reasoning и content (порции), done (итоговый ответ с usage), error
+// JSON-режим не стримится: проверенный ответ приходит одним событием content. Если включена модерация,
+// ответ тоже приходит одной порцией после проверки, заблокированный - событием error (CONTENT_BLOCKED)
+func (app *application) streamCompletion(c *gin.Context, userID int, providerName string, provider ai.Provider, aiReq ai.ChatRequest) {
+	// В отличие от чатов, ответ нигде не сохраняется, поэтому генерация прерывается вместе с запросом
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Minute)
+	defer cancel()
+	ctx = ai.WithRequestMeta(ctx, ai.RequestMeta{UserID: userID})
+
+	startSSE(c, 2*time.Minute+10*time.Second)
+
+	// Ответ, который еще не прошел модерацию, клиенту не показывается
+	buffered := app.moderator != nil
+
+	onChunk := func(chunk ai.StreamChunk) error {
+		if buffered {
+			return nil
+		}
+		if chunk.ReasoningDelta != "" {
+			if err := writeSSE(c, "reasoning", gin.H{"delta": chunk.ReasoningDelta}); err != nil {
+				return err
+			}
+		}
+		if chunk.ContentDelta != "" {
+			if err := writeSSE(c, "content", gin.H{"delta": chunk.ContentDelta}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var aiResp *ai.ChatResponse
+	var err error
+	if aiReq.ResponseFormat.IsJSON() {
+		aiResp, err = ai.ChatStructured(ctx, provider, aiReq)
+		buffered = true
+	} else {
+		aiReq.Stream = true
+		aiResp, err = provider.ChatStream(ctx, aiReq, onChunk)
+	}
+	if err != nil {
+		if aiResp != nil {
+			app.recordUsage(userID, nil, database.UsageSourceCompletion, providerName, aiResp)
+		}
+		app.logger.Error("Error streaming AI completion", "error", err, "provider", providerName, "user_id", userID)
+		writeSSE(c, "error", completionError(err))
+		return
+	}
+
+	app.recordUsage(userID, nil, database.UsageSourceCompletion, providerName, aiResp)
+
+	if apiErr := app.moderateOutput(ctx, userID, aiResp.Content); apiErr != nil {
+		writeSSE(c, "error", apiErr)
+		return
+	}
+
+	// Ответ прошел модерацию: отправляем его целиком
+	if buffered {
+		if aiResp.ReasoningContent != "" {
+			writeSSE(c, "reasoning", gin.H{"delta": aiResp.ReasoningContent})
+		}
+		writeSSE(c, "content", gin.H{"delta": aiResp.Content})
+	}
+
+	writeSSE(c, "done", aiResp)
+}
+
+// completionError преобразует ошибку провайдера в ответ API
+func completionError(err error) *APIError {
+	if errors.Is(err, ai.ErrInvalidStructuredOutput) {
+		return &APIError{
+			Status:  http.StatusBadGateway,
+			Message: "AI response does not match the requested response_format",
+			Code:    "INVALID_STRUCTURED_OUTPUT",
+		}
+	}
+	return &APIError{
+		Status:  http.StatusBadGateway,
+		Message: "failed to generate AI response",
+		Code:    "AI_GENERATION_FAILED",
+	}
+}
@@ -40,6 +40,36 @@ var (
 		Message: "unauthorized",
 		Code:    "UNAUTHORIZED",
 	}
+	ErrTokenQuotaExceeded = &APIError{
+		Status:  http.StatusTooManyRequests,
+		Message: "daily token quota exceeded",
+		Code:    "TOKEN_QUOTA_EXCEEDED",
+	}
+	ErrInvalidAIModel = &APIError{
+		Status:  http.StatusBadRequest,
+		Message: "invalid ai_model or provider not available",
+		Code:    "INVALID_AI_MODEL",
+	}
+	ErrCandidateSelectionRequired = &APIError{
+		Status:  http.StatusConflict,
+		Message: "select one of the compared answers before continuing the conversation",
+		Code:    "CANDIDATE_SELECTION_REQUIRED",
+	}
+	ErrContentBlocked = &APIError{
+		Status:  http.StatusUnprocessableEntity,
+		Message: "content blocked by moderation",
+		Code:    "CONTENT_BLOCKED",
+	}
+	ErrAdminRequired = &APIError{
+		Status:  http.StatusForbidden,
+		Message: "administrator access required",
+		Code:    "ADMIN_REQUIRED",
+	}
+	ErrKnowledgeDisabled = &APIError{
+		Status:  http.StatusServiceUnavailable,
+		Message: "knowledge base is disabled: embeddings provider is not configured",
+		Code:    "KNOWLEDGE_DISABLED",
+	}
 )
 
 // errorResponse отправляет структурированный ответ об ошибке
@@ -0,0 +1,436 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"mindforge/internal/database"
+	"mindforge/internal/export"
+
+	"github.com/gin-gonic/gin"
+)
+
+// exportStaleTimeout - задача экспорта без обновлений прогресса дольше этого времени считается прерванной
+// (сервер остановлен во время экспорта) и не блокирует новый экспорт; прогресс сохраняется каждые 20 чатов
+const exportStaleTimeout = 15 * time.Minute
+
+// errExportTooLarge возвращается при записи архива больше лимита EXPORT_MAX_SIZE_MB
+var errExportTooLarge = errors.New("export archive is too large")
+
+// limitedWriter пишет в w не больше limit байт, после чего возвращает errExportTooLarge
+type limitedWriter struct {
+	w     io.Writer
+	limit int64
+	n     int64
+}
+
+func (l *limitedWriter) Write(p []byte) (int, error) {
+	if l.n+int64(len(p)) > l.limit {
+		return 0, errExportTooLarge
+	}
+	n, err := l.w.Write(p)
+	l.n += int64(n)
+	return n, err
+}
+
+type createExportRequest struct {
+	Format string `json:"format" binding:"omitempty,oneof=markdown md json html"`
+}
+
+type exportJobResponse struct {
+	ID          int     `json:"id"`
+	Format      string  `json:"format"`
+	Status      string  `json:"status"`
+	ChatsCount  int     `json:"chats_count"`
+	SizeBytes   int64   `json:"size_bytes"`
+	Error       string  `json:"error,omitempty"`
+	DownloadURL string  `json:"download_url,omitempty"`
+	CreatedAt   string  `json:"created_at"`
+	CompletedAt *string `json:"completed_at,omitempty"`
+	ExpiresAt   string  `json:"expires_at"`
+}
+
+func newExportJobResponse(job *database.ExportJob) exportJobResponse {
+	response := exportJobResponse{
+		ID:         job.ID,
+		Format:     job.Format,
+		Status:     job.Status,
+		ChatsCount: job.ChatsCount,
+		SizeBytes:  job.SizeBytes,
+		Error:      job.Error,
+		CreatedAt:  job.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		ExpiresAt:  job.ExpiresAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+	if job.CompletedAt != nil {
+		completedAt := job.CompletedAt.Format("2006-01-02T15:04:05Z07:00")
+		response.CompletedAt = &completedAt
+	}
+	if job.Status == database.ExportStatusCompleted {
+		response.DownloadURL = "/api/v1/export/" + strconv.Itoa(job.ID) + "/download"
+	}
+	return response
+}
+
+// buildExportChat собирает переписку чата для экспорта
+// Экспортируется ход диалога: невыбранные ответы-кандидаты и заблокированные ответы пропускаются
+func (app *application) buildExportChat(chat *database.Chat) (*export.Chat, error) {
+	messages, err := app.models.Messages.GetByChatID(chat.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &export.Chat{
+		Title:     chat.Title,
+		AIModel:   chat.AIModel,
+		CreatedAt: chat.CreatedAt,
+		UpdatedAt: chat.UpdatedAt,
+		Messages:  make([]export.Message, 0, len(messages)),
+	}
+	if persona := app.chatPersona(chat); persona != nil {
+		result.Persona = persona.Name
+	}
+
+	for _, msg := range messages {
+		if !msg.InContext() {
+			continue
+		}
+
+		exported := export.Message{
+			Role:      msg.Role,
+			Content:   msg.Content,
+			Model:     msg.Model,
+			CreatedAt: msg.CreatedAt,
+		}
+
+		if len(msg.Metadata) > 0 {
+			var metadata messageMetadata
+			if err := json.Unmarshal(msg.Metadata, &metadata); err == nil {
+				for _, citation := range metadata.Citations {
+					exported.References = append(exported.References, export.Reference{
+						Index:         citation.Index,
+						CollectionID:  citation.CollectionID,
+						DocumentID:    citation.DocumentID,
+						DocumentTitle: citation.DocumentTitle,
+					})
+				}
+			}
+		}
+
+		result.Messages = append(result.Messages, exported)
+	}
+
+	return result, nil
+}
+
+// attachmentDisposition формирует заголовок Content-Disposition для скачивания файла с произвольным именем
+func attachmentDisposition(fileName string) string {
+	return "attachment; filename*=UTF-8''" + url.PathEscape(fileName)
+}
+
+// handleExportChat отдает переписку чата файлом (?format=markdown|json|html, по умолчанию markdown)
+func (app *application) handleExportChat(c *gin.Context) {
+	userID, apiErr := getUserIDFromContext(c)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	chatID, apiErr := getChatIDFromParam(c)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	chat, apiErr := app.validateChatOwnership(c, chatID, userID)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	format, err := export.ParseFormat(c.Query("format"))
+	if err != nil {
+		errorResponse(c, &APIError{
+			Status:  400,
+			Message: "format must be markdown, json or html",
+			Code:    "INVALID_EXPORT_FORMAT",
+		})
+		return
+	}
+
+	exported, err := app.buildExportChat(chat)
+	if err != nil {
+		app.logger.Error("Error building chat export", "error", err, "chat_id", chatID)
+		internalErrorResponse(c, err)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := export.Write(&buf, format, exported); err != nil {
+		app.logger.Error("Error rendering chat export", "error", err, "chat_id", chatID)
+		internalErrorResponse(c, err)
+		return
+	}
+
+	c.Header("Content-Disposition", attachmentDisposition(export.FileName(chat.ID, chat.Title, format)))
+	c.Data(http.StatusOK, format.ContentType(), buf.Bytes())
+}
+
+// handleCreateExport запускает фоновый экспорт всех чатов пользователя в ZIP архив
+// Чаты из корзины не экспортируются; архив доступен для скачивания EXPORT_TTL_HOURS часов
+func (app *application) handleCreateExport(c *gin.Context) {
+	userID, apiErr := getUserIDFromContext(c)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	var req createExportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		validationErrorResponse(c, err)
+		return
+	}
+
+	format, err := export.ParseFormat(req.Format)
+	if err != nil {
+		errorResponse(c, &APIError{
+			Status:  400,
+			Message: "format must be markdown, json or html",
+			Code:    "INVALID_EXPORT_FORMAT",
+		})
+		return
+	}
+
+	// Одновременно выполняется не больше одного экспорта пользователя
+	job, err := app.models.Exports.Create(userID, string(format), time.Now().Add(app.exportTTL), exportStaleTimeout)
+	if errors.Is(err, database.ErrExportInProgress) {
+		errorResponse(c, &APIError{
+			Status:  409,
+			Message: "export is already in progress",
+			Code:    "EXPORT_IN_PROGRESS",
+		})
+		return
+	}
+	if err != nil {
+		app.logger.Error("Error creating export job", "error", err, "user_id", userID)
+		internalErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, newExportJobResponse(job))
+
+	// Сборка архива в фоне (горутина)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				app.logger.Error("Panic in export job", "error", r, "export_id", job.ID)
+				app.models.Exports.Fail(job.ID, "internal error")
+			}
+		}()
+		app.runExportJob(job, format)
+	}()
+}
+
+// runExportJob собирает ZIP архив со всеми чатами пользователя и сохраняет его в задаче
+// Архив больше EXPORT_MAX_SIZE_MB не сохраняется: задача завершается ошибкой
+func (app *application) runExportJob(job *database.ExportJob, format export.Format) {
+	chats, err := app.models.Chats.GetByUserID(job.UserID)
+	if err != nil {
+		app.logger.Error("Error loading chats for export", "error", err, "export_id", job.ID)
+		app.models.Exports.Fail(job.ID, "failed to load chats")
+		return
+	}
+
+	var buf bytes.Buffer
+	archive := export.NewArchive(&limitedWriter{w: &buf, limit: app.exportMaxBytes})
+	for i, chat := range chats {
+		exported, err := app.buildExportChat(chat)
+		if err == nil {
+			err = archive.Add(export.FileName(chat.ID, chat.Title, format), format, exported)
+		}
+		if errors.Is(err, errExportTooLarge) {
+			app.failLargeExport(job)
+			return
+		}
+		if err != nil {
+			app.logger.Error("Error exporting chat", "error", err, "export_id", job.ID, "chat_id", chat.ID)
+			app.models.Exports.Fail(job.ID, "failed to export chat "+strconv.Itoa(chat.ID))
+			return
+		}
+
+		if (i+1)%20 == 0 {
+			app.models.Exports.UpdateProgress(job.ID, i+1)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		if errors.Is(err, errExportTooLarge) {
+			app.failLargeExport(job)
+			return
+		}
+		app.models.Exports.Fail(job.ID, "failed to build archive")
+		return
+	}
+
+	if err := app.models.Exports.Complete(job.ID, len(chats), buf.Bytes()); err != nil {
+		app.logger.Error("Error saving export archive", "error", err, "export_id", job.ID)
+		app.models.Exports.Fail(job.ID, "failed to save archive")
+		return
+	}
+
+	app.logger.Info("Export completed", "export_id", job.ID, "user_id", job.UserID, "chats", len(chats), "size_bytes", buf.Len())
+}
+
+// failLargeExport завершает ошибкой задачу, архив которой превысил лимит размера
+func (app *application) failLargeExport(job *database.ExportJob) {
+	app.logger.Warn("Export archive exceeds size limit", "export_id", job.ID, "user_id", job.UserID, "limit_bytes", app.exportMaxBytes)
+	app.models.Exports.Fail(job.ID, fmt.Sprintf("export archive exceeds the size limit of %d MB", app.exportMaxBytes>>20))
+}
+
+// validateExportOwnership проверяет принадлежность задачи экспорта пользователю
+// Истекшие задачи считаются удаленными
+func (app *application) validateExportOwnership(exportID, userID int) (*database.ExportJob, *APIError) {
+	job, err := app.models.Exports.GetByID(exportID)
+	if err != nil {
+		if err.Error() == "export job not found" {
+			return nil, &APIError{
+				Status:  404,
+				Message: "export not found",
+				Code:    "EXPORT_NOT_FOUND",
+			}
+		}
+		return nil, &APIError{
+			Status:  500,
+			Message: "internal server error",
+			Code:    "INTERNAL_ERROR",
+		}
+	}
+
+	if job.UserID != userID {
+		return nil, &APIError{
+			Status:  403,
+			Message: "forbidden",
+			Code:    "FORBIDDEN",
+		}
+	}
+
+	if !job.ExpiresAt.After(time.Now()) {
+		return nil, &APIError{
+			Status:  410,
+			Message: "export has expired",
+			Code:    "EXPORT_EXPIRED",
+		}
+	}
+
+	return job, nil
+}
+
+// handleGetExports получает неистекшие задачи экспорта пользователя
+func (app *application) handleGetExports(c *gin.Context) {
+	userID, apiErr := getUserIDFromContext(c)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	jobs, err := app.models.Exports.GetByUserID(userID)
+	if err != nil {
+		app.logger.Error("Error getting export jobs", "error", err, "user_id", userID)
+		internalErrorResponse(c, err)
+		return
+	}
+
+	response := make([]exportJobResponse, len(jobs))
+	for i, job := range jobs {
+		response[i] = newExportJobResponse(job)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// handleGetExport получает статус задачи экспорта
+func (app *application) handleGetExport(c *gin.Context) {
+	userID, apiErr := getUserIDFromContext(c)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	exportID, apiErr := getIDFromParam(c, "id", "export", "INVALID_EXPORT_ID")
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	job, apiErr := app.validateExportOwnership(exportID, userID)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	c.JSON(http.StatusOK, newExportJobResponse(job))
+}
+
+// handleDownloadExport отдает готовый ZIP архив
+func (app *application) handleDownloadExport(c *gin.Context) {
+	userID, apiErr := getUserIDFromContext(c)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	exportID, apiErr := getIDFromParam(c, "id", "export", "INVALID_EXPORT_ID")
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	job, apiErr := app.validateExportOwnership(exportID, userID)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	if job.Status != database.ExportStatusCompleted {
+		errorResponse(c, &APIError{
+			Status:  409,
+			Message: "export is not ready",
+			Code:    "EXPORT_NOT_READY",
+		})
+		return
+	}
+
+	archive, err := app.models.Exports.GetArchive(exportID)
+	if err != nil {
+		app.logger.Error("Error loading export archive", "error", err, "export_id", exportID)
+		internalErrorResponse(c, err)
+		return
+	}
+
+	fileName := "mindforge-export-" + job.CreatedAt.UTC().Format("2006-01-02") + ".zip"
+	c.Header("Content-Disposition", attachmentDisposition(fileName))
+	c.Data(http.StatusOK, "application/zip", archive)
+}
+
+// purgeExpiredExports периодически удаляет истекшие задачи экспорта вместе с архивами
+func purgeExpiredExports(exports database.ExportJobModel, logger *slog.Logger) {
+	ticker := time.NewTicker(10 * time.Minute)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		deleted, err := exports.DeleteExpired()
+		if err != nil {
+			logger.Warn("Error purging expired exports", "error", err)
+			continue
+		}
+		if deleted > 0 {
+			logger.Debug("Expired exports purged", "deleted", deleted)
+		}
+	}
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+
+	"mindforge/internal/database"
+	"mindforge/internal/realtime"
+
+	"github.com/gin-gonic/gin"
+)
+
+type createFolderRequest struct {
+	Name     string `json:"name" binding:"required,min=1,max=200"`
+	ParentID *int   `json:"parent_id"`
+}
+
+// updateFolderRequest - частичное обновление папки; parent_id=0 переносит папку на верхний уровень
+type updateFolderRequest struct {
+	Name     *string `json:"name" binding:"omitempty,min=1,max=200"`
+	ParentID *int    `json:"parent_id" binding:"omitempty,min=0"`
+}
+
+type folderResponse struct {
+	ID         int    `json:"id"`
+	ParentID   *int   `json:"parent_id"`
+	Name       string `json:"name"`
+	ChatsCount int    `json:"chats_count"`
+	CreatedAt  string `json:"created_at"`
+	UpdatedAt  string `json:"updated_at"`
+}
+
+func newFolderResponse(folder *database.Folder) folderResponse {
+	return folderResponse{
+		ID:         folder.ID,
+		ParentID:   folder.ParentID,
+		Name:       folder.Name,
+		ChatsCount: folder.ChatsCount,
+		CreatedAt:  folder.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		UpdatedAt:  folder.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+}
+
+// validateFolderOwnership проверяет принадлежность папки пользователю
+func (app *application) validateFolderOwnership(folderID, userID int) (*database.Folder, *APIError) {
+	folder, err := app.models.Folders.GetByID(folderID)
+	if err != nil {
+		if err.Error() == "folder not found" {
+			return nil, &APIError{
+				Status:  404,
+				Message: "folder not found",
+				Code:    "FOLDER_NOT_FOUND",
+			}
+		}
+		return nil, &APIError{
+			Status:  500,
+			Message: "internal server error",
+			Code:    "INTERNAL_ERROR",
+		}
+	}
+
+	if folder.UserID != userID {
+		return nil, &APIError{
+			Status:  403,
+			Message: "forbidden",
+			Code:    "FORBIDDEN",
+		}
+	}
+
+	return folder, nil
+}
+
+// handleCreateFolder создает папку (parent_id - вложенная папка)
+func (app *application) handleCreateFolder(c *gin.Context) {
+	userID, apiErr := getUserIDFromContext(c)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	var req createFolderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		validationErrorResponse(c, err)
+		return
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		errorResponse(c, &APIError{
+			Status:  400,
+			Message: "name cannot be empty",
+			Code:    "VALIDATION_ERROR",
+		})
+		return
+	}
+
+	if req.ParentID != nil {
+		if _, apiErr := app.validateFolderOwnership(*req.ParentID, userID); apiErr != nil {
+			errorResponse(c, apiErr)
+			return
+		}
+	}
+
+	folder, err := app.models.Folders.Insert(&database.Folder{
+		UserID:   userID,
+		ParentID: req.ParentID,
+		Name:     name,
+	})
+	if err != nil {
+		app.logger.Error("Error creating folder", "error", err, "user_id", userID)
+		internalErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, newFolderResponse(folder))
+}
+
+// handleGetFolders получает все папки пользователя; дерево строится клиентом по parent_id
+func (app *application) handleGetFolders(c *gin.Context) {
+	userID, apiErr := getUserIDFromContext(c)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	folders, err := app.models.Folders.GetByUserID(userID)
+	if err != nil {
+		app.logger.Error("Error getting folders", "error", err, "user_id", userID)
+		internalErrorResponse(c, err)
+		return
+	}
+
+	response := make([]folderResponse, len(folders))
+	for i, folder := range folders {
+		response[i] = newFolderResponse(folder)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// handleUpdateFolder переименовывает папку и/или переносит ее в другую папку
+func (app *application) handleUpdateFolder(c *gin.Context) {
+	userID, apiErr := getUserIDFromContext(c)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	folderID, apiErr := getIDFromParam(c, "id", "folder", "INVALID_FOLDER_ID")
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	folder, apiErr := app.validateFolderOwnership(folderID, userID)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	var req updateFolderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		validationErrorResponse(c, err)
+		return
+	}
+
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			errorResponse(c, &APIError{
+				Status:  400,
+				Message: "name cannot be empty",
+				Code:    "VALIDATION_ERROR",
+			})
+			return
+		}
+		folder.Name = name
+	}
+
+	if req.ParentID != nil {
+		folder.ParentID = nil
+		if *req.ParentID != 0 {
+			if _, apiErr := app.validateFolderOwnership(*req.ParentID, userID); apiErr != nil {
+				errorResponse(c, apiErr)
+				return
+			}
+
+			// Папку нельзя перенести в саму себя или в свою подпапку
+			cycle, err := app.models.Folders.IsInSubtree(folderID, *req.ParentID)
+			if err != nil {
+				app.logger.Error("Error checking folder tree", "error", err, "folder_id", folderID)
+				internalErrorResponse(c, err)
+				return
+			}
+			if cycle {
+				errorResponse(c, &APIError{
+					Status:  400,
+					Message: "folder cannot be moved into itself or its subfolder",
+					Code:    "INVALID_FOLDER_PARENT",
+				})
+				return
+			}
+			folder.ParentID = req.ParentID
+		}
+	}
+
+	updated, err := app.models.Folders.Update(folder)
+	if err != nil {
+		app.logger.Error("Error updating folder", "error", err, "folder_id", folderID)
+		internalErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newFolderResponse(updated))
+}
+
+// handleDeleteFolder удаляет папку вместе с подпапками
+// Чаты по умолчанию переносятся в корень; ?delete_chats=true перемещает их в корзину
+func (app *application) handleDeleteFolder(c *gin.Context) {
+	userID, apiErr := getUserIDFromContext(c)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	folderID, apiErr := getIDFromParam(c, "id", "folder", "INVALID_FOLDER_ID")
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	if _, apiErr := app.validateFolderOwnership(folderID, userID); apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	trashChats := c.Query("delete_chats") == "true"
+	chatIDs, err := app.models.Folders.Delete(folderID, trashChats)
+	if err != nil {
+		app.logger.Error("Error deleting folder", "error", err, "folder_id", folderID)
+		internalErrorResponse(c, err)
+		return
+	}
+
+	for _, chatID := range chatIDs {
+		if trashChats {
+			app.realtime.Publish(userID, realtime.EventChatDeleted, chatID, chatDeletedEvent{Permanent: false})
+		} else {
+			app.publishChatUpdated(chatID)
+		}
+	}
+
+	message := "folder deleted, chats moved to root"
+	if trashChats {
+		message = "folder deleted, chats moved to trash"
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": message,
+	})
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"net/http"
+	"time"
+	"unicode/utf8"
+
+	"mindforge/internal/database"
+	"mindforge/internal/realtime"
+
+	"github.com/gin-gonic/gin"
+)
+
+// forkChatRequest - from_message_id ограничивает копируемую историю; без него копируется весь диалог
+type forkChatRequest struct {
+	FromMessageID *int `json:"from_message_id" binding:"omitempty,min=1"`
+}
+
+// bindForkRequest разбирает необязательное тело запроса ответвления
+func bindForkRequest(c *gin.Context) (forkChatRequest, bool) {
+	var req forkChatRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			validationErrorResponse(c, err)
+			return req, false
+		}
+	}
+	return req, true
+}
+
+// forkHistory обрезает историю по сообщению fromMessageID (включительно)
+// Сообщение должно входить в контекст диалога: невыбранных кандидатов и заблокированные ответы ветвить нельзя
+func forkHistory(messages []*database.Message, fromMessageID *int) ([]*database.Message, *APIError) {
+	if fromMessageID == nil {
+		return messages, nil
+	}
+
+	for i, msg := range messages {
+		if msg.ID == *fromMessageID {
+			return messages[:i+1], nil
+		}
+	}
+
+	return nil, &APIError{
+		Status:  http.StatusNotFound,
+		Message: "message not found",
+		Code:    "MESSAGE_NOT_FOUND",
+	}
+}
+
+// forkTitle формирует название ответвления
+func forkTitle(title string) string {
+	const suffix = " (ветка)"
+	maxLen := 200 - utf8.RuneCountInString(suffix)
+	if utf8.RuneCountInString(title) > maxLen {
+		title = string([]rune(title)[:maxLen])
+	}
+	return title + suffix
+}
+
+// handleForkChat создает новый чат с настройками и историей чата пользователя до from_message_id
+// Исходный чат не меняется; ответвление хранит ссылку на исходный чат и сообщение
+func (app *application) handleForkChat(c *gin.Context) {
+	userID, apiErr := getUserIDFromContext(c)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	chatID, apiErr := getChatIDFromParam(c)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	source, apiErr := app.validateChatOwnership(c, chatID, userID)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	req, ok := bindForkRequest(c)
+	if !ok {
+		return
+	}
+
+	allMessages, err := app.models.Messages.GetByChatID(chatID)
+	if err != nil {
+		app.logger.Error("Error getting messages", "error", err, "chat_id", chatID)
+		internalErrorResponse(c, err)
+		return
+	}
+
+	history := make([]*database.Message, 0, len(allMessages))
+	for _, msg := range allMessages {
+		if msg.InContext() {
+			history = append(history, msg)
+		}
+	}
+
+	history, apiErr = forkHistory(history, req.FromMessageID)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	// Копии сообщений без связей с кандидатами исходного чата
+	messages := make([]*database.Message, len(history))
+	for i, msg := range history {
+		messages[i] = &database.Message{
+			Role:             msg.Role,
+			Content:          msg.Content,
+			ReasoningContent: msg.ReasoningContent,
+			Metadata:         msg.Metadata,
+			Model:            msg.Model,
+			CreatedAt:        msg.CreatedAt,
+		}
+	}
+
+	fromMessageID := req.FromMessageID
+	if fromMessageID == nil && len(history) > 0 {
+		fromMessageID = &history[len(history)-1].ID
+	}
+
+	now := time.Now()
+	fork, err := app.models.Chats.InsertWithHistory(&database.Chat{
+		UserID:              userID,
+		AIModel:             source.AIModel,
+		Title:               forkTitle(source.Title),
+		ResponseFormat:      source.ResponseFormat,
+		PersonaID:           source.PersonaID,
+		FolderID:            source.FolderID,
+		ForkedFromChatID:    &source.ID,
+		ForkedFromMessageID: fromMessageID,
+		CreatedAt:           now,
+		UpdatedAt:           now,
+	}, messages)
+	if err != nil {
+		app.logger.Error("Error forking chat", "error", err, "chat_id", chatID)
+		internalErrorResponse(c, err)
+		return
+	}
+
+	// Ответвление использует те же базы знаний, что и исходный чат
+	collections, err := app.models.Collections.GetByChatID(chatID)
+	if err != nil {
+		app.logger.Error("Error getting chat collections", "error", err, "chat_id", chatID)
+	} else if len(collections) > 0 {
+		collectionIDs := make([]int, len(collections))
+		for i, collection := range collections {
+			collectionIDs[i] = collection.ID
+		}
+		if err := app.models.Collections.SetChatCollections(fork.ID, collectionIDs); err != nil {
+			app.logger.Error("Error copying chat collections", "error", err, "chat_id", fork.ID)
+		}
+	}
+
+	app.publishChat(realtime.EventChatCreated, fork)
+
+	c.JSON(http.StatusCreated, newChatResponse(fork))
+}
+
+// handleForkSharedChat создает чат пользователя из публичного снимка чата
+// Копируются только опубликованные данные: модель и сообщения снимка; персона, базы знаний,
+// рассуждения и служебные данные владельца не переносятся
+func (app *application) handleForkSharedChat(c *gin.Context) {
+	userID, apiErr := getUserIDFromContext(c)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	share, apiErr := app.findShare(c.Param("token"))
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	req, ok := bindForkRequest(c)
+	if !ok {
+		return
+	}
+
+	// Модель снимка может быть недоступна в текущей конфигурации провайдеров
+	if apiErr := app.validateAIModel(share.AIModel); apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	snapshot, err := app.sharedSnapshot(share)
+	if err != nil {
+		app.logger.Error("Error loading shared chat", "error", err, "share_id", share.ID)
+		internalErrorResponse(c, err)
+		return
+	}
+
+	snapshot, apiErr = forkHistory(snapshot, req.FromMessageID)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	messages := make([]*database.Message, len(snapshot))
+	for i, msg := range snapshot {
+		messages[i] = &database.Message{
+			Role:      msg.Role,
+			Content:   msg.Content,
+			Model:     msg.Model,
+			CreatedAt: msg.CreatedAt,
+		}
+	}
+
+	now := time.Now()
+	fork, err := app.models.Chats.InsertWithHistory(&database.Chat{
+		UserID:            userID,
+		AIModel:           share.AIModel,
+		Title:             forkTitle(share.Title),
+		ForkedFromShareID: &share.ID,
+		CreatedAt:         now,
+		UpdatedAt:         now,
+	}, messages)
+	if err != nil {
+		app.logger.Error("Error forking shared chat", "error", err, "share_id", share.ID)
+		internalErrorResponse(c, err)
+		return
+	}
+
+	app.publishChat(realtime.EventChatCreated, fork)
+
+	c.JSON(http.StatusCreated, newChatResponse(fork))
+}
@@ -360,3 +360,16 @@ func (app *application) handleHealth(c *gin.Context) {
 		"message": "service is running",
 	})
 }
+
+// handleMetrics возвращает метрики запросов к AI провайдерам (не требует аутентификации)
+// Endpoint не проксируется через nginx и доступен только внутри сети
+func (app *application) handleMetrics(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"providers": app.aiProviderFactory.List(),
+		"ai":        app.aiMetrics.Snapshot(),
+		"realtime": gin.H{
+			"broker":      app.realtime.BrokerName(),
+			"connections": app.realtime.Connections(),
+		},
+	})
+}
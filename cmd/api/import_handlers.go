@@ -0,0 +1,386 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"mindforge/internal/ai"
+	"mindforge/internal/chatimport"
+	"mindforge/internal/database"
+	"mindforge/internal/env"
+	"mindforge/internal/realtime"
+
+	"github.com/gin-gonic/gin"
+)
+
+// importStaleTimeout - задача импорта без обновлений прогресса дольше этого времени считается прерванной
+// (сервер остановлен во время импорта) и не блокирует новый импорт; прогресс сохраняется каждые 20 диалогов
+const importStaleTimeout = 15 * time.Minute
+
+// importOptions - параметры импорта, общие для всех диалогов файла
+type importOptions struct {
+	// aiModel - модель импортированных чатов; пусто - модель из источника, если она поддерживается
+	aiModel  string
+	folderID *int
+}
+
+type importJobResponse struct {
+	ID          int                     `json:"id"`
+	FileName    string                  `json:"file_name"`
+	Source      string                  `json:"source,omitempty"`
+	Status      string                  `json:"status"`
+	Total       int                     `json:"total"`
+	Imported    int                     `json:"imported"`
+	Failed      int                     `json:"failed"`
+	Results     []database.ImportResult `json:"results,omitempty"`
+	Error       string                  `json:"error,omitempty"`
+	CreatedAt   string                  `json:"created_at"`
+	CompletedAt *string                 `json:"completed_at,omitempty"`
+}
+
+func newImportJobResponse(job *database.ImportJob) importJobResponse {
+	response := importJobResponse{
+		ID:        job.ID,
+		FileName:  job.FileName,
+		Source:    job.Source,
+		Status:    job.Status,
+		Total:     job.Total,
+		Imported:  job.Imported,
+		Failed:    job.Failed,
+		Error:     job.Error,
+		CreatedAt: job.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+	if len(job.Results) > 0 {
+		json.Unmarshal(job.Results, &response.Results)
+	}
+	if job.CompletedAt != nil {
+		completedAt := job.CompletedAt.Format("2006-01-02T15:04:05Z07:00")
+		response.CompletedAt = &completedAt
+	}
+	return response
+}
+
+// handleCreateImport принимает файл экспорта и запускает фоновый импорт диалогов
+// Файл передается в поле file (multipart/form-data) или телом запроса (application/json, application/zip);
+// необязательные поля ai_model и folder_id задают модель и папку импортированных чатов
+func (app *application) handleCreateImport(c *gin.Context) {
+	userID, apiErr := getUserIDFromContext(c)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	maxBytes := env.GetEnvInt("IMPORT_MAX_BYTES", 50<<20)
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, int64(maxBytes)+4096)
+
+	tooLarge := &APIError{
+		Status:  http.StatusRequestEntityTooLarge,
+		Message: "import file is too large",
+		Code:    "IMPORT_TOO_LARGE",
+	}
+
+	var data []byte
+	var fileName string
+	if strings.HasPrefix(c.ContentType(), "multipart/form-data") {
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				errorResponse(c, tooLarge)
+				return
+			}
+			validationErrorResponse(c, err)
+			return
+		}
+		if fileHeader.Size > int64(maxBytes) {
+			errorResponse(c, tooLarge)
+			return
+		}
+
+		file, err := fileHeader.Open()
+		if err != nil {
+			validationErrorResponse(c, err)
+			return
+		}
+		data, err = io.ReadAll(file)
+		file.Close()
+		if err != nil {
+			validationErrorResponse(c, err)
+			return
+		}
+		fileName = filepath.Base(fileHeader.Filename)
+	} else {
+		var err error
+		data, err = io.ReadAll(c.Request.Body)
+		if err != nil {
+			errorResponse(c, tooLarge)
+			return
+		}
+	}
+
+	if len(data) == 0 {
+		errorResponse(c, &APIError{
+			Status:  400,
+			Message: "import file is empty",
+			Code:    "VALIDATION_ERROR",
+		})
+		return
+	}
+	if len(data) > maxBytes {
+		errorResponse(c, tooLarge)
+		return
+	}
+	if utf8.RuneCountInString(fileName) > 255 {
+		fileName = string([]rune(fileName)[:255])
+	}
+
+	var opts importOptions
+	if aiModel := strings.TrimSpace(c.Request.FormValue("ai_model")); aiModel != "" {
+		if apiErr := app.validateAIModel(aiModel); apiErr != nil {
+			errorResponse(c, apiErr)
+			return
+		}
+		opts.aiModel = aiModel
+	}
+	if folder := c.Request.FormValue("folder_id"); folder != "" {
+		folderID, err := strconv.Atoi(folder)
+		if err != nil || folderID <= 0 {
+			errorResponse(c, &APIError{
+				Status:  400,
+				Message: "invalid folder id",
+				Code:    "INVALID_FOLDER_ID",
+			})
+			return
+		}
+		if _, apiErr := app.validateFolderOwnership(folderID, userID); apiErr != nil {
+			errorResponse(c, apiErr)
+			return
+		}
+		opts.folderID = &folderID
+	}
+
+	// Одновременно выполняется не больше одного импорта пользователя
+	job, err := app.models.Imports.Create(userID, fileName, importStaleTimeout)
+	if errors.Is(err, database.ErrImportInProgress) {
+		errorResponse(c, &APIError{
+			Status:  409,
+			Message: "import is already in progress",
+			Code:    "IMPORT_IN_PROGRESS",
+		})
+		return
+	}
+	if err != nil {
+		app.logger.Error("Error creating import job", "error", err, "user_id", userID)
+		internalErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, newImportJobResponse(job))
+
+	// Импорт в фоне (горутина)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				app.logger.Error("Panic in import job", "error", r, "import_id", job.ID)
+				app.models.Imports.Fail(job.ID, "internal error")
+			}
+		}()
+		app.runImportJob(job, data, opts, maxBytes)
+	}()
+}
+
+// runImportJob разбирает файл и создает чаты; ошибка одного диалога не прерывает импорт остальных
+func (app *application) runImportJob(job *database.ImportJob, data []byte, opts importOptions, maxBytes int) {
+	source, conversations, err := chatimport.Parse(data, chatimport.Limits{
+		MaxConversations: env.GetEnvInt("IMPORT_MAX_CONVERSATIONS", 1000),
+		MaxBytes:         int64(maxBytes),
+	})
+	if err != nil {
+		app.logger.Warn("Import file rejected", "error", err, "import_id", job.ID)
+		app.models.Imports.Fail(job.ID, err.Error())
+		return
+	}
+
+	if err := app.models.Imports.Start(job.ID, source, len(conversations)); err != nil {
+		app.logger.Error("Error starting import job", "error", err, "import_id", job.ID)
+	}
+
+	results := make([]database.ImportResult, len(conversations))
+	var imported, failed int
+	for i, conv := range conversations {
+		result := database.ImportResult{Index: i, Title: conv.Title}
+
+		chat, err := app.importConversation(job.UserID, conv, opts)
+		if err != nil {
+			result.Status = "failed"
+			result.Error = err.Error()
+			failed++
+		} else {
+			result.Status = "imported"
+			result.ChatID = chat.ID
+			imported++
+		}
+		results[i] = result
+
+		if (i+1)%20 == 0 {
+			app.models.Imports.UpdateProgress(job.ID, imported, failed)
+		}
+	}
+
+	if err := app.models.Imports.Complete(job.ID, results); err != nil {
+		app.logger.Error("Error completing import job", "error", err, "import_id", job.ID)
+		return
+	}
+
+	app.logger.Info("Import completed",
+		"import_id", job.ID,
+		"user_id", job.UserID,
+		"source", source,
+		"imported", imported,
+		"failed", failed,
+	)
+}
+
+// importConversation создает чат из разобранного диалога
+func (app *application) importConversation(userID int, conv chatimport.Conversation, opts importOptions) (*database.Chat, error) {
+	if conv.Err != nil {
+		return nil, conv.Err
+	}
+	source := conv.Chat
+
+	aiModel := opts.aiModel
+	if aiModel == "" {
+		// Модель источника сохраняется, только если она поддерживается настроенными провайдерами
+		info, known := ai.LookupModel(source.AIModel)
+		if !known || app.validateAIModel(info.ID) != nil {
+			return nil, errors.New("model of the conversation is not available: pass ai_model")
+		}
+		aiModel = info.ID
+	}
+
+	title := strings.TrimSpace(source.Title)
+	if title == "" {
+		title = "Импортированный чат"
+	}
+	if utf8.RuneCountInString(title) > 200 {
+		title = string([]rune(title)[:200])
+	}
+
+	now := time.Now()
+	messages := make([]*database.Message, 0, len(source.Messages))
+	for _, msg := range source.Messages {
+		if msg.Role != "user" && msg.Role != "assistant" && msg.Role != "system" {
+			continue
+		}
+		if strings.TrimSpace(msg.Content) == "" {
+			continue
+		}
+		createdAt := msg.CreatedAt
+		if createdAt.IsZero() {
+			createdAt = now
+		}
+		messages = append(messages, &database.Message{
+			Role:      msg.Role,
+			Content:   msg.Content,
+			Model:     msg.Model,
+			CreatedAt: createdAt,
+		})
+	}
+	if len(messages) == 0 {
+		return nil, errors.New("conversation has no messages")
+	}
+
+	chat := &database.Chat{
+		UserID:    userID,
+		AIModel:   aiModel,
+		Title:     title,
+		FolderID:  opts.folderID,
+		CreatedAt: source.CreatedAt,
+		UpdatedAt: source.UpdatedAt,
+	}
+	if chat.CreatedAt.IsZero() {
+		chat.CreatedAt = messages[0].CreatedAt
+	}
+	if chat.UpdatedAt.IsZero() {
+		chat.UpdatedAt = messages[len(messages)-1].CreatedAt
+	}
+
+	created, err := app.models.Chats.InsertWithHistory(chat, messages)
+	if err != nil {
+		app.logger.Error("Error importing chat", "error", err, "user_id", userID)
+		return nil, errors.New("failed to save conversation")
+	}
+
+	app.publishChat(realtime.EventChatCreated, created)
+	return created, nil
+}
+
+// handleGetImports получает последние задачи импорта пользователя (без подробных отчетов)
+func (app *application) handleGetImports(c *gin.Context) {
+	userID, apiErr := getUserIDFromContext(c)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	jobs, err := app.models.Imports.GetByUserID(userID)
+	if err != nil {
+		app.logger.Error("Error getting import jobs", "error", err, "user_id", userID)
+		internalErrorResponse(c, err)
+		return
+	}
+
+	response := make([]importJobResponse, len(jobs))
+	for i, job := range jobs {
+		response[i] = newImportJobResponse(job)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// handleGetImport получает статус задачи импорта и отчет по каждому диалогу
+func (app *application) handleGetImport(c *gin.Context) {
+	userID, apiErr := getUserIDFromContext(c)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	importID, apiErr := getIDFromParam(c, "id", "import", "INVALID_IMPORT_ID")
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	job, err := app.models.Imports.GetByID(importID)
+	if err != nil {
+		if err.Error() == "import job not found" {
+			errorResponse(c, &APIError{
+				Status:  404,
+				Message: "import not found",
+				Code:    "IMPORT_NOT_FOUND",
+			})
+			return
+		}
+		internalErrorResponse(c, err)
+		return
+	}
+
+	if job.UserID != userID {
+		errorResponse(c, &APIError{
+			Status:  403,
+			Message: "forbidden",
+			Code:    "FORBIDDEN",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, newImportJobResponse(job))
+}
@@ -0,0 +1,567 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"mindforge/internal/ai"
+	"mindforge/internal/database"
+	"mindforge/internal/env"
+
+	"github.com/gin-gonic/gin"
+)
+
+type createCollectionRequest struct {
+	Name        string `json:"name" binding:"required,min=1,max=200"`
+	Description string `json:"description" binding:"max=2000"`
+}
+
+type collectionResponse struct {
+	ID             int    `json:"id"`
+	Name           string `json:"name"`
+	Description    string `json:"description"`
+	EmbeddingModel string `json:"embedding_model"`
+	DocumentsCount int    `json:"documents_count"`
+	CreatedAt      string `json:"created_at"`
+	UpdatedAt      string `json:"updated_at"`
+}
+
+type createDocumentRequest struct {
+	Title   string `json:"title" binding:"required,min=1,max=255"`
+	Content string `json:"content" binding:"required,min=1"`
+}
+
+type documentResponse struct {
+	ID           int    `json:"id"`
+	CollectionID int    `json:"collection_id"`
+	Title        string `json:"title"`
+	Status       string `json:"status"`
+	Error        string `json:"error,omitempty"`
+	ChunksCount  int    `json:"chunks_count"`
+	CreatedAt    string `json:"created_at"`
+	UpdatedAt    string `json:"updated_at"`
+}
+
+type setChatCollectionsRequest struct {
+	CollectionIDs []int `json:"collection_ids" binding:"required,max=20"`
+}
+
+func newCollectionResponse(collection *database.Collection) collectionResponse {
+	return collectionResponse{
+		ID:             collection.ID,
+		Name:           collection.Name,
+		Description:    collection.Description,
+		EmbeddingModel: collection.EmbeddingModel,
+		DocumentsCount: collection.DocumentsCount,
+		CreatedAt:      collection.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		UpdatedAt:      collection.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+}
+
+func newDocumentResponse(document *database.Document) documentResponse {
+	return documentResponse{
+		ID:           document.ID,
+		CollectionID: document.CollectionID,
+		Title:        document.Title,
+		Status:       document.Status,
+		Error:        document.Error,
+		ChunksCount:  document.ChunksCount,
+		CreatedAt:    document.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		UpdatedAt:    document.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+}
+
+// handleCreateCollection создает коллекцию знаний
+func (app *application) handleCreateCollection(c *gin.Context) {
+	userID, apiErr := getUserIDFromContext(c)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	if app.knowledge == nil {
+		errorResponse(c, ErrKnowledgeDisabled)
+		return
+	}
+
+	var req createCollectionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		validationErrorResponse(c, err)
+		return
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		errorResponse(c, &APIError{
+			Status:  400,
+			Message: "name cannot be empty",
+			Code:    "VALIDATION_ERROR",
+		})
+		return
+	}
+
+	// Модель эмбеддингов фиксируется при создании: векторы разных моделей несравнимы
+	embeddingModel := app.knowledge.Embedder().GetDefaultModel()
+
+	collection, err := app.models.Collections.Create(userID, name, strings.TrimSpace(req.Description), embeddingModel)
+	if err != nil {
+		app.logger.Error("Error creating collection", "error", err, "user_id", userID)
+		internalErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, newCollectionResponse(collection))
+}
+
+// handleGetCollections получает список коллекций пользователя
+func (app *application) handleGetCollections(c *gin.Context) {
+	userID, apiErr := getUserIDFromContext(c)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	collections, err := app.models.Collections.GetByUserID(userID)
+	if err != nil {
+		app.logger.Error("Error getting collections", "error", err, "user_id", userID)
+		internalErrorResponse(c, err)
+		return
+	}
+
+	response := make([]collectionResponse, len(collections))
+	for i, collection := range collections {
+		response[i] = newCollectionResponse(collection)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// handleGetCollection получает коллекцию
+func (app *application) handleGetCollection(c *gin.Context) {
+	userID, apiErr := getUserIDFromContext(c)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	collectionID, apiErr := getIDFromParam(c, "id", "collection", "INVALID_COLLECTION_ID")
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	collection, apiErr := app.validateCollectionOwnership(collectionID, userID)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	c.JSON(http.StatusOK, newCollectionResponse(collection))
+}
+
+// handleDeleteCollection удаляет коллекцию вместе с документами
+func (app *application) handleDeleteCollection(c *gin.Context) {
+	userID, apiErr := getUserIDFromContext(c)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	collectionID, apiErr := getIDFromParam(c, "id", "collection", "INVALID_COLLECTION_ID")
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	if _, apiErr := app.validateCollectionOwnership(collectionID, userID); apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	if err := app.models.Collections.Delete(collectionID); err != nil {
+		app.logger.Error("Error deleting collection", "error", err, "collection_id", collectionID)
+		internalErrorResponse(c, err)
+		return
+	}
+	if app.knowledge != nil {
+		app.knowledge.Invalidate(collectionID)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "collection deleted successfully",
+	})
+}
+
+// handleCreateDocument загружает документ в коллекцию
+// Принимает JSON {"title", "content"} или multipart/form-data с текстовым файлом в поле "file"
+// Нарезка и получение эмбеддингов выполняются в фоне, статус документа - "processing"
+func (app *application) handleCreateDocument(c *gin.Context) {
+	userID, apiErr := getUserIDFromContext(c)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	collectionID, apiErr := getIDFromParam(c, "id", "collection", "INVALID_COLLECTION_ID")
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	collection, apiErr := app.validateCollectionOwnership(collectionID, userID)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+	if app.knowledge == nil {
+		errorResponse(c, ErrKnowledgeDisabled)
+		return
+	}
+
+	maxBytes := env.GetEnvInt("KNOWLEDGE_MAX_DOCUMENT_BYTES", 1<<20)
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, int64(maxBytes)+4096)
+
+	var title, content string
+	if strings.HasPrefix(c.ContentType(), "multipart/form-data") {
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			validationErrorResponse(c, err)
+			return
+		}
+		if fileHeader.Size > int64(maxBytes) {
+			errorResponse(c, &APIError{
+				Status:  http.StatusRequestEntityTooLarge,
+				Message: "document is too large",
+				Code:    "DOCUMENT_TOO_LARGE",
+			})
+			return
+		}
+
+		file, err := fileHeader.Open()
+		if err != nil {
+			validationErrorResponse(c, err)
+			return
+		}
+		data, err := io.ReadAll(file)
+		file.Close()
+		if err != nil {
+			validationErrorResponse(c, err)
+			return
+		}
+
+		title = c.PostForm("title")
+		if title == "" {
+			title = filepath.Base(fileHeader.Filename)
+		}
+		content = string(data)
+	} else {
+		var req createDocumentRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			validationErrorResponse(c, err)
+			return
+		}
+		title = req.Title
+		content = req.Content
+	}
+
+	title = strings.TrimSpace(title)
+	content = strings.TrimSpace(content)
+	if title == "" || content == "" {
+		errorResponse(c, &APIError{
+			Status:  400,
+			Message: "document title and content cannot be empty",
+			Code:    "VALIDATION_ERROR",
+		})
+		return
+	}
+	if len(content) > maxBytes {
+		errorResponse(c, &APIError{
+			Status:  http.StatusRequestEntityTooLarge,
+			Message: "document is too large",
+			Code:    "DOCUMENT_TOO_LARGE",
+		})
+		return
+	}
+	if !utf8.ValidString(content) {
+		errorResponse(c, &APIError{
+			Status:  400,
+			Message: "only UTF-8 text documents are supported",
+			Code:    "UNSUPPORTED_DOCUMENT",
+		})
+		return
+	}
+	if utf8.RuneCountInString(title) > 255 {
+		title = string([]rune(title)[:255])
+	}
+
+	document, err := app.models.Documents.Create(collectionID, title, content)
+	if err != nil {
+		app.logger.Error("Error creating document", "error", err, "collection_id", collectionID)
+		internalErrorResponse(c, err)
+		return
+	}
+	app.models.Collections.Touch(collectionID)
+
+	c.JSON(http.StatusAccepted, newDocumentResponse(document))
+
+	// Индексация документа в фоне (горутина)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				app.logger.Error("Panic in document ingestion", "error", r, "document_id", document.ID)
+			}
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+		defer cancel()
+
+		if err := app.knowledge.Ingest(ctx, document, collection.EmbeddingModel); err != nil {
+			app.logger.Error("Error ingesting document",
+				"error", err,
+				"document_id", document.ID,
+				"collection_id", collectionID,
+			)
+			return
+		}
+
+		app.logger.Info("Document ingested", "document_id", document.ID, "collection_id", collectionID)
+	}()
+}
+
+// handleGetDocuments получает список документов коллекции
+func (app *application) handleGetDocuments(c *gin.Context) {
+	userID, apiErr := getUserIDFromContext(c)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	collectionID, apiErr := getIDFromParam(c, "id", "collection", "INVALID_COLLECTION_ID")
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	if _, apiErr := app.validateCollectionOwnership(collectionID, userID); apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	documents, err := app.models.Documents.GetByCollectionID(collectionID)
+	if err != nil {
+		app.logger.Error("Error getting documents", "error", err, "collection_id", collectionID)
+		internalErrorResponse(c, err)
+		return
+	}
+
+	response := make([]documentResponse, len(documents))
+	for i, document := range documents {
+		response[i] = newDocumentResponse(document)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// handleDeleteDocument удаляет документ из коллекции
+func (app *application) handleDeleteDocument(c *gin.Context) {
+	userID, apiErr := getUserIDFromContext(c)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	collectionID, apiErr := getIDFromParam(c, "id", "collection", "INVALID_COLLECTION_ID")
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	documentID, apiErr := getIDFromParam(c, "document_id", "document", "INVALID_DOCUMENT_ID")
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	if _, apiErr := app.validateCollectionOwnership(collectionID, userID); apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	document, err := app.models.Documents.GetByID(documentID)
+	if err != nil || document.CollectionID != collectionID {
+		errorResponse(c, &APIError{
+			Status:  404,
+			Message: "document not found",
+			Code:    "DOCUMENT_NOT_FOUND",
+		})
+		return
+	}
+
+	if err := app.models.Documents.Delete(documentID); err != nil {
+		app.logger.Error("Error deleting document", "error", err, "document_id", documentID)
+		internalErrorResponse(c, err)
+		return
+	}
+	if app.knowledge != nil {
+		app.knowledge.Invalidate(collectionID)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "document deleted successfully",
+	})
+}
+
+// handleGetChatCollections получает коллекции, привязанные к чату
+func (app *application) handleGetChatCollections(c *gin.Context) {
+	userID, apiErr := getUserIDFromContext(c)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	chatID, apiErr := getChatIDFromParam(c)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	if _, apiErr := app.validateChatOwnership(c, chatID, userID); apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	collections, err := app.models.Collections.GetByChatID(chatID)
+	if err != nil {
+		app.logger.Error("Error getting chat collections", "error", err, "chat_id", chatID)
+		internalErrorResponse(c, err)
+		return
+	}
+
+	response := make([]collectionResponse, len(collections))
+	for i, collection := range collections {
+		response[i] = newCollectionResponse(collection)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// handleSetChatCollections привязывает коллекции к чату (полностью заменяет текущий набор)
+func (app *application) handleSetChatCollections(c *gin.Context) {
+	userID, apiErr := getUserIDFromContext(c)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	chatID, apiErr := getChatIDFromParam(c)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	if _, apiErr := app.validateChatOwnership(c, chatID, userID); apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	var req setChatCollectionsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		validationErrorResponse(c, err)
+		return
+	}
+
+	// Привязывать можно только собственные коллекции
+	for _, collectionID := range req.CollectionIDs {
+		if _, apiErr := app.validateCollectionOwnership(collectionID, userID); apiErr != nil {
+			errorResponse(c, apiErr)
+			return
+		}
+	}
+
+	if err := app.models.Collections.SetChatCollections(chatID, req.CollectionIDs); err != nil {
+		app.logger.Error("Error setting chat collections", "error", err, "chat_id", chatID)
+		internalErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "chat collections updated successfully",
+	})
+}
+
+// knowledgeCitation описывает фрагмент базы знаний, использованный при генерации ответа
+type knowledgeCitation struct {
+	Index         int     `json:"index"`
+	CollectionID  int     `json:"collection_id"`
+	DocumentID    int     `json:"document_id"`
+	DocumentTitle string  `json:"document_title"`
+	ChunkID       int     `json:"chunk_id"`
+	Score         float64 `json:"score"`
+	Snippet       string  `json:"snippet"`
+}
+
+// retrieveKnowledge ищет фрагменты из коллекций, привязанных к чату и его персоне, и формирует системное сообщение
+// Возвращает nil, если к чату не привязаны коллекции или ничего не найдено
+func (app *application) retrieveKnowledge(ctx context.Context, chatID int, persona *database.Persona, query string) (*ai.Message, []knowledgeCitation, error) {
+	collections, err := app.models.Collections.GetByChatID(chatID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if persona != nil && len(persona.CollectionIDs) > 0 {
+		personaCollections, err := app.models.Collections.GetByPersonaID(persona.ID)
+		if err != nil {
+			return nil, nil, err
+		}
+		seen := make(map[int]bool, len(collections))
+		for _, collection := range collections {
+			seen[collection.ID] = true
+		}
+		for _, collection := range personaCollections {
+			if !seen[collection.ID] {
+				collections = append(collections, collection)
+			}
+		}
+	}
+	if len(collections) == 0 || app.knowledge == nil {
+		return nil, nil, nil
+	}
+
+	chunks, err := app.knowledge.Retrieve(ctx, collections, query, env.GetEnvInt("KNOWLEDGE_TOP_K", 4))
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(chunks) == 0 {
+		return nil, nil, nil
+	}
+
+	var b strings.Builder
+	b.WriteString("Используй приведенные ниже фрагменты из базы знаний пользователя, если они относятся к вопросу. ")
+	b.WriteString("Ссылайся на них в ответе в формате [номер]. Если во фрагментах нет ответа, так и скажи.\n")
+
+	citations := make([]knowledgeCitation, len(chunks))
+	for i, chunk := range chunks {
+		fmt.Fprintf(&b, "\n[%d] %s\n%s\n", i+1, chunk.DocumentTitle, chunk.Content)
+
+		snippet := chunk.Content
+		if utf8.RuneCountInString(snippet) > 200 {
+			snippet = string([]rune(snippet)[:200]) + "…"
+		}
+		citations[i] = knowledgeCitation{
+			Index:         i + 1,
+			CollectionID:  chunk.CollectionID,
+			DocumentID:    chunk.DocumentID,
+			DocumentTitle: chunk.DocumentTitle,
+			ChunkID:       chunk.ID,
+			Score:         chunk.Score,
+			Snippet:       snippet,
+		}
+	}
+
+	return &ai.Message{Role: "system", Content: b.String()}, citations, nil
+}
@@ -7,6 +7,10 @@ import (
 	"mindforge/internal/ai"
 	"mindforge/internal/database"
 	"mindforge/internal/env"
+	"mindforge/internal/knowledge"
+	"mindforge/internal/moderation"
+	"mindforge/internal/realtime"
+	"mindforge/internal/secrets"
 	"os"
 	"time"
 
@@ -20,7 +24,19 @@ type application struct {
 	db                *sql.DB
 	models            database.Models
 	aiProviderFactory *ai.ProviderFactory
-	logger            *slog.Logger
+	aiMetrics         *ai.Metrics
+	knowledge         *knowledge.Service
+	// moderator проверяет сообщения и ответы моделей (nil - модерация выключена)
+	moderator moderation.Moderator
+	// dailyTokenQuota - дневной лимит токенов на пользователя (0 - без ограничений)
+	dailyTokenQuota int
+	// exportTTL - время, в течение которого доступен архив экспорта чатов
+	exportTTL time.Duration
+	// exportMaxBytes - максимальный размер архива экспорта
+	exportMaxBytes int64
+	// realtime рассылает события чатов по WebSocket-подключениям пользователей
+	realtime *realtime.Hub
+	logger   *slog.Logger
 }
 
 func main() {
@@ -93,7 +109,68 @@ func main() {
 	)
 
 	models := database.NewModels(db)
-	aiFactory := ai.NewProviderFactory()
+
+	// Секреты провайдеров: переменные окружения, SECRETS_DIR и зашифрованный SECRETS_FILE
+	secretStore, err := secrets.FromEnv()
+	if err != nil {
+		logger.Error("Failed to load secrets", "error", err)
+		os.Exit(1)
+	}
+	logger.Info("Secret sources loaded", "sources", secretStore.Sources())
+
+	aiFactory, err := newProviderFactory(secretStore, logger)
+	if err != nil {
+		logger.Error("Failed to initialize AI providers", "error", err)
+		os.Exit(1)
+	}
+
+	// Перехватчики применяются ко всем провайдерам: логирование запросов и метрики для /metrics
+	aiMetrics := ai.NewMetrics()
+	aiFactory.Use(
+		ai.LoggingInterceptor(logger),
+		ai.MetricsInterceptor(aiMetrics),
+	)
+
+	// Удаление персональных данных ставится перед кэшем: в кэш попадают только запросы и ответы
+	// с плейсхолдерами, а не сами данные
+	// Запрос к базе знаний тоже содержит сообщение пользователя, поэтому данные удаляются и из эмбеддингов
+	redactionInterceptor, embedderRedaction, err := newRedactionInterceptors(models, logger)
+	if err != nil {
+		logger.Error("Failed to initialize PII redaction", "error", err)
+		os.Exit(1)
+	}
+	if redactionInterceptor != nil {
+		aiFactory.Use(redactionInterceptor)
+		aiFactory.UseEmbedder(embedderRedaction)
+	}
+
+	// Кэш ответов ставится после метрик, чтобы попадания учитывались в метриках отдельно
+	cacheInterceptor, err := newResponseCache(models, logger)
+	if err != nil {
+		logger.Error("Failed to initialize AI response cache", "error", err)
+		os.Exit(1)
+	}
+	if cacheInterceptor != nil {
+		aiFactory.Use(cacheInterceptor)
+	}
+
+	moderator, err := newModerator(aiFactory, logger)
+	if err != nil {
+		logger.Error("Failed to initialize moderation", "error", err)
+		os.Exit(1)
+	}
+
+	knowledgeService, err := newKnowledgeService(db, models, aiFactory, logger)
+	if err != nil {
+		logger.Error("Failed to initialize knowledge base", "error", err)
+		os.Exit(1)
+	}
+
+	realtimeHub, err := newRealtimeHub(db, dbDSN, logger)
+	if err != nil {
+		logger.Error("Failed to initialize realtime events", "error", err)
+		os.Exit(1)
+	}
 
 	// JWT_SECRET обязателен для безопасности
 	jwtSecret := env.GetEnvString("JWT_SECRET", "")
@@ -108,11 +185,85 @@ func main() {
 		db:                db,
 		models:            models,
 		aiProviderFactory: aiFactory,
+		aiMetrics:         aiMetrics,
+		knowledge:         knowledgeService,
+		moderator:         moderator,
+		dailyTokenQuota:   env.GetEnvInt("AI_DAILY_TOKEN_QUOTA", 0),
+		exportTTL:         time.Duration(env.GetEnvInt("EXPORT_TTL_HOURS", 24)) * time.Hour,
+		exportMaxBytes:    int64(env.GetEnvInt("EXPORT_MAX_SIZE_MB", 200)) << 20,
+		realtime:          realtimeHub,
 		logger:            logger,
 	}
 
+	// Чаты из корзины удаляются окончательно после CHAT_TRASH_RETENTION_DAYS (0 - хранятся до ручного удаления)
+	if retentionDays := env.GetEnvInt("CHAT_TRASH_RETENTION_DAYS", 30); retentionDays > 0 {
+		go purgeTrashedChats(models.Chats, time.Duration(retentionDays)*24*time.Hour, logger)
+	}
+	go purgeExpiredExports(models.Exports, logger)
+
+	// Импорты, прерванные остановкой сервера, отмечаются как failed
+	if interrupted, err := models.Imports.FailStale(importStaleTimeout); err != nil {
+		logger.Error("Error failing interrupted imports", "error", err)
+	} else if interrupted > 0 {
+		logger.Warn("Interrupted imports marked as failed", "count", interrupted)
+	}
+	if interrupted, err := models.Exports.FailStale(exportStaleTimeout); err != nil {
+		logger.Error("Error failing interrupted exports", "error", err)
+	} else if interrupted > 0 {
+		logger.Warn("Interrupted exports marked as failed", "count", interrupted)
+	}
+
 	if err := app.serve(); err != nil {
 		logger.Error("Server failed", "error", err)
 		os.Exit(1)
 	}
 }
+
+// newKnowledgeService выбирает векторный индекс и создает сервис базы знаний
+// KNOWLEDGE_VECTOR_BACKEND: auto (pgvector, если расширение установлено), pgvector или memory
+// Возвращает nil, если провайдер эмбеддингов не настроен: база знаний отключается
+func newKnowledgeService(db *sql.DB, models database.Models, aiFactory *ai.ProviderFactory, logger *slog.Logger) (*knowledge.Service, error) {
+	embeddingsProvider := env.GetEnvString("EMBEDDINGS_PROVIDER", "openai")
+	embedder, err := aiFactory.GetEmbedder(embeddingsProvider)
+	if err != nil {
+		logger.Warn("Knowledge base disabled: embeddings provider is not configured",
+			"embeddings_provider", embeddingsProvider)
+		return nil, nil
+	}
+
+	backend := env.GetEnvString("KNOWLEDGE_VECTOR_BACKEND", "auto")
+	if backend == "auto" {
+		hasPgVector, err := database.HasPgVector(db)
+		if err != nil {
+			return nil, err
+		}
+		backend = "memory"
+		if hasPgVector {
+			backend = "pgvector"
+		}
+	}
+
+	var index knowledge.VectorIndex
+	switch backend {
+	case "pgvector":
+		index = knowledge.NewPgVectorIndex(models.Documents)
+	case "memory":
+		index = knowledge.NewMemoryIndex(models.Documents)
+	default:
+		return nil, fmt.Errorf("unknown KNOWLEDGE_VECTOR_BACKEND: %s", backend)
+	}
+
+	logger.Info("Knowledge base initialized",
+		"vector_backend", index.Name(),
+		"embeddings_provider", embedder.GetName(),
+		"embeddings_model", embedder.GetDefaultModel(),
+	)
+
+	return knowledge.NewService(
+		models.Documents,
+		embedder,
+		index,
+		env.GetEnvInt("KNOWLEDGE_CHUNK_SIZE", 1000),
+		env.GetEnvInt("KNOWLEDGE_CHUNK_OVERLAP", 200),
+	), nil
+}
@@ -81,3 +81,56 @@ func (app *application) jwtAuthMiddleware() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// apiKeyAuthMiddleware аутентифицирует запросы к OpenAI-совместимому API по ключу MindForge
+// Ключ передается как "Authorization: Bearer mf-...", ошибки возвращаются в формате OpenAI
+func (app *application) apiKeyAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authHeader := c.GetHeader("Authorization")
+		parts := strings.SplitN(authHeader, " ", 2)
+		if len(parts) != 2 || parts[0] != "Bearer" || strings.TrimSpace(parts[1]) == "" {
+			openAIErrorResponse(c, 401, "invalid_request_error", "missing_api_key",
+				"you must provide an API key in the Authorization header (Bearer mf-...)")
+			return
+		}
+
+		apiKey, err := app.models.APIKeys.GetByKey(strings.TrimSpace(parts[1]))
+		if err != nil {
+			openAIErrorResponse(c, 401, "invalid_request_error", "invalid_api_key", "incorrect API key provided")
+			return
+		}
+
+		if err := app.models.APIKeys.TouchLastUsed(apiKey.ID); err != nil {
+			app.logger.Warn("Error updating api key last used time", "error", err, "api_key_id", apiKey.ID)
+		}
+
+		// Сохраняем userID в контексте так же, как jwtAuthMiddleware
+		c.Set("userID", apiKey.UserID)
+		c.Set("apiKeyID", apiKey.ID)
+		c.Next()
+	}
+}
+
+// adminMiddleware пропускает только администраторов (users.is_admin)
+// Используется после jwtAuthMiddleware
+func (app *application) adminMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, apiErr := getUserIDFromContext(c)
+		if apiErr != nil {
+			errorResponse(c, apiErr)
+			return
+		}
+
+		user, err := app.models.Users.GetByID(userID)
+		if err != nil {
+			errorResponse(c, ErrUnauthorized)
+			return
+		}
+		if !user.IsAdmin {
+			errorResponse(c, ErrAdminRequired)
+			return
+		}
+
+		c.Next()
+	}
+}
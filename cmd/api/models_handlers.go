@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"mindforge/internal/ai"
+
+	"github.com/gin-gonic/gin"
+)
+
+// availableModels возвращает модели настроенных провайдеров
+// Если провайдер умеет возвращать список моделей из своего API (ai.ModelLister), используется он,
+// иначе - реестр известных моделей. При ошибке API также используется реестр
+func (app *application) availableModels(ctx context.Context) []ai.ModelInfo {
+	models := make([]ai.ModelInfo, 0)
+	listed := make(map[string]bool)
+
+	for _, name := range app.aiProviderFactory.List() {
+		provider, err := app.aiProviderFactory.Get(name)
+		if err != nil {
+			continue
+		}
+		lister, ok := ai.As[ai.ModelLister](provider)
+		if !ok {
+			continue
+		}
+
+		providerModels, err := lister.ListModels(ctx)
+		if err != nil {
+			app.logger.Warn("Error listing provider models", "error", err, "provider", name)
+			continue
+		}
+		models = append(models, providerModels...)
+		listed[name] = true
+	}
+
+	for _, model := range ai.Models() {
+		if listed[model.Provider] {
+			continue
+		}
+		if _, err := app.aiProviderFactory.Get(model.Provider); err != nil {
+			continue
+		}
+		models = append(models, model)
+	}
+
+	return models
+}
+
+// findAvailableModel ищет модель среди доступных (без учета регистра)
+// В отличие от ai.ResolveModel, неизвестная модель не направляется провайдеру по умолчанию
+func (app *application) findAvailableModel(ctx context.Context, name string) (ai.ModelInfo, bool) {
+	for _, model := range app.availableModels(ctx) {
+		if strings.EqualFold(model.ID, name) {
+			return model, true
+		}
+	}
+	return ai.ModelInfo{}, false
+}
+
+// handleGetModels возвращает модели, доступные для чатов
+func (app *application) handleGetModels(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	c.JSON(http.StatusOK, gin.H{
+		"models": app.availableModels(ctx),
+	})
+}
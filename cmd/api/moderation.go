@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"mindforge/internal/ai"
+	"mindforge/internal/database"
+	"mindforge/internal/env"
+	"mindforge/internal/moderation"
+
+	"github.com/gin-gonic/gin"
+)
+
+// moderationExcerptLength - сколько символов заблокированного текста сохраняется в журнал модерации
+const moderationExcerptLength = 500
+
+// blockedContentPlaceholder сохраняется вместо текста заблокированного ответа модели
+const blockedContentPlaceholder = "Ответ скрыт модерацией"
+
+// newModerator собирает модератора из настроек окружения
+// MODERATION_RULES_FILE - JSON с правилами (ключевые слова и регулярные выражения по языкам),
+// MODERATION_MODEL - модель для дополнительной проверки. Без настроек модерация выключена (nil)
+func newModerator(aiFactory *ai.ProviderFactory, logger *slog.Logger) (moderation.Moderator, error) {
+	var chain moderation.Chain
+
+	if path := env.GetEnvString("MODERATION_RULES_FILE", ""); path != "" {
+		rules, err := moderation.LoadRules(path)
+		if err != nil {
+			return nil, err
+		}
+		moderator, err := moderation.NewRuleModerator(rules)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, moderator)
+		logger.Info("Rule-based moderation enabled", "rules_file", path, "rules", len(rules))
+	}
+
+	if aiModel := env.GetEnvString("MODERATION_MODEL", ""); aiModel != "" {
+		providerName, model := ai.ResolveModel(aiModel)
+		provider, err := aiFactory.Get(providerName)
+		if err != nil {
+			return nil, fmt.Errorf("moderation model %s: provider %s is not configured", aiModel, providerName)
+		}
+		chain = append(chain, moderation.NewModelModerator(provider, model, splitList(env.GetEnvString("MODERATION_CATEGORIES", ""))))
+		logger.Info("Model-based moderation enabled", "model", aiModel)
+	}
+
+	if len(chain) == 0 {
+		return nil, nil
+	}
+	return chain, nil
+}
+
+// moderate проверяет текст и возвращает решение о блокировке или nil, если текст допустим
+// Ошибка модератора не блокирует работу: текст пропускается, ошибка пишется в лог
+func (app *application) moderate(ctx context.Context, stage, text string) *moderation.Verdict {
+	if app.moderator == nil {
+		return nil
+	}
+
+	verdict, err := app.moderator.Moderate(ctx, text)
+	if err != nil {
+		app.logger.Warn("Moderation check failed, content allowed", "error", err, "stage", stage)
+		return nil
+	}
+	if !verdict.Blocked {
+		return nil
+	}
+	return &verdict
+}
+
+// recordModerationEvent сохраняет срабатывание модерации в журнал для администраторов
+func (app *application) recordModerationEvent(userID int, chatID, messageID *int, stage string, verdict *moderation.Verdict, text string) {
+	excerpt := []rune(text)
+	if len(excerpt) > moderationExcerptLength {
+		excerpt = excerpt[:moderationExcerptLength]
+	}
+
+	event := &database.ModerationEvent{
+		UserID:    &userID,
+		ChatID:    chatID,
+		MessageID: messageID,
+		Stage:     stage,
+		Moderator: verdict.Moderator,
+		Category:  verdict.Category,
+		Rule:      verdict.Rule,
+		Excerpt:   string(excerpt),
+	}
+
+	app.logger.Warn("Content blocked by moderation",
+		"stage", stage,
+		"user_id", userID,
+		"moderator", verdict.Moderator,
+		"category", verdict.Category,
+		"rule", verdict.Rule,
+	)
+	if err := app.models.Moderation.Insert(event); err != nil {
+		app.logger.Error("Error recording moderation event", "error", err, "user_id", userID)
+	}
+}
+
+// moderateInput проверяет сообщение пользователя перед отправкой провайдеру
+// Возвращает ErrContentBlocked, если сообщение заблокировано
+func (app *application) moderateInput(ctx context.Context, userID int, chatID *int, text string) *APIError {
+	verdict := app.moderate(ctx, database.ModerationStageInput, text)
+	if verdict == nil {
+		return nil
+	}
+
+	app.recordModerationEvent(userID, chatID, nil, database.ModerationStageInput, verdict, text)
+	return ErrContentBlocked
+}
+
+// moderateOutput проверяет ответ модели на разовый запрос, который нигде не сохраняется
+// Возвращает ErrContentBlocked, если ответ заблокирован
+func (app *application) moderateOutput(ctx context.Context, userID int, text string) *APIError {
+	verdict := app.moderate(ctx, database.ModerationStageOutput, text)
+	if verdict == nil {
+		return nil
+	}
+
+	app.recordModerationEvent(userID, nil, nil, database.ModerationStageOutput, verdict, text)
+	return ErrContentBlocked
+}
+
+// promptText собирает текст всех сообщений запроса для проверки модерацией
+// В разовых запросах системные сообщения и ответы ассистента задает сам клиент,
+// поэтому проверяется весь промпт, который получит провайдер
+func promptText(messages []ai.Message) string {
+	parts := make([]string, len(messages))
+	for i, msg := range messages {
+		parts[i] = msg.Content
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// blockModeratedMessage заменяет текст заблокированного ответа модели заглушкой
+// Исходный текст сохраняется только в журнале модерации
+func blockModeratedMessage(msg *database.Message) {
+	msg.Content = blockedContentPlaceholder
+	msg.ReasoningContent = ""
+	msg.ModerationStatus = database.ModerationStatusContentBlocked
+}
+
+// handleGetModerationEvents возвращает журнал модерации (только для администраторов)
+// Параметры: stage (input/output), limit (по умолчанию 50, максимум 200), offset
+func (app *application) handleGetModerationEvents(c *gin.Context) {
+	stage := c.Query("stage")
+	if stage != "" && stage != database.ModerationStageInput && stage != database.ModerationStageOutput {
+		errorResponse(c, &APIError{
+			Status:  http.StatusBadRequest,
+			Message: "stage must be input or output",
+			Code:    "VALIDATION_ERROR",
+		})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	events, err := app.models.Moderation.List(stage, limit, offset)
+	if err != nil {
+		app.logger.Error("Error getting moderation events", "error", err)
+		internalErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"events": events,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
@@ -0,0 +1,407 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"mindforge/internal/ai"
+	"mindforge/internal/database"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OpenAI-совместимый API (/v1/chat/completions, /v1/models)
+// Позволяет использовать MindForge как шлюз к DeepSeek, GigaChat и Qwen из любых OpenAI-клиентов.
+// Аутентификация - API ключами MindForge, ошибки возвращаются в формате OpenAI
+
+type openAIError struct {
+	Message string `json:"message"`
+	Type    string `json:"type"`
+	Code    string `json:"code,omitempty"`
+}
+
+// openAIContent принимает content как строку или как массив частей [{"type":"text","text":"..."}]
+type openAIContent string
+
+func (c *openAIContent) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*c = openAIContent(text)
+		return nil
+	}
+
+	var parts []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(data, &parts); err != nil {
+		return errors.New("content must be a string or an array of content parts")
+	}
+
+	var b strings.Builder
+	for _, part := range parts {
+		if part.Type != "text" {
+			return fmt.Errorf("unsupported content part type %q", part.Type)
+		}
+		b.WriteString(part.Text)
+	}
+	*c = openAIContent(b.String())
+	return nil
+}
+
+type openAIMessage struct {
+	Role    string        `json:"role"`
+	Content openAIContent `json:"content"`
+}
+
+type openAIChatCompletionRequest struct {
+	Model          string             `json:"model"`
+	Messages       []openAIMessage    `json:"messages"`
+	Stream         bool               `json:"stream"`
+	Temperature    *float64           `json:"temperature"`
+	TopP           *float64           `json:"top_p"`
+	MaxTokens      int                `json:"max_tokens"`
+	ResponseFormat *ai.ResponseFormat `json:"response_format"`
+	StreamOptions  *struct {
+		IncludeUsage bool `json:"include_usage"`
+	} `json:"stream_options"`
+}
+
+type openAIUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+type openAIResponseMessage struct {
+	Role             string `json:"role,omitempty"`
+	Content          string `json:"content,omitempty"`
+	ReasoningContent string `json:"reasoning_content,omitempty"`
+}
+
+type openAIChoice struct {
+	Index        int                    `json:"index"`
+	Message      *openAIResponseMessage `json:"message,omitempty"`
+	Delta        *openAIResponseMessage `json:"delta,omitempty"`
+	FinishReason *string                `json:"finish_reason"`
+}
+
+type openAIChatCompletion struct {
+	ID      string         `json:"id"`
+	Object  string         `json:"object"`
+	Created int64          `json:"created"`
+	Model   string         `json:"model"`
+	Choices []openAIChoice `json:"choices"`
+	Usage   *openAIUsage   `json:"usage,omitempty"`
+}
+
+type openAIModel struct {
+	ID      string `json:"id"`
+	Object  string `json:"object"`
+	Created int64  `json:"created"`
+	OwnedBy string `json:"owned_by"`
+}
+
+// openAIErrorResponse отправляет ошибку в формате OpenAI
+func openAIErrorResponse(c *gin.Context, status int, errType, code, message string) {
+	c.JSON(status, gin.H{"error": openAIError{Message: message, Type: errType, Code: code}})
+	c.Abort()
+}
+
+// handleOpenAIModels возвращает модели доступных провайдеров в формате OpenAI
+func (app *application) handleOpenAIModels(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	data := make([]openAIModel, 0)
+	for _, model := range app.availableModels(ctx) {
+		data = append(data, openAIModel{
+			ID:      model.ID,
+			Object:  "model",
+			OwnedBy: model.Provider,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"object": "list",
+		"data":   data,
+	})
+}
+
+// handleOpenAIChatCompletions принимает запрос в формате OpenAI Chat Completions
+// и направляет его провайдеру, определенному по модели
+func (app *application) handleOpenAIChatCompletions(c *gin.Context) {
+	userID, apiErr := getUserIDFromContext(c)
+	if apiErr != nil {
+		openAIErrorResponse(c, apiErr.Status, "invalid_request_error", apiErr.Code, apiErr.Message)
+		return
+	}
+
+	var req openAIChatCompletionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		openAIErrorResponse(c, http.StatusBadRequest, "invalid_request_error", "invalid_request", err.Error())
+		return
+	}
+
+	if msg := validateOpenAIRequest(&req); msg != "" {
+		openAIErrorResponse(c, http.StatusBadRequest, "invalid_request_error", "invalid_request", msg)
+		return
+	}
+
+	if err := req.ResponseFormat.Validate(); err != nil {
+		openAIErrorResponse(c, http.StatusBadRequest, "invalid_request_error", "invalid_response_format", err.Error())
+		return
+	}
+
+	if apiErr := app.checkTokenQuota(userID); apiErr != nil {
+		openAIErrorResponse(c, apiErr.Status, "insufficient_quota", "insufficient_quota", apiErr.Message)
+		return
+	}
+
+	// Принимаются только модели из /v1/models: клиент, запросивший, например, gpt-4o,
+	// получает ошибку, а не ответ другой модели
+	listCtx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	info, found := app.findAvailableModel(listCtx, req.Model)
+	cancel()
+	if !found {
+		openAIErrorResponse(c, http.StatusNotFound, "invalid_request_error", "model_not_found",
+			fmt.Sprintf("model %q is not available", req.Model))
+		return
+	}
+	providerName, model := info.Provider, info.ID
+	provider, err := app.aiProviderFactory.Get(providerName)
+	if err != nil {
+		openAIErrorResponse(c, http.StatusNotFound, "invalid_request_error", "model_not_found",
+			fmt.Sprintf("model %q is not available", req.Model))
+		return
+	}
+
+	messages := make([]ai.Message, len(req.Messages))
+	for i, msg := range req.Messages {
+		messages[i] = ai.Message{Role: msg.Role, Content: string(msg.Content)}
+	}
+
+	if apiErr := app.moderateInput(c.Request.Context(), userID, nil, promptText(messages)); apiErr != nil {
+		openAIErrorResponse(c, apiErr.Status, "invalid_request_error", "content_filter", apiErr.Message)
+		return
+	}
+
+	aiReq := ai.ChatRequest{
+		Model:          model,
+		Messages:       messages,
+		ResponseFormat: req.ResponseFormat,
+		Temperature:    req.Temperature,
+		TopP:           req.TopP,
+		MaxTokens:      req.MaxTokens,
+		NoCache:        noCacheRequested(c),
+	}
+
+	completionID, err := database.GenerateSafeToken()
+	if err != nil {
+		openAIErrorResponse(c, http.StatusInternalServerError, "api_error", "internal_error", "internal server error")
+		return
+	}
+	completion := openAIChatCompletion{
+		ID:      "chatcmpl-" + strings.TrimRight(completionID, "=")[:24],
+		Created: time.Now().Unix(),
+		Model:   modelOrRequested(model, req.Model),
+	}
+
+	app.logger.Info("OpenAI-compatible completion",
+		"user_id", userID,
+		"provider", providerName,
+		"model", completion.Model,
+		"messages_count", len(messages),
+		"stream", req.Stream,
+	)
+
+	if req.Stream {
+		includeUsage := req.StreamOptions != nil && req.StreamOptions.IncludeUsage
+		app.streamOpenAICompletion(c, userID, providerName, provider, aiReq, completion, includeUsage)
+		return
+	}
+
+	extendWriteDeadline(c, 2*time.Minute+10*time.Second)
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Minute)
+	defer cancel()
+	ctx = ai.WithRequestMeta(ctx, ai.RequestMeta{UserID: userID})
+
+	aiResp, err := ai.ChatStructured(ctx, provider, aiReq)
+	if err != nil {
+		// Неудачные попытки JSON-режима тоже расходуют токены
+		if aiResp != nil {
+			app.recordUsage(userID, nil, database.UsageSourceCompletion, providerName, aiResp)
+		}
+		app.logger.Error("Error getting OpenAI-compatible completion", "error", err, "provider", providerName, "user_id", userID)
+		status, code := openAIGenerationError(err)
+		openAIErrorResponse(c, status, "api_error", code, completionError(err).Message)
+		return
+	}
+
+	app.recordUsage(userID, nil, database.UsageSourceCompletion, providerName, aiResp)
+
+	if apiErr := app.moderateOutput(ctx, userID, aiResp.Content); apiErr != nil {
+		openAIErrorResponse(c, apiErr.Status, "invalid_request_error", "content_filter", apiErr.Message)
+		return
+	}
+
+	stop := "stop"
+	completion.Object = "chat.completion"
+	if aiResp.Model != "" {
+		completion.Model = aiResp.Model
+	}
+	completion.Choices = []openAIChoice{{
+		Message: &openAIResponseMessage{
+			Role:             "assistant",
+			Content:          aiResp.Content,
+			ReasoningContent: aiResp.ReasoningContent,
+		},
+		FinishReason: &stop,
+	}}
+	completion.Usage = &openAIUsage{
+		PromptTokens:     aiResp.Usage.PromptTokens,
+		CompletionTokens: aiResp.Usage.CompletionTokens,
+		TotalTokens:      aiResp.Usage.TotalTokens,
+	}
+
+	c.JSON(http.StatusOK, completion)
+}
+
+// streamOpenAICompletion передает ответ порциями chat.completion.chunk, завершая поток "data: [DONE]"
+// Если включена модерация, ответ отправляется одной порцией после проверки
+func (app *application) streamOpenAICompletion(c *gin.Context, userID int, providerName string, provider ai.Provider, aiReq ai.ChatRequest, completion openAIChatCompletion, includeUsage bool) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Minute)
+	defer cancel()
+	ctx = ai.WithRequestMeta(ctx, ai.RequestMeta{UserID: userID})
+
+	startSSE(c, 2*time.Minute+10*time.Second)
+
+	completion.Object = "chat.completion.chunk"
+	writeChunk := func(delta openAIResponseMessage, finishReason *string) error {
+		chunk := completion
+		chunk.Choices = []openAIChoice{{Delta: &delta, FinishReason: finishReason}}
+		return writeSSEData(c, chunk)
+	}
+
+	// Первая порция по соглашению OpenAI содержит только роль
+	if err := writeChunk(openAIResponseMessage{Role: "assistant"}, nil); err != nil {
+		return
+	}
+
+	// Ответ, который еще не прошел модерацию, клиенту не показывается
+	buffered := app.moderator != nil
+
+	onChunk := func(chunk ai.StreamChunk) error {
+		if buffered || (chunk.ReasoningDelta == "" && chunk.ContentDelta == "") {
+			return nil
+		}
+		return writeChunk(openAIResponseMessage{
+			Content:          chunk.ContentDelta,
+			ReasoningContent: chunk.ReasoningDelta,
+		}, nil)
+	}
+
+	var aiResp *ai.ChatResponse
+	var err error
+	if aiReq.ResponseFormat.IsJSON() {
+		// JSON-ответ проверяется целиком и отправляется одной порцией
+		aiResp, err = ai.ChatStructured(ctx, provider, aiReq)
+		buffered = true
+	} else {
+		aiReq.Stream = true
+		aiResp, err = provider.ChatStream(ctx, aiReq, onChunk)
+	}
+	if err != nil {
+		if aiResp != nil {
+			app.recordUsage(userID, nil, database.UsageSourceCompletion, providerName, aiResp)
+		}
+		app.logger.Error("Error streaming OpenAI-compatible completion", "error", err, "provider", providerName, "user_id", userID)
+		_, code := openAIGenerationError(err)
+		writeSSEData(c, gin.H{"error": openAIError{Message: completionError(err).Message, Type: "api_error", Code: code}})
+		writeSSEData(c, "[DONE]")
+		return
+	}
+
+	app.recordUsage(userID, nil, database.UsageSourceCompletion, providerName, aiResp)
+
+	if apiErr := app.moderateOutput(ctx, userID, aiResp.Content); apiErr != nil {
+		writeSSEData(c, gin.H{"error": openAIError{Message: apiErr.Message, Type: "invalid_request_error", Code: "content_filter"}})
+		writeSSEData(c, "[DONE]")
+		return
+	}
+
+	// Ответ прошел модерацию: отправляем его одной порцией
+	if buffered {
+		writeChunk(openAIResponseMessage{
+			Content:          aiResp.Content,
+			ReasoningContent: aiResp.ReasoningContent,
+		}, nil)
+	}
+
+	stop := "stop"
+	writeChunk(openAIResponseMessage{}, &stop)
+
+	if includeUsage {
+		usageChunk := completion
+		usageChunk.Choices = []openAIChoice{}
+		usageChunk.Usage = &openAIUsage{
+			PromptTokens:     aiResp.Usage.PromptTokens,
+			CompletionTokens: aiResp.Usage.CompletionTokens,
+			TotalTokens:      aiResp.Usage.TotalTokens,
+		}
+		writeSSEData(c, usageChunk)
+	}
+
+	writeSSEData(c, "[DONE]")
+}
+
+// validateOpenAIRequest проверяет обязательные поля запроса и возвращает текст ошибки
+func validateOpenAIRequest(req *openAIChatCompletionRequest) string {
+	if strings.TrimSpace(req.Model) == "" {
+		return "model is required"
+	}
+	if len(req.Messages) == 0 {
+		return "messages must contain at least one message"
+	}
+	for i, msg := range req.Messages {
+		switch msg.Role {
+		case "system", "user", "assistant":
+		case "developer":
+			// Новые клиенты OpenAI передают системные инструкции с ролью developer
+			req.Messages[i].Role = "system"
+		default:
+			return fmt.Sprintf("messages[%d].role %q is not supported", i, msg.Role)
+		}
+	}
+	if req.Temperature != nil && (*req.Temperature < 0 || *req.Temperature > 2) {
+		return "temperature must be between 0 and 2"
+	}
+	if req.TopP != nil && (*req.TopP <= 0 || *req.TopP > 1) {
+		return "top_p must be in (0, 1]"
+	}
+	if req.MaxTokens < 0 {
+		return "max_tokens must be positive"
+	}
+	return ""
+}
+
+// openAIGenerationError возвращает HTTP статус и код ошибки OpenAI для ошибки генерации
+func openAIGenerationError(err error) (int, string) {
+	if errors.Is(err, ai.ErrInvalidStructuredOutput) {
+		return http.StatusBadGateway, "invalid_structured_output"
+	}
+	return http.StatusBadGateway, "upstream_error"
+}
+
+// modelOrRequested возвращает определенную модель или модель из запроса, если провайдер использует модель по умолчанию
+func modelOrRequested(model, requested string) string {
+	if model != "" {
+		return model
+	}
+	return requested
+}
@@ -0,0 +1,355 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+
+	"mindforge/internal/ai"
+	"mindforge/internal/database"
+
+	"github.com/gin-gonic/gin"
+)
+
+type createPersonaRequest struct {
+	Name          string   `json:"name" binding:"required,min=1,max=200"`
+	Description   string   `json:"description" binding:"max=2000"`
+	AIModel       string   `json:"ai_model" binding:"required,max=100"`
+	SystemPrompt  string   `json:"system_prompt" binding:"max=10000"`
+	Temperature   *float64 `json:"temperature" binding:"omitempty,min=0,max=2"`
+	TopP          *float64 `json:"top_p" binding:"omitempty,min=0,max=1"`
+	MaxTokens     int      `json:"max_tokens" binding:"min=0,max=32768"`
+	CollectionIDs []int    `json:"collection_ids" binding:"max=20"`
+}
+
+// updatePersonaRequest - частичное обновление персоны: передаются только изменяемые поля
+type updatePersonaRequest struct {
+	Name          *string  `json:"name" binding:"omitempty,min=1,max=200"`
+	Description   *string  `json:"description" binding:"omitempty,max=2000"`
+	AIModel       *string  `json:"ai_model" binding:"omitempty,min=1,max=100"`
+	SystemPrompt  *string  `json:"system_prompt" binding:"omitempty,max=10000"`
+	Temperature   *float64 `json:"temperature" binding:"omitempty,min=0,max=2"`
+	TopP          *float64 `json:"top_p" binding:"omitempty,min=0,max=1"`
+	MaxTokens     *int     `json:"max_tokens" binding:"omitempty,min=0,max=32768"`
+	CollectionIDs *[]int   `json:"collection_ids" binding:"omitempty,max=20"`
+}
+
+type personaResponse struct {
+	ID            int      `json:"id"`
+	Name          string   `json:"name"`
+	Description   string   `json:"description"`
+	AIModel       string   `json:"ai_model"`
+	SystemPrompt  string   `json:"system_prompt"`
+	Temperature   *float64 `json:"temperature,omitempty"`
+	TopP          *float64 `json:"top_p,omitempty"`
+	MaxTokens     int      `json:"max_tokens"`
+	CollectionIDs []int    `json:"collection_ids"`
+	CreatedAt     string   `json:"created_at"`
+	UpdatedAt     string   `json:"updated_at"`
+}
+
+func newPersonaResponse(persona *database.Persona) personaResponse {
+	return personaResponse{
+		ID:            persona.ID,
+		Name:          persona.Name,
+		Description:   persona.Description,
+		AIModel:       persona.AIModel,
+		SystemPrompt:  persona.SystemPrompt,
+		Temperature:   persona.Temperature,
+		TopP:          persona.TopP,
+		MaxTokens:     persona.MaxTokens,
+		CollectionIDs: persona.CollectionIDs,
+		CreatedAt:     persona.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		UpdatedAt:     persona.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+}
+
+// validatePersonaOwnership проверяет принадлежность персоны пользователю
+func (app *application) validatePersonaOwnership(personaID, userID int) (*database.Persona, *APIError) {
+	persona, err := app.models.Personas.GetByID(personaID)
+	if err != nil {
+		if err.Error() == "persona not found" {
+			return nil, &APIError{
+				Status:  404,
+				Message: "persona not found",
+				Code:    "PERSONA_NOT_FOUND",
+			}
+		}
+		return nil, &APIError{
+			Status:  500,
+			Message: "internal server error",
+			Code:    "INTERNAL_ERROR",
+		}
+	}
+
+	if persona.UserID != userID {
+		return nil, &APIError{
+			Status:  403,
+			Message: "forbidden",
+			Code:    "FORBIDDEN",
+		}
+	}
+
+	return persona, nil
+}
+
+// validateAIModel проверяет, что провайдер модели настроен
+func (app *application) validateAIModel(aiModel string) *APIError {
+	providerName, _ := ai.ResolveModel(aiModel)
+	if _, err := app.aiProviderFactory.Get(providerName); err != nil {
+		app.logger.Warn("Invalid AI model/provider", "model", aiModel, "provider", providerName, "error", err)
+		return ErrInvalidAIModel
+	}
+	return nil
+}
+
+// validatePersonaCollections проверяет, что все коллекции персоны принадлежат пользователю
+func (app *application) validatePersonaCollections(collectionIDs []int, userID int) *APIError {
+	for _, collectionID := range collectionIDs {
+		if _, apiErr := app.validateCollectionOwnership(collectionID, userID); apiErr != nil {
+			return apiErr
+		}
+	}
+	return nil
+}
+
+// handleCreatePersona создает персону
+func (app *application) handleCreatePersona(c *gin.Context) {
+	userID, apiErr := getUserIDFromContext(c)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	var req createPersonaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		validationErrorResponse(c, err)
+		return
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		errorResponse(c, &APIError{
+			Status:  400,
+			Message: "name cannot be empty",
+			Code:    "VALIDATION_ERROR",
+		})
+		return
+	}
+
+	aiModel := strings.TrimSpace(req.AIModel)
+	if apiErr := app.validateAIModel(aiModel); apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	if apiErr := app.validatePersonaCollections(req.CollectionIDs, userID); apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	persona, err := app.models.Personas.Insert(&database.Persona{
+		UserID:        userID,
+		Name:          name,
+		Description:   strings.TrimSpace(req.Description),
+		AIModel:       aiModel,
+		SystemPrompt:  strings.TrimSpace(req.SystemPrompt),
+		Temperature:   req.Temperature,
+		TopP:          req.TopP,
+		MaxTokens:     req.MaxTokens,
+		CollectionIDs: req.CollectionIDs,
+	})
+	if err != nil {
+		app.logger.Error("Error creating persona", "error", err, "user_id", userID)
+		internalErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, newPersonaResponse(persona))
+}
+
+// handleGetPersonas получает список персон пользователя
+func (app *application) handleGetPersonas(c *gin.Context) {
+	userID, apiErr := getUserIDFromContext(c)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	personas, err := app.models.Personas.GetByUserID(userID)
+	if err != nil {
+		app.logger.Error("Error getting personas", "error", err, "user_id", userID)
+		internalErrorResponse(c, err)
+		return
+	}
+
+	response := make([]personaResponse, len(personas))
+	for i, persona := range personas {
+		response[i] = newPersonaResponse(persona)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// handleGetPersona получает персону
+func (app *application) handleGetPersona(c *gin.Context) {
+	userID, apiErr := getUserIDFromContext(c)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	personaID, apiErr := getIDFromParam(c, "id", "persona", "INVALID_PERSONA_ID")
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	persona, apiErr := app.validatePersonaOwnership(personaID, userID)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	c.JSON(http.StatusOK, newPersonaResponse(persona))
+}
+
+// handleUpdatePersona частично обновляет персону
+// Изменения действуют со следующего ответа во всех чатах персоны. Новую модель получают чаты,
+// работавшие с прежней моделью персоны; чаты с явно выбранной моделью сохраняют ее
+func (app *application) handleUpdatePersona(c *gin.Context) {
+	userID, apiErr := getUserIDFromContext(c)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	personaID, apiErr := getIDFromParam(c, "id", "persona", "INVALID_PERSONA_ID")
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	persona, apiErr := app.validatePersonaOwnership(personaID, userID)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	var req updatePersonaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		validationErrorResponse(c, err)
+		return
+	}
+
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			errorResponse(c, &APIError{
+				Status:  400,
+				Message: "name cannot be empty",
+				Code:    "VALIDATION_ERROR",
+			})
+			return
+		}
+		persona.Name = name
+	}
+	if req.Description != nil {
+		persona.Description = strings.TrimSpace(*req.Description)
+	}
+	if req.AIModel != nil {
+		aiModel := strings.TrimSpace(*req.AIModel)
+		if apiErr := app.validateAIModel(aiModel); apiErr != nil {
+			errorResponse(c, apiErr)
+			return
+		}
+		persona.AIModel = aiModel
+	}
+	if req.SystemPrompt != nil {
+		persona.SystemPrompt = strings.TrimSpace(*req.SystemPrompt)
+	}
+	if req.Temperature != nil {
+		persona.Temperature = req.Temperature
+	}
+	if req.TopP != nil {
+		persona.TopP = req.TopP
+	}
+	if req.MaxTokens != nil {
+		persona.MaxTokens = *req.MaxTokens
+	}
+	if req.CollectionIDs != nil {
+		if apiErr := app.validatePersonaCollections(*req.CollectionIDs, userID); apiErr != nil {
+			errorResponse(c, apiErr)
+			return
+		}
+		persona.CollectionIDs = *req.CollectionIDs
+	}
+
+	updated, chatIDs, err := app.models.Personas.Update(persona)
+	if err != nil {
+		app.logger.Error("Error updating persona", "error", err, "persona_id", personaID)
+		internalErrorResponse(c, err)
+		return
+	}
+
+	// Чаты, которые перешли на новую модель персоны
+	for _, chatID := range chatIDs {
+		app.publishChatUpdated(chatID)
+	}
+
+	c.JSON(http.StatusOK, newPersonaResponse(updated))
+}
+
+// handleDeletePersona удаляет персону; ее чаты продолжают работать с моделью чата
+func (app *application) handleDeletePersona(c *gin.Context) {
+	userID, apiErr := getUserIDFromContext(c)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	personaID, apiErr := getIDFromParam(c, "id", "persona", "INVALID_PERSONA_ID")
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	if _, apiErr := app.validatePersonaOwnership(personaID, userID); apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	if err := app.models.Personas.Delete(personaID); err != nil {
+		app.logger.Error("Error deleting persona", "error", err, "persona_id", personaID)
+		internalErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "persona deleted successfully",
+	})
+}
+
+// chatPersona загружает актуальную персону чата или возвращает nil, если персона не назначена
+// Ошибка загрузки не блокирует ответ: чат отвечает со своими настройками
+func (app *application) chatPersona(chat *database.Chat) *database.Persona {
+	if chat.PersonaID == nil {
+		return nil
+	}
+
+	persona, err := app.models.Personas.GetByID(*chat.PersonaID)
+	if err != nil {
+		app.logger.Warn("Error loading chat persona, using chat settings", "error", err, "chat_id", chat.ID, "persona_id", *chat.PersonaID)
+		return nil
+	}
+	return persona
+}
+
+// applyPersona дополняет запрос к AI параметрами генерации персоны
+func applyPersona(req *ai.ChatRequest, persona *database.Persona) {
+	if persona == nil {
+		return
+	}
+	req.Temperature = persona.Temperature
+	req.TopP = persona.TopP
+	req.MaxTokens = persona.MaxTokens
+}
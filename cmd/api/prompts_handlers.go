@@ -0,0 +1,603 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"mindforge/internal/database"
+	"mindforge/internal/prompts"
+
+	"github.com/gin-gonic/gin"
+)
+
+type createPromptRequest struct {
+	Name        string             `json:"name" binding:"required,min=1,max=200"`
+	Description string             `json:"description" binding:"max=2000"`
+	Content     string             `json:"content" binding:"required,min=1,max=10000"`
+	Variables   []prompts.Variable `json:"variables" binding:"max=50"`
+	WorkspaceID *int               `json:"workspace_id"`
+	// Global - шаблон доступен всем пользователям (только для администраторов)
+	Global bool `json:"global"`
+}
+
+// updatePromptRequest - частичное обновление шаблона: передаются только изменяемые поля
+// Изменение content или variables создает новую версию. workspace_id=0 убирает шаблон из рабочего пространства
+type updatePromptRequest struct {
+	Name        *string             `json:"name" binding:"omitempty,min=1,max=200"`
+	Description *string             `json:"description" binding:"omitempty,max=2000"`
+	Content     *string             `json:"content" binding:"omitempty,min=1,max=10000"`
+	Variables   *[]prompts.Variable `json:"variables" binding:"omitempty,max=50"`
+	WorkspaceID *int                `json:"workspace_id"`
+}
+
+type renderPromptRequest struct {
+	// Version - версия шаблона; по умолчанию текущая
+	Version   *int              `json:"version"`
+	Variables map[string]string `json:"variables"`
+}
+
+type promptResponse struct {
+	ID          int                `json:"id"`
+	UserID      int                `json:"user_id"`
+	WorkspaceID *int               `json:"workspace_id,omitempty"`
+	IsGlobal    bool               `json:"is_global"`
+	Name        string             `json:"name"`
+	Description string             `json:"description"`
+	Version     int                `json:"version"`
+	Content     string             `json:"content"`
+	Variables   []prompts.Variable `json:"variables"`
+	Editable    bool               `json:"editable"`
+	CreatedAt   string             `json:"created_at"`
+	UpdatedAt   string             `json:"updated_at"`
+}
+
+type promptVersionResponse struct {
+	Version   int                `json:"version"`
+	Content   string             `json:"content"`
+	Variables []prompts.Variable `json:"variables"`
+	CreatedBy *int               `json:"created_by,omitempty"`
+	CreatedAt string             `json:"created_at"`
+}
+
+// promptTemplateRef сохраняется в metadata сообщения, отправленного по шаблону
+type promptTemplateRef struct {
+	ID      int `json:"id"`
+	Version int `json:"version"`
+}
+
+// decodePromptVariables разбирает сохраненный JSON с описаниями переменных
+func decodePromptVariables(data json.RawMessage) []prompts.Variable {
+	variables := []prompts.Variable{}
+	if len(data) > 0 {
+		_ = json.Unmarshal(data, &variables)
+	}
+	return variables
+}
+
+func newPromptResponse(template *database.PromptTemplate, editable bool) promptResponse {
+	return promptResponse{
+		ID:          template.ID,
+		UserID:      template.UserID,
+		WorkspaceID: template.WorkspaceID,
+		IsGlobal:    template.IsGlobal,
+		Name:        template.Name,
+		Description: template.Description,
+		Version:     template.Version,
+		Content:     template.Content,
+		Variables:   decodePromptVariables(template.Variables),
+		Editable:    editable,
+		CreatedAt:   template.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		UpdatedAt:   template.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+}
+
+func newPromptVersionResponse(version *database.PromptTemplateVersion) promptVersionResponse {
+	return promptVersionResponse{
+		Version:   version.Version,
+		Content:   version.Content,
+		Variables: decodePromptVariables(version.Variables),
+		CreatedBy: version.CreatedBy,
+		CreatedAt: version.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+}
+
+// isAdmin проверяет права администратора пользователя
+func (app *application) isAdmin(userID int) bool {
+	user, err := app.models.Users.GetByID(userID)
+	return err == nil && user.IsAdmin
+}
+
+// validatePromptAccess проверяет, что шаблон доступен пользователю:
+// собственный, глобальный или из рабочего пространства, в котором он состоит
+func (app *application) validatePromptAccess(templateID, userID int) (*database.PromptTemplate, *APIError) {
+	template, err := app.models.Prompts.GetByID(templateID)
+	if err != nil {
+		if err.Error() == "prompt template not found" {
+			return nil, &APIError{
+				Status:  404,
+				Message: "prompt template not found",
+				Code:    "PROMPT_NOT_FOUND",
+			}
+		}
+		return nil, &APIError{
+			Status:  500,
+			Message: "internal server error",
+			Code:    "INTERNAL_ERROR",
+		}
+	}
+
+	if template.UserID == userID || template.IsGlobal {
+		return template, nil
+	}
+	if template.WorkspaceID != nil {
+		if member, err := app.models.Workspaces.IsMember(*template.WorkspaceID, userID); err == nil && member {
+			return template, nil
+		}
+	}
+
+	return nil, &APIError{
+		Status:  403,
+		Message: "forbidden",
+		Code:    "FORBIDDEN",
+	}
+}
+
+// canEditPrompt сообщает, может ли пользователь изменять шаблон
+// Глобальные шаблоны изменяют администраторы, остальные - только автор
+func (app *application) canEditPrompt(template *database.PromptTemplate, userID int) bool {
+	if template.IsGlobal {
+		return app.isAdmin(userID)
+	}
+	return template.UserID == userID
+}
+
+// checkPromptWorkspace проверяет, что пользователь может поделиться шаблоном в рабочем пространстве
+func (app *application) checkPromptWorkspace(workspaceID, userID int) *APIError {
+	_, apiErr := app.validateWorkspaceMembership(workspaceID, userID)
+	return apiErr
+}
+
+// normalizePromptVariables согласует переменные с текстом шаблона и кодирует их для сохранения
+func normalizePromptVariables(content string, variables []prompts.Variable) (json.RawMessage, *APIError) {
+	normalized, err := prompts.Normalize(content, variables)
+	if err != nil {
+		return nil, &APIError{
+			Status:  400,
+			Message: err.Error(),
+			Code:    "INVALID_PROMPT_VARIABLES",
+		}
+	}
+
+	data, _ := json.Marshal(normalized)
+	return data, nil
+}
+
+// renderPrompt подставляет значения переменных в текущую или указанную версию шаблона
+func (app *application) renderPrompt(template *database.PromptTemplate, version *int, values map[string]string) (string, promptTemplateRef, *APIError) {
+	content, variables := template.Content, template.Variables
+	ref := promptTemplateRef{ID: template.ID, Version: template.Version}
+
+	if version != nil && *version != template.Version {
+		v, err := app.models.Prompts.GetVersion(template.ID, *version)
+		if err != nil {
+			return "", ref, &APIError{
+				Status:  404,
+				Message: "prompt template version not found",
+				Code:    "PROMPT_VERSION_NOT_FOUND",
+			}
+		}
+		content, variables = v.Content, v.Variables
+		ref.Version = v.Version
+	}
+
+	rendered, err := prompts.Render(content, decodePromptVariables(variables), values)
+	if err != nil {
+		return "", ref, &APIError{
+			Status:  400,
+			Message: err.Error(),
+			Code:    "PROMPT_RENDER_ERROR",
+		}
+	}
+
+	rendered = strings.TrimSpace(rendered)
+	if rendered == "" {
+		return "", ref, &APIError{
+			Status:  400,
+			Message: "rendered prompt is empty",
+			Code:    "PROMPT_RENDER_ERROR",
+		}
+	}
+	if len(rendered) > 10000 {
+		return "", ref, &APIError{
+			Status:  400,
+			Message: "rendered prompt too long (max 10000 characters)",
+			Code:    "PROMPT_RENDER_ERROR",
+		}
+	}
+
+	return rendered, ref, nil
+}
+
+// handleCreatePrompt создает шаблон промпта
+func (app *application) handleCreatePrompt(c *gin.Context) {
+	userID, apiErr := getUserIDFromContext(c)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	var req createPromptRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		validationErrorResponse(c, err)
+		return
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		errorResponse(c, &APIError{
+			Status:  400,
+			Message: "name cannot be empty",
+			Code:    "VALIDATION_ERROR",
+		})
+		return
+	}
+
+	if req.Global {
+		if !app.isAdmin(userID) {
+			errorResponse(c, ErrAdminRequired)
+			return
+		}
+		if req.WorkspaceID != nil {
+			errorResponse(c, &APIError{
+				Status:  400,
+				Message: "global template cannot belong to a workspace",
+				Code:    "VALIDATION_ERROR",
+			})
+			return
+		}
+	}
+	if req.WorkspaceID != nil {
+		if apiErr := app.checkPromptWorkspace(*req.WorkspaceID, userID); apiErr != nil {
+			errorResponse(c, apiErr)
+			return
+		}
+	}
+
+	variables, apiErr := normalizePromptVariables(req.Content, req.Variables)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	template, err := app.models.Prompts.Create(&database.PromptTemplate{
+		UserID:      userID,
+		WorkspaceID: req.WorkspaceID,
+		IsGlobal:    req.Global,
+		Name:        name,
+		Description: strings.TrimSpace(req.Description),
+		Content:     req.Content,
+		Variables:   variables,
+	})
+	if err != nil {
+		app.logger.Error("Error creating prompt template", "error", err, "user_id", userID)
+		internalErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, newPromptResponse(template, true))
+}
+
+// handleGetPrompts получает доступные пользователю шаблоны
+// Параметры: q - поиск по названию, описанию и тексту; scope - personal, workspace или global
+func (app *application) handleGetPrompts(c *gin.Context) {
+	userID, apiErr := getUserIDFromContext(c)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	scope := c.Query("scope")
+	if scope != "" && scope != database.PromptScopePersonal && scope != database.PromptScopeWorkspace && scope != database.PromptScopeGlobal {
+		errorResponse(c, &APIError{
+			Status:  400,
+			Message: "scope must be personal, workspace or global",
+			Code:    "VALIDATION_ERROR",
+		})
+		return
+	}
+
+	templates, err := app.models.Prompts.GetAvailable(userID, scope, c.Query("q"))
+	if err != nil {
+		app.logger.Error("Error getting prompt templates", "error", err, "user_id", userID)
+		internalErrorResponse(c, err)
+		return
+	}
+
+	admin := app.isAdmin(userID)
+	response := make([]promptResponse, len(templates))
+	for i, template := range templates {
+		editable := template.UserID == userID
+		if template.IsGlobal {
+			editable = admin
+		}
+		response[i] = newPromptResponse(template, editable)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// handleGetPrompt получает шаблон; ?version= возвращает указанную версию
+func (app *application) handleGetPrompt(c *gin.Context) {
+	userID, apiErr := getUserIDFromContext(c)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	templateID, apiErr := getIDFromParam(c, "id", "prompt", "INVALID_PROMPT_ID")
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	template, apiErr := app.validatePromptAccess(templateID, userID)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	if v := c.Query("version"); v != "" {
+		version, err := strconv.Atoi(v)
+		if err != nil || version <= 0 {
+			errorResponse(c, &APIError{
+				Status:  400,
+				Message: "invalid version",
+				Code:    "VALIDATION_ERROR",
+			})
+			return
+		}
+
+		pv, err := app.models.Prompts.GetVersion(templateID, version)
+		if err != nil {
+			errorResponse(c, &APIError{
+				Status:  404,
+				Message: "prompt template version not found",
+				Code:    "PROMPT_VERSION_NOT_FOUND",
+			})
+			return
+		}
+		template.Version, template.Content, template.Variables = pv.Version, pv.Content, pv.Variables
+	}
+
+	c.JSON(http.StatusOK, newPromptResponse(template, app.canEditPrompt(template, userID)))
+}
+
+// handleGetPromptVersions получает историю версий шаблона
+func (app *application) handleGetPromptVersions(c *gin.Context) {
+	userID, apiErr := getUserIDFromContext(c)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	templateID, apiErr := getIDFromParam(c, "id", "prompt", "INVALID_PROMPT_ID")
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	if _, apiErr := app.validatePromptAccess(templateID, userID); apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	versions, err := app.models.Prompts.GetVersions(templateID)
+	if err != nil {
+		app.logger.Error("Error getting prompt template versions", "error", err, "template_id", templateID)
+		internalErrorResponse(c, err)
+		return
+	}
+
+	response := make([]promptVersionResponse, len(versions))
+	for i, version := range versions {
+		response[i] = newPromptVersionResponse(version)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// handleUpdatePrompt изменяет шаблон; изменение текста или переменных создает новую версию
+func (app *application) handleUpdatePrompt(c *gin.Context) {
+	userID, apiErr := getUserIDFromContext(c)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	templateID, apiErr := getIDFromParam(c, "id", "prompt", "INVALID_PROMPT_ID")
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	template, apiErr := app.validatePromptAccess(templateID, userID)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+	if !app.canEditPrompt(template, userID) {
+		errorResponse(c, &APIError{
+			Status:  403,
+			Message: "only the author can edit this prompt template",
+			Code:    "FORBIDDEN",
+		})
+		return
+	}
+
+	var req updatePromptRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		validationErrorResponse(c, err)
+		return
+	}
+
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			errorResponse(c, &APIError{
+				Status:  400,
+				Message: "name cannot be empty",
+				Code:    "VALIDATION_ERROR",
+			})
+			return
+		}
+		template.Name = name
+	}
+	if req.Description != nil {
+		template.Description = strings.TrimSpace(*req.Description)
+	}
+
+	if req.WorkspaceID != nil {
+		if *req.WorkspaceID == 0 {
+			template.WorkspaceID = nil
+		} else {
+			if template.IsGlobal {
+				errorResponse(c, &APIError{
+					Status:  400,
+					Message: "global template cannot belong to a workspace",
+					Code:    "VALIDATION_ERROR",
+				})
+				return
+			}
+			if apiErr := app.checkPromptWorkspace(*req.WorkspaceID, userID); apiErr != nil {
+				errorResponse(c, apiErr)
+				return
+			}
+			template.WorkspaceID = req.WorkspaceID
+		}
+	}
+
+	newVersion := req.Content != nil || req.Variables != nil
+	if newVersion {
+		if req.Content != nil {
+			template.Content = *req.Content
+		}
+		variables := decodePromptVariables(template.Variables)
+		if req.Variables != nil {
+			variables = *req.Variables
+		} else {
+			// Описания переменных, исчезнувших из нового текста, отбрасываются
+			variables = keepUsedVariables(template.Content, variables)
+		}
+
+		encoded, apiErr := normalizePromptVariables(template.Content, variables)
+		if apiErr != nil {
+			errorResponse(c, apiErr)
+			return
+		}
+		template.Variables = encoded
+	}
+
+	updated, err := app.models.Prompts.Update(template, newVersion, userID)
+	if err != nil {
+		app.logger.Error("Error updating prompt template", "error", err, "template_id", templateID)
+		internalErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newPromptResponse(updated, true))
+}
+
+// keepUsedVariables оставляет описания только тех переменных, которые есть в тексте
+func keepUsedVariables(content string, variables []prompts.Variable) []prompts.Variable {
+	used := make(map[string]bool)
+	for _, name := range prompts.Placeholders(content) {
+		used[name] = true
+	}
+
+	var result []prompts.Variable
+	for _, v := range variables {
+		if used[v.Name] {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// handleDeletePrompt удаляет шаблон со всеми версиями
+func (app *application) handleDeletePrompt(c *gin.Context) {
+	userID, apiErr := getUserIDFromContext(c)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	templateID, apiErr := getIDFromParam(c, "id", "prompt", "INVALID_PROMPT_ID")
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	template, apiErr := app.validatePromptAccess(templateID, userID)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+	if !app.canEditPrompt(template, userID) {
+		errorResponse(c, &APIError{
+			Status:  403,
+			Message: "only the author can delete this prompt template",
+			Code:    "FORBIDDEN",
+		})
+		return
+	}
+
+	if err := app.models.Prompts.Delete(templateID); err != nil {
+		app.logger.Error("Error deleting prompt template", "error", err, "template_id", templateID)
+		internalErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "prompt template deleted successfully",
+	})
+}
+
+// handleRenderPrompt подставляет значения переменных и возвращает готовый текст (предпросмотр)
+func (app *application) handleRenderPrompt(c *gin.Context) {
+	userID, apiErr := getUserIDFromContext(c)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	templateID, apiErr := getIDFromParam(c, "id", "prompt", "INVALID_PROMPT_ID")
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	template, apiErr := app.validatePromptAccess(templateID, userID)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	var req renderPromptRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		validationErrorResponse(c, err)
+		return
+	}
+
+	content, ref, apiErr := app.renderPrompt(template, req.Version, req.Variables)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"content": content,
+		"version": ref.Version,
+	})
+}
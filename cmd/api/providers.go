@@ -0,0 +1,283 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mindforge/internal/ai"
+	"mindforge/internal/database"
+	"mindforge/internal/env"
+	"mindforge/internal/secrets"
+	"strings"
+	"time"
+)
+
+// providerSecret - учетные данные провайдера и источник, из которого они получены
+type providerSecret struct {
+	key    string
+	value  string
+	source string
+}
+
+// lookupSecret ищет первый заданный секрет из списка альтернативных имен
+func lookupSecret(store *secrets.Store, keys ...string) (providerSecret, error) {
+	for _, key := range keys {
+		value, source, err := store.Lookup(key)
+		if err == nil {
+			return providerSecret{key: key, value: value, source: source}, nil
+		}
+		if !errors.Is(err, secrets.ErrNotFound) {
+			return providerSecret{}, fmt.Errorf("secret %s: %w", key, err)
+		}
+	}
+	return providerSecret{}, nil
+}
+
+// tlsOptionsFromEnv читает настройки TLS провайдера из переменных окружения с префиксом
+// Пути к сертификатам не секретны, поэтому читаются напрямую из окружения
+func tlsOptionsFromEnv(prefix string) (ai.TLSOptions, error) {
+	insecure, err := env.GetEnvBool(prefix+"_TLS_INSECURE", false)
+	if err != nil {
+		return ai.TLSOptions{}, err
+	}
+
+	return ai.TLSOptions{
+		CAFile:   env.GetEnvString(prefix+"_CA_FILE", ""),
+		CertFile: env.GetEnvString(prefix+"_CERT_FILE", ""),
+		KeyFile:  env.GetEnvString(prefix+"_KEY_FILE", ""),
+		Insecure: insecure,
+	}, nil
+}
+
+// newProviderFactory создает фабрику и регистрирует только те провайдеры, для которых заданы учетные данные
+// Для каждого провайдера в лог пишется, настроен он или отключен. Ошибка возвращается только
+// при некорректной конфигурации (например, не читается CA файл), а не при отсутствии ключа
+func newProviderFactory(store *secrets.Store, logger *slog.Logger) (*ai.ProviderFactory, error) {
+	factory := ai.NewProviderFactory()
+
+	// DeepSeek
+	secret, err := lookupSecret(store, "DEEPSEEK_API_KEY")
+	if err != nil {
+		return nil, err
+	}
+	if secret.value == "" {
+		logProviderDisabled(logger, "deepseek", "DEEPSEEK_API_KEY")
+	} else {
+		tlsOpts, err := tlsOptionsFromEnv("DEEPSEEK")
+		if err != nil {
+			return nil, err
+		}
+		provider, err := ai.NewDeepSeekProvider(secret.value, ai.WithTLS(tlsOpts))
+		if err != nil {
+			return nil, fmt.Errorf("deepseek: %w", err)
+		}
+		factory.Register("deepseek", provider)
+		logProviderConfigured(logger, "deepseek", secret)
+	}
+
+	// Qwen (DashScope)
+	secret, err = lookupSecret(store, "QWEN_API_KEY", "DASHSCOPE_API_KEY")
+	if err != nil {
+		return nil, err
+	}
+	if secret.value == "" {
+		logProviderDisabled(logger, "qwen", "QWEN_API_KEY", "DASHSCOPE_API_KEY")
+	} else {
+		tlsOpts, err := tlsOptionsFromEnv("QWEN")
+		if err != nil {
+			return nil, err
+		}
+		provider, err := ai.NewQwenProvider(secret.value,
+			ai.WithBaseURL(env.GetEnvString("QWEN_API_BASE_URL", "")),
+			ai.WithTLS(tlsOpts),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("qwen: %w", err)
+		}
+		factory.Register("qwen", provider)
+		logProviderConfigured(logger, "qwen", secret)
+	}
+
+	// GigaChat: Authorization key для OAuth или заранее полученный access token
+	secret, err = lookupSecret(store, "GIGACHAT_AUTH_KEY")
+	if err != nil {
+		return nil, err
+	}
+	token, err := lookupSecret(store, "GIGACHAT_ACCESS_TOKEN", "GIGACHAT_API_KEY")
+	if err != nil {
+		return nil, err
+	}
+	if secret.value == "" && token.value == "" {
+		logProviderDisabled(logger, "gigachat", "GIGACHAT_AUTH_KEY", "GIGACHAT_ACCESS_TOKEN")
+	} else {
+		tlsOpts, err := tlsOptionsFromEnv("GIGACHAT")
+		if err != nil {
+			return nil, err
+		}
+		provider, err := ai.NewGigaChatProvider(secret.value,
+			ai.WithAccessToken(token.value),
+			ai.WithScope(env.GetEnvString("GIGACHAT_SCOPE", "")),
+			ai.WithOAuthURL(env.GetEnvString("GIGACHAT_OAUTH_URL", "")),
+			ai.WithBaseURL(env.GetEnvString("GIGACHAT_API_URL", "")),
+			ai.WithDefaultModel(env.GetEnvString("GIGACHAT_MODEL", "")),
+			ai.WithTLS(tlsOpts),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("gigachat: %w", err)
+		}
+		factory.Register("gigachat", provider)
+		if secret.value == "" {
+			secret = token
+			logger.Warn("GigaChat uses a static access token without refresh: set GIGACHAT_AUTH_KEY")
+		}
+		logProviderConfigured(logger, "gigachat", secret)
+	}
+
+	// Эмбеддинги (OpenAI-совместимый API)
+	secret, err = lookupSecret(store, "EMBEDDINGS_API_KEY")
+	if err != nil {
+		return nil, err
+	}
+	if secret.value == "" {
+		logProviderDisabled(logger, "openai embeddings", "EMBEDDINGS_API_KEY")
+	} else {
+		tlsOpts, err := tlsOptionsFromEnv("EMBEDDINGS")
+		if err != nil {
+			return nil, err
+		}
+		embedder, err := ai.NewOpenAIEmbedder(secret.value,
+			ai.WithBaseURL(env.GetEnvString("EMBEDDINGS_API_URL", "")),
+			ai.WithDefaultModel(env.GetEnvString("EMBEDDINGS_MODEL", "")),
+			ai.WithTLS(tlsOpts),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("embeddings: %w", err)
+		}
+		factory.RegisterEmbedder("openai", embedder)
+		logProviderConfigured(logger, "openai embeddings", secret)
+	}
+
+	if len(factory.List()) == 0 {
+		logger.Warn("No AI providers configured, chat responses are unavailable",
+			"secret_sources", store.Sources())
+	}
+
+	return factory, nil
+}
+
+func logProviderConfigured(logger *slog.Logger, name string, secret providerSecret) {
+	logger.Info("AI provider configured",
+		"provider", name,
+		"secret", secret.key,
+		"secret_source", secret.source,
+	)
+}
+
+func logProviderDisabled(logger *slog.Logger, name string, keys ...string) {
+	logger.Warn("AI provider disabled: credentials are not set",
+		"provider", name,
+		"missing", strings.Join(keys, " or "),
+	)
+}
+
+// newResponseCache создает перехватчик кэша ответов по AI_CACHE_BACKEND
+// off (по умолчанию) - кэш выключен, memory - LRU в памяти процесса, postgres - общий кэш для всех реплик
+func newResponseCache(models database.Models, logger *slog.Logger) (ai.Interceptor, error) {
+	backend := env.GetEnvString("AI_CACHE_BACKEND", "off")
+	ttl := time.Duration(env.GetEnvInt("AI_CACHE_TTL", 3600)) * time.Second
+
+	var store ai.CacheStore
+	switch backend {
+	case "off", "":
+		return nil, nil
+	case "memory":
+		store = ai.NewMemoryCache(env.GetEnvInt("AI_CACHE_MAX_ENTRIES", 1000))
+	case "postgres":
+		store = models.ResponseCache
+		go purgeExpiredResponseCache(models.ResponseCache, logger)
+	default:
+		return nil, fmt.Errorf("unknown AI_CACHE_BACKEND: %s", backend)
+	}
+
+	logger.Info("AI response cache enabled", "backend", backend, "ttl", ttl.String())
+	return ai.CacheInterceptor(store, ttl, logger), nil
+}
+
+// purgeExpiredResponseCache периодически удаляет истекшие записи кэша ответов из PostgreSQL
+func purgeExpiredResponseCache(cache database.ResponseCacheModel, logger *slog.Logger) {
+	ticker := time.NewTicker(10 * time.Minute)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		deleted, err := cache.DeleteExpired()
+		if err != nil {
+			logger.Warn("Error purging AI response cache", "error", err)
+			continue
+		}
+		if deleted > 0 {
+			logger.Debug("Expired AI response cache entries purged", "deleted", deleted)
+		}
+	}
+}
+
+// newRedactionInterceptors создает перехватчики удаления персональных данных по AI_PII_REDACTION:
+// для запросов к моделям и для запросов эмбеддингов (поиск по базе знаний, индексация документов)
+// AI_PII_REDACTION - список провайдеров через запятую, для которых редактирование обязательно,
+// или all для всех провайдеров; пусто - выключено. AI_PII_ENTITIES ограничивает распознаваемые типы
+func newRedactionInterceptors(models database.Models, logger *slog.Logger) (ai.Interceptor, ai.EmbedderInterceptor, error) {
+	providers := splitList(env.GetEnvString("AI_PII_REDACTION", ""))
+	if len(providers) == 0 {
+		return nil, nil, nil
+	}
+
+	redactor, err := ai.NewRedactor(splitList(env.GetEnvString("AI_PII_ENTITIES", ""))...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	enforced := make(map[string]bool, len(providers))
+	for _, name := range providers {
+		enforced[name] = true
+	}
+	enabled := func(provider string) bool {
+		return enforced["all"] || enforced[provider]
+	}
+
+	audit := func(ctx context.Context, report ai.RedactionReport) {
+		event := &database.PIIRedactionEvent{
+			Provider: report.Provider,
+			Model:    report.Model,
+			Entities: report.Entities,
+		}
+		meta, ok := ai.RequestMetaFrom(ctx)
+		if ok {
+			event.UserID = &meta.UserID
+			event.ChatID = meta.ChatID
+		}
+
+		logger.Info("PII redacted from AI request",
+			"provider", report.Provider,
+			"model", report.Model,
+			"entities", report.Entities,
+			"user_id", meta.UserID,
+		)
+		if err := models.PIIRedactions.Insert(event); err != nil {
+			logger.Error("Error recording PII redaction audit", "error", err, "provider", report.Provider)
+		}
+	}
+
+	logger.Info("PII redaction enabled", "providers", providers)
+	return ai.RedactionInterceptor(redactor, enabled, audit), ai.RedactionEmbedderInterceptor(redactor, enabled, audit), nil
+}
+
+// splitList разбирает список значений через запятую, пропуская пустые
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
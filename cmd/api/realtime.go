@@ -0,0 +1,198 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"mindforge/internal/database"
+	"mindforge/internal/env"
+	"mindforge/internal/realtime"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+)
+
+const (
+	// wsPingInterval - период событий ping; прокси не закрывают соединение по таймауту простоя
+	wsPingInterval = 30 * time.Second
+	// wsWriteTimeout - максимальное время отправки одного события
+	wsWriteTimeout = 10 * time.Second
+	// wsBearerProtocol - подпротокол для передачи токена из браузера, где заголовок Authorization недоступен:
+	// new WebSocket(url, ["bearer", accessToken])
+	wsBearerProtocol = "bearer"
+)
+
+// newRealtimeHub создает хаб событий реального времени
+// REALTIME_BROKER: postgres (LISTEN/NOTIFY, события доходят до подключений на всех репликах) или memory (одна реплика)
+func newRealtimeHub(db *sql.DB, dsn string, logger *slog.Logger) (*realtime.Hub, error) {
+	var broker realtime.Broker
+	switch name := env.GetEnvString("REALTIME_BROKER", "postgres"); name {
+	case "postgres":
+		broker = realtime.NewPostgresBroker(db, dsn, logger)
+	case "memory":
+		broker = realtime.NewMemoryBroker()
+	default:
+		return nil, fmt.Errorf("unknown REALTIME_BROKER: %s", name)
+	}
+
+	hub, err := realtime.NewHub(broker, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Realtime events initialized", "broker", hub.BrokerName())
+	return hub, nil
+}
+
+// chatDeletedEvent - данные события chat.deleted
+type chatDeletedEvent struct {
+	// Permanent - чат удален окончательно; иначе перемещен в корзину
+	Permanent bool `json:"permanent"`
+}
+
+// generationStatusEvent - данные события generation.status
+type generationStatusEvent struct {
+	Status string `json:"status"`
+	// MessageID - сохраненный ответ (для статуса completed)
+	MessageID *int   `json:"message_id,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// publishChat отправляет событие с текущим состоянием чата (chat.created, chat.updated)
+func (app *application) publishChat(eventType string, chat *database.Chat) {
+	app.realtime.Publish(chat.UserID, eventType, chat.ID, newChatResponse(chat))
+}
+
+// publishChatUpdated загружает чат и отправляет chat.updated
+func (app *application) publishChatUpdated(chatID int) {
+	chat, err := app.models.Chats.GetByID(chatID)
+	if err != nil {
+		app.logger.Warn("Error loading chat for realtime event", "error", err, "chat_id", chatID)
+		return
+	}
+	app.publishChat(realtime.EventChatUpdated, chat)
+}
+
+// publishMessage отправляет message.created; рассуждения модели в событие не включаются
+func (app *application) publishMessage(userID int, msg *database.Message) {
+	app.realtime.Publish(userID, realtime.EventMessageCreated, msg.ChatID, newMessageResponse(msg, false))
+}
+
+// publishGeneration отправляет generation.status
+func (app *application) publishGeneration(chat *database.Chat, status string, messageID *int, errMsg string) {
+	app.realtime.Publish(chat.UserID, realtime.EventGenerationStatus, chat.ID, generationStatusEvent{
+		Status:    status,
+		MessageID: messageID,
+		Error:     errMsg,
+	})
+}
+
+// wsAuthHeader переносит токен из подпротокола WebSocket в заголовок Authorization для jwtAuthMiddleware
+func wsAuthHeader() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			protocols := strings.Split(c.GetHeader("Sec-WebSocket-Protocol"), ",")
+			if len(protocols) == 2 && strings.TrimSpace(protocols[0]) == wsBearerProtocol {
+				c.Request.Header.Set("Authorization", "Bearer "+strings.TrimSpace(protocols[1]))
+			}
+		}
+		c.Next()
+	}
+}
+
+// handleWebSocket открывает соединение, по которому пользователю приходят события его чатов
+// События отправляются JSON-сообщениями realtime.Event; сообщения клиента игнорируются
+func (app *application) handleWebSocket(c *gin.Context) {
+	userID, apiErr := getUserIDFromContext(c)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	if !strings.EqualFold(c.GetHeader("Upgrade"), "websocket") {
+		errorResponse(c, &APIError{
+			Status:  http.StatusBadRequest,
+			Message: "websocket upgrade required",
+			Code:    "WEBSOCKET_UPGRADE_REQUIRED",
+		})
+		return
+	}
+
+	server := websocket.Server{
+		// Токен проверен jwtAuthMiddleware, поэтому Origin не проверяется: cookies для аутентификации не используются
+		Handshake: func(config *websocket.Config, req *http.Request) error {
+			// В ответе подтверждается только подпротокол bearer, сам токен клиенту не возвращается
+			config.Protocol = nil
+			if strings.HasPrefix(req.Header.Get("Sec-WebSocket-Protocol"), wsBearerProtocol) {
+				config.Protocol = []string{wsBearerProtocol}
+			}
+			return nil
+		},
+		Handler: func(conn *websocket.Conn) {
+			app.serveWebSocket(conn, userID)
+		},
+	}
+	server.ServeHTTP(c.Writer, c.Request)
+}
+
+// serveWebSocket передает события подписки в соединение до его закрытия
+func (app *application) serveWebSocket(conn *websocket.Conn, userID int) {
+	defer conn.Close()
+
+	// Дедлайны чтения и записи сервера (ReadTimeout, WriteTimeout) остаются на перехваченном соединении
+	conn.SetDeadline(time.Time{})
+	conn.MaxPayloadBytes = 4096
+
+	sub := app.realtime.Subscribe(userID)
+	defer sub.Close()
+
+	app.logger.Debug("WebSocket connected", "user_id", userID)
+
+	// Чтение нужно, чтобы обработать закрытие соединения клиентом
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			var msg string
+			if err := websocket.Message.Receive(conn, &msg); err != nil {
+				return
+			}
+		}
+	}()
+
+	send := func(event realtime.Event) error {
+		conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+		return websocket.JSON.Send(conn, event)
+	}
+
+	if err := send(realtime.Event{Type: realtime.EventReady, Time: time.Now().UTC()}); err != nil {
+		return
+	}
+
+	ticker := time.NewTicker(wsPingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-closed:
+			app.logger.Debug("WebSocket closed by client", "user_id", userID)
+			return
+		case event, ok := <-sub.Events():
+			// Подписка закрыта: подключение отстало или сервер останавливается
+			if !ok {
+				return
+			}
+			if err := send(event); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if err := send(realtime.Event{Type: realtime.EventPing, Time: time.Now().UTC()}); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -12,6 +12,9 @@ func (app *application) initializeRoutes() http.Handler {
 	// Health check endpoint (не требует аутентификации)
 	g.GET("/health", app.handleHealth)
 
+	// Метрики AI провайдеров (не требуют аутентификации, не проксируются nginx)
+	g.GET("/metrics", app.handleMetrics)
+
 	v1 := g.Group("/api/v1")
 	{
 		// Аутентификация
@@ -21,17 +24,150 @@ func (app *application) initializeRoutes() http.Handler {
 		v1.POST("/logout", app.handleLogout)
 		v1.GET("/profile", app.jwtAuthMiddleware(), app.handleGetProfile)
 
+		// События чатов в реальном времени (WebSocket, требует аутентификации)
+		v1.GET("/ws", wsAuthHeader(), app.jwtAuthMiddleware(), app.handleWebSocket)
+
 		// Чаты (требуют аутентификации)
 		chats := v1.Group("/chats", app.jwtAuthMiddleware())
 		{
 			chats.POST("", app.handleCreateChat)
 			chats.GET("", app.handleGetChats)
 			chats.GET("/:id", app.handleGetChat)
+			chats.PATCH("/:id", app.handleUpdateChat)
 			chats.PUT("/:id/title", app.handleUpdateChatTitle)
+			chats.PUT("/:id/response-format", app.handleUpdateChatResponseFormat)
 			chats.DELETE("/:id", app.handleDeleteChat)
+			chats.POST("/:id/restore", app.handleRestoreChat)
 			chats.POST("/:id/messages", app.handleCreateMessage)
 			chats.GET("/:id/messages", app.handleGetMessages)
+			chats.POST("/:id/compare", app.handleCompareMessage)
+			chats.POST("/:id/messages/:message_id/select", app.handleSelectCandidate)
+			chats.GET("/:id/collections", app.handleGetChatCollections)
+			chats.PUT("/:id/collections", app.handleSetChatCollections)
+			chats.PUT("/:id/tags", app.handleSetChatTags)
+			chats.GET("/:id/export", app.handleExportChat)
+			chats.POST("/:id/share", app.handleCreateShare)
+			chats.POST("/:id/fork", app.handleForkChat)
+		}
+
+		// Публичные ссылки на чаты: управление требует аутентификации, просмотр снимка - нет
+		shares := v1.Group("/shares", app.jwtAuthMiddleware())
+		{
+			shares.GET("", app.handleGetShares)
+			shares.DELETE("/:id", app.handleDeleteShare)
+		}
+		v1.GET("/shared/:token", app.handleGetSharedChat)
+		v1.POST("/shared/:token/fork", app.jwtAuthMiddleware(), app.handleForkSharedChat)
+
+		// Экспорт всех чатов пользователя в ZIP архив (требует аутентификации)
+		exports := v1.Group("/export", app.jwtAuthMiddleware())
+		{
+			exports.POST("", app.handleCreateExport)
+			exports.GET("", app.handleGetExports)
+			exports.GET("/:id", app.handleGetExport)
+			exports.GET("/:id/download", app.handleDownloadExport)
+		}
+
+		// Импорт диалогов из экспорта ChatGPT и JSON экспорта MindForge (требует аутентификации)
+		imports := v1.Group("/import", app.jwtAuthMiddleware())
+		{
+			imports.POST("", app.handleCreateImport)
+			imports.GET("", app.handleGetImports)
+			imports.GET("/:id", app.handleGetImport)
 		}
+
+		// Папки и теги чатов (требуют аутентификации)
+		folders := v1.Group("/folders", app.jwtAuthMiddleware())
+		{
+			folders.POST("", app.handleCreateFolder)
+			folders.GET("", app.handleGetFolders)
+			folders.PATCH("/:id", app.handleUpdateFolder)
+			folders.DELETE("/:id", app.handleDeleteFolder)
+		}
+
+		tags := v1.Group("/tags", app.jwtAuthMiddleware())
+		{
+			tags.POST("", app.handleCreateTag)
+			tags.GET("", app.handleGetTags)
+			tags.PATCH("/:id", app.handleUpdateTag)
+			tags.DELETE("/:id", app.handleDeleteTag)
+		}
+
+		// Полнотекстовый поиск по чатам и сообщениям пользователя
+		v1.GET("/search", app.jwtAuthMiddleware(), app.handleSearch)
+
+		// Модели настроенных провайдеров
+		v1.GET("/models", app.jwtAuthMiddleware(), app.handleGetModels)
+
+		// Разовые запросы к модели без сохранения в чат (требуют аутентификации)
+		v1.POST("/completions", app.jwtAuthMiddleware(), app.handleCreateCompletion)
+		v1.GET("/usage", app.jwtAuthMiddleware(), app.handleGetUsage)
+
+		// API ключи для OpenAI-совместимого API (требуют аутентификации)
+		apiKeys := v1.Group("/api-keys", app.jwtAuthMiddleware())
+		{
+			apiKeys.POST("", app.handleCreateAPIKey)
+			apiKeys.GET("", app.handleGetAPIKeys)
+			apiKeys.DELETE("/:id", app.handleDeleteAPIKey)
+		}
+
+		// Базы знаний (требуют аутентификации)
+		collections := v1.Group("/collections", app.jwtAuthMiddleware())
+		{
+			collections.POST("", app.handleCreateCollection)
+			collections.GET("", app.handleGetCollections)
+			collections.GET("/:id", app.handleGetCollection)
+			collections.DELETE("/:id", app.handleDeleteCollection)
+			collections.POST("/:id/documents", app.handleCreateDocument)
+			collections.GET("/:id/documents", app.handleGetDocuments)
+			collections.DELETE("/:id/documents/:document_id", app.handleDeleteDocument)
+		}
+
+		// Персоны ассистента (требуют аутентификации)
+		personas := v1.Group("/personas", app.jwtAuthMiddleware())
+		{
+			personas.POST("", app.handleCreatePersona)
+			personas.GET("", app.handleGetPersonas)
+			personas.GET("/:id", app.handleGetPersona)
+			personas.PATCH("/:id", app.handleUpdatePersona)
+			personas.DELETE("/:id", app.handleDeletePersona)
+		}
+
+		// Рабочие пространства (требуют аутентификации)
+		workspaces := v1.Group("/workspaces", app.jwtAuthMiddleware())
+		{
+			workspaces.POST("", app.handleCreateWorkspace)
+			workspaces.GET("", app.handleGetWorkspaces)
+			workspaces.DELETE("/:id", app.handleDeleteWorkspace)
+			workspaces.GET("/:id/members", app.handleGetWorkspaceMembers)
+			workspaces.POST("/:id/members", app.handleAddWorkspaceMember)
+			workspaces.DELETE("/:id/members/:user_id", app.handleRemoveWorkspaceMember)
+		}
+
+		// Шаблоны промптов (требуют аутентификации)
+		prompts := v1.Group("/prompts", app.jwtAuthMiddleware())
+		{
+			prompts.POST("", app.handleCreatePrompt)
+			prompts.GET("", app.handleGetPrompts)
+			prompts.GET("/:id", app.handleGetPrompt)
+			prompts.PATCH("/:id", app.handleUpdatePrompt)
+			prompts.DELETE("/:id", app.handleDeletePrompt)
+			prompts.GET("/:id/versions", app.handleGetPromptVersions)
+			prompts.POST("/:id/render", app.handleRenderPrompt)
+		}
+
+		// Администрирование (требует аутентификации и прав администратора)
+		admin := v1.Group("/admin", app.jwtAuthMiddleware(), app.adminMiddleware())
+		{
+			admin.GET("/moderation-events", app.handleGetModerationEvents)
+		}
+	}
+
+	// OpenAI-совместимый API (аутентификация по API ключам MindForge)
+	openai := g.Group("/v1", app.apiKeyAuthMiddleware())
+	{
+		openai.GET("/models", app.handleOpenAIModels)
+		openai.POST("/chat/completions", app.handleOpenAIChatCompletions)
 	}
 
 	return g
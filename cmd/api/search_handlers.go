@@ -0,0 +1,187 @@
+package main
+
+import (
+	"html"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"mindforge/internal/database"
+
+	"github.com/gin-gonic/gin"
+)
+
+type chatSearchResponse struct {
+	ChatID    int     `json:"chat_id"`
+	Title     string  `json:"title"`
+	Snippet   string  `json:"snippet"`
+	AIModel   string  `json:"ai_model"`
+	Rank      float64 `json:"rank"`
+	UpdatedAt string  `json:"updated_at"`
+}
+
+type messageSearchResponse struct {
+	ChatID    int     `json:"chat_id"`
+	ChatTitle string  `json:"chat_title"`
+	MessageID int     `json:"message_id"`
+	Role      string  `json:"role"`
+	Model     string  `json:"model,omitempty"`
+	Snippet   string  `json:"snippet"`
+	Rank      float64 `json:"rank"`
+	CreatedAt string  `json:"created_at"`
+}
+
+// escapeSnippet экранирует HTML во фрагменте, сохраняя только разметку подсветки <mark>
+func escapeSnippet(snippet string) string {
+	var b strings.Builder
+	for i, part := range strings.Split(snippet, database.SearchHighlightStart) {
+		if i > 0 {
+			b.WriteString(database.SearchHighlightStart)
+		}
+		for j, text := range strings.Split(part, database.SearchHighlightStop) {
+			if j > 0 {
+				b.WriteString(database.SearchHighlightStop)
+			}
+			b.WriteString(html.EscapeString(text))
+		}
+	}
+	return b.String()
+}
+
+// parseSearchTime разбирает границу периода поиска: дату (2006-01-02) или время в RFC 3339
+// Для даты в параметре to берется конец дня, чтобы период включал весь день
+func parseSearchTime(value string, endOfDay bool) (*time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, err
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return &t, nil
+}
+
+// handleSearch выполняет полнотекстовый поиск по названиям чатов и сообщениям пользователя
+// Параметры: q (обязательный), model, role (user/assistant), from, to, limit (по умолчанию 20, максимум 100), offset.
+// При фильтре role поиск по названиям чатов не выполняется
+func (app *application) handleSearch(c *gin.Context) {
+	userID, apiErr := getUserIDFromContext(c)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	q := database.SearchQuery{
+		Text:  strings.TrimSpace(c.Query("q")),
+		Model: strings.TrimSpace(c.Query("model")),
+		Role:  c.Query("role"),
+		Limit: 20,
+	}
+
+	if q.Text == "" {
+		errorResponse(c, &APIError{
+			Status:  400,
+			Message: "q is required",
+			Code:    "VALIDATION_ERROR",
+		})
+		return
+	}
+	if len(q.Text) > 500 {
+		errorResponse(c, &APIError{
+			Status:  400,
+			Message: "q too long (max 500 characters)",
+			Code:    "VALIDATION_ERROR",
+		})
+		return
+	}
+	if q.Role != "" && q.Role != "user" && q.Role != "assistant" {
+		errorResponse(c, &APIError{
+			Status:  400,
+			Message: "role must be user or assistant",
+			Code:    "VALIDATION_ERROR",
+		})
+		return
+	}
+
+	var err error
+	if v := c.Query("from"); v != "" {
+		if q.From, err = parseSearchTime(v, false); err != nil {
+			errorResponse(c, &APIError{
+				Status:  400,
+				Message: "invalid from: use YYYY-MM-DD or RFC 3339",
+				Code:    "VALIDATION_ERROR",
+			})
+			return
+		}
+	}
+	if v := c.Query("to"); v != "" {
+		if q.To, err = parseSearchTime(v, true); err != nil {
+			errorResponse(c, &APIError{
+				Status:  400,
+				Message: "invalid to: use YYYY-MM-DD or RFC 3339",
+				Code:    "VALIDATION_ERROR",
+			})
+			return
+		}
+	}
+
+	if limit, err := strconv.Atoi(c.DefaultQuery("limit", "20")); err == nil && limit > 0 && limit <= 100 {
+		q.Limit = limit
+	}
+	if offset, err := strconv.Atoi(c.DefaultQuery("offset", "0")); err == nil && offset > 0 {
+		q.Offset = offset
+	}
+
+	chatResults := []chatSearchResponse{}
+	if q.Role == "" {
+		chats, err := app.models.Search.SearchChats(userID, q)
+		if err != nil {
+			app.logger.Error("Error searching chats", "error", err, "user_id", userID)
+			internalErrorResponse(c, err)
+			return
+		}
+		for _, r := range chats {
+			chatResults = append(chatResults, chatSearchResponse{
+				ChatID:    r.ChatID,
+				Title:     r.Title,
+				Snippet:   escapeSnippet(r.Snippet),
+				AIModel:   r.AIModel,
+				Rank:      r.Rank,
+				UpdatedAt: r.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
+			})
+		}
+	}
+
+	messages, err := app.models.Search.SearchMessages(userID, q)
+	if err != nil {
+		app.logger.Error("Error searching messages", "error", err, "user_id", userID)
+		internalErrorResponse(c, err)
+		return
+	}
+	messageResults := make([]messageSearchResponse, len(messages))
+	for i, r := range messages {
+		messageResults[i] = messageSearchResponse{
+			ChatID:    r.ChatID,
+			ChatTitle: r.ChatTitle,
+			MessageID: r.MessageID,
+			Role:      r.Role,
+			Model:     r.Model,
+			Snippet:   escapeSnippet(r.Snippet),
+			Rank:      r.Rank,
+			CreatedAt: r.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"query":    q.Text,
+		"chats":    chatResults,
+		"messages": messageResults,
+		"limit":    q.Limit,
+		"offset":   q.Offset,
+	})
+}
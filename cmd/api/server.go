@@ -19,6 +19,9 @@ func (app *application) serve() error {
 		WriteTimeout: 30 * time.Second,
 	}
 
+	// Shutdown не закрывает перехваченные WebSocket-соединения, поэтому они закрываются через хаб
+	srv.RegisterOnShutdown(app.realtime.Close)
+
 	// Канал для получения сигналов от ОС
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
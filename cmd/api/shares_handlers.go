@@ -0,0 +1,302 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"mindforge/internal/database"
+
+	"github.com/gin-gonic/gin"
+)
+
+// createShareRequest - expires_in_hours=0 или отсутствие поля - ссылка без срока действия
+type createShareRequest struct {
+	ExpiresInHours int `json:"expires_in_hours" binding:"min=0,max=8760"`
+}
+
+type shareResponse struct {
+	ID          int     `json:"id"`
+	ChatID      int     `json:"chat_id"`
+	TokenPrefix string  `json:"token_prefix"`
+	Title       string  `json:"title"`
+	ViewsCount  int     `json:"views_count"`
+	Expired     bool    `json:"expired"`
+	ExpiresAt   *string `json:"expires_at"`
+	CreatedAt   string  `json:"created_at"`
+	// Token и URL возвращаются только при создании ссылки
+	Token string `json:"token,omitempty"`
+	URL   string `json:"url,omitempty"`
+}
+
+func newShareResponse(share *database.ChatShare) shareResponse {
+	response := shareResponse{
+		ID:          share.ID,
+		ChatID:      share.ChatID,
+		TokenPrefix: share.TokenPrefix,
+		Title:       share.Title,
+		ViewsCount:  share.ViewsCount,
+		Expired:     share.Expired(),
+		CreatedAt:   share.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+	if share.ExpiresAt != nil {
+		expiresAt := share.ExpiresAt.Format("2006-01-02T15:04:05Z07:00")
+		response.ExpiresAt = &expiresAt
+	}
+	return response
+}
+
+// sharedMessageResponse - сообщение публичного снимка чата; служебные данные и рассуждения не публикуются
+type sharedMessageResponse struct {
+	// ID нужен для ответвления снимка с определенного сообщения (from_message_id)
+	ID        int    `json:"id"`
+	Role      string `json:"role"`
+	Content   string `json:"content"`
+	Model     string `json:"model,omitempty"`
+	CreatedAt string `json:"created_at"`
+}
+
+// sharedChatResponse - публичный снимок чата; сведения о владельце не включаются
+type sharedChatResponse struct {
+	Title     string                  `json:"title"`
+	AIModel   string                  `json:"ai_model"`
+	SharedAt  string                  `json:"shared_at"`
+	ExpiresAt *string                 `json:"expires_at,omitempty"`
+	Messages  []sharedMessageResponse `json:"messages"`
+}
+
+// sharedSnapshot загружает сообщения снимка: ход диалога до момента публикации
+func (app *application) sharedSnapshot(share *database.ChatShare) ([]*database.Message, error) {
+	if share.LastMessageID == nil {
+		return nil, nil
+	}
+
+	messages, err := app.models.Messages.GetUpTo(share.ChatID, *share.LastMessageID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Состав диалога зафиксирован при публикации, текущий статус кандидатов не учитывается
+	included := make(map[int]bool, len(share.MessageIDs))
+	for _, id := range share.MessageIDs {
+		included[id] = true
+	}
+
+	snapshot := make([]*database.Message, 0, len(share.MessageIDs))
+	for _, msg := range messages {
+		if included[msg.ID] {
+			snapshot = append(snapshot, msg)
+		}
+	}
+	return snapshot, nil
+}
+
+// findShare получает действующую ссылку по токену
+func (app *application) findShare(token string) (*database.ChatShare, *APIError) {
+	share, err := app.models.Shares.GetByToken(token)
+	if err != nil {
+		if err.Error() == "share not found" {
+			return nil, &APIError{
+				Status:  404,
+				Message: "shared chat not found",
+				Code:    "SHARE_NOT_FOUND",
+			}
+		}
+		return nil, &APIError{
+			Status:  500,
+			Message: "internal server error",
+			Code:    "INTERNAL_ERROR",
+		}
+	}
+
+	if share.Expired() {
+		return nil, &APIError{
+			Status:  410,
+			Message: "share link has expired",
+			Code:    "SHARE_EXPIRED",
+		}
+	}
+
+	return share, nil
+}
+
+// handleCreateShare создает публичную ссылку на снимок чата
+// Ссылка показывает только сообщения, отправленные до ее создания; токен возвращается только в этом ответе
+func (app *application) handleCreateShare(c *gin.Context) {
+	userID, apiErr := getUserIDFromContext(c)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	chatID, apiErr := getChatIDFromParam(c)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	chat, apiErr := app.validateChatOwnership(c, chatID, userID)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	var req createShareRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			validationErrorResponse(c, err)
+			return
+		}
+	}
+
+	var expiresAt *time.Time
+	if req.ExpiresInHours > 0 {
+		t := time.Now().Add(time.Duration(req.ExpiresInHours) * time.Hour)
+		expiresAt = &t
+	}
+
+	token, err := database.GenerateShareToken()
+	if err != nil {
+		app.logger.Error("Error generating share token", "error", err, "chat_id", chatID)
+		internalErrorResponse(c, err)
+		return
+	}
+
+	share, err := app.models.Shares.Create(chat, token, expiresAt)
+	if err != nil {
+		app.logger.Error("Error creating share", "error", err, "chat_id", chatID)
+		internalErrorResponse(c, err)
+		return
+	}
+
+	response := newShareResponse(share)
+	response.Token = token
+	response.URL = "/api/v1/shared/" + token
+
+	c.JSON(http.StatusCreated, response)
+}
+
+// handleGetShares получает ссылки пользователя (?chat_id - ссылки одного чата)
+func (app *application) handleGetShares(c *gin.Context) {
+	userID, apiErr := getUserIDFromContext(c)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	var chatID *int
+	if v := c.Query("chat_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil || id <= 0 {
+			errorResponse(c, &APIError{
+				Status:  400,
+				Message: "invalid chat id",
+				Code:    "INVALID_CHAT_ID",
+			})
+			return
+		}
+		chatID = &id
+	}
+
+	shares, err := app.models.Shares.GetByUserID(userID, chatID)
+	if err != nil {
+		app.logger.Error("Error getting shares", "error", err, "user_id", userID)
+		internalErrorResponse(c, err)
+		return
+	}
+
+	response := make([]shareResponse, len(shares))
+	for i, share := range shares {
+		response[i] = newShareResponse(share)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// handleDeleteShare отзывает ссылку
+func (app *application) handleDeleteShare(c *gin.Context) {
+	userID, apiErr := getUserIDFromContext(c)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	shareID, apiErr := getIDFromParam(c, "id", "share", "INVALID_SHARE_ID")
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	share, err := app.models.Shares.GetByID(shareID)
+	if err != nil {
+		if err.Error() == "share not found" {
+			errorResponse(c, &APIError{
+				Status:  404,
+				Message: "share not found",
+				Code:    "SHARE_NOT_FOUND",
+			})
+			return
+		}
+		internalErrorResponse(c, err)
+		return
+	}
+
+	if share.UserID != userID {
+		errorResponse(c, &APIError{
+			Status:  403,
+			Message: "forbidden",
+			Code:    "FORBIDDEN",
+		})
+		return
+	}
+
+	if err := app.models.Shares.Delete(shareID); err != nil {
+		app.logger.Error("Error deleting share", "error", err, "share_id", shareID)
+		internalErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "share revoked successfully",
+	})
+}
+
+// handleGetSharedChat отдает публичный снимок чата по токену (без аутентификации)
+func (app *application) handleGetSharedChat(c *gin.Context) {
+	share, apiErr := app.findShare(c.Param("token"))
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	messages, err := app.sharedSnapshot(share)
+	if err != nil {
+		app.logger.Error("Error loading shared chat", "error", err, "share_id", share.ID)
+		internalErrorResponse(c, err)
+		return
+	}
+
+	app.models.Shares.IncrementViews(share.ID)
+
+	response := sharedChatResponse{
+		Title:    share.Title,
+		AIModel:  share.AIModel,
+		SharedAt: share.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		Messages: make([]sharedMessageResponse, len(messages)),
+	}
+	if share.ExpiresAt != nil {
+		expiresAt := share.ExpiresAt.Format("2006-01-02T15:04:05Z07:00")
+		response.ExpiresAt = &expiresAt
+	}
+	for i, msg := range messages {
+		response.Messages[i] = sharedMessageResponse{
+			ID:        msg.ID,
+			Role:      msg.Role,
+			Content:   msg.Content,
+			Model:     msg.Model,
+			CreatedAt: msg.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// extendWriteDeadline продлевает дедлайн записи ответа для долгих синхронных запросов к AI
+// Сервер ограничивает время записи ответа (WriteTimeout), которого не хватает на ответ модели
+func extendWriteDeadline(c *gin.Context, timeout time.Duration) {
+	rc := http.NewResponseController(c.Writer)
+	// Ошибку игнорируем: если дедлайн продлить нельзя, ответ просто будет ограничен WriteTimeout
+	_ = rc.SetWriteDeadline(time.Now().Add(timeout))
+}
+
+// startSSE подготавливает ответ к передаче Server-Sent Events
+// Для потока дедлайн записи продлевается на время генерации
+func startSSE(c *gin.Context, timeout time.Duration) {
+	extendWriteDeadline(c, timeout)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	// Отключаем буферизацию в nginx, иначе события будут приходить пачками
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+}
+
+// writeSSE отправляет одно именованное событие и сразу сбрасывает буфер
+func writeSSE(c *gin.Context, event string, data interface{}) error {
+	c.SSEvent(event, data)
+	c.Writer.Flush()
+	return c.Request.Context().Err()
+}
+
+// writeSSEData отправляет событие без имени (только строку data:), как это делает OpenAI API
+// Строки передаются как есть (например, "[DONE]"), остальные значения сериализуются в JSON
+func writeSSEData(c *gin.Context, data interface{}) error {
+	payload, ok := data.(string)
+	if !ok {
+		encoded, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
+		payload = string(encoded)
+	}
+
+	if _, err := fmt.Fprintf(c.Writer, "data: %s\n\n", payload); err != nil {
+		return err
+	}
+	c.Writer.Flush()
+	return c.Request.Context().Err()
+}
@@ -0,0 +1,299 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+
+	"mindforge/internal/database"
+	"mindforge/internal/realtime"
+
+	"github.com/gin-gonic/gin"
+)
+
+type createTagRequest struct {
+	Name  string `json:"name" binding:"required,min=1,max=100"`
+	Color string `json:"color" binding:"omitempty,hexcolor,len=7"`
+}
+
+// updateTagRequest - частичное обновление тега; пустой color сбрасывает цвет
+type updateTagRequest struct {
+	Name  *string `json:"name" binding:"omitempty,min=1,max=100"`
+	Color *string `json:"color" binding:"omitempty,hexcolor,len=7"`
+}
+
+type setChatTagsRequest struct {
+	TagIDs []int `json:"tag_ids" binding:"required,max=50"`
+}
+
+type tagResponse struct {
+	ID         int    `json:"id"`
+	Name       string `json:"name"`
+	Color      string `json:"color,omitempty"`
+	ChatsCount int    `json:"chats_count"`
+	CreatedAt  string `json:"created_at"`
+}
+
+func newTagResponse(tag *database.Tag) tagResponse {
+	return tagResponse{
+		ID:         tag.ID,
+		Name:       tag.Name,
+		Color:      tag.Color,
+		ChatsCount: tag.ChatsCount,
+		CreatedAt:  tag.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+}
+
+// validateTagOwnership проверяет принадлежность тега пользователю
+func (app *application) validateTagOwnership(tagID, userID int) (*database.Tag, *APIError) {
+	tag, err := app.models.Tags.GetByID(tagID)
+	if err != nil {
+		if err.Error() == "tag not found" {
+			return nil, &APIError{
+				Status:  404,
+				Message: "tag not found",
+				Code:    "TAG_NOT_FOUND",
+			}
+		}
+		return nil, &APIError{
+			Status:  500,
+			Message: "internal server error",
+			Code:    "INTERNAL_ERROR",
+		}
+	}
+
+	if tag.UserID != userID {
+		return nil, &APIError{
+			Status:  403,
+			Message: "forbidden",
+			Code:    "FORBIDDEN",
+		}
+	}
+
+	return tag, nil
+}
+
+// validateTagName проверяет, что у пользователя нет другого тега с таким названием
+func (app *application) validateTagName(userID int, name string, tagID int) *APIError {
+	existing, err := app.models.Tags.GetByName(userID, name)
+	if err != nil {
+		if err.Error() == "tag not found" {
+			return nil
+		}
+		return &APIError{
+			Status:  500,
+			Message: "internal server error",
+			Code:    "INTERNAL_ERROR",
+		}
+	}
+
+	if existing.ID != tagID {
+		return &APIError{
+			Status:  409,
+			Message: "tag with this name already exists",
+			Code:    "TAG_EXISTS",
+		}
+	}
+	return nil
+}
+
+// handleCreateTag создает тег
+func (app *application) handleCreateTag(c *gin.Context) {
+	userID, apiErr := getUserIDFromContext(c)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	var req createTagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		validationErrorResponse(c, err)
+		return
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		errorResponse(c, &APIError{
+			Status:  400,
+			Message: "name cannot be empty",
+			Code:    "VALIDATION_ERROR",
+		})
+		return
+	}
+
+	if apiErr := app.validateTagName(userID, name, 0); apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	tag, err := app.models.Tags.Insert(&database.Tag{
+		UserID: userID,
+		Name:   name,
+		Color:  strings.ToLower(req.Color),
+	})
+	if err != nil {
+		app.logger.Error("Error creating tag", "error", err, "user_id", userID)
+		internalErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, newTagResponse(tag))
+}
+
+// handleGetTags получает все теги пользователя
+func (app *application) handleGetTags(c *gin.Context) {
+	userID, apiErr := getUserIDFromContext(c)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	tags, err := app.models.Tags.GetByUserID(userID)
+	if err != nil {
+		app.logger.Error("Error getting tags", "error", err, "user_id", userID)
+		internalErrorResponse(c, err)
+		return
+	}
+
+	response := make([]tagResponse, len(tags))
+	for i, tag := range tags {
+		response[i] = newTagResponse(tag)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// handleUpdateTag переименовывает тег и/или меняет его цвет
+func (app *application) handleUpdateTag(c *gin.Context) {
+	userID, apiErr := getUserIDFromContext(c)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	tagID, apiErr := getIDFromParam(c, "id", "tag", "INVALID_TAG_ID")
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	tag, apiErr := app.validateTagOwnership(tagID, userID)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	var req updateTagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		validationErrorResponse(c, err)
+		return
+	}
+
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			errorResponse(c, &APIError{
+				Status:  400,
+				Message: "name cannot be empty",
+				Code:    "VALIDATION_ERROR",
+			})
+			return
+		}
+		if apiErr := app.validateTagName(userID, name, tagID); apiErr != nil {
+			errorResponse(c, apiErr)
+			return
+		}
+		tag.Name = name
+	}
+	if req.Color != nil {
+		tag.Color = strings.ToLower(*req.Color)
+	}
+
+	updated, err := app.models.Tags.Update(tag)
+	if err != nil {
+		app.logger.Error("Error updating tag", "error", err, "tag_id", tagID)
+		internalErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newTagResponse(updated))
+}
+
+// handleDeleteTag удаляет тег и снимает его со всех чатов
+func (app *application) handleDeleteTag(c *gin.Context) {
+	userID, apiErr := getUserIDFromContext(c)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	tagID, apiErr := getIDFromParam(c, "id", "tag", "INVALID_TAG_ID")
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	if _, apiErr := app.validateTagOwnership(tagID, userID); apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	if err := app.models.Tags.Delete(tagID); err != nil {
+		app.logger.Error("Error deleting tag", "error", err, "tag_id", tagID)
+		internalErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "tag deleted successfully",
+	})
+}
+
+// handleSetChatTags задает теги чата (полностью заменяет текущий набор)
+func (app *application) handleSetChatTags(c *gin.Context) {
+	userID, apiErr := getUserIDFromContext(c)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	chatID, apiErr := getChatIDFromParam(c)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	if _, apiErr := app.validateChatOwnership(c, chatID, userID); apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	var req setChatTagsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		validationErrorResponse(c, err)
+		return
+	}
+
+	// Отмечать чат можно только собственными тегами
+	for _, tagID := range req.TagIDs {
+		if _, apiErr := app.validateTagOwnership(tagID, userID); apiErr != nil {
+			errorResponse(c, apiErr)
+			return
+		}
+	}
+
+	if err := app.models.Tags.SetChatTags(chatID, req.TagIDs); err != nil {
+		app.logger.Error("Error setting chat tags", "error", err, "chat_id", chatID)
+		internalErrorResponse(c, err)
+		return
+	}
+
+	chat, err := app.models.Chats.GetByID(chatID)
+	if err != nil {
+		internalErrorResponse(c, err)
+		return
+	}
+
+	app.publishChat(realtime.EventChatUpdated, chat)
+
+	c.JSON(http.StatusOK, newChatResponse(chat))
+}
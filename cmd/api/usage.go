@@ -0,0 +1,95 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"mindforge/internal/ai"
+	"mindforge/internal/database"
+
+	"github.com/gin-gonic/gin"
+)
+
+type usageResponse struct {
+	Period    string                `json:"period"`
+	Since     string                `json:"since"`
+	Quota     int                   `json:"quota"` // 0 - без ограничений
+	Remaining *int                  `json:"remaining,omitempty"`
+	Usage     database.UsageSummary `json:"usage"`
+}
+
+// usageDayStart возвращает начало текущих суток (UTC), с которого считается дневная квота
+func usageDayStart() time.Time {
+	return time.Now().UTC().Truncate(24 * time.Hour)
+}
+
+// checkTokenQuota проверяет, что пользователь не израсходовал дневную квоту токенов
+// Квота общая для чатов и completions; AI_DAILY_TOKEN_QUOTA=0 отключает ограничение
+func (app *application) checkTokenQuota(userID int) *APIError {
+	if app.dailyTokenQuota <= 0 {
+		return nil
+	}
+
+	summary, err := app.models.Usage.GetSummary(userID, usageDayStart())
+	if err != nil {
+		// Ошибка учета не должна блокировать работу пользователя
+		app.logger.Error("Error checking token quota", "error", err, "user_id", userID)
+		return nil
+	}
+
+	if summary.TotalTokens >= app.dailyTokenQuota {
+		return ErrTokenQuotaExceeded
+	}
+
+	return nil
+}
+
+// recordUsage сохраняет расход токенов по ответу провайдера
+// chatID равен nil для запросов без чата
+func (app *application) recordUsage(userID int, chatID *int, source, providerName string, resp *ai.ChatResponse) {
+	record := &database.UsageRecord{
+		UserID:           userID,
+		ChatID:           chatID,
+		Source:           source,
+		Provider:         providerName,
+		Model:            resp.Model,
+		PromptTokens:     resp.Usage.PromptTokens,
+		CompletionTokens: resp.Usage.CompletionTokens,
+		TotalTokens:      resp.Usage.TotalTokens,
+		Cached:           resp.Cached,
+	}
+
+	if err := app.models.Usage.Insert(record); err != nil {
+		app.logger.Error("Error recording usage", "error", err, "user_id", userID, "source", source)
+	}
+}
+
+// handleGetUsage возвращает расход токенов пользователя за текущие сутки
+func (app *application) handleGetUsage(c *gin.Context) {
+	userID, apiErr := getUserIDFromContext(c)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	since := usageDayStart()
+	summary, err := app.models.Usage.GetSummary(userID, since)
+	if err != nil {
+		app.logger.Error("Error getting usage", "error", err, "user_id", userID)
+		internalErrorResponse(c, err)
+		return
+	}
+
+	response := usageResponse{
+		Period: "day",
+		Since:  since.Format("2006-01-02T15:04:05Z07:00"),
+		Quota:  app.dailyTokenQuota,
+		Usage:  *summary,
+	}
+	if app.dailyTokenQuota > 0 {
+		remaining := max(app.dailyTokenQuota-summary.TotalTokens, 0)
+		response.Remaining = &remaining
+	}
+
+	c.JSON(http.StatusOK, response)
+}
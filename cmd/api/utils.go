@@ -2,6 +2,7 @@ package main
 
 import (
 	"strconv"
+	"strings"
 
 	"mindforge/internal/database"
 
@@ -51,7 +52,26 @@ func getUserIDFromContext(c *gin.Context) (int, *APIError) {
 }
 
 // validateChatOwnership проверяет принадлежность чата пользователю
+// Чаты из корзины недоступны, пока их не восстановят
 func (app *application) validateChatOwnership(c *gin.Context, chatID, userID int) (*database.Chat, *APIError) {
+	chat, apiErr := app.findOwnedChat(chatID, userID)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	if chat.DeletedAt != nil {
+		return nil, &APIError{
+			Status:  410,
+			Message: "chat is in trash",
+			Code:    "CHAT_IN_TRASH",
+		}
+	}
+
+	return chat, nil
+}
+
+// findOwnedChat получает чат пользователя, в том числе находящийся в корзине
+func (app *application) findOwnedChat(chatID, userID int) (*database.Chat, *APIError) {
 	chat, err := app.models.Chats.GetByID(chatID)
 	if err != nil {
 		if err.Error() == "chat not found" {
@@ -78,3 +98,212 @@ func (app *application) validateChatOwnership(c *gin.Context, chatID, userID int
 
 	return chat, nil
 }
+
+// getIDFromParam извлекает и валидирует положительный числовой ID из параметра URL
+// entity используется в сообщении об ошибке, code - в коде ошибки (например, INVALID_COLLECTION_ID)
+func getIDFromParam(c *gin.Context, param, entity, code string) (int, *APIError) {
+	idStr := c.Param(param)
+	if idStr == "" {
+		return 0, &APIError{
+			Status:  400,
+			Message: entity + " id is required",
+			Code:    code,
+		}
+	}
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil || id <= 0 {
+		return 0, &APIError{
+			Status:  400,
+			Message: "invalid " + entity + " id",
+			Code:    code,
+		}
+	}
+
+	return id, nil
+}
+
+// validateCollectionOwnership проверяет принадлежность коллекции пользователю
+func (app *application) validateCollectionOwnership(collectionID, userID int) (*database.Collection, *APIError) {
+	collection, err := app.models.Collections.GetByID(collectionID)
+	if err != nil {
+		if err.Error() == "collection not found" {
+			return nil, &APIError{
+				Status:  404,
+				Message: "collection not found",
+				Code:    "COLLECTION_NOT_FOUND",
+			}
+		}
+		return nil, &APIError{
+			Status:  500,
+			Message: "internal server error",
+			Code:    "INTERNAL_ERROR",
+		}
+	}
+
+	if collection.UserID != userID {
+		return nil, &APIError{
+			Status:  403,
+			Message: "forbidden",
+			Code:    "FORBIDDEN",
+		}
+	}
+
+	return collection, nil
+}
+
+// noCacheRequested проверяет, запросил ли клиент ответ в обход кэша (Cache-Control: no-cache или no-store)
+func noCacheRequested(c *gin.Context) bool {
+	cacheControl := strings.ToLower(c.GetHeader("Cache-Control"))
+	return strings.Contains(cacheControl, "no-cache") || strings.Contains(cacheControl, "no-store")
+}
+
+// pageResponse - страница списка с курсором для продолжения
+// next_cursor передается в тот же параметр (before или after), null - элементов больше нет.
+// prev_cursor указывает на другой край страницы и позволяет листать в обратном направлении
+// (например, получать новые сообщения через after)
+type pageResponse[T any] struct {
+	Data       []T     `json:"data"`
+	NextCursor *string `json:"next_cursor"`
+	PrevCursor *string `json:"prev_cursor,omitempty"`
+	Total      *int    `json:"total,omitempty"`
+}
+
+// parsePageQuery разбирает параметры пагинации limit, before и after
+func parsePageQuery(c *gin.Context, defaultLimit, maxLimit int) (database.PageQuery, *APIError) {
+	page := database.PageQuery{Limit: defaultLimit}
+
+	if v := c.Query("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 || limit > maxLimit {
+			return page, &APIError{
+				Status:  400,
+				Message: "limit must be between 1 and " + strconv.Itoa(maxLimit),
+				Code:    "VALIDATION_ERROR",
+			}
+		}
+		page.Limit = limit
+	}
+
+	before, after := c.Query("before"), c.Query("after")
+	if before != "" && after != "" {
+		return page, &APIError{
+			Status:  400,
+			Message: "before and after cannot be used together",
+			Code:    "VALIDATION_ERROR",
+		}
+	}
+
+	var err error
+	if before != "" {
+		page.Before, err = database.DecodeCursor(before)
+	} else if after != "" {
+		page.After, err = database.DecodeCursor(after)
+	}
+	if err != nil {
+		return page, &APIError{
+			Status:  400,
+			Message: "invalid cursor",
+			Code:    "INVALID_CURSOR",
+		}
+	}
+
+	return page, nil
+}
+
+// setPageCursors заполняет курсоры страницы по ее самому старому и самому новому элементам
+// next_cursor - самый старый элемент при листании назад (before) и самый новый при листании вперед (after)
+func (r *pageResponse[T]) setPageCursors(page database.PageQuery, hasMore bool, oldest, newest database.Cursor) {
+	next, prev := oldest.Encode(), newest.Encode()
+	if page.After != nil {
+		next, prev = prev, next
+	}
+
+	if hasMore {
+		r.NextCursor = &next
+	}
+	r.PrevCursor = &prev
+}
+
+// parseChatFilter разбирает фильтры списка чатов
+// archived: false (по умолчанию) - без архивных, true - только архивные, all - все;
+// pinned: true/false - только закрепленные или только незакрепленные;
+// trashed=true - чаты из корзины (фильтр archived по умолчанию не применяется);
+// folder_id - чаты папки (root - чаты вне папок); tag_id (можно повторять) - чаты со всеми тегами
+func parseChatFilter(c *gin.Context) (database.ChatFilter, *APIError) {
+	var filter database.ChatFilter
+
+	switch c.Query("trashed") {
+	case "", "false":
+	case "true":
+		filter.Trashed = true
+	default:
+		return filter, &APIError{
+			Status:  400,
+			Message: "trashed must be true or false",
+			Code:    "VALIDATION_ERROR",
+		}
+	}
+
+	switch archived := c.Query("archived"); archived {
+	case "":
+		if !filter.Trashed {
+			filter.Archived = boolPtr(false)
+		}
+	case "true", "false":
+		filter.Archived = boolPtr(archived == "true")
+	case "all":
+	default:
+		return filter, &APIError{
+			Status:  400,
+			Message: "archived must be true, false or all",
+			Code:    "VALIDATION_ERROR",
+		}
+	}
+
+	switch pinned := c.Query("pinned"); pinned {
+	case "":
+	case "true", "false":
+		filter.Pinned = boolPtr(pinned == "true")
+	default:
+		return filter, &APIError{
+			Status:  400,
+			Message: "pinned must be true or false",
+			Code:    "VALIDATION_ERROR",
+		}
+	}
+
+	if folder := c.Query("folder_id"); folder != "" {
+		folderID := 0
+		if folder != "root" {
+			id, err := strconv.Atoi(folder)
+			if err != nil || id <= 0 {
+				return filter, &APIError{
+					Status:  400,
+					Message: "folder_id must be a folder id or root",
+					Code:    "INVALID_FOLDER_ID",
+				}
+			}
+			folderID = id
+		}
+		filter.FolderID = &folderID
+	}
+
+	for _, tag := range c.QueryArray("tag_id") {
+		id, err := strconv.Atoi(tag)
+		if err != nil || id <= 0 {
+			return filter, &APIError{
+				Status:  400,
+				Message: "invalid tag_id",
+				Code:    "INVALID_TAG_ID",
+			}
+		}
+		filter.TagIDs = append(filter.TagIDs, id)
+	}
+
+	return filter, nil
+}
+
+func boolPtr(v bool) *bool {
+	return &v
+}
@@ -0,0 +1,326 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+
+	"mindforge/internal/database"
+
+	"github.com/gin-gonic/gin"
+)
+
+type createWorkspaceRequest struct {
+	Name string `json:"name" binding:"required,min=1,max=200"`
+}
+
+type addWorkspaceMemberRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type workspaceResponse struct {
+	ID        int    `json:"id"`
+	OwnerID   int    `json:"owner_id"`
+	Name      string `json:"name"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+type workspaceMemberResponse struct {
+	UserID    int    `json:"user_id"`
+	Username  string `json:"username"`
+	Email     string `json:"email"`
+	Role      string `json:"role"`
+	CreatedAt string `json:"created_at"`
+}
+
+func newWorkspaceResponse(workspace *database.Workspace) workspaceResponse {
+	return workspaceResponse{
+		ID:        workspace.ID,
+		OwnerID:   workspace.OwnerID,
+		Name:      workspace.Name,
+		CreatedAt: workspace.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		UpdatedAt: workspace.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+}
+
+// validateWorkspaceMembership проверяет, что пользователь состоит в рабочем пространстве
+func (app *application) validateWorkspaceMembership(workspaceID, userID int) (*database.Workspace, *APIError) {
+	workspace, err := app.models.Workspaces.GetByID(workspaceID)
+	if err != nil {
+		if err.Error() == "workspace not found" {
+			return nil, &APIError{
+				Status:  404,
+				Message: "workspace not found",
+				Code:    "WORKSPACE_NOT_FOUND",
+			}
+		}
+		return nil, &APIError{
+			Status:  500,
+			Message: "internal server error",
+			Code:    "INTERNAL_ERROR",
+		}
+	}
+
+	member, err := app.models.Workspaces.IsMember(workspaceID, userID)
+	if err != nil {
+		return nil, &APIError{
+			Status:  500,
+			Message: "internal server error",
+			Code:    "INTERNAL_ERROR",
+		}
+	}
+	if !member {
+		return nil, &APIError{
+			Status:  403,
+			Message: "forbidden",
+			Code:    "FORBIDDEN",
+		}
+	}
+
+	return workspace, nil
+}
+
+// validateWorkspaceOwnership проверяет, что пользователь - владелец рабочего пространства
+func (app *application) validateWorkspaceOwnership(workspaceID, userID int) (*database.Workspace, *APIError) {
+	workspace, apiErr := app.validateWorkspaceMembership(workspaceID, userID)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	if workspace.OwnerID != userID {
+		return nil, &APIError{
+			Status:  403,
+			Message: "only the workspace owner can do this",
+			Code:    "FORBIDDEN",
+		}
+	}
+
+	return workspace, nil
+}
+
+// handleCreateWorkspace создает рабочее пространство, создатель становится владельцем
+func (app *application) handleCreateWorkspace(c *gin.Context) {
+	userID, apiErr := getUserIDFromContext(c)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	var req createWorkspaceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		validationErrorResponse(c, err)
+		return
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		errorResponse(c, &APIError{
+			Status:  400,
+			Message: "name cannot be empty",
+			Code:    "VALIDATION_ERROR",
+		})
+		return
+	}
+
+	workspace, err := app.models.Workspaces.Create(userID, name)
+	if err != nil {
+		app.logger.Error("Error creating workspace", "error", err, "user_id", userID)
+		internalErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, newWorkspaceResponse(workspace))
+}
+
+// handleGetWorkspaces получает рабочие пространства пользователя
+func (app *application) handleGetWorkspaces(c *gin.Context) {
+	userID, apiErr := getUserIDFromContext(c)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	workspaces, err := app.models.Workspaces.GetByUserID(userID)
+	if err != nil {
+		app.logger.Error("Error getting workspaces", "error", err, "user_id", userID)
+		internalErrorResponse(c, err)
+		return
+	}
+
+	response := make([]workspaceResponse, len(workspaces))
+	for i, workspace := range workspaces {
+		response[i] = newWorkspaceResponse(workspace)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// handleDeleteWorkspace удаляет рабочее пространство (только владелец)
+func (app *application) handleDeleteWorkspace(c *gin.Context) {
+	userID, apiErr := getUserIDFromContext(c)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	workspaceID, apiErr := getIDFromParam(c, "id", "workspace", "INVALID_WORKSPACE_ID")
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	if _, apiErr := app.validateWorkspaceOwnership(workspaceID, userID); apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	if err := app.models.Workspaces.Delete(workspaceID); err != nil {
+		app.logger.Error("Error deleting workspace", "error", err, "workspace_id", workspaceID)
+		internalErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "workspace deleted successfully",
+	})
+}
+
+// handleGetWorkspaceMembers получает участников рабочего пространства
+func (app *application) handleGetWorkspaceMembers(c *gin.Context) {
+	userID, apiErr := getUserIDFromContext(c)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	workspaceID, apiErr := getIDFromParam(c, "id", "workspace", "INVALID_WORKSPACE_ID")
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	if _, apiErr := app.validateWorkspaceMembership(workspaceID, userID); apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	members, err := app.models.Workspaces.GetMembers(workspaceID)
+	if err != nil {
+		app.logger.Error("Error getting workspace members", "error", err, "workspace_id", workspaceID)
+		internalErrorResponse(c, err)
+		return
+	}
+
+	response := make([]workspaceMemberResponse, len(members))
+	for i, member := range members {
+		response[i] = workspaceMemberResponse{
+			UserID:    member.UserID,
+			Username:  member.Username,
+			Email:     member.Email,
+			Role:      member.Role,
+			CreatedAt: member.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// handleAddWorkspaceMember добавляет пользователя в рабочее пространство по email (только владелец)
+func (app *application) handleAddWorkspaceMember(c *gin.Context) {
+	userID, apiErr := getUserIDFromContext(c)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	workspaceID, apiErr := getIDFromParam(c, "id", "workspace", "INVALID_WORKSPACE_ID")
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	if _, apiErr := app.validateWorkspaceOwnership(workspaceID, userID); apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	var req addWorkspaceMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		validationErrorResponse(c, err)
+		return
+	}
+
+	user, err := app.models.Users.GetByEmail(strings.ToLower(strings.TrimSpace(req.Email)))
+	if err != nil {
+		errorResponse(c, &APIError{
+			Status:  404,
+			Message: "user not found",
+			Code:    "USER_NOT_FOUND",
+		})
+		return
+	}
+
+	if err := app.models.Workspaces.AddMember(workspaceID, user.ID); err != nil {
+		app.logger.Error("Error adding workspace member", "error", err, "workspace_id", workspaceID)
+		internalErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "member added successfully",
+	})
+}
+
+// handleRemoveWorkspaceMember исключает участника (владелец) или выходит из рабочего пространства (сам участник)
+func (app *application) handleRemoveWorkspaceMember(c *gin.Context) {
+	userID, apiErr := getUserIDFromContext(c)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	workspaceID, apiErr := getIDFromParam(c, "id", "workspace", "INVALID_WORKSPACE_ID")
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	memberID, apiErr := getIDFromParam(c, "user_id", "user", "INVALID_USER_ID")
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	workspace, apiErr := app.validateWorkspaceMembership(workspaceID, userID)
+	if apiErr != nil {
+		errorResponse(c, apiErr)
+		return
+	}
+
+	if workspace.OwnerID != userID && memberID != userID {
+		errorResponse(c, &APIError{
+			Status:  403,
+			Message: "only the workspace owner can remove other members",
+			Code:    "FORBIDDEN",
+		})
+		return
+	}
+	if memberID == workspace.OwnerID {
+		errorResponse(c, &APIError{
+			Status:  400,
+			Message: "workspace owner cannot be removed",
+			Code:    "VALIDATION_ERROR",
+		})
+		return
+	}
+
+	if err := app.models.Workspaces.RemoveMember(workspaceID, memberID); err != nil {
+		app.logger.Error("Error removing workspace member", "error", err, "workspace_id", workspaceID)
+		internalErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "member removed successfully",
+	})
+}
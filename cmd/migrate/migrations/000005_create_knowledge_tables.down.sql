@@ -0,0 +1,14 @@
+ALTER TABLE messages DROP COLUMN IF EXISTS metadata;
+
+DROP INDEX IF EXISTS idx_chat_collections_collection_id;
+DROP TABLE IF EXISTS chat_collections;
+
+DROP INDEX IF EXISTS idx_document_chunks_collection_id;
+DROP INDEX IF EXISTS idx_document_chunks_document_id;
+DROP TABLE IF EXISTS document_chunks;
+
+DROP INDEX IF EXISTS idx_documents_collection_id;
+DROP TABLE IF EXISTS documents;
+
+DROP INDEX IF EXISTS idx_collections_user_id;
+DROP TABLE IF EXISTS collections;
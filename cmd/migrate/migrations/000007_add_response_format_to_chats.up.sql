@@ -0,0 +1 @@
+ALTER TABLE chats ADD COLUMN IF NOT EXISTS response_format JSONB;
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS usage_records (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    chat_id INTEGER,
+    source VARCHAR(20) NOT NULL CHECK(source IN ('chat', 'completion')),
+    provider VARCHAR(50) NOT NULL,
+    model VARCHAR(100) NOT NULL DEFAULT '',
+    prompt_tokens INTEGER NOT NULL DEFAULT 0,
+    completion_tokens INTEGER NOT NULL DEFAULT 0,
+    total_tokens INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (chat_id) REFERENCES chats(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_usage_records_user_id_created_at ON usage_records(user_id, created_at);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_messages_parent_message_id;
+ALTER TABLE messages DROP COLUMN IF EXISTS candidate_status;
+ALTER TABLE messages DROP COLUMN IF EXISTS parent_message_id;
+ALTER TABLE messages DROP COLUMN IF EXISTS model;
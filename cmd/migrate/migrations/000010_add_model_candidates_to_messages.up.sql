@@ -0,0 +1,10 @@
+-- Модель, сгенерировавшая ответ ассистента
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS model VARCHAR(100) NOT NULL DEFAULT '';
+
+-- Режим сравнения: ответы нескольких моделей сохраняются как кандидаты,
+-- привязанные к сообщению пользователя; в историю попадает только выбранный
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS parent_message_id INTEGER REFERENCES messages(id) ON DELETE CASCADE;
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS candidate_status VARCHAR(20)
+    CHECK(candidate_status IN ('pending', 'selected', 'rejected'));
+
+CREATE INDEX IF NOT EXISTS idx_messages_parent_message_id ON messages(parent_message_id);
@@ -0,0 +1,2 @@
+ALTER TABLE usage_records DROP COLUMN IF EXISTS cached;
+DROP TABLE IF EXISTS ai_response_cache;
@@ -0,0 +1,12 @@
+-- Общий кэш ответов AI для нескольких реплик (AI_CACHE_BACKEND=postgres)
+CREATE TABLE IF NOT EXISTS ai_response_cache (
+    cache_key CHAR(64) PRIMARY KEY,
+    response BYTEA NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_ai_response_cache_expires_at ON ai_response_cache(expires_at);
+
+-- Ответы из кэша учитываются отдельно и не расходуют квоту
+ALTER TABLE usage_records ADD COLUMN IF NOT EXISTS cached BOOLEAN NOT NULL DEFAULT FALSE;
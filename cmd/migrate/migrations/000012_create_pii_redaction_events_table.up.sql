@@ -0,0 +1,15 @@
+-- Аудит удаления персональных данных из запросов к внешним провайдерам
+-- Сами значения не хранятся, только количество по типам (email, phone, card, ...)
+CREATE TABLE IF NOT EXISTS pii_redaction_events (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER,
+    chat_id INTEGER,
+    provider VARCHAR(50) NOT NULL,
+    model VARCHAR(100) NOT NULL DEFAULT '',
+    entities JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL,
+    FOREIGN KEY (chat_id) REFERENCES chats(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_pii_redaction_events_created_at ON pii_redaction_events(created_at);
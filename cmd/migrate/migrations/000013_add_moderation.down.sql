@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS moderation_events;
+ALTER TABLE messages DROP COLUMN IF EXISTS moderation_status;
+ALTER TABLE users DROP COLUMN IF EXISTS is_admin;
@@ -0,0 +1,25 @@
+-- Администраторы видят журнал модерации
+ALTER TABLE users ADD COLUMN IF NOT EXISTS is_admin BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Ответ модели, заблокированный модерацией, сохраняется без исходного текста
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS moderation_status VARCHAR(30)
+    CHECK(moderation_status IN ('content_blocked'));
+
+-- Журнал срабатываний модерации (входящие сообщения и ответы моделей)
+CREATE TABLE IF NOT EXISTS moderation_events (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER,
+    chat_id INTEGER,
+    message_id INTEGER,
+    stage VARCHAR(10) NOT NULL CHECK(stage IN ('input', 'output')),
+    moderator VARCHAR(20) NOT NULL,
+    category VARCHAR(100) NOT NULL DEFAULT '',
+    rule TEXT NOT NULL DEFAULT '',
+    excerpt TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL,
+    FOREIGN KEY (chat_id) REFERENCES chats(id) ON DELETE SET NULL,
+    FOREIGN KEY (message_id) REFERENCES messages(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_moderation_events_created_at ON moderation_events(created_at);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS prompt_template_versions;
+DROP TABLE IF EXISTS prompt_templates;
+DROP TABLE IF EXISTS workspace_members;
+DROP TABLE IF EXISTS workspaces;
@@ -0,0 +1,30 @@
+-- Персоны: именованные наборы настроек ассистента (модель, системный промпт, параметры, базы знаний)
+CREATE TABLE IF NOT EXISTS personas (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    name VARCHAR(200) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    ai_model VARCHAR(100) NOT NULL,
+    system_prompt TEXT NOT NULL DEFAULT '',
+    temperature DOUBLE PRECISION,
+    top_p DOUBLE PRECISION,
+    max_tokens INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_personas_user_id ON personas(user_id);
+
+-- Коллекции знаний персоны дополняют коллекции, привязанные к чату
+CREATE TABLE IF NOT EXISTS persona_collections (
+    persona_id INTEGER NOT NULL,
+    collection_id INTEGER NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (persona_id, collection_id),
+    FOREIGN KEY (persona_id) REFERENCES personas(id) ON DELETE CASCADE,
+    FOREIGN KEY (collection_id) REFERENCES collections(id) ON DELETE CASCADE
+);
+
+-- Настройки персоны применяются при каждой генерации, поэтому изменения персоны действуют на все ее чаты
+ALTER TABLE chats ADD COLUMN IF NOT EXISTS persona_id INTEGER REFERENCES personas(id) ON DELETE SET NULL;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_chats_title_fts_english;
+DROP INDEX IF EXISTS idx_chats_title_fts_russian;
+DROP INDEX IF EXISTS idx_messages_content_fts_english;
+DROP INDEX IF EXISTS idx_messages_content_fts_russian;
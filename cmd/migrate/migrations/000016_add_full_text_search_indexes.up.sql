@@ -0,0 +1,6 @@
+-- Полнотекстовый поиск по сообщениям и названиям чатов (русская и английская морфология)
+-- Выражения индексов должны совпадать с выражениями в запросах поиска
+CREATE INDEX IF NOT EXISTS idx_messages_content_fts_russian ON messages USING GIN (to_tsvector('russian', content));
+CREATE INDEX IF NOT EXISTS idx_messages_content_fts_english ON messages USING GIN (to_tsvector('english', content));
+CREATE INDEX IF NOT EXISTS idx_chats_title_fts_russian ON chats USING GIN (to_tsvector('russian', title));
+CREATE INDEX IF NOT EXISTS idx_chats_title_fts_english ON chats USING GIN (to_tsvector('english', title));
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_chats_deleted_at;
+ALTER TABLE chats DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE chats DROP COLUMN IF EXISTS is_pinned;
+ALTER TABLE chats DROP COLUMN IF EXISTS is_archived;
@@ -0,0 +1,7 @@
+-- Архив и закрепление чатов, корзина удаленных чатов
+-- deleted_at заполняется при удалении чата; фоновая задача удаляет чаты из корзины после срока хранения
+ALTER TABLE chats ADD COLUMN IF NOT EXISTS is_archived BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE chats ADD COLUMN IF NOT EXISTS is_pinned BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE chats ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_chats_deleted_at ON chats(deleted_at) WHERE deleted_at IS NOT NULL;
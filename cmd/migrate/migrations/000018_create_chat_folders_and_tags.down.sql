@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS chat_tag_links;
+DROP TABLE IF EXISTS chat_tags;
+ALTER TABLE chats DROP COLUMN IF EXISTS folder_id;
+DROP TABLE IF EXISTS chat_folders;
@@ -0,0 +1,40 @@
+-- Папки чатов пользователя; вложенность задается parent_id, подпапки удаляются вместе с родителем
+CREATE TABLE IF NOT EXISTS chat_folders (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    parent_id INTEGER,
+    name VARCHAR(200) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (parent_id) REFERENCES chat_folders(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_chat_folders_user_id ON chat_folders(user_id);
+CREATE INDEX IF NOT EXISTS idx_chat_folders_parent_id ON chat_folders(parent_id);
+
+-- При удалении папки ее чаты переходят в корень
+ALTER TABLE chats ADD COLUMN IF NOT EXISTS folder_id INTEGER REFERENCES chat_folders(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_chats_folder_id ON chats(folder_id);
+
+-- Теги чатов пользователя
+CREATE TABLE IF NOT EXISTS chat_tags (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    color VARCHAR(7) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    UNIQUE (user_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS chat_tag_links (
+    chat_id INTEGER NOT NULL,
+    tag_id INTEGER NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (chat_id, tag_id),
+    FOREIGN KEY (chat_id) REFERENCES chats(id) ON DELETE CASCADE,
+    FOREIGN KEY (tag_id) REFERENCES chat_tags(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_chat_tag_links_tag_id ON chat_tag_links(tag_id);
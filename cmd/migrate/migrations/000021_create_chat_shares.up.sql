@@ -0,0 +1,21 @@
+-- Публичные ссылки на чаты только для чтения
+-- Ссылка показывает снимок чата: название и модель на момент публикации и сообщения до last_message_id
+CREATE TABLE IF NOT EXISTS chat_shares (
+    id SERIAL PRIMARY KEY,
+    chat_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    -- Храним только SHA-256 хеш токена, сама ссылка показывается пользователю один раз
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    token_prefix VARCHAR(16) NOT NULL,
+    title VARCHAR(200) NOT NULL,
+    ai_model VARCHAR(100) NOT NULL,
+    last_message_id INTEGER,
+    views_count INTEGER NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (chat_id) REFERENCES chats(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_chat_shares_user_id ON chat_shares(user_id);
+CREATE INDEX IF NOT EXISTS idx_chat_shares_chat_id ON chat_shares(chat_id);
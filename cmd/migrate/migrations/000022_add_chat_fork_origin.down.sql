@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_chats_forked_from_chat_id;
+ALTER TABLE chats DROP COLUMN IF EXISTS forked_from_share_id;
+ALTER TABLE chats DROP COLUMN IF EXISTS forked_from_message_id;
+ALTER TABLE chats DROP COLUMN IF EXISTS forked_from_chat_id;
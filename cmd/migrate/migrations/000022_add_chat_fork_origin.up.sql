@@ -0,0 +1,7 @@
+-- Происхождение ответвлений чатов: исходный чат и сообщение, на котором история была скопирована,
+-- либо публичная ссылка, из снимка которой сделано ответвление
+ALTER TABLE chats ADD COLUMN IF NOT EXISTS forked_from_chat_id INTEGER REFERENCES chats(id) ON DELETE SET NULL;
+ALTER TABLE chats ADD COLUMN IF NOT EXISTS forked_from_message_id INTEGER REFERENCES messages(id) ON DELETE SET NULL;
+ALTER TABLE chats ADD COLUMN IF NOT EXISTS forked_from_share_id INTEGER REFERENCES chat_shares(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_chats_forked_from_chat_id ON chats(forked_from_chat_id);
@@ -0,0 +1,13 @@
+-- updated_at обновляется по ходу импорта: задача без обновлений дольше таймаута считается прерванной
+-- (сервер остановлен во время импорта) и не блокирует новый импорт пользователя
+ALTER TABLE import_jobs ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP;
+UPDATE import_jobs SET updated_at = COALESCE(completed_at, created_at);
+
+-- До уникального индекса у пользователя может остаться несколько задач pending: оставляем последнюю
+UPDATE import_jobs
+SET status = 'failed', error = 'import was interrupted', completed_at = CURRENT_TIMESTAMP
+WHERE status = 'pending'
+  AND id NOT IN (SELECT MAX(id) FROM import_jobs WHERE status = 'pending' GROUP BY user_id);
+
+-- Одновременно выполняется не больше одного импорта пользователя
+CREATE UNIQUE INDEX IF NOT EXISTS idx_import_jobs_user_pending ON import_jobs(user_id) WHERE status = 'pending';
@@ -0,0 +1 @@
+ALTER TABLE chat_shares DROP COLUMN IF EXISTS message_ids;
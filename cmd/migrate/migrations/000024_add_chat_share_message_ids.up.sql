@@ -0,0 +1,14 @@
+-- Состав снимка фиксируется при публикации: кандидат сравнения, выбранный после создания ссылки,
+-- в снимок не попадает. Условие совпадает с Message.InContext
+ALTER TABLE chat_shares ADD COLUMN IF NOT EXISTS message_ids INTEGER[] NOT NULL DEFAULT '{}';
+
+-- Для существующих ссылок фиксируется текущий состав
+UPDATE chat_shares s
+SET message_ids = ARRAY(
+    SELECT m.id FROM messages m
+    WHERE m.chat_id = s.chat_id AND m.id <= s.last_message_id
+      AND m.moderation_status IS NULL
+      AND (m.candidate_status IS NULL OR m.candidate_status = 'selected')
+    ORDER BY m.id
+)
+WHERE s.last_message_id IS NOT NULL;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_export_jobs_user_pending;
+ALTER TABLE export_jobs DROP COLUMN IF EXISTS updated_at;
@@ -0,0 +1,13 @@
+-- updated_at обновляется по ходу экспорта: задача без обновлений дольше таймаута считается прерванной
+-- (сервер остановлен во время экспорта) и не блокирует новый экспорт пользователя
+ALTER TABLE export_jobs ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP;
+UPDATE export_jobs SET updated_at = COALESCE(completed_at, created_at);
+
+-- До уникального индекса у пользователя может остаться несколько задач pending: оставляем последнюю
+UPDATE export_jobs
+SET status = 'failed', error = 'export was interrupted', completed_at = CURRENT_TIMESTAMP
+WHERE status = 'pending'
+  AND id NOT IN (SELECT MAX(id) FROM export_jobs WHERE status = 'pending' GROUP BY user_id);
+
+-- Одновременно выполняется не больше одного экспорта пользователя
+CREATE UNIQUE INDEX IF NOT EXISTS idx_export_jobs_user_pending ON export_jobs(user_id) WHERE status = 'pending';
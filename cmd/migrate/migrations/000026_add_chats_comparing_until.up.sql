@@ -0,0 +1,4 @@
+-- comparing_until - до этого времени в чате идет сравнение моделей: новые сообщения не принимаются,
+-- пока ответы-кандидаты не сохранены. Время, а не флаг, чтобы сравнение, прерванное остановкой сервера,
+-- не блокировало чат навсегда
+ALTER TABLE chats ADD COLUMN IF NOT EXISTS comparing_until TIMESTAMP WITH TIME ZONE;
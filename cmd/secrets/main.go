@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"mindforge/internal/secrets"
+)
+
+// Утилита для работы с зашифрованным файлом секретов (SECRETS_FILE)
+//
+//	go run ./cmd/secrets keygen                       - сгенерировать ключ для SECRETS_KEY
+//	go run ./cmd/secrets encrypt < secrets.json > f   - зашифровать JSON {"KEY": "value"}
+//	go run ./cmd/secrets decrypt < f                  - расшифровать файл
+//
+// Для encrypt и decrypt ключ берется из переменной SECRETS_KEY
+func main() {
+	if len(os.Args) < 2 {
+		log.Fatal("Please provide a command: keygen, encrypt or decrypt")
+	}
+
+	switch os.Args[1] {
+	case "keygen":
+		key, err := secrets.GenerateKey()
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println(key)
+
+	case "encrypt":
+		key := requireKey()
+		input, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		// Проверяем формат до шифрования, чтобы ошибка не обнаружилась только при запуске сервера
+		var values map[string]string
+		if err := json.Unmarshal(input, &values); err != nil {
+			log.Fatal("Input must be a JSON object of strings: ", err)
+		}
+
+		encrypted, err := secrets.Encrypt(input, key)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println(encrypted)
+
+	case "decrypt":
+		key := requireKey()
+		input, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		plaintext, err := secrets.Decrypt(string(trimNewline(input)), key)
+		if err != nil {
+			log.Fatal(err)
+		}
+		os.Stdout.Write(plaintext)
+
+	default:
+		log.Fatal("Unknown command. Use keygen, encrypt or decrypt")
+	}
+}
+
+func requireKey() string {
+	key := os.Getenv("SECRETS_KEY")
+	if key == "" {
+		log.Fatal("SECRETS_KEY environment variable is required")
+	}
+	return key
+}
+
+func trimNewline(data []byte) []byte {
+	for len(data) > 0 && (data[len(data)-1] == '\n' || data[len(data)-1] == '\r') {
+		data = data[:len(data)-1]
+	}
+	return data
+}
@@ -0,0 +1,42 @@
+package ai
+
+import "strings"
+
+// Приведение истории диалога к ограничениям конкретного API.
+// История чата могла быть накоплена с другой моделью (модель чата можно сменить),
+// поэтому провайдеры адаптируют сообщения перед отправкой
+
+// mergeSystemMessages объединяет все системные сообщения в одно и ставит его первым
+// Нужно для API, которые принимают system только первым сообщением (например, GigaChat)
+func mergeSystemMessages(messages []Message) []Message {
+	var system []string
+	rest := make([]Message, 0, len(messages))
+	for _, msg := range messages {
+		if msg.Role == "system" {
+			system = append(system, msg.Content)
+			continue
+		}
+		rest = append(rest, msg)
+	}
+
+	if len(system) == 0 {
+		return rest
+	}
+
+	return append([]Message{{Role: "system", Content: strings.Join(system, "\n\n")}}, rest...)
+}
+
+// mergeConsecutiveRoles склеивает идущие подряд сообщения одной роли
+// Такое бывает, например, если ответ модели не был получен и пользователь написал еще раз;
+// часть API (GigaChat, deepseek-reasoner) требует чередования user/assistant
+func mergeConsecutiveRoles(messages []Message) []Message {
+	result := make([]Message, 0, len(messages))
+	for _, msg := range messages {
+		if n := len(result); n > 0 && result[n-1].Role == msg.Role && msg.Role != "system" {
+			result[n-1].Content += "\n\n" + msg.Content
+			continue
+		}
+		result = append(result, msg)
+	}
+	return result
+}
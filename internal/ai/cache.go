@@ -0,0 +1,198 @@
+package ai
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// CacheStore - хранилище кэша ответов: ключ - хеш запроса, значение - сериализованный ChatResponse
+// Реализации: MemoryCache (LRU в памяти процесса) и database.ResponseCacheModel (PostgreSQL, общий для реплик)
+type CacheStore interface {
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+}
+
+// CacheKey вычисляет ключ кэша по провайдеру, модели, параметрам генерации и сообщениям
+func CacheKey(provider, model string, req ChatRequest) string {
+	data, _ := json.Marshal(struct {
+		Provider       string          `json:"provider"`
+		Model          string          `json:"model"`
+		Messages       []Message       `json:"messages"`
+		ResponseFormat *ResponseFormat `json:"response_format,omitempty"`
+		Temperature    *float64        `json:"temperature,omitempty"`
+		TopP           *float64        `json:"top_p,omitempty"`
+		MaxTokens      int             `json:"max_tokens,omitempty"`
+	}{provider, model, req.Messages, req.ResponseFormat, req.Temperature, req.TopP, req.MaxTokens})
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+type cacheValidatorKey struct{}
+
+// withCacheValidator добавляет в контекст проверку ответа перед сохранением в кэш
+// Используется ChatStructured: ответ, не прошедший проверку формата, не должен возвращаться из кэша
+func withCacheValidator(ctx context.Context, validate func(content string) error) context.Context {
+	return context.WithValue(ctx, cacheValidatorKey{}, validate)
+}
+
+// cacheable сообщает, можно ли сохранить ответ в кэш
+func cacheable(ctx context.Context, resp *ChatResponse) bool {
+	validate, ok := ctx.Value(cacheValidatorKey{}).(func(string) error)
+	return !ok || validate(resp.Content) == nil
+}
+
+// CacheInterceptor возвращает ответы на одинаковые запросы из кэша
+// Ответ из кэша помечается Cached=true; в потоковом режиме он отдается одной порцией.
+// Запросы с NoCache, ответы с ошибкой и ответы, не прошедшие проверку из контекста (withCacheValidator),
+// не кэшируются. Ошибки хранилища не прерывают запрос
+func CacheInterceptor(store CacheStore, ttl time.Duration, logger *slog.Logger) Interceptor {
+	lookup := func(ctx context.Context, key string) *ChatResponse {
+		data, ok, err := store.Get(ctx, key)
+		if err != nil {
+			logger.WarnContext(ctx, "AI response cache read failed", "error", err)
+			return nil
+		}
+		if !ok {
+			return nil
+		}
+
+		var resp ChatResponse
+		if err := json.Unmarshal(data, &resp); err != nil {
+			logger.WarnContext(ctx, "AI response cache entry is corrupted", "error", err)
+			return nil
+		}
+		resp.Cached = true
+		return &resp
+	}
+
+	save := func(ctx context.Context, key string, resp *ChatResponse) {
+		data, err := json.Marshal(resp)
+		if err != nil {
+			return
+		}
+		if err := store.Set(ctx, key, data, ttl); err != nil {
+			logger.WarnContext(ctx, "AI response cache write failed", "error", err)
+		}
+	}
+
+	return InterceptorFuncs(
+		func(p Provider, next ChatFunc) ChatFunc {
+			return func(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+				if req.NoCache {
+					return next(ctx, req)
+				}
+
+				key := CacheKey(p.GetName(), modelOrDefault(req.Model, p.GetDefaultModel()), req)
+				if resp := lookup(ctx, key); resp != nil {
+					return resp, nil
+				}
+
+				resp, err := next(ctx, req)
+				if err == nil && cacheable(ctx, resp) {
+					save(ctx, key, resp)
+				}
+				return resp, err
+			}
+		},
+		func(p Provider, next ChatStreamFunc) ChatStreamFunc {
+			return func(ctx context.Context, req ChatRequest, onChunk StreamHandler) (*ChatResponse, error) {
+				if req.NoCache {
+					return next(ctx, req, onChunk)
+				}
+
+				key := CacheKey(p.GetName(), modelOrDefault(req.Model, p.GetDefaultModel()), req)
+				if resp := lookup(ctx, key); resp != nil {
+					if resp.ReasoningContent != "" {
+						if err := onChunk(StreamChunk{ReasoningDelta: resp.ReasoningContent}); err != nil {
+							return nil, err
+						}
+					}
+					if err := onChunk(StreamChunk{ContentDelta: resp.Content}); err != nil {
+						return nil, err
+					}
+					return resp, nil
+				}
+
+				resp, err := next(ctx, req, onChunk)
+				if err == nil && cacheable(ctx, resp) {
+					save(ctx, key, resp)
+				}
+				return resp, err
+			}
+		},
+	)
+}
+
+// MemoryCache - LRU кэш в памяти процесса с ограничением количества записей
+type MemoryCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	items      map[string]*list.Element
+	order      *list.List // в начале - недавно использованные записи
+}
+
+type memoryCacheEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+// NewMemoryCache создает LRU кэш на maxEntries записей
+func NewMemoryCache(maxEntries int) *MemoryCache {
+	return &MemoryCache{
+		maxEntries: max(maxEntries, 1),
+		items:      make(map[string]*list.Element),
+		order:      list.New(),
+	}
+}
+
+func (c *MemoryCache) Get(_ context.Context, key string) ([]byte, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[key]
+	if !ok {
+		return nil, false, nil
+	}
+
+	entry := elem.Value.(*memoryCacheEntry)
+	if time.Now().After(entry.expiresAt) {
+		c.order.Remove(elem)
+		delete(c.items, key)
+		return nil, false, nil
+	}
+
+	c.order.MoveToFront(elem)
+	return entry.value, true, nil
+}
+
+func (c *MemoryCache) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expiresAt := time.Now().Add(ttl)
+	if elem, ok := c.items[key]; ok {
+		entry := elem.Value.(*memoryCacheEntry)
+		entry.value, entry.expiresAt = value, expiresAt
+		c.order.MoveToFront(elem)
+		return nil
+	}
+
+	c.items[key] = c.order.PushFront(&memoryCacheEntry{key: key, value: value, expiresAt: expiresAt})
+
+	// Вытесняем давно не использованные записи
+	for c.order.Len() > c.maxEntries {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*memoryCacheEntry).key)
+	}
+
+	return nil
+}
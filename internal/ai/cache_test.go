@@ -0,0 +1,314 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestMemoryCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	cache := NewMemoryCache(2)
+
+	cache.Set(ctx, "a", []byte("1"), time.Hour)
+	cache.Set(ctx, "b", []byte("2"), time.Hour)
+
+	// Чтение делает запись недавно использованной: вытесняется b
+	if _, ok, _ := cache.Get(ctx, "a"); !ok {
+		t.Fatal("a should be cached")
+	}
+	cache.Set(ctx, "c", []byte("3"), time.Hour)
+
+	if _, ok, _ := cache.Get(ctx, "b"); ok {
+		t.Error("b should be evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok, _ := cache.Get(ctx, key); !ok {
+			t.Errorf("%s should be cached", key)
+		}
+	}
+
+	// Перезапись существующего ключа не вытесняет другие записи
+	cache.Set(ctx, "a", []byte("4"), time.Hour)
+	if value, ok, _ := cache.Get(ctx, "a"); !ok || string(value) != "4" {
+		t.Errorf("a = %q, %v; want 4", value, ok)
+	}
+	if _, ok, _ := cache.Get(ctx, "c"); !ok {
+		t.Error("c should stay cached after overwriting a")
+	}
+}
+
+func TestMemoryCacheTTL(t *testing.T) {
+	ctx := context.Background()
+	cache := NewMemoryCache(10)
+
+	cache.Set(ctx, "expired", []byte("1"), -time.Second)
+	cache.Set(ctx, "fresh", []byte("2"), time.Hour)
+
+	if _, ok, _ := cache.Get(ctx, "expired"); ok {
+		t.Error("expired entry should not be returned")
+	}
+	if _, ok, _ := cache.Get(ctx, "fresh"); !ok {
+		t.Error("fresh entry should be returned")
+	}
+	if _, ok := cache.items["expired"]; ok {
+		t.Error("expired entry should be removed on read")
+	}
+
+	// Перезапись продлевает время жизни
+	cache.Set(ctx, "expired", []byte("3"), time.Hour)
+	if value, ok, _ := cache.Get(ctx, "expired"); !ok || string(value) != "3" {
+		t.Errorf("renewed entry = %q, %v; want 3", value, ok)
+	}
+}
+
+func TestCacheKey(t *testing.T) {
+	req := ChatRequest{Messages: []Message{{Role: "user", Content: "привет"}}}
+	key := CacheKey("fake", "fake-model", req)
+
+	if CacheKey("fake", "fake-model", req) != key {
+		t.Error("key must be stable")
+	}
+	if CacheKey("fake", "other-model", req) == key {
+		t.Error("key must depend on model")
+	}
+
+	temperature := 0.5
+	withTemperature := req
+	withTemperature.Temperature = &temperature
+	if CacheKey("fake", "fake-model", withTemperature) == key {
+		t.Error("key must depend on generation parameters")
+	}
+
+	// NoCache и Stream не влияют на ключ: потоковый и обычный запросы используют одну запись
+	other := req
+	other.NoCache, other.Stream = true, true
+	if CacheKey("fake", "fake-model", other) != key {
+		t.Error("key must not depend on NoCache and Stream")
+	}
+}
+
+func newCachedProvider() (*fakeProvider, Provider) {
+	provider := newFakeProvider()
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+	return provider, Chain(provider, CacheInterceptor(NewMemoryCache(10), time.Hour, logger))
+}
+
+func TestCacheInterceptorChat(t *testing.T) {
+	ctx := context.Background()
+	provider, cached := newCachedProvider()
+	req := ChatRequest{Messages: []Message{{Role: "user", Content: "привет"}}}
+
+	first, err := cached.Chat(ctx, req)
+	if err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	if first.Cached {
+		t.Error("first response should not be cached")
+	}
+
+	second, err := cached.Chat(ctx, req)
+	if err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	if !second.Cached || second.Content != first.Content || second.Usage != first.Usage {
+		t.Errorf("second response = %+v, want cached copy of %+v", second, first)
+	}
+	if provider.calls != 1 {
+		t.Errorf("provider calls = %d, want 1", provider.calls)
+	}
+
+	// NoCache обходит кэш
+	bypass := req
+	bypass.NoCache = true
+	resp, err := cached.Chat(ctx, bypass)
+	if err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	if resp.Cached || provider.calls != 2 {
+		t.Errorf("NoCache: cached = %v, provider calls = %d; want false, 2", resp.Cached, provider.calls)
+	}
+}
+
+func TestCacheInterceptorDoesNotCacheErrors(t *testing.T) {
+	ctx := context.Background()
+	provider, cached := newCachedProvider()
+	req := ChatRequest{Messages: []Message{{Role: "user", Content: "привет"}}}
+
+	provider.err = errors.New("upstream failed")
+	if _, err := cached.Chat(ctx, req); err == nil {
+		t.Fatal("Chat: expected error")
+	}
+
+	provider.err = nil
+	resp, err := cached.Chat(ctx, req)
+	if err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	if resp.Cached || provider.calls != 2 {
+		t.Errorf("cached = %v, provider calls = %d; want false, 2", resp.Cached, provider.calls)
+	}
+}
+
+func TestCacheInterceptorStream(t *testing.T) {
+	ctx := context.Background()
+	provider, cached := newCachedProvider()
+	provider.reply.ReasoningContent = "думаю"
+	provider.chunks = []StreamChunk{{ReasoningDelta: "ду"}, {ReasoningDelta: "маю"}, {ContentDelta: "отв"}, {ContentDelta: "ет"}}
+	req := ChatRequest{Messages: []Message{{Role: "user", Content: "привет"}}}
+
+	var chunks []StreamChunk
+	collect := func(chunk StreamChunk) error {
+		chunks = append(chunks, chunk)
+		return nil
+	}
+
+	if _, err := cached.ChatStream(ctx, req, collect); err != nil {
+		t.Fatalf("ChatStream: %v", err)
+	}
+	if len(chunks) != 4 {
+		t.Fatalf("first stream: got %d chunks, want 4", len(chunks))
+	}
+
+	// Повторный запрос отдается из кэша одной порцией рассуждений и одной порцией ответа
+	chunks = nil
+	resp, err := cached.ChatStream(ctx, req, collect)
+	if err != nil {
+		t.Fatalf("ChatStream: %v", err)
+	}
+	want := []StreamChunk{{ReasoningDelta: "думаю"}, {ContentDelta: "ответ"}}
+	if len(chunks) != len(want) || chunks[0] != want[0] || chunks[1] != want[1] {
+		t.Errorf("cached chunks = %+v, want %+v", chunks, want)
+	}
+	if !resp.Cached || provider.calls != 1 {
+		t.Errorf("cached = %v, provider calls = %d; want true, 1", resp.Cached, provider.calls)
+	}
+
+	// Потоковый и обычный запросы используют одну запись кэша
+	if resp, err := cached.Chat(ctx, req); err != nil || !resp.Cached {
+		t.Errorf("Chat after stream: cached = %v, err = %v; want cached", resp != nil && resp.Cached, err)
+	}
+}
+
+func TestCacheInterceptorDoesNotCacheFailedStreams(t *testing.T) {
+	ctx := context.Background()
+	discard := func(StreamChunk) error { return nil }
+
+	t.Run("provider error", func(t *testing.T) {
+		provider, cached := newCachedProvider()
+		req := ChatRequest{Messages: []Message{{Role: "user", Content: "привет"}}}
+
+		provider.err = errors.New("stream broken")
+		if _, err := cached.ChatStream(ctx, req, discard); err == nil {
+			t.Fatal("ChatStream: expected error")
+		}
+
+		provider.err = nil
+		resp, err := cached.ChatStream(ctx, req, discard)
+		if err != nil {
+			t.Fatalf("ChatStream: %v", err)
+		}
+		if resp.Cached || provider.calls != 2 {
+			t.Errorf("cached = %v, provider calls = %d; want false, 2", resp.Cached, provider.calls)
+		}
+	})
+
+	t.Run("client disconnected", func(t *testing.T) {
+		provider, cached := newCachedProvider()
+		req := ChatRequest{Messages: []Message{{Role: "user", Content: "привет"}}}
+
+		// Клиент отключился на первой порции: неполный ответ не должен попасть в кэш
+		gone := errors.New("client gone")
+		_, err := cached.ChatStream(ctx, req, func(StreamChunk) error { return gone })
+		if !errors.Is(err, gone) {
+			t.Fatalf("ChatStream error = %v, want %v", err, gone)
+		}
+
+		resp, err := cached.ChatStream(ctx, req, discard)
+		if err != nil {
+			t.Fatalf("ChatStream: %v", err)
+		}
+		if resp.Cached || provider.calls != 2 {
+			t.Errorf("cached = %v, provider calls = %d; want false, 2", resp.Cached, provider.calls)
+		}
+	})
+
+	t.Run("no cache", func(t *testing.T) {
+		provider, cached := newCachedProvider()
+		req := ChatRequest{Messages: []Message{{Role: "user", Content: "привет"}}, NoCache: true}
+
+		for i := 0; i < 2; i++ {
+			resp, err := cached.ChatStream(ctx, req, discard)
+			if err != nil {
+				t.Fatalf("ChatStream: %v", err)
+			}
+			if resp.Cached {
+				t.Error("NoCache stream must not be served from cache")
+			}
+		}
+		if provider.calls != 2 {
+			t.Errorf("provider calls = %d, want 2", provider.calls)
+		}
+	})
+}
+
+// failingStore - хранилище кэша, все операции которого завершаются ошибкой
+type failingStore struct{}
+
+func (failingStore) Get(context.Context, string) ([]byte, bool, error) {
+	return nil, false, errors.New("store is down")
+}
+
+func (failingStore) Set(context.Context, string, []byte, time.Duration) error {
+	return errors.New("store is down")
+}
+
+func TestCacheInterceptorIgnoresStoreErrors(t *testing.T) {
+	provider := newFakeProvider()
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+	cached := Chain(provider, CacheInterceptor(failingStore{}, time.Hour, logger))
+
+	resp, err := cached.Chat(context.Background(), ChatRequest{})
+	if err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	if resp.Content != "ответ" || provider.calls != 1 {
+		t.Errorf("content = %q, provider calls = %d", resp.Content, provider.calls)
+	}
+}
+
+func TestCacheInterceptorSkipsInvalidStructuredOutput(t *testing.T) {
+	ctx := context.Background()
+	provider, cached := newCachedProvider()
+	provider.reply.Content = "не JSON"
+	req := ChatRequest{
+		Messages:       []Message{{Role: "user", Content: "верни JSON"}},
+		ResponseFormat: &ResponseFormat{Type: ResponseFormatJSONObject},
+	}
+
+	// Обе попытки (ответ и исправление) не проходят проверку и не сохраняются
+	for i := 0; i < 2; i++ {
+		if _, err := ChatStructured(ctx, cached, req); !errors.Is(err, ErrInvalidStructuredOutput) {
+			t.Fatalf("ChatStructured: err = %v, want ErrInvalidStructuredOutput", err)
+		}
+	}
+	if provider.calls != 4 {
+		t.Errorf("provider calls = %d, want 4: invalid replies must not be served from cache", provider.calls)
+	}
+
+	// Корректный ответ кэшируется
+	provider.reply.Content = `{"ok": true}`
+	if _, err := ChatStructured(ctx, cached, req); err != nil {
+		t.Fatalf("ChatStructured: %v", err)
+	}
+	resp, err := ChatStructured(ctx, cached, req)
+	if err != nil {
+		t.Fatalf("ChatStructured: %v", err)
+	}
+	if !resp.Cached || provider.calls != 5 {
+		t.Errorf("cached = %v, provider calls = %d; want true, 5", resp.Cached, provider.calls)
+	}
+}
@@ -7,8 +7,6 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"os"
-	"time"
 )
 
 const (
@@ -17,25 +15,36 @@ const (
 )
 
 type DeepSeekProvider struct {
-	apiKey  string
-	baseURL string
-	client  *http.Client
+	apiKey       string
+	baseURL      string
+	defaultModel string
+	client       *http.Client
+	// streamClient - клиент без общего таймаута для потоковых ответов
+	streamClient *http.Client
 }
 
 // NewDeepSeekProvider создает новый провайдер DeepSeek
-func NewDeepSeekProvider() *DeepSeekProvider {
-	apiKey := os.Getenv("DEEPSEEK_API_KEY")
+// Ошибка возвращается, если не задан API ключ или некорректны настройки TLS
+func NewDeepSeekProvider(apiKey string, opts ...Option) (*DeepSeekProvider, error) {
 	if apiKey == "" {
-		apiKey = os.Getenv("DEEPSEEK_API_KEY") // Можно использовать значение по умолчанию для тестов
+		return nil, fmt.Errorf("%w: deepseek", ErrAPIKeyMissing)
 	}
 
-	return &DeepSeekProvider{
-		apiKey:  apiKey,
-		baseURL: deepseekAPIURL,
-		client: &http.Client{
-			Timeout: 60 * time.Second,
-		},
+	o, client, err := applyOptions("deepseek", providerOptions{
+		baseURL:      deepseekAPIURL,
+		defaultModel: deepseekDefaultModel,
+	}, opts)
+	if err != nil {
+		return nil, err
 	}
+
+	return &DeepSeekProvider{
+		apiKey:       apiKey,
+		baseURL:      o.baseURL,
+		defaultModel: o.defaultModel,
+		client:       client,
+		streamClient: newStreamingClient(client, o.timeout),
+	}, nil
 }
 
 // GetName возвращает имя провайдера
@@ -45,40 +54,16 @@ func (p *DeepSeekProvider) GetName() string {
 
 // GetDefaultModel возвращает модель по умолчанию
 func (p *DeepSeekProvider) GetDefaultModel() string {
-	return deepseekDefaultModel
+	return p.defaultModel
 }
 
 // Chat отправляет запрос к DeepSeek API
 func (p *DeepSeekProvider) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
-	if p.apiKey == "" {
-		return nil, fmt.Errorf("%w: DEEPSEEK_API_KEY not set", ErrAPIKeyMissing)
-	}
-
-	// Подготавливаем запрос в формате DeepSeek
-	deepseekReq := map[string]interface{}{
-		"model":    req.Model,
-		"messages": convertMessages(req.Messages),
-		"stream":   false,
-	}
-
-	// Если модель не указана, используем модель по умолчанию
-	if deepseekReq["model"] == "" {
-		deepseekReq["model"] = deepseekDefaultModel
-	}
-
-	jsonData, err := json.Marshal(deepseekReq)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
-	}
-
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.baseURL, bytes.NewBuffer(jsonData))
+	httpReq, err := p.newRequest(ctx, req, false)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, err
 	}
 
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", p.apiKey))
-
 	resp, err := p.client.Do(httpReq)
 	if err != nil {
 		return nil, fmt.Errorf("%w: %w", ErrAPIRequestFailed, err)
@@ -96,6 +81,8 @@ func (p *DeepSeekProvider) Chat(ctx context.Context, req ChatRequest) (*ChatResp
 			Message struct {
 				Role    string `json:"role"`
 				Content string `json:"content"`
+				// reasoning_content возвращает deepseek-reasoner вместе с итоговым ответом
+				ReasoningContent string `json:"reasoning_content"`
 			} `json:"message"`
 		} `json:"choices"`
 		Model string `json:"model"`
@@ -115,8 +102,9 @@ func (p *DeepSeekProvider) Chat(ctx context.Context, req ChatRequest) (*ChatResp
 	}
 
 	response := &ChatResponse{
-		Content: deepseekResp.Choices[0].Message.Content,
-		Model:   deepseekResp.Model,
+		Content:          deepseekResp.Choices[0].Message.Content,
+		ReasoningContent: deepseekResp.Choices[0].Message.ReasoningContent,
+		Model:            deepseekResp.Model,
 	}
 	response.Usage.PromptTokens = deepseekResp.Usage.PromptTokens
 	response.Usage.CompletionTokens = deepseekResp.Usage.CompletionTokens
@@ -125,7 +113,108 @@ func (p *DeepSeekProvider) Chat(ctx context.Context, req ChatRequest) (*ChatResp
 	return response, nil
 }
 
+// ChatStream отправляет потоковый запрос к DeepSeek API
+func (p *DeepSeekProvider) ChatStream(ctx context.Context, req ChatRequest, onChunk StreamHandler) (*ChatResponse, error) {
+	httpReq, err := p.newRequest(ctx, req, true)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.streamClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrAPIRequestFailed, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("%w: status %d, body: %s", ErrAPIRequestFailed, resp.StatusCode, string(body))
+	}
+
+	return readOpenAIStream(resp.Body, modelOrDefault(req.Model, p.defaultModel), onChunk)
+}
+
+// SupportsResponseFormat сообщает, какие форматы ответа DeepSeek поддерживает нативно
+// DeepSeek умеет только json_object, json_schema эмулируется
+func (p *DeepSeekProvider) SupportsResponseFormat(formatType string) bool {
+	return formatType == ResponseFormatJSONObject
+}
+
+// newRequest подготавливает HTTP запрос к DeepSeek API
+func (p *DeepSeekProvider) newRequest(ctx context.Context, req ChatRequest, stream bool) (*http.Request, error) {
+	if p.apiKey == "" {
+		return nil, fmt.Errorf("%w: deepseek", ErrAPIKeyMissing)
+	}
+
+	model := modelOrDefault(req.Model, p.defaultModel)
+	messages := req.Messages
+	if info, ok := LookupModel(model); ok && info.Reasoning {
+		// deepseek-reasoner не принимает идущие подряд сообщения одной роли
+		messages = mergeConsecutiveRoles(messages)
+	}
+
+	// Подготавливаем запрос в формате DeepSeek
+	deepseekReq := map[string]interface{}{
+		"model":    model,
+		"messages": convertMessages(messages),
+		"stream":   stream,
+	}
+	if stream {
+		// Просим вернуть usage последним событием потока
+		deepseekReq["stream_options"] = map[string]bool{"include_usage": true}
+	}
+	if p.SupportsResponseFormat(formatType(req.ResponseFormat)) {
+		deepseekReq["response_format"] = req.ResponseFormat
+	}
+	applyGenerationParams(deepseekReq, req)
+
+	jsonData, err := json.Marshal(deepseekReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.baseURL, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", p.apiKey))
+
+	return httpReq, nil
+}
+
+// formatType возвращает тип формата ответа или пустую строку, если формат не задан
+func formatType(f *ResponseFormat) string {
+	if f == nil {
+		return ""
+	}
+	return f.Type
+}
+
+// applyGenerationParams добавляет в тело запроса заданные параметры генерации
+func applyGenerationParams(body map[string]interface{}, req ChatRequest) {
+	if req.Temperature != nil {
+		body["temperature"] = *req.Temperature
+	}
+	if req.TopP != nil {
+		body["top_p"] = *req.TopP
+	}
+	if req.MaxTokens > 0 {
+		body["max_tokens"] = req.MaxTokens
+	}
+}
+
+// modelOrDefault возвращает модель из запроса или модель по умолчанию, если она не указана
+func modelOrDefault(model, defaultModel string) string {
+	if model == "" {
+		return defaultModel
+	}
+	return model
+}
+
 // convertMessages конвертирует сообщения в формат API
+// Передаются только роль и текст: рассуждения модели в контекст не попадают
 func convertMessages(messages []Message) []map[string]string {
 	result := make([]map[string]string, len(messages))
 	for i, msg := range messages {
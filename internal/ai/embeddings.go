@@ -0,0 +1,173 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+)
+
+const (
+	openAIEmbeddingsDefaultURL   = "https://api.openai.com/v1/embeddings"
+	openAIEmbeddingsDefaultModel = "text-embedding-3-small"
+)
+
+// EmbeddingRequest представляет запрос на получение эмбеддингов
+type EmbeddingRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+// EmbeddingResponse представляет ответ с эмбеддингами
+// Порядок векторов совпадает с порядком Input в запросе
+type EmbeddingResponse struct {
+	Vectors [][]float32 `json:"vectors"`
+	Model   string      `json:"model"`
+	Usage   struct {
+		PromptTokens int `json:"prompt_tokens"`
+		TotalTokens  int `json:"total_tokens"`
+	} `json:"usage"`
+}
+
+// Embedder определяет интерфейс для получения векторных представлений текста
+type Embedder interface {
+	// Embed возвращает эмбеддинги для каждого элемента Input
+	Embed(ctx context.Context, req EmbeddingRequest) (*EmbeddingResponse, error)
+
+	// GetDefaultModel возвращает модель эмбеддингов по умолчанию
+	GetDefaultModel() string
+
+	// GetName возвращает имя провайдера эмбеддингов
+	GetName() string
+}
+
+// EmbedderInterceptor оборачивает провайдера эмбеддингов дополнительной логикой
+type EmbedderInterceptor func(next Embedder) Embedder
+
+// OpenAIEmbedder работает с любым OpenAI-совместимым /embeddings endpoint
+type OpenAIEmbedder struct {
+	apiKey  string
+	baseURL string
+	model   string
+	client  *http.Client
+}
+
+// NewOpenAIEmbedder создает провайдер эмбеддингов для OpenAI-совместимого API
+// Через WithBaseURL можно указать любой совместимый endpoint /embeddings
+func NewOpenAIEmbedder(apiKey string, opts ...Option) (*OpenAIEmbedder, error) {
+	if apiKey == "" {
+		return nil, fmt.Errorf("%w: openai embeddings", ErrAPIKeyMissing)
+	}
+
+	o, client, err := applyOptions("openai embeddings", providerOptions{
+		baseURL:      openAIEmbeddingsDefaultURL,
+		defaultModel: openAIEmbeddingsDefaultModel,
+	}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OpenAIEmbedder{
+		apiKey:  apiKey,
+		baseURL: o.baseURL,
+		model:   o.defaultModel,
+		client:  client,
+	}, nil
+}
+
+// GetName возвращает имя провайдера
+func (e *OpenAIEmbedder) GetName() string {
+	return "openai"
+}
+
+// GetDefaultModel возвращает модель по умолчанию
+func (e *OpenAIEmbedder) GetDefaultModel() string {
+	return e.model
+}
+
+// Embed отправляет запрос к /embeddings
+func (e *OpenAIEmbedder) Embed(ctx context.Context, req EmbeddingRequest) (*EmbeddingResponse, error) {
+	if e.apiKey == "" {
+		return nil, fmt.Errorf("%w: openai embeddings", ErrAPIKeyMissing)
+	}
+
+	if len(req.Input) == 0 {
+		return &EmbeddingResponse{Model: req.Model}, nil
+	}
+
+	model := req.Model
+	if model == "" {
+		model = e.model
+	}
+
+	jsonData, err := json.Marshal(map[string]interface{}{
+		"model": model,
+		"input": req.Input,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", e.baseURL, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", e.apiKey))
+
+	resp, err := e.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrAPIRequestFailed, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("%w: status %d, body: %s", ErrAPIRequestFailed, resp.StatusCode, string(body))
+	}
+
+	var embeddingsResp struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+		Model string `json:"model"`
+		Usage struct {
+			PromptTokens int `json:"prompt_tokens"`
+			TotalTokens  int `json:"total_tokens"`
+		} `json:"usage"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&embeddingsResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	if len(embeddingsResp.Data) != len(req.Input) {
+		return nil, fmt.Errorf("%w: expected %d embeddings, got %d",
+			ErrAPIRequestFailed, len(req.Input), len(embeddingsResp.Data))
+	}
+
+	// API не гарантирует порядок, поэтому сортируем по index
+	sort.Slice(embeddingsResp.Data, func(i, j int) bool {
+		return embeddingsResp.Data[i].Index < embeddingsResp.Data[j].Index
+	})
+
+	response := &EmbeddingResponse{
+		Vectors: make([][]float32, len(embeddingsResp.Data)),
+		Model:   embeddingsResp.Model,
+	}
+	for i, item := range embeddingsResp.Data {
+		response.Vectors[i] = item.Embedding
+	}
+	if response.Model == "" {
+		response.Model = model
+	}
+	response.Usage.PromptTokens = embeddingsResp.Usage.PromptTokens
+	response.Usage.TotalTokens = embeddingsResp.Usage.TotalTokens
+
+	return response, nil
+}
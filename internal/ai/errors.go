@@ -6,4 +6,6 @@ var (
 	ErrProviderNotFound = errors.New("ai provider not found")
 	ErrAPIKeyMissing    = errors.New("api key is missing")
 	ErrAPIRequestFailed = errors.New("api request failed")
+	// ErrInvalidStructuredOutput - ответ модели не прошел проверку формата даже после попытки исправления
+	ErrInvalidStructuredOutput = errors.New("invalid structured output")
 )
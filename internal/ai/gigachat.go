@@ -4,81 +4,98 @@ import (
 	"bytes"
 	"context"
 	"crypto/rand"
-	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
-	"os"
+	"strings"
 	"sync"
 	"time"
 )
 
 const (
-	gigachatAPIURL       = "https://gigachat.devices.sberbank.ru/api/v1/chat/completions"
+	gigachatAPIURL       = "https://gigachat.devices.sberbank.ru/api/v1"
 	gigachatOAuthURL     = "https://ngw.devices.sberbank.ru:9443/api/v2/oauth"
 	gigachatDefaultModel = "GigaChat"
-	gigachatScope        = "GIGACHAT_API_PERS"
+	gigachatDefaultScope = "GIGACHAT_API_PERS"
+	// Список моделей меняется редко, поэтому ответ /models кэшируется
+	gigachatModelsTTL = 10 * time.Minute
 	// Токен действует 30 минут, обновляем за 5 минут до истечения
 	tokenRefreshMargin = 5 * time.Minute
 )
 
+// gigachatScopes - допустимые scope OAuth: физические лица, ИП и юрлица по предоплате, юрлица по постоплате
+var gigachatScopes = map[string]bool{
+	"GIGACHAT_API_PERS": true,
+	"GIGACHAT_API_B2B":  true,
+	"GIGACHAT_API_CORP": true,
+}
+
 type GigaChatProvider struct {
 	authKey      string
-	clientID     string
+	scope        string
+	oauthURL     string
 	baseURL      string
+	defaultModel string
 	client       *http.Client
+	// streamClient - клиент без общего таймаута для потоковых ответов
+	streamClient *http.Client
 	tokenMutex   sync.RWMutex
 	accessToken  string
 	tokenExpires time.Time
+
+	modelsMutex   sync.Mutex
+	models        []ModelInfo
+	modelsExpires time.Time
 }
 
 // NewGigaChatProvider создает новый провайдер GigaChat
-func NewGigaChatProvider() *GigaChatProvider {
-	// Получаем Authorization key (Base64 encoded client_id:client_secret)
-	authKey := os.Getenv("GIGACHAT_AUTH_KEY")
-	if authKey == "" {
-		// Используем предоставленный ключ по умолчанию
-		authKey = "MDE5YjBhOWEtOTdiNS03MmVlLWI5NGMtYjYyN2EwMjhhNWRkOjUzNTUxM2VmLTQ4MWItNGE0Yi1hNDkzLWMyYTRjZDQ2NmVhNA=="
-	}
-
-	clientID := os.Getenv("GIGACHAT_CLIENT_ID")
-	if clientID == "" {
-		// Используем предоставленный client ID по умолчанию
-		clientID = "019b0a9a-97b5-72ee-b94c-b627a028a5dd"
+// authKey - Authorization key (Base64 client_id:client_secret), по нему access token получается через OAuth.
+// Без authKey используется токен из WithAccessToken как есть, без обновления: после его истечения
+// запросы завершаются ошибкой авторизации.
+// WithBaseURL задает корень API (https://gigachat.devices.sberbank.ru/api/v1), WithScope - scope
+// корпоративных аккаунтов, WithOAuthURL - адрес получения токена.
+// Сертификаты GigaChat выпущены НУЦ Минцифры (Russian Trusted Root CA), которого нет в системных
+// хранилищах, поэтому корневой сертификат передается через WithTLS. Проверка сертификатов
+// отключается только явным TLSOptions.Insecure
+func NewGigaChatProvider(authKey string, opts ...Option) (*GigaChatProvider, error) {
+	// Один клиент используется и для OAuth, и для запросов к API: оба endpoint'а
+	// подписаны одним корневым сертификатом
+	o, client, err := applyOptions("gigachat", providerOptions{
+		baseURL:      gigachatAPIURL,
+		defaultModel: gigachatDefaultModel,
+		scope:        gigachatDefaultScope,
+		oauthURL:     gigachatOAuthURL,
+	}, opts)
+	if err != nil {
+		return nil, err
 	}
-
-	// Если указан прямой access token (для обратной совместимости)
-	accessToken := os.Getenv("GIGACHAT_ACCESS_TOKEN")
-	if accessToken == "" {
-		accessToken = os.Getenv("GIGACHAT_API_KEY")
+	if !gigachatScopes[o.scope] {
+		return nil, fmt.Errorf("gigachat: unknown scope %q", o.scope)
 	}
 
-	// Создаем HTTP клиент с поддержкой пропуска проверки SSL для OAuth endpoint
-	// (GigaChat использует самоподписанный сертификат)
-	tr := &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	if authKey == "" && o.accessToken == "" {
+		return nil, fmt.Errorf("%w: gigachat", ErrAPIKeyMissing)
 	}
 
 	provider := &GigaChatProvider{
-		authKey:  authKey,
-		clientID: clientID,
-		baseURL:  gigachatAPIURL,
-		client: &http.Client{
-			Timeout:   60 * time.Second,
-			Transport: tr,
-		},
+		authKey:      authKey,
+		scope:        o.scope,
+		oauthURL:     o.oauthURL,
+		baseURL:      strings.TrimRight(o.baseURL, "/"),
+		defaultModel: o.defaultModel,
+		client:       client,
+		streamClient: newStreamingClient(client, o.timeout),
 	}
 
-	// Если указан прямой токен, используем его (но он может быть устаревшим)
-	if accessToken != "" {
-		provider.accessToken = accessToken
-		// Устанавливаем время истечения в прошлом, чтобы при первом запросе получить новый токен
-		provider.tokenExpires = time.Now().Add(-1 * time.Minute)
+	// С Authorization key токен получается через OAuth при первом запросе; без него
+	// заранее полученный токен используется как есть
+	if o.accessToken != "" && authKey == "" {
+		provider.accessToken = o.accessToken
 	}
 
-	return provider
+	return provider, nil
 }
 
 // GetName возвращает имя провайдера
@@ -88,11 +105,16 @@ func (p *GigaChatProvider) GetName() string {
 
 // GetDefaultModel возвращает модель по умолчанию
 func (p *GigaChatProvider) GetDefaultModel() string {
-	return gigachatDefaultModel
+	return p.defaultModel
 }
 
 // getAccessToken получает или обновляет access token через OAuth
 func (p *GigaChatProvider) getAccessToken(ctx context.Context) (string, error) {
+	// Статический токен не обновляется: получить новый без Authorization key нельзя
+	if p.authKey == "" {
+		return p.accessToken, nil
+	}
+
 	p.tokenMutex.RLock()
 	// Проверяем, не истек ли токен (с запасом времени)
 	if p.accessToken != "" && time.Now().Before(p.tokenExpires.Add(-tokenRefreshMargin)) {
@@ -116,9 +138,9 @@ func (p *GigaChatProvider) getAccessToken(ctx context.Context) (string, error) {
 
 	// Подготавливаем запрос
 	data := url.Values{}
-	data.Set("scope", gigachatScope)
+	data.Set("scope", p.scope)
 
-	req, err := http.NewRequestWithContext(ctx, "POST", gigachatOAuthURL, bytes.NewBufferString(data.Encode()))
+	req, err := http.NewRequestWithContext(ctx, "POST", p.oauthURL, bytes.NewBufferString(data.Encode()))
 	if err != nil {
 		return "", fmt.Errorf("failed to create OAuth request: %w", err)
 	}
@@ -167,64 +189,12 @@ func (p *GigaChatProvider) getAccessToken(ctx context.Context) (string, error) {
 
 // Chat отправляет запрос к GigaChat API
 func (p *GigaChatProvider) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
-	// Получаем актуальный access token
-	accessToken, err := p.getAccessToken(ctx)
+	resp, err := p.do(ctx, req, false)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get access token: %w", err)
-	}
-
-	// Определяем модель
-	model := req.Model
-	if model == "" {
-		model = gigachatDefaultModel
-	}
-
-	// Конвертируем сообщения в формат GigaChat (OpenAI-совместимый формат)
-	gigachatMessages := convertMessages(req.Messages)
-
-	// Подготавливаем запрос в формате GigaChat
-	gigachatReq := map[string]interface{}{
-		"model":       model,
-		"messages":    gigachatMessages,
-		"stream":      false,
-		"temperature": 0.7,
-		"max_tokens":  2000,
-	}
-
-	jsonData, err := json.Marshal(gigachatReq)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
-	}
-
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.baseURL, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", accessToken))
-
-	resp, err := p.client.Do(httpReq)
-	if err != nil {
-		return nil, fmt.Errorf("%w: %w", ErrAPIRequestFailed, err)
+		return nil, err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		// Если токен истек (401), попробуем получить новый и повторить запрос
-		if resp.StatusCode == http.StatusUnauthorized {
-			// Сбрасываем токен и получаем новый
-			p.tokenMutex.Lock()
-			p.accessToken = ""
-			p.tokenMutex.Unlock()
-
-			// Повторяем запрос с новым токеном
-			return p.Chat(ctx, req)
-		}
-		return nil, fmt.Errorf("%w: status %d, body: %s", ErrAPIRequestFailed, resp.StatusCode, string(body))
-	}
-
 	var gigachatResp struct {
 		ID      string `json:"id"`
 		Choices []struct {
@@ -260,6 +230,172 @@ func (p *GigaChatProvider) Chat(ctx context.Context, req ChatRequest) (*ChatResp
 	return response, nil
 }
 
+// ChatStream отправляет потоковый запрос к GigaChat API
+func (p *GigaChatProvider) ChatStream(ctx context.Context, req ChatRequest, onChunk StreamHandler) (*ChatResponse, error) {
+	resp, err := p.do(ctx, req, true)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	return readOpenAIStream(resp.Body, modelOrDefault(req.Model, p.defaultModel), onChunk)
+}
+
+// do выполняет запрос генерации к GigaChat API и возвращает ответ со статусом 200
+func (p *GigaChatProvider) do(ctx context.Context, req ChatRequest, stream bool) (*http.Response, error) {
+	// Подготавливаем запрос в формате GigaChat (OpenAI-совместимый формат)
+	gigachatReq := map[string]interface{}{
+		"model":       modelOrDefault(req.Model, p.defaultModel),
+		"messages":    convertMessages(adaptGigaChatMessages(req.Messages)),
+		"stream":      stream,
+		"temperature": 0.7,
+		"max_tokens":  2000,
+	}
+	// Параметры из запроса переопределяют значения по умолчанию
+	applyGenerationParams(gigachatReq, req)
+
+	client := p.client
+	if stream {
+		client = p.streamClient
+	}
+	return p.doRequest(ctx, client, "POST", "/chat/completions", gigachatReq)
+}
+
+// doRequest выполняет авторизованный запрос к GigaChat API и возвращает ответ со статусом 200
+// При 401 токен сбрасывается и запрос повторяется один раз с новым токеном
+func (p *GigaChatProvider) doRequest(ctx context.Context, client *http.Client, method, path string, payload interface{}) (*http.Response, error) {
+	var jsonData []byte
+	if payload != nil {
+		var err error
+		jsonData, err = json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal request: %w", err)
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		// Получаем актуальный access token
+		accessToken, err := p.getAccessToken(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get access token: %w", err)
+		}
+
+		var body io.Reader
+		if jsonData != nil {
+			body = bytes.NewReader(jsonData)
+		}
+
+		httpReq, err := http.NewRequestWithContext(ctx, method, p.baseURL+path, body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+
+		if jsonData != nil {
+			httpReq.Header.Set("Content-Type", "application/json")
+		}
+		httpReq.Header.Set("Accept", "application/json")
+		httpReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", accessToken))
+
+		resp, err := client.Do(httpReq)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrAPIRequestFailed, err)
+		}
+
+		if resp.StatusCode == http.StatusOK {
+			return resp, nil
+		}
+
+		respBody, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		// Если токен истек (401), получаем новый и повторяем запрос
+		if resp.StatusCode == http.StatusUnauthorized && attempt == 0 && p.authKey != "" {
+			p.tokenMutex.Lock()
+			p.accessToken = ""
+			p.tokenMutex.Unlock()
+			continue
+		}
+
+		return nil, fmt.Errorf("%w: status %d, body: %s", ErrAPIRequestFailed, resp.StatusCode, string(respBody))
+	}
+}
+
+// ListModels возвращает модели, доступные аккаунту (GET /models)
+// Размер контекстного окна берется из реестра известных моделей
+func (p *GigaChatProvider) ListModels(ctx context.Context) ([]ModelInfo, error) {
+	p.modelsMutex.Lock()
+	defer p.modelsMutex.Unlock()
+
+	if p.models != nil && time.Now().Before(p.modelsExpires) {
+		return p.models, nil
+	}
+
+	resp, err := p.doRequest(ctx, p.client, "GET", "/models", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var modelsResp struct {
+		Data []struct {
+			ID   string `json:"id"`
+			Type string `json:"type"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&modelsResp); err != nil {
+		return nil, fmt.Errorf("failed to decode models response: %w", err)
+	}
+
+	models := make([]ModelInfo, 0, len(modelsResp.Data))
+	for _, m := range modelsResp.Data {
+		// Модели эмбеддингов (type=embedder) в чатах не используются
+		if m.Type != "" && m.Type != "chat" {
+			continue
+		}
+		info, ok := LookupModel(m.ID)
+		if !ok {
+			info = ModelInfo{ID: m.ID, Provider: "gigachat"}
+		}
+		models = append(models, info)
+	}
+
+	p.models = models
+	p.modelsExpires = time.Now().Add(gigachatModelsTTL)
+	return models, nil
+}
+
+// CountTokens считает токены текстов токенизатором модели (POST /tokens/count)
+func (p *GigaChatProvider) CountTokens(ctx context.Context, model string, texts []string) ([]int, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+
+	resp, err := p.doRequest(ctx, p.client, "POST", "/tokens/count", map[string]interface{}{
+		"model": modelOrDefault(model, p.defaultModel),
+		"input": texts,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var countResp []struct {
+		Tokens int `json:"tokens"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&countResp); err != nil {
+		return nil, fmt.Errorf("failed to decode tokens response: %w", err)
+	}
+	if len(countResp) != len(texts) {
+		return nil, fmt.Errorf("%w: expected %d token counts, got %d", ErrAPIRequestFailed, len(texts), len(countResp))
+	}
+
+	counts := make([]int, len(countResp))
+	for i, c := range countResp {
+		counts[i] = c.Tokens
+	}
+	return counts, nil
+}
+
 // generateUUID генерирует UUID v4 (для RqUID)
 func generateUUID() string {
 	b := make([]byte, 16)
@@ -277,3 +413,9 @@ func generateUUID() string {
 	return fmt.Sprintf("%08x-%04x-%04x-%04x-%012x",
 		b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
 }
+
+// adaptGigaChatMessages приводит историю к правилам GigaChat:
+// системное сообщение допускается только одно и только первым, роли user/assistant должны чередоваться
+func adaptGigaChatMessages(messages []Message) []Message {
+	return mergeConsecutiveRoles(mergeSystemMessages(messages))
+}
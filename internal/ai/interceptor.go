@@ -0,0 +1,82 @@
+package ai
+
+import "context"
+
+// Interceptor оборачивает провайдера дополнительной логикой (логирование, метрики, кэш и т.п.)
+// Каждая сквозная задача - отдельная обертка; обертки комбинируются через Chain
+type Interceptor func(next Provider) Provider
+
+// Chain оборачивает провайдера перехватчиками
+// Первый перехватчик - внешний: он первым получает запрос и последним - ответ
+func Chain(provider Provider, interceptors ...Interceptor) Provider {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		provider = interceptors[i](provider)
+	}
+	return provider
+}
+
+// Wrapper - необязательный интерфейс обертки, позволяющий получить обернутого провайдера
+type Wrapper interface {
+	Unwrap() Provider
+}
+
+// As ищет в цепочке оберток провайдера, реализующего интерфейс T
+// Используется для необязательных интерфейсов (ModelLister, TokenCounter, ResponseFormatSupporter),
+// которые обертки не обязаны пробрасывать
+func As[T any](provider Provider) (T, bool) {
+	for provider != nil {
+		if t, ok := provider.(T); ok {
+			return t, true
+		}
+		w, ok := provider.(Wrapper)
+		if !ok {
+			break
+		}
+		provider = w.Unwrap()
+	}
+
+	var zero T
+	return zero, false
+}
+
+// ChatFunc и ChatStreamFunc - сигнатуры методов генерации для InterceptorFuncs
+type (
+	ChatFunc       func(ctx context.Context, req ChatRequest) (*ChatResponse, error)
+	ChatStreamFunc func(ctx context.Context, req ChatRequest, onChunk StreamHandler) (*ChatResponse, error)
+)
+
+// InterceptorFuncs создает перехватчик из функций, оборачивающих Chat и ChatStream
+// Остальные методы провайдера пробрасываются без изменений. nil означает отсутствие обертки
+func InterceptorFuncs(chat func(p Provider, next ChatFunc) ChatFunc, stream func(p Provider, next ChatStreamFunc) ChatStreamFunc) Interceptor {
+	return func(next Provider) Provider {
+		w := &interceptedProvider{next: next, chat: next.Chat, stream: next.ChatStream}
+		if chat != nil {
+			w.chat = chat(next, next.Chat)
+		}
+		if stream != nil {
+			w.stream = stream(next, next.ChatStream)
+		}
+		return w
+	}
+}
+
+// interceptedProvider - провайдер с обернутыми методами генерации
+type interceptedProvider struct {
+	next   Provider
+	chat   ChatFunc
+	stream ChatStreamFunc
+}
+
+func (p *interceptedProvider) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	return p.chat(ctx, req)
+}
+
+func (p *interceptedProvider) ChatStream(ctx context.Context, req ChatRequest, onChunk StreamHandler) (*ChatResponse, error) {
+	return p.stream(ctx, req, onChunk)
+}
+
+func (p *interceptedProvider) GetDefaultModel() string { return p.next.GetDefaultModel() }
+
+func (p *interceptedProvider) GetName() string { return p.next.GetName() }
+
+func (p *interceptedProvider) Unwrap() Provider { return p.next }
@@ -0,0 +1,340 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeProvider - провайдер для тестов перехватчиков: отвечает содержимым reply и считает вызовы
+type fakeProvider struct {
+	name         string
+	defaultModel string
+	reply        ChatResponse
+	// chunks - порции потокового ответа; по умолчанию ответ отдается одной порцией
+	chunks []StreamChunk
+	err    error
+
+	calls    int
+	requests []ChatRequest
+	onCall   func()
+}
+
+func newFakeProvider() *fakeProvider {
+	p := &fakeProvider{name: "fake", defaultModel: "fake-model"}
+	p.reply.Content = "ответ"
+	p.reply.Model = "fake-model"
+	p.reply.Usage.PromptTokens = 10
+	p.reply.Usage.CompletionTokens = 5
+	p.reply.Usage.TotalTokens = 15
+	return p
+}
+
+func (p *fakeProvider) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	p.calls++
+	p.requests = append(p.requests, req)
+	if p.onCall != nil {
+		p.onCall()
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	resp := p.reply
+	return &resp, nil
+}
+
+func (p *fakeProvider) ChatStream(ctx context.Context, req ChatRequest, onChunk StreamHandler) (*ChatResponse, error) {
+	p.calls++
+	p.requests = append(p.requests, req)
+	if p.onCall != nil {
+		p.onCall()
+	}
+
+	chunks := p.chunks
+	if chunks == nil {
+		chunks = []StreamChunk{{ReasoningDelta: p.reply.ReasoningContent, ContentDelta: p.reply.Content}}
+	}
+	for _, chunk := range chunks {
+		if err := onChunk(chunk); err != nil {
+			return nil, err
+		}
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	resp := p.reply
+	return &resp, nil
+}
+
+func (p *fakeProvider) GetDefaultModel() string { return p.defaultModel }
+
+func (p *fakeProvider) GetName() string { return p.name }
+
+// listingProvider реализует необязательный интерфейс ModelLister
+type listingProvider struct {
+	*fakeProvider
+}
+
+func (p listingProvider) ListModels(ctx context.Context) ([]ModelInfo, error) {
+	return []ModelInfo{{ID: "fake-model", Provider: p.name}}, nil
+}
+
+// recordingInterceptor записывает в trace вход и выход запроса
+func recordingInterceptor(name string, trace *[]string) Interceptor {
+	return InterceptorFuncs(
+		func(p Provider, next ChatFunc) ChatFunc {
+			return func(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+				*trace = append(*trace, name+" in")
+				resp, err := next(ctx, req)
+				*trace = append(*trace, name+" out")
+				return resp, err
+			}
+		},
+		nil,
+	)
+}
+
+func TestChainOrder(t *testing.T) {
+	var trace []string
+	provider := newFakeProvider()
+	provider.onCall = func() { trace = append(trace, "provider") }
+
+	chained := Chain(provider, recordingInterceptor("first", &trace), recordingInterceptor("second", &trace))
+	if _, err := chained.Chat(context.Background(), ChatRequest{}); err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+
+	want := []string{"first in", "second in", "provider", "second out", "first out"}
+	if !reflect.DeepEqual(trace, want) {
+		t.Errorf("trace = %v, want %v", trace, want)
+	}
+}
+
+func TestInterceptorFuncsPassesThroughNilWrapper(t *testing.T) {
+	var trace []string
+	provider := newFakeProvider()
+
+	// Обертка задана только для Chat: ChatStream вызывается без нее
+	chained := Chain(provider, recordingInterceptor("chat", &trace))
+	_, err := chained.ChatStream(context.Background(), ChatRequest{}, func(StreamChunk) error { return nil })
+	if err != nil {
+		t.Fatalf("ChatStream: %v", err)
+	}
+	if len(trace) != 0 {
+		t.Errorf("trace = %v, want empty", trace)
+	}
+	if provider.calls != 1 {
+		t.Errorf("provider calls = %d, want 1", provider.calls)
+	}
+	if chained.GetName() != "fake" || chained.GetDefaultModel() != "fake-model" {
+		t.Errorf("name/model = %q/%q, want fake/fake-model", chained.GetName(), chained.GetDefaultModel())
+	}
+}
+
+func TestAsUnwrapsSeveralWrappers(t *testing.T) {
+	provider := listingProvider{newFakeProvider()}
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+
+	chained := Chain(provider,
+		LoggingInterceptor(logger),
+		MetricsInterceptor(NewMetrics()),
+		CacheInterceptor(NewMemoryCache(10), 0, logger),
+	)
+
+	if _, ok := chained.(ModelLister); ok {
+		t.Fatal("wrapper should not implement ModelLister itself")
+	}
+
+	lister, ok := As[ModelLister](chained)
+	if !ok {
+		t.Fatal("As[ModelLister] did not find the wrapped provider")
+	}
+	models, err := lister.ListModels(context.Background())
+	if err != nil || len(models) != 1 || models[0].ID != "fake-model" {
+		t.Errorf("ListModels = %v, %v", models, err)
+	}
+
+	if _, ok := As[TokenCounter](chained); ok {
+		t.Error("As[TokenCounter] found an interface the provider does not implement")
+	}
+
+	// Цепочка Unwrap приводит к исходному провайдеру
+	var unwrapped Provider = chained
+	depth := 0
+	for {
+		w, ok := unwrapped.(Wrapper)
+		if !ok {
+			break
+		}
+		unwrapped = w.Unwrap()
+		depth++
+	}
+	if depth != 3 {
+		t.Errorf("unwrap depth = %d, want 3", depth)
+	}
+	if unwrapped != Provider(provider) {
+		t.Error("Unwrap chain did not end at the original provider")
+	}
+}
+
+// logRecords разбирает JSON-лог slog в список записей
+func logRecords(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var records []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var record map[string]any
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("invalid log line %q: %v", line, err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestLoggingInterceptor(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	provider := newFakeProvider()
+	chained := Chain(provider, LoggingInterceptor(logger))
+
+	req := ChatRequest{Messages: []Message{{Role: "user", Content: "секретный вопрос"}}}
+	if _, err := chained.Chat(context.Background(), req); err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	if _, err := chained.ChatStream(context.Background(), req, func(StreamChunk) error { return nil }); err != nil {
+		t.Fatalf("ChatStream: %v", err)
+	}
+
+	records := logRecords(t, &buf)
+	if len(records) != 2 {
+		t.Fatalf("got %d log records, want 2", len(records))
+	}
+	for i, record := range records {
+		if record["level"] != "INFO" || record["msg"] != "AI provider request completed" {
+			t.Errorf("record %d: level/msg = %v/%v", i, record["level"], record["msg"])
+		}
+		if record["provider"] != "fake" || record["model"] != "fake-model" {
+			t.Errorf("record %d: provider/model = %v/%v", i, record["provider"], record["model"])
+		}
+		if record["stream"] != (i == 1) {
+			t.Errorf("record %d: stream = %v, want %v", i, record["stream"], i == 1)
+		}
+		// Числа в JSON разбираются как float64
+		if record["messages"] != 1.0 || record["request_chars"] != 16.0 || record["total_tokens"] != 15.0 {
+			t.Errorf("record %d: messages/request_chars/total_tokens = %v/%v/%v",
+				i, record["messages"], record["request_chars"], record["total_tokens"])
+		}
+		if _, ok := record["latency_ms"]; !ok {
+			t.Errorf("record %d: latency_ms is missing", i)
+		}
+	}
+
+	if strings.Contains(buf.String(), "секретный") {
+		t.Error("message content must not be logged")
+	}
+}
+
+func TestLoggingInterceptorErrors(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	provider := newFakeProvider()
+	provider.err = errors.New("upstream failed")
+	chained := Chain(provider, LoggingInterceptor(logger))
+
+	if _, err := chained.Chat(context.Background(), ChatRequest{Model: "other"}); err == nil {
+		t.Fatal("Chat: expected error")
+	}
+	if _, err := chained.ChatStream(context.Background(), ChatRequest{}, func(StreamChunk) error { return nil }); err == nil {
+		t.Fatal("ChatStream: expected error")
+	}
+
+	records := logRecords(t, &buf)
+	if len(records) != 2 {
+		t.Fatalf("got %d log records, want 2", len(records))
+	}
+	for i, record := range records {
+		if record["level"] != "WARN" || record["msg"] != "AI provider request failed" {
+			t.Errorf("record %d: level/msg = %v/%v", i, record["level"], record["msg"])
+		}
+		if record["error"] != "upstream failed" {
+			t.Errorf("record %d: error = %v", i, record["error"])
+		}
+		if _, ok := record["total_tokens"]; ok {
+			t.Errorf("record %d: failed request must not report usage", i)
+		}
+	}
+	if records[0]["model"] != "other" {
+		t.Errorf("model = %v, want requested model", records[0]["model"])
+	}
+}
+
+func TestMetricsInterceptor(t *testing.T) {
+	metrics := NewMetrics()
+	provider := newFakeProvider()
+	chained := Chain(provider, MetricsInterceptor(metrics))
+	ctx := context.Background()
+	discard := func(StreamChunk) error { return nil }
+
+	if _, err := chained.Chat(ctx, ChatRequest{}); err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	if _, err := chained.ChatStream(ctx, ChatRequest{}, discard); err != nil {
+		t.Fatalf("ChatStream: %v", err)
+	}
+
+	provider.err = errors.New("upstream failed")
+	chained.Chat(ctx, ChatRequest{})
+	chained.ChatStream(ctx, ChatRequest{}, discard)
+
+	provider.err = nil
+	provider.reply.Cached = true
+	if _, err := chained.Chat(ctx, ChatRequest{}); err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+
+	// Запрос другой модели учитывается отдельно
+	provider.reply.Cached = false
+	if _, err := chained.Chat(ctx, ChatRequest{Model: "fake-large"}); err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+
+	snapshot := metrics.Snapshot()
+	if len(snapshot.Models) != 2 {
+		t.Fatalf("got %d models, want 2: %+v", len(snapshot.Models), snapshot.Models)
+	}
+
+	large, def := snapshot.Models[0], snapshot.Models[1]
+	if large.Model != "fake-large" || def.Model != "fake-model" {
+		t.Fatalf("models = %q, %q; want sorted fake-large, fake-model", large.Model, def.Model)
+	}
+	if large.Requests != 1 || large.PromptTokens != 10 || large.CompletionTokens != 5 {
+		t.Errorf("fake-large = %+v", large)
+	}
+
+	want := ModelMetrics{
+		Provider:         "fake",
+		Model:            "fake-model",
+		Requests:         5,
+		Errors:           2,
+		StreamRequests:   2,
+		CachedRequests:   1,
+		PromptTokens:     20,
+		CompletionTokens: 10,
+	}
+	got := def
+	got.TotalLatencyMs, got.AvgLatencyMs, got.MaxLatencyMs = 0, 0, 0
+	if got != want {
+		t.Errorf("fake-model = %+v, want %+v", got, want)
+	}
+	if def.MaxLatencyMs < def.AvgLatencyMs {
+		t.Errorf("max latency %d < avg latency %d", def.MaxLatencyMs, def.AvgLatencyMs)
+	}
+}
@@ -0,0 +1,475 @@
+package ai
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// JSONSchema - скомпилированная JSON Schema для проверки ответов модели
+// Поддерживается подмножество стандарта, достаточное для описания структурированных ответов:
+// type, enum, const, properties, required, additionalProperties, items, minItems, maxItems,
+// minLength, maxLength, pattern, minimum, maximum, anyOf, oneOf, allOf.
+// Схема с другими ключевыми словами ($ref, format, not и т.д.) отклоняется: иначе ответ,
+// нарушающий такое ограничение, прошел бы проверку
+type JSONSchema struct {
+	root map[string]interface{}
+}
+
+// CompileJSONSchema разбирает схему и проверяет, что она корректна
+func CompileJSONSchema(raw json.RawMessage) (*JSONSchema, error) {
+	if len(bytes.TrimSpace(raw)) == 0 {
+		return nil, fmt.Errorf("schema is empty")
+	}
+
+	var root map[string]interface{}
+	if err := json.Unmarshal(raw, &root); err != nil {
+		return nil, fmt.Errorf("schema must be a JSON object: %w", err)
+	}
+	if err := checkSchema(root, "#"); err != nil {
+		return nil, err
+	}
+
+	return &JSONSchema{root: root}, nil
+}
+
+// Validate проверяет документ на соответствие схеме
+func (s *JSONSchema) Validate(document []byte) error {
+	var value interface{}
+	decoder := json.NewDecoder(bytes.NewReader(document))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+	return validateValue(s.root, value, "$")
+}
+
+var knownSchemaTypes = map[string]bool{
+	"object": true, "array": true, "string": true, "number": true,
+	"integer": true, "boolean": true, "null": true,
+}
+
+// annotationKeywords - ключевые слова, которые описывают схему и не влияют на проверку
+var annotationKeywords = map[string]bool{
+	"$schema": true, "$id": true, "$comment": true,
+	"title": true, "description": true, "default": true, "examples": true,
+}
+
+// schemaKeywords - поддерживаемые ключевые слова проверки
+var schemaKeywords = map[string]bool{
+	"type": true, "enum": true, "const": true,
+	"properties": true, "required": true, "additionalProperties": true,
+	"items": true, "minItems": true, "maxItems": true,
+	"minLength": true, "maxLength": true, "pattern": true,
+	"minimum": true, "maximum": true,
+	"anyOf": true, "oneOf": true, "allOf": true,
+}
+
+// checkSchema рекурсивно проверяет ключевые слова схемы
+func checkSchema(schema map[string]interface{}, path string) error {
+	// Сортируем ключи, чтобы сообщения об ошибках были детерминированными
+	keys := make([]string, 0, len(schema))
+	for key := range schema {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if !schemaKeywords[key] && !annotationKeywords[key] {
+			return fmt.Errorf("%s: keyword %q is not supported", path, key)
+		}
+	}
+
+	if t, ok := schema["type"]; ok {
+		if _, isString := t.(string); !isString {
+			if _, isArray := t.([]interface{}); !isArray {
+				return fmt.Errorf("%s: type must be a string or an array of strings", path)
+			}
+		}
+		for _, name := range schemaTypes(t) {
+			if !knownSchemaTypes[name] {
+				return fmt.Errorf("%s: unknown type %q", path, name)
+			}
+		}
+	}
+
+	if enum, ok := schema["enum"]; ok {
+		if _, ok := enum.([]interface{}); !ok {
+			return fmt.Errorf("%s: enum must be an array", path)
+		}
+	}
+
+	for _, key := range []string{"minItems", "maxItems", "minLength", "maxLength"} {
+		if value, ok := schema[key]; ok {
+			n, ok := value.(float64)
+			if !ok || n < 0 || n != math.Trunc(n) {
+				return fmt.Errorf("%s: %s must be a non-negative integer", path, key)
+			}
+		}
+	}
+	for _, key := range []string{"minimum", "maximum"} {
+		if value, ok := schema[key]; ok {
+			if _, ok := value.(float64); !ok {
+				return fmt.Errorf("%s: %s must be a number", path, key)
+			}
+		}
+	}
+
+	if pattern, ok := schema["pattern"]; ok {
+		patternString, ok := pattern.(string)
+		if !ok {
+			return fmt.Errorf("%s: pattern must be a string", path)
+		}
+		if _, err := regexp.Compile(patternString); err != nil {
+			return fmt.Errorf("%s: invalid pattern: %w", path, err)
+		}
+	}
+
+	if required, ok := schema["required"]; ok {
+		names, ok := required.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s: required must be an array of strings", path)
+		}
+		for i, name := range names {
+			if _, ok := name.(string); !ok {
+				return fmt.Errorf("%s.required[%d]: must be a string", path, i)
+			}
+		}
+	}
+
+	if props, ok := schema["properties"]; ok {
+		propsMap, ok := props.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s: properties must be an object", path)
+		}
+		for name, sub := range propsMap {
+			subSchema, ok := sub.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("%s.properties.%s: schema must be an object", path, name)
+			}
+			if err := checkSchema(subSchema, path+".properties."+name); err != nil {
+				return err
+			}
+		}
+	}
+
+	if items, ok := schema["items"]; ok {
+		sub, ok := items.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s.items: schema must be an object", path)
+		}
+		if err := checkSchema(sub, path+".items"); err != nil {
+			return err
+		}
+	}
+
+	if additional, ok := schema["additionalProperties"]; ok {
+		switch sub := additional.(type) {
+		case bool:
+		case map[string]interface{}:
+			if err := checkSchema(sub, path+".additionalProperties"); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("%s: additionalProperties must be a boolean or a schema", path)
+		}
+	}
+
+	for _, key := range []string{"anyOf", "oneOf", "allOf"} {
+		if list, ok := schema[key]; ok {
+			items, ok := list.([]interface{})
+			if !ok || len(items) == 0 {
+				return fmt.Errorf("%s.%s: must be a non-empty array", path, key)
+			}
+			for i, item := range items {
+				sub, ok := item.(map[string]interface{})
+				if !ok {
+					return fmt.Errorf("%s.%s[%d]: schema must be an object", path, key, i)
+				}
+				if err := checkSchema(sub, fmt.Sprintf("%s.%s[%d]", path, key, i)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// schemaTypes возвращает список типов из ключевого слова type (строка или массив строк)
+func schemaTypes(t interface{}) []string {
+	switch v := t.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		types := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				types = append(types, s)
+			} else {
+				types = append(types, fmt.Sprint(item))
+			}
+		}
+		return types
+	default:
+		return []string{fmt.Sprint(v)}
+	}
+}
+
+func validateValue(schema map[string]interface{}, value interface{}, path string) error {
+	if t, ok := schema["type"]; ok {
+		types := schemaTypes(t)
+		matched := false
+		for _, name := range types {
+			if matchesType(name, value) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return fmt.Errorf("%s: expected %s, got %s", path, strings.Join(types, " or "), jsonTypeName(value))
+		}
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, candidate := range enum {
+			if jsonEqual(candidate, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s: value is not one of the allowed values", path)
+		}
+	}
+
+	if constant, ok := schema["const"]; ok && !jsonEqual(constant, value) {
+		return fmt.Errorf("%s: value must be %v", path, constant)
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if err := validateObject(schema, v, path); err != nil {
+			return err
+		}
+	case []interface{}:
+		if err := validateArray(schema, v, path); err != nil {
+			return err
+		}
+	case string:
+		length := utf8.RuneCountInString(v)
+		if min, ok := schemaNumber(schema, "minLength"); ok && float64(length) < min {
+			return fmt.Errorf("%s: string is shorter than %v", path, min)
+		}
+		if max, ok := schemaNumber(schema, "maxLength"); ok && float64(length) > max {
+			return fmt.Errorf("%s: string is longer than %v", path, max)
+		}
+		if pattern, ok := schema["pattern"].(string); ok {
+			// Схема уже проверена в CompileJSONSchema, поэтому MustCompile безопасен
+			if !regexp.MustCompile(pattern).MatchString(v) {
+				return fmt.Errorf("%s: string does not match pattern %q", path, pattern)
+			}
+		}
+	case json.Number:
+		n, _ := v.Float64()
+		if min, ok := schemaNumber(schema, "minimum"); ok && n < min {
+			return fmt.Errorf("%s: value is less than %v", path, min)
+		}
+		if max, ok := schemaNumber(schema, "maximum"); ok && n > max {
+			return fmt.Errorf("%s: value is greater than %v", path, max)
+		}
+	}
+
+	if list, ok := schema["allOf"].([]interface{}); ok {
+		for _, item := range list {
+			if err := validateValue(item.(map[string]interface{}), value, path); err != nil {
+				return err
+			}
+		}
+	}
+
+	if list, ok := schema["anyOf"].([]interface{}); ok {
+		var firstErr error
+		matched := false
+		for _, item := range list {
+			err := validateValue(item.(map[string]interface{}), value, path)
+			if err == nil {
+				matched = true
+				break
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+		if !matched {
+			return fmt.Errorf("%s: value does not match any of the schemas (%v)", path, firstErr)
+		}
+	}
+
+	if list, ok := schema["oneOf"].([]interface{}); ok {
+		matches := 0
+		for _, item := range list {
+			if validateValue(item.(map[string]interface{}), value, path) == nil {
+				matches++
+			}
+		}
+		if matches != 1 {
+			return fmt.Errorf("%s: value must match exactly one schema, matched %d", path, matches)
+		}
+	}
+
+	return nil
+}
+
+func validateObject(schema map[string]interface{}, obj map[string]interface{}, path string) error {
+	if required, ok := schema["required"].([]interface{}); ok {
+		for _, item := range required {
+			name, _ := item.(string)
+			if _, exists := obj[name]; !exists {
+				return fmt.Errorf("%s: missing required property %q", path, name)
+			}
+		}
+	}
+
+	props, _ := schema["properties"].(map[string]interface{})
+
+	// Сортируем ключи, чтобы сообщения об ошибках были детерминированными
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		childPath := path + "." + key
+		if sub, ok := props[key].(map[string]interface{}); ok {
+			if err := validateValue(sub, obj[key], childPath); err != nil {
+				return err
+			}
+			continue
+		}
+
+		switch additional := schema["additionalProperties"].(type) {
+		case bool:
+			if !additional {
+				return fmt.Errorf("%s: additional property %q is not allowed", path, key)
+			}
+		case map[string]interface{}:
+			if err := validateValue(additional, obj[key], childPath); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func validateArray(schema map[string]interface{}, arr []interface{}, path string) error {
+	if min, ok := schemaNumber(schema, "minItems"); ok && float64(len(arr)) < min {
+		return fmt.Errorf("%s: array has fewer than %v items", path, min)
+	}
+	if max, ok := schemaNumber(schema, "maxItems"); ok && float64(len(arr)) > max {
+		return fmt.Errorf("%s: array has more than %v items", path, max)
+	}
+
+	if items, ok := schema["items"].(map[string]interface{}); ok {
+		for i, item := range arr {
+			if err := validateValue(items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func matchesType(name string, value interface{}) bool {
+	switch name {
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "null":
+		return value == nil
+	case "number":
+		_, ok := value.(json.Number)
+		return ok
+	case "integer":
+		n, ok := value.(json.Number)
+		if !ok {
+			return false
+		}
+		f, err := n.Float64()
+		return err == nil && f == math.Trunc(f)
+	default:
+		return false
+	}
+}
+
+func jsonTypeName(value interface{}) string {
+	switch value.(type) {
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case json.Number:
+		return "number"
+	case nil:
+		return "null"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+// schemaNumber читает числовое ключевое слово схемы (схема разобрана без UseNumber, поэтому float64)
+func schemaNumber(schema map[string]interface{}, key string) (float64, bool) {
+	n, ok := schema[key].(float64)
+	return n, ok
+}
+
+// jsonEqual сравнивает значения из схемы (float64) и документа (json.Number)
+func jsonEqual(a, b interface{}) bool {
+	aj, errA := json.Marshal(normalizeNumbers(a))
+	bj, errB := json.Marshal(normalizeNumbers(b))
+	return errA == nil && errB == nil && bytes.Equal(aj, bj)
+}
+
+// normalizeNumbers рекурсивно приводит json.Number к float64
+func normalizeNumbers(v interface{}) interface{} {
+	switch value := v.(type) {
+	case json.Number:
+		f, _ := value.Float64()
+		return f
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(value))
+		for key, item := range value {
+			result[key] = normalizeNumbers(item)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(value))
+		for i, item := range value {
+			result[i] = normalizeNumbers(item)
+		}
+		return result
+	default:
+		return v
+	}
+}
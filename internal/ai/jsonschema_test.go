@@ -0,0 +1,108 @@
+package ai
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestJSONSchemaKeywords(t *testing.T) {
+	tests := []struct {
+		keyword string
+		schema  string
+		valid   []string
+		invalid []string
+	}{
+		{"type", `{"type": "integer"}`, []string{`1`, `2.0`}, []string{`1.5`, `"1"`}},
+		{"type list", `{"type": ["string", "null"]}`, []string{`"a"`, `null`}, []string{`1`}},
+		{"enum", `{"enum": ["a", 1]}`, []string{`"a"`, `1`}, []string{`"b"`, `2`}},
+		{"const", `{"const": {"a": 1}}`, []string{`{"a": 1}`}, []string{`{"a": 2}`}},
+		{"properties", `{"properties": {"a": {"type": "string"}}}`, []string{`{"a": "x"}`, `{"b": 1}`}, []string{`{"a": 1}`}},
+		{"required", `{"required": ["a"]}`, []string{`{"a": null}`, `"not an object"`}, []string{`{}`}},
+		{"additionalProperties false", `{"properties": {"a": {}}, "additionalProperties": false}`, []string{`{"a": 1}`}, []string{`{"a": 1, "b": 2}`}},
+		{"additionalProperties schema", `{"additionalProperties": {"type": "number"}}`, []string{`{"a": 1}`}, []string{`{"a": "1"}`}},
+		{"items", `{"items": {"type": "boolean"}}`, []string{`[]`, `[true]`}, []string{`[true, 1]`}},
+		{"minItems", `{"minItems": 1}`, []string{`[1]`}, []string{`[]`}},
+		{"maxItems", `{"maxItems": 1}`, []string{`[1]`}, []string{`[1, 2]`}},
+		{"minLength", `{"minLength": 2}`, []string{`"ая"`}, []string{`"я"`}},
+		{"maxLength", `{"maxLength": 2}`, []string{`"ая"`}, []string{`"абв"`}},
+		{"pattern", `{"pattern": "^[a-z]+$"}`, []string{`"abc"`}, []string{`"ABC"`}},
+		{"minimum", `{"minimum": 1}`, []string{`1`}, []string{`0.5`}},
+		{"maximum", `{"maximum": 1}`, []string{`1`}, []string{`1.5`}},
+		{"anyOf", `{"anyOf": [{"type": "string"}, {"type": "number"}]}`, []string{`"a"`, `1`}, []string{`true`}},
+		{"oneOf", `{"oneOf": [{"type": "integer"}, {"type": "number"}]}`, []string{`1.5`}, []string{`1`}},
+		{"allOf", `{"allOf": [{"minimum": 1}, {"maximum": 2}]}`, []string{`1.5`}, []string{`3`}},
+		{"annotations", `{"$schema": "https://json-schema.org/draft/2020-12/schema", "title": "t", "description": "d", "default": 1, "examples": [1], "type": "number"}`, []string{`1`}, []string{`"1"`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.keyword, func(t *testing.T) {
+			schema, err := CompileJSONSchema(json.RawMessage(tt.schema))
+			if err != nil {
+				t.Fatalf("CompileJSONSchema: %v", err)
+			}
+			for _, document := range tt.valid {
+				if err := schema.Validate([]byte(document)); err != nil {
+					t.Errorf("Validate(%s): %v", document, err)
+				}
+			}
+			for _, document := range tt.invalid {
+				if err := schema.Validate([]byte(document)); err == nil {
+					t.Errorf("Validate(%s): expected error", document)
+				}
+			}
+		})
+	}
+}
+
+func TestCompileJSONSchemaRejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		err    string
+	}{
+		{"empty", ``, "schema is empty"},
+		{"not an object", `[]`, "must be a JSON object"},
+		{"$ref", `{"$ref": "#/$defs/a"}`, `"$ref" is not supported`},
+		{"$defs", `{"$defs": {"a": {}}}`, `"$defs" is not supported`},
+		{"format", `{"type": "string", "format": "email"}`, `"format" is not supported`},
+		{"exclusiveMinimum", `{"exclusiveMinimum": 1}`, `"exclusiveMinimum" is not supported`},
+		{"exclusiveMaximum", `{"exclusiveMaximum": 1}`, `"exclusiveMaximum" is not supported`},
+		{"multipleOf", `{"multipleOf": 2}`, `"multipleOf" is not supported`},
+		{"patternProperties", `{"patternProperties": {"^a": {}}}`, `"patternProperties" is not supported`},
+		{"prefixItems", `{"prefixItems": [{}]}`, `"prefixItems" is not supported`},
+		{"uniqueItems", `{"uniqueItems": true}`, `"uniqueItems" is not supported`},
+		{"not", `{"not": {"type": "null"}}`, `"not" is not supported`},
+		{"if", `{"if": {}, "then": {}, "else": {}}`, `"else" is not supported`},
+		{"dependentRequired", `{"dependentRequired": {"a": ["b"]}}`, `"dependentRequired" is not supported`},
+		{"nested unsupported keyword", `{"properties": {"a": {"format": "date"}}}`, `#.properties.a: keyword "format"`},
+		{"unsupported keyword in items", `{"items": {"uniqueItems": true}}`, `#.items: keyword "uniqueItems"`},
+		{"array-form items", `{"items": [{"type": "string"}]}`, "items: schema must be an object"},
+		{"unknown type", `{"type": "date"}`, `unknown type "date"`},
+		{"type is not a string", `{"type": 1}`, "type must be a string or an array of strings"},
+		{"enum is not an array", `{"enum": "a"}`, "enum must be an array"},
+		{"required is not an array", `{"required": "a"}`, "required must be an array of strings"},
+		{"required item is not a string", `{"required": ["a", 1]}`, "required[1]: must be a string"},
+		{"additionalProperties is a string", `{"additionalProperties": "no"}`, "additionalProperties must be a boolean or a schema"},
+		{"additionalProperties is null", `{"additionalProperties": null}`, "additionalProperties must be a boolean or a schema"},
+		{"negative minLength", `{"minLength": -1}`, "minLength must be a non-negative integer"},
+		{"fractional maxItems", `{"maxItems": 1.5}`, "maxItems must be a non-negative integer"},
+		{"minimum is a string", `{"minimum": "1"}`, "minimum must be a number"},
+		{"pattern is not a string", `{"pattern": 1}`, "pattern must be a string"},
+		{"invalid pattern", `{"pattern": "("}`, "invalid pattern"},
+		{"property schema is not an object", `{"properties": {"a": true}}`, "schema must be an object"},
+		{"empty anyOf", `{"anyOf": []}`, "must be a non-empty array"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := CompileJSONSchema(json.RawMessage(tt.schema))
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("error = %q, want it to contain %q", err, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package ai
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// LoggingInterceptor пишет в лог каждый запрос к провайдеру: размер запроса, задержку и расход токенов
+// Содержимое сообщений не логируется
+func LoggingInterceptor(logger *slog.Logger) Interceptor {
+	log := func(ctx context.Context, p Provider, req ChatRequest, stream bool, started time.Time, resp *ChatResponse, err error) {
+		attrs := []any{
+			"provider", p.GetName(),
+			"model", modelOrDefault(req.Model, p.GetDefaultModel()),
+			"stream", stream,
+			"messages", len(req.Messages),
+			"request_chars", requestChars(req),
+			"latency_ms", time.Since(started).Milliseconds(),
+		}
+		if err != nil {
+			logger.WarnContext(ctx, "AI provider request failed", append(attrs, "error", err)...)
+			return
+		}
+		logger.InfoContext(ctx, "AI provider request completed", append(attrs,
+			"response_model", resp.Model,
+			"cached", resp.Cached,
+			"prompt_tokens", resp.Usage.PromptTokens,
+			"completion_tokens", resp.Usage.CompletionTokens,
+			"total_tokens", resp.Usage.TotalTokens,
+		)...)
+	}
+
+	return InterceptorFuncs(
+		func(p Provider, next ChatFunc) ChatFunc {
+			return func(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+				started := time.Now()
+				resp, err := next(ctx, req)
+				log(ctx, p, req, false, started, resp, err)
+				return resp, err
+			}
+		},
+		func(p Provider, next ChatStreamFunc) ChatStreamFunc {
+			return func(ctx context.Context, req ChatRequest, onChunk StreamHandler) (*ChatResponse, error) {
+				started := time.Now()
+				resp, err := next(ctx, req, onChunk)
+				log(ctx, p, req, true, started, resp, err)
+				return resp, err
+			}
+		},
+	)
+}
+
+// requestChars возвращает суммарную длину сообщений запроса в символах
+func requestChars(req ChatRequest) int {
+	total := 0
+	for _, msg := range req.Messages {
+		total += len([]rune(msg.Content))
+	}
+	return total
+}
@@ -0,0 +1,127 @@
+package ai
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Metrics накапливает метрики запросов к провайдерам в памяти процесса
+type Metrics struct {
+	mu      sync.Mutex
+	started time.Time
+	stats   map[metricsKey]*ModelMetrics
+}
+
+type metricsKey struct {
+	provider string
+	model    string
+}
+
+// ModelMetrics - метрики запросов к одной модели провайдера
+type ModelMetrics struct {
+	Provider       string `json:"provider"`
+	Model          string `json:"model"`
+	Requests       int64  `json:"requests"`
+	Errors         int64  `json:"errors"`
+	StreamRequests int64  `json:"stream_requests"`
+	// CachedRequests - ответы из кэша; их токены не входят в PromptTokens/CompletionTokens
+	CachedRequests   int64 `json:"cached_requests"`
+	PromptTokens     int64 `json:"prompt_tokens"`
+	CompletionTokens int64 `json:"completion_tokens"`
+	// Задержка в миллисекундах
+	TotalLatencyMs int64 `json:"total_latency_ms"`
+	AvgLatencyMs   int64 `json:"avg_latency_ms"`
+	MaxLatencyMs   int64 `json:"max_latency_ms"`
+}
+
+// MetricsSnapshot - срез метрик на момент запроса
+type MetricsSnapshot struct {
+	Since  time.Time      `json:"since"`
+	Models []ModelMetrics `json:"models"`
+}
+
+// NewMetrics создает пустой набор метрик
+func NewMetrics() *Metrics {
+	return &Metrics{
+		started: time.Now(),
+		stats:   make(map[metricsKey]*ModelMetrics),
+	}
+}
+
+// Observe учитывает один запрос к провайдеру
+func (m *Metrics) Observe(provider, model string, stream bool, latency time.Duration, resp *ChatResponse, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := metricsKey{provider: provider, model: model}
+	s, ok := m.stats[key]
+	if !ok {
+		s = &ModelMetrics{Provider: provider, Model: model}
+		m.stats[key] = s
+	}
+
+	latencyMs := latency.Milliseconds()
+	s.Requests++
+	s.TotalLatencyMs += latencyMs
+	s.MaxLatencyMs = max(s.MaxLatencyMs, latencyMs)
+	if stream {
+		s.StreamRequests++
+	}
+	if err != nil {
+		s.Errors++
+		return
+	}
+	if resp.Cached {
+		s.CachedRequests++
+		return
+	}
+	s.PromptTokens += int64(resp.Usage.PromptTokens)
+	s.CompletionTokens += int64(resp.Usage.CompletionTokens)
+}
+
+// Snapshot возвращает копию метрик, отсортированную по провайдеру и модели
+func (m *Metrics) Snapshot() MetricsSnapshot {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	models := make([]ModelMetrics, 0, len(m.stats))
+	for _, s := range m.stats {
+		item := *s
+		if item.Requests > 0 {
+			item.AvgLatencyMs = item.TotalLatencyMs / item.Requests
+		}
+		models = append(models, item)
+	}
+	sort.Slice(models, func(i, j int) bool {
+		if models[i].Provider != models[j].Provider {
+			return models[i].Provider < models[j].Provider
+		}
+		return models[i].Model < models[j].Model
+	})
+
+	return MetricsSnapshot{Since: m.started, Models: models}
+}
+
+// MetricsInterceptor учитывает количество запросов, ошибки, задержку и токены каждого запроса
+func MetricsInterceptor(m *Metrics) Interceptor {
+	return InterceptorFuncs(
+		func(p Provider, next ChatFunc) ChatFunc {
+			return func(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+				started := time.Now()
+				resp, err := next(ctx, req)
+				m.Observe(p.GetName(), modelOrDefault(req.Model, p.GetDefaultModel()), false, time.Since(started), resp, err)
+				return resp, err
+			}
+		},
+		func(p Provider, next ChatStreamFunc) ChatStreamFunc {
+			return func(ctx context.Context, req ChatRequest, onChunk StreamHandler) (*ChatResponse, error) {
+				started := time.Now()
+				resp, err := next(ctx, req, onChunk)
+				m.Observe(p.GetName(), modelOrDefault(req.Model, p.GetDefaultModel()), true, time.Since(started), resp, err)
+				return resp, err
+			}
+		},
+	)
+}
@@ -0,0 +1,67 @@
+package ai
+
+import "strings"
+
+// ModelInfo описывает модель, доступную через одного из провайдеров
+type ModelInfo struct {
+	ID       string `json:"id"`
+	Provider string `json:"provider"`
+	// Reasoning - модель возвращает рассуждения отдельно от итогового ответа
+	Reasoning bool `json:"reasoning,omitempty"`
+	// ContextWindow - размер контекстного окна модели в токенах (0 - неизвестен)
+	ContextWindow int `json:"context_window,omitempty"`
+}
+
+// knownModels содержит модели, которые можно явно указать в chats.ai_model
+var knownModels = []ModelInfo{
+	{ID: "deepseek-chat", Provider: "deepseek", ContextWindow: 128000},
+	{ID: "deepseek-reasoner", Provider: "deepseek", Reasoning: true, ContextWindow: 128000},
+	{ID: "GigaChat", Provider: "gigachat", ContextWindow: 32768},
+	{ID: "GigaChat-Pro", Provider: "gigachat", ContextWindow: 32768},
+	{ID: "GigaChat-Max", Provider: "gigachat", ContextWindow: 32768},
+	{ID: "GigaChat-2", Provider: "gigachat", ContextWindow: 131072},
+	{ID: "GigaChat-2-Pro", Provider: "gigachat", ContextWindow: 131072},
+	{ID: "GigaChat-2-Max", Provider: "gigachat", ContextWindow: 131072},
+	{ID: "qwen3-max", Provider: "qwen", ContextWindow: 262144},
+	{ID: "qwen-plus", Provider: "qwen", ContextWindow: 131072},
+	{ID: "qwen-flash", Provider: "qwen", ContextWindow: 1000000},
+}
+
+// Models возвращает список всех известных моделей
+func Models() []ModelInfo {
+	models := make([]ModelInfo, len(knownModels))
+	copy(models, knownModels)
+	return models
+}
+
+// LookupModel ищет известную модель по ID без учета регистра
+func LookupModel(id string) (ModelInfo, bool) {
+	for _, m := range knownModels {
+		if strings.EqualFold(m.ID, id) {
+			return m, true
+		}
+	}
+	return ModelInfo{}, false
+}
+
+// ResolveModel определяет провайдера и конкретную модель по значению chats.ai_model
+// Для неизвестных моделей провайдер угадывается по названию, а model возвращается пустой,
+// то есть будет использована модель провайдера по умолчанию
+func ResolveModel(aiModel string) (providerName, model string) {
+	if info, ok := LookupModel(aiModel); ok {
+		return info.Provider, info.ID
+	}
+
+	// Маппинг названий моделей на провайдеров
+	name := strings.ToLower(aiModel)
+	switch {
+	case strings.Contains(name, "deepseek") || strings.Contains(name, "deep-seek"):
+		return "deepseek", "" // DeepSeek напрямую
+	case strings.Contains(name, "gigachat"):
+		return "gigachat", "" // GigaChat
+	case strings.Contains(name, "qwen"):
+		return "qwen", "" // Qwen
+	default:
+		return "deepseek", "" // По умолчанию DeepSeek
+	}
+}
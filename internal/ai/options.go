@@ -0,0 +1,112 @@
+package ai
+
+import (
+	"net/http"
+	"time"
+)
+
+// Option настраивает провайдера при создании
+// Провайдеры не читают окружение сами: учетные данные и настройки передаются
+// через конструктор, а их источник (env, файлы, зашифрованный файл) выбирает вызывающий код
+type Option func(*providerOptions)
+
+type providerOptions struct {
+	baseURL      string
+	defaultModel string
+	timeout      time.Duration
+	tls          TLSOptions
+	httpClient   *http.Client
+
+	// Настройки GigaChat
+	accessToken string
+	scope       string
+	oauthURL    string
+}
+
+// WithBaseURL задает URL API провайдера
+func WithBaseURL(url string) Option {
+	return func(o *providerOptions) {
+		if url != "" {
+			o.baseURL = url
+		}
+	}
+}
+
+// WithDefaultModel задает модель по умолчанию
+func WithDefaultModel(model string) Option {
+	return func(o *providerOptions) {
+		if model != "" {
+			o.defaultModel = model
+		}
+	}
+}
+
+// WithTimeout задает таймаут HTTP запросов к провайдеру
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *providerOptions) {
+		if timeout > 0 {
+			o.timeout = timeout
+		}
+	}
+}
+
+// WithTLS задает настройки TLS (дополнительные корневые сертификаты, mTLS)
+func WithTLS(tls TLSOptions) Option {
+	return func(o *providerOptions) {
+		o.tls = tls
+	}
+}
+
+// WithHTTPClient задает готовый HTTP клиент; настройки WithTimeout и WithTLS при этом не применяются
+func WithHTTPClient(client *http.Client) Option {
+	return func(o *providerOptions) {
+		o.httpClient = client
+	}
+}
+
+// WithAccessToken задает заранее полученный access token (GigaChat, для обратной совместимости)
+// Токен используется только без Authorization key и не обновляется
+func WithAccessToken(token string) Option {
+	return func(o *providerOptions) {
+		o.accessToken = token
+	}
+}
+
+// WithScope задает scope OAuth (GigaChat: GIGACHAT_API_PERS, GIGACHAT_API_B2B или GIGACHAT_API_CORP)
+func WithScope(scope string) Option {
+	return func(o *providerOptions) {
+		if scope != "" {
+			o.scope = scope
+		}
+	}
+}
+
+// WithOAuthURL задает URL получения access token (GigaChat)
+func WithOAuthURL(url string) Option {
+	return func(o *providerOptions) {
+		if url != "" {
+			o.oauthURL = url
+		}
+	}
+}
+
+// applyOptions применяет опции к значениям по умолчанию и создает HTTP клиент
+func applyOptions(name string, defaults providerOptions, opts []Option) (providerOptions, *http.Client, error) {
+	o := defaults
+	if o.timeout == 0 {
+		o.timeout = 60 * time.Second
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if o.httpClient != nil {
+		return o, o.httpClient, nil
+	}
+
+	client, err := newHTTPClient(name, o.timeout, o.tls)
+	if err != nil {
+		return o, nil, err
+	}
+	return o, client, nil
+}
@@ -2,6 +2,7 @@ package ai
 
 import (
 	"context"
+	"sort"
 )
 
 // Message представляет сообщение в диалоге
@@ -15,13 +16,27 @@ type ChatRequest struct {
 	Model    string    `json:"model"`
 	Messages []Message `json:"messages"`
 	Stream   bool      `json:"stream,omitempty"`
+	// ResponseFormat задает структурированный формат ответа (json_object / json_schema)
+	// Провайдеры без нативной поддержки игнорируют поле, эмуляцию выполняет ChatStructured
+	ResponseFormat *ResponseFormat `json:"response_format,omitempty"`
+	// Параметры генерации; nil/0 означает значение по умолчанию провайдера
+	Temperature *float64 `json:"temperature,omitempty"`
+	TopP        *float64 `json:"top_p,omitempty"`
+	MaxTokens   int      `json:"max_tokens,omitempty"`
+	// NoCache - не использовать кэш ответов для этого запроса (ответ не читается из кэша и не сохраняется)
+	NoCache bool `json:"-"`
 }
 
 // ChatResponse представляет ответ от AI провайдера
 type ChatResponse struct {
 	Content string `json:"content"`
-	Model   string `json:"model"`
-	Usage   struct {
+	// ReasoningContent содержит рассуждения reasoning-моделей (например, deepseek-reasoner)
+	// Рассуждения не должны отправляться обратно в контексте следующих запросов
+	ReasoningContent string `json:"reasoning_content,omitempty"`
+	Model            string `json:"model"`
+	// Cached - ответ получен из кэша, провайдер не вызывался
+	Cached bool `json:"cached,omitempty"`
+	Usage  struct {
 		PromptTokens     int `json:"prompt_tokens"`
 		CompletionTokens int `json:"completion_tokens"`
 		TotalTokens      int `json:"total_tokens"`
@@ -33,6 +48,10 @@ type Provider interface {
 	// Chat отправляет запрос к AI и возвращает ответ
 	Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error)
 
+	// ChatStream отправляет запрос к AI в потоковом режиме, передавая каждую порцию ответа в onChunk,
+	// и возвращает собранный целиком ответ
+	ChatStream(ctx context.Context, req ChatRequest, onChunk StreamHandler) (*ChatResponse, error)
+
 	// GetDefaultModel возвращает модель по умолчанию для провайдера
 	GetDefaultModel() string
 
@@ -40,23 +59,33 @@ type Provider interface {
 	GetName() string
 }
 
+// ModelLister - необязательный интерфейс провайдера, который умеет получать список моделей из API
+type ModelLister interface {
+	ListModels(ctx context.Context) ([]ModelInfo, error)
+}
+
+// TokenCounter - необязательный интерфейс провайдера с точным подсчетом токенов
+// Возвращает количество токенов для каждого текста в том же порядке
+type TokenCounter interface {
+	CountTokens(ctx context.Context, model string, texts []string) ([]int, error)
+}
+
 // ProviderFactory создает провайдера по имени
 type ProviderFactory struct {
-	providers map[string]Provider
+	providers    map[string]Provider
+	embedders    map[string]Embedder
+	interceptors []Interceptor
+
+	embedderInterceptors []EmbedderInterceptor
 }
 
-// NewProviderFactory создает новую фабрику провайдеров
+// NewProviderFactory создает пустую фабрику провайдеров
+// Провайдеры регистрируются вызывающим кодом: регистрируются только настроенные провайдеры
 func NewProviderFactory() *ProviderFactory {
-	factory := &ProviderFactory{
+	return &ProviderFactory{
 		providers: make(map[string]Provider),
+		embedders: make(map[string]Embedder),
 	}
-
-	// Регистрируем провайдеры
-	factory.Register("deepseek", NewDeepSeekProvider())
-	factory.Register("gigachat", NewGigaChatProvider())
-	factory.Register("qwen", NewQwenProvider())
-
-	return factory
 }
 
 // Register регистрирует провайдера
@@ -64,13 +93,42 @@ func (f *ProviderFactory) Register(name string, provider Provider) {
 	f.providers[name] = provider
 }
 
+// RegisterEmbedder регистрирует провайдера эмбеддингов
+func (f *ProviderFactory) RegisterEmbedder(name string, embedder Embedder) {
+	f.embedders[name] = embedder
+}
+
+// GetEmbedder возвращает провайдера эмбеддингов по имени
+func (f *ProviderFactory) GetEmbedder(name string) (Embedder, error) {
+	embedder, exists := f.embedders[name]
+	if !exists {
+		return nil, ErrProviderNotFound
+	}
+	for i := len(f.embedderInterceptors) - 1; i >= 0; i-- {
+		embedder = f.embedderInterceptors[i](embedder)
+	}
+	return embedder, nil
+}
+
+// UseEmbedder добавляет перехватчики, которые применяются ко всем провайдерам эмбеддингов
+// Порядок применения такой же, как у Use: первый - внешний
+func (f *ProviderFactory) UseEmbedder(interceptors ...EmbedderInterceptor) {
+	f.embedderInterceptors = append(f.embedderInterceptors, interceptors...)
+}
+
+// Use добавляет перехватчики, которые применяются ко всем провайдерам фабрики
+// Перехватчики применяются в порядке добавления: первый - внешний
+func (f *ProviderFactory) Use(interceptors ...Interceptor) {
+	f.interceptors = append(f.interceptors, interceptors...)
+}
+
 // Get возвращает провайдера по имени
 func (f *ProviderFactory) Get(name string) (Provider, error) {
 	provider, exists := f.providers[name]
 	if !exists {
 		return nil, ErrProviderNotFound
 	}
-	return provider, nil
+	return Chain(provider, f.interceptors...), nil
 }
 
 // List возвращает список доступных провайдеров
@@ -79,5 +137,6 @@ func (f *ProviderFactory) List() []string {
 	for name := range f.providers {
 		names = append(names, name)
 	}
+	sort.Strings(names)
 	return names
 }
@@ -7,8 +7,6 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"os"
-	"time"
 )
 
 const (
@@ -17,30 +15,36 @@ const (
 )
 
 type QwenProvider struct {
-	apiKey  string
-	baseURL string
-	client  *http.Client
+	apiKey       string
+	baseURL      string
+	defaultModel string
+	client       *http.Client
+	// streamClient - клиент без общего таймаута для потоковых ответов
+	streamClient *http.Client
 }
 
 // NewQwenProvider создает новый провайдер Qwen
-func NewQwenProvider() *QwenProvider {
-	apiKey := os.Getenv("QWEN_API_KEY")
+// Ошибка возвращается, если не задан API ключ или некорректны настройки TLS
+func NewQwenProvider(apiKey string, opts ...Option) (*QwenProvider, error) {
 	if apiKey == "" {
-		apiKey = os.Getenv("DASHSCOPE_API_KEY") // Альтернативное имя переменной
+		return nil, fmt.Errorf("%w: qwen", ErrAPIKeyMissing)
 	}
 
-	baseURL := os.Getenv("QWEN_API_BASE_URL")
-	if baseURL == "" {
-		baseURL = qwenDefaultAPIURL
+	o, client, err := applyOptions("qwen", providerOptions{
+		baseURL:      qwenDefaultAPIURL,
+		defaultModel: qwenDefaultModel,
+	}, opts)
+	if err != nil {
+		return nil, err
 	}
 
 	return &QwenProvider{
-		apiKey:  apiKey,
-		baseURL: baseURL,
-		client: &http.Client{
-			Timeout: 60 * time.Second,
-		},
-	}
+		apiKey:       apiKey,
+		baseURL:      o.baseURL,
+		defaultModel: o.defaultModel,
+		client:       client,
+		streamClient: newStreamingClient(client, o.timeout),
+	}, nil
 }
 
 // GetName возвращает имя провайдера
@@ -50,44 +54,18 @@ func (p *QwenProvider) GetName() string {
 
 // GetDefaultModel возвращает модель по умолчанию
 func (p *QwenProvider) GetDefaultModel() string {
-	return qwenDefaultModel
+	return p.defaultModel
 }
 
 // Chat отправляет запрос к Qwen API
 func (p *QwenProvider) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
-	if p.apiKey == "" {
-		return nil, fmt.Errorf("%w: QWEN_API_KEY or DASHSCOPE_API_KEY not set", ErrAPIKeyMissing)
-	}
-
-	// Определяем модель
-	model := req.Model
-	if model == "" {
-		model = qwenDefaultModel
-	}
-
-	// Конвертируем сообщения в формат OpenAI (MuleRouter использует OpenAI-совместимый формат)
-	qwenMessages := convertMessages(req.Messages)
-
-	// Подготавливаем запрос в формате OpenAI (MuleRouter совместим с OpenAI API)
-	qwenReq := map[string]interface{}{
-		"model":    model,
-		"messages": qwenMessages,
-		"stream":   false,
-	}
+	model := modelOrDefault(req.Model, p.defaultModel)
 
-	jsonData, err := json.Marshal(qwenReq)
+	httpReq, err := p.newRequest(ctx, req, false)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
+		return nil, err
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.baseURL, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", p.apiKey))
-
 	resp, err := p.client.Do(httpReq)
 	if err != nil {
 		return nil, fmt.Errorf("%w: %w", ErrAPIRequestFailed, err)
@@ -103,8 +81,9 @@ func (p *QwenProvider) Chat(ctx context.Context, req ChatRequest) (*ChatResponse
 		ID      string `json:"id"`
 		Choices []struct {
 			Message struct {
-				Role    string `json:"role"`
-				Content string `json:"content"`
+				Role             string `json:"role"`
+				Content          string `json:"content"`
+				ReasoningContent string `json:"reasoning_content"`
 			} `json:"message"`
 		} `json:"choices"`
 		Model string `json:"model"`
@@ -124,8 +103,9 @@ func (p *QwenProvider) Chat(ctx context.Context, req ChatRequest) (*ChatResponse
 	}
 
 	response := &ChatResponse{
-		Content: qwenResp.Choices[0].Message.Content,
-		Model:   qwenResp.Model,
+		Content:          qwenResp.Choices[0].Message.Content,
+		ReasoningContent: qwenResp.Choices[0].Message.ReasoningContent,
+		Model:            qwenResp.Model,
 	}
 	if response.Model == "" {
 		response.Model = model
@@ -136,3 +116,66 @@ func (p *QwenProvider) Chat(ctx context.Context, req ChatRequest) (*ChatResponse
 
 	return response, nil
 }
+
+// ChatStream отправляет потоковый запрос к Qwen API
+func (p *QwenProvider) ChatStream(ctx context.Context, req ChatRequest, onChunk StreamHandler) (*ChatResponse, error) {
+	httpReq, err := p.newRequest(ctx, req, true)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.streamClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrAPIRequestFailed, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("%w: status %d, body: %s", ErrAPIRequestFailed, resp.StatusCode, string(body))
+	}
+
+	return readOpenAIStream(resp.Body, modelOrDefault(req.Model, p.defaultModel), onChunk)
+}
+
+// SupportsResponseFormat сообщает, какие форматы ответа Qwen поддерживает нативно
+// OpenAI-совместимый API Qwen принимает и json_object, и json_schema
+func (p *QwenProvider) SupportsResponseFormat(formatType string) bool {
+	return formatType == ResponseFormatJSONObject || formatType == ResponseFormatJSONSchema
+}
+
+// newRequest подготавливает HTTP запрос к Qwen API
+func (p *QwenProvider) newRequest(ctx context.Context, req ChatRequest, stream bool) (*http.Request, error) {
+	if p.apiKey == "" {
+		return nil, fmt.Errorf("%w: qwen", ErrAPIKeyMissing)
+	}
+
+	// Подготавливаем запрос в формате OpenAI (MuleRouter совместим с OpenAI API)
+	qwenReq := map[string]interface{}{
+		"model":    modelOrDefault(req.Model, p.defaultModel),
+		"messages": convertMessages(req.Messages),
+		"stream":   stream,
+	}
+	if stream {
+		qwenReq["stream_options"] = map[string]bool{"include_usage": true}
+	}
+	if p.SupportsResponseFormat(formatType(req.ResponseFormat)) {
+		qwenReq["response_format"] = req.ResponseFormat
+	}
+	applyGenerationParams(qwenReq, req)
+
+	jsonData, err := json.Marshal(qwenReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.baseURL, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", p.apiKey))
+
+	return httpReq, nil
+}
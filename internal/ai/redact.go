@@ -0,0 +1,412 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Типы персональных данных, которые распознает Redactor
+const (
+	PIIEmail    = "email"
+	PIIPhone    = "phone"
+	PIICard     = "card"
+	PIISNILS    = "snils"
+	PIIPassport = "passport"
+	PIIINN      = "inn"
+)
+
+// piiDetector находит в тексте один тип персональных данных
+// valid дополнительно проверяет совпадение (контрольные суммы), nil - без проверки
+type piiDetector struct {
+	entity string
+	re     *regexp.Regexp
+	valid  func(match string) bool
+}
+
+// piiDetectors применяются по порядку: более специфичные шаблоны раньше, чтобы, например,
+// номер карты не был принят за ИНН
+var piiDetectors = []piiDetector{
+	{PIIEmail, regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`), nil},
+	{PIIPhone, regexp.MustCompile(`(?:\+7|\b8)[\s\-(]*\d{3}[\s\-)]*\d{3}[\s\-]*\d{2}[\s\-]*\d{2}\b`), nil},
+	{PIICard, regexp.MustCompile(`\b(?:\d[ \-]?){12,18}\d\b`), validCardNumber},
+	{PIISNILS, regexp.MustCompile(`\b\d{3}[\- ]?\d{3}[\- ]?\d{3}[\- ]?\d{2}\b`), validSNILS},
+	{PIIPassport, regexp.MustCompile(`\b\d{2}\s?\d{2}\s\d{6}\b`), nil},
+	{PIIINN, regexp.MustCompile(`\b(?:\d{12}|\d{10})\b`), validINN},
+}
+
+// Redactor заменяет персональные данные в тексте на плейсхолдеры вида [EMAIL_1]
+type Redactor struct {
+	detectors []piiDetector
+}
+
+// NewRedactor создает Redactor для указанных типов данных; без аргументов распознаются все типы
+func NewRedactor(entities ...string) (*Redactor, error) {
+	if len(entities) == 0 {
+		return &Redactor{detectors: piiDetectors}, nil
+	}
+
+	var detectors []piiDetector
+	for _, entity := range entities {
+		found := false
+		for _, d := range piiDetectors {
+			if d.entity == entity {
+				detectors = append(detectors, d)
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown PII entity type: %s", entity)
+		}
+	}
+
+	// Сохраняем порядок применения из piiDetectors
+	sort.SliceStable(detectors, func(i, j int) bool {
+		return detectorIndex(detectors[i].entity) < detectorIndex(detectors[j].entity)
+	})
+	return &Redactor{detectors: detectors}, nil
+}
+
+func detectorIndex(entity string) int {
+	for i, d := range piiDetectors {
+		if d.entity == entity {
+			return i
+		}
+	}
+	return len(piiDetectors)
+}
+
+// Redaction хранит соответствие плейсхолдеров исходным значениям в рамках одного запроса
+// Одинаковые значения получают один и тот же плейсхолдер
+type Redaction struct {
+	placeholders map[string]string // значение -> плейсхолдер
+	originals    map[string]string // плейсхолдер -> значение
+	counts       map[string]int    // тип -> количество различных значений
+}
+
+// NewRedaction создает пустое соответствие плейсхолдеров
+func NewRedaction() *Redaction {
+	return &Redaction{
+		placeholders: make(map[string]string),
+		originals:    make(map[string]string),
+		counts:       make(map[string]int),
+	}
+}
+
+// Counts возвращает количество замененных значений по типам
+func (r *Redaction) Counts() map[string]int {
+	counts := make(map[string]int, len(r.counts))
+	for k, v := range r.counts {
+		counts[k] = v
+	}
+	return counts
+}
+
+// Empty сообщает, что в запросе не найдено персональных данных
+func (r *Redaction) Empty() bool {
+	return len(r.originals) == 0
+}
+
+// Redact заменяет найденные персональные данные на плейсхолдеры
+func (rd *Redactor) Redact(text string, r *Redaction) string {
+	for _, d := range rd.detectors {
+		text = d.re.ReplaceAllStringFunc(text, func(match string) string {
+			if d.valid != nil && !d.valid(match) {
+				return match
+			}
+			if placeholder, ok := r.placeholders[match]; ok {
+				return placeholder
+			}
+			r.counts[d.entity]++
+			placeholder := fmt.Sprintf("[%s_%d]", strings.ToUpper(d.entity), r.counts[d.entity])
+			r.placeholders[match] = placeholder
+			r.originals[placeholder] = match
+			return placeholder
+		})
+	}
+	return text
+}
+
+// Restore подставляет исходные значения вместо плейсхолдеров
+func (r *Redaction) Restore(text string) string {
+	if r.Empty() || !strings.Contains(text, "[") {
+		return text
+	}
+
+	pairs := make([]string, 0, len(r.originals)*2)
+	for placeholder, original := range r.originals {
+		pairs = append(pairs, placeholder, original)
+	}
+	return strings.NewReplacer(pairs...).Replace(text)
+}
+
+// maxPlaceholderLen - максимальная длина плейсхолдера ([PASSPORT_123])
+const maxPlaceholderLen = 24
+
+// streamRestorer восстанавливает плейсхолдеры в потоковом ответе
+// Плейсхолдер может прийти по частям в разных порциях, поэтому незавершенный хвост вида "[EMA"
+// придерживается до следующей порции
+type streamRestorer struct {
+	redaction *Redaction
+	pending   string
+}
+
+// Write принимает очередную порцию и возвращает текст, который уже можно отдать клиенту
+func (s *streamRestorer) Write(delta string) string {
+	text := s.pending + delta
+	s.pending = ""
+
+	if i := strings.LastIndex(text, "["); i >= 0 && !strings.Contains(text[i:], "]") && len(text)-i < maxPlaceholderLen {
+		s.pending = text[i:]
+		text = text[:i]
+	}
+	return s.redaction.Restore(text)
+}
+
+// Flush возвращает придержанный остаток
+func (s *streamRestorer) Flush() string {
+	text := s.pending
+	s.pending = ""
+	return s.redaction.Restore(text)
+}
+
+// RedactionReport - запись аудита об удаленных из запроса персональных данных
+// Сами значения в отчет не попадают, только их количество по типам
+type RedactionReport struct {
+	Provider string
+	Model    string
+	Entities map[string]int
+}
+
+// RedactionInterceptor заменяет персональные данные в сообщениях на плейсхолдеры перед отправкой провайдеру
+// и восстанавливает их в ответе. enabled определяет, для каких провайдеров редактирование обязательно
+// (например, не нужно для локальной модели). audit вызывается для каждого запроса, в котором найдены данные.
+// Подсчет токенов (TokenCounter) тоже получает тексты с плейсхолдерами: иначе ai.As нашел бы
+// счетчик провайдера под оберткой, и история ушла бы провайдеру без замен
+func RedactionInterceptor(redactor *Redactor, enabled func(provider string) bool, audit func(ctx context.Context, report RedactionReport)) Interceptor {
+	// redact возвращает запрос с замененными данными или nil, если заменять нечего
+	redact := func(ctx context.Context, p Provider, req ChatRequest) (ChatRequest, *Redaction) {
+		if !enabled(p.GetName()) {
+			return req, nil
+		}
+
+		redaction := NewRedaction()
+		messages := make([]Message, len(req.Messages))
+		for i, msg := range req.Messages {
+			messages[i] = Message{Role: msg.Role, Content: redactor.Redact(msg.Content, redaction)}
+		}
+		if redaction.Empty() {
+			return req, nil
+		}
+
+		if audit != nil {
+			audit(ctx, RedactionReport{
+				Provider: p.GetName(),
+				Model:    modelOrDefault(req.Model, p.GetDefaultModel()),
+				Entities: redaction.Counts(),
+			})
+		}
+
+		req.Messages = messages
+		return req, redaction
+	}
+
+	intercept := InterceptorFuncs(
+		func(p Provider, next ChatFunc) ChatFunc {
+			return func(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+				req, redaction := redact(ctx, p, req)
+				resp, err := next(ctx, req)
+				if err != nil || redaction == nil {
+					return resp, err
+				}
+
+				restored := *resp
+				restored.Content = redaction.Restore(resp.Content)
+				restored.ReasoningContent = redaction.Restore(resp.ReasoningContent)
+				return &restored, nil
+			}
+		},
+		func(p Provider, next ChatStreamFunc) ChatStreamFunc {
+			return func(ctx context.Context, req ChatRequest, onChunk StreamHandler) (*ChatResponse, error) {
+				req, redaction := redact(ctx, p, req)
+				if redaction == nil {
+					return next(ctx, req, onChunk)
+				}
+
+				content := &streamRestorer{redaction: redaction}
+				reasoning := &streamRestorer{redaction: redaction}
+				resp, err := next(ctx, req, func(chunk StreamChunk) error {
+					restored := StreamChunk{
+						ContentDelta:   content.Write(chunk.ContentDelta),
+						ReasoningDelta: reasoning.Write(chunk.ReasoningDelta),
+					}
+					if restored.ContentDelta == "" && restored.ReasoningDelta == "" {
+						return nil
+					}
+					return onChunk(restored)
+				})
+				if err != nil {
+					return nil, err
+				}
+
+				if tail := (StreamChunk{ReasoningDelta: reasoning.Flush(), ContentDelta: content.Flush()}); tail.ContentDelta != "" || tail.ReasoningDelta != "" {
+					if err := onChunk(tail); err != nil {
+						return nil, err
+					}
+				}
+
+				restored := *resp
+				restored.Content = redaction.Restore(resp.Content)
+				restored.ReasoningContent = redaction.Restore(resp.ReasoningContent)
+				return &restored, nil
+			}
+		},
+	)
+
+	return func(next Provider) Provider {
+		wrapped := intercept(next)
+		if !enabled(next.GetName()) {
+			return wrapped
+		}
+		counter, ok := As[TokenCounter](next)
+		if !ok {
+			return wrapped
+		}
+		return &redactingTokenCounter{Provider: wrapped, next: next, counter: counter, redactor: redactor}
+	}
+}
+
+// redactingTokenCounter - обертка RedactionInterceptor для провайдеров с подсчетом токенов
+type redactingTokenCounter struct {
+	Provider
+	next     Provider
+	counter  TokenCounter
+	redactor *Redactor
+}
+
+func (p *redactingTokenCounter) Unwrap() Provider { return p.next }
+
+// CountTokens считает токены текстов после замены персональных данных, то есть именно тех текстов,
+// которые провайдер получит в запросе
+func (p *redactingTokenCounter) CountTokens(ctx context.Context, model string, texts []string) ([]int, error) {
+	redaction := NewRedaction()
+	redacted := make([]string, len(texts))
+	for i, text := range texts {
+		redacted[i] = p.redactor.Redact(text, redaction)
+	}
+	return p.counter.CountTokens(ctx, model, redacted)
+}
+
+// RedactionEmbedderInterceptor заменяет персональные данные в текстах перед получением эмбеддингов
+// Эмбеддинги запроса к базе знаний и фрагментов документов строятся по тексту с плейсхолдерами;
+// восстанавливать ответ не нужно, он содержит только векторы
+func RedactionEmbedderInterceptor(redactor *Redactor, enabled func(provider string) bool, audit func(ctx context.Context, report RedactionReport)) EmbedderInterceptor {
+	return func(next Embedder) Embedder {
+		if !enabled(next.GetName()) {
+			return next
+		}
+		return &redactingEmbedder{Embedder: next, redactor: redactor, audit: audit}
+	}
+}
+
+// redactingEmbedder - провайдер эмбеддингов, получающий тексты без персональных данных
+type redactingEmbedder struct {
+	Embedder
+	redactor *Redactor
+	audit    func(ctx context.Context, report RedactionReport)
+}
+
+func (e *redactingEmbedder) Embed(ctx context.Context, req EmbeddingRequest) (*EmbeddingResponse, error) {
+	redaction := NewRedaction()
+	input := make([]string, len(req.Input))
+	for i, text := range req.Input {
+		input[i] = e.redactor.Redact(text, redaction)
+	}
+
+	if !redaction.Empty() {
+		if e.audit != nil {
+			e.audit(ctx, RedactionReport{
+				Provider: e.GetName(),
+				Model:    modelOrDefault(req.Model, e.GetDefaultModel()),
+				Entities: redaction.Counts(),
+			})
+		}
+		req.Input = input
+	}
+
+	return e.Embedder.Embed(ctx, req)
+}
+
+// digitsOnly оставляет в строке только цифры
+func digitsOnly(s string) []int {
+	digits := make([]int, 0, len(s))
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			digits = append(digits, int(r-'0'))
+		}
+	}
+	return digits
+}
+
+// validCardNumber проверяет номер карты по алгоритму Луна
+func validCardNumber(s string) bool {
+	digits := digitsOnly(s)
+	if len(digits) < 13 || len(digits) > 19 {
+		return false
+	}
+
+	sum := 0
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := digits[i]
+		if (len(digits)-1-i)%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return sum%10 == 0
+}
+
+// validSNILS проверяет контрольное число СНИЛС
+func validSNILS(s string) bool {
+	digits := digitsOnly(s)
+	if len(digits) != 11 {
+		return false
+	}
+
+	sum := 0
+	for i := 0; i < 9; i++ {
+		sum += digits[i] * (9 - i)
+	}
+	check := sum % 101
+	if check == 100 {
+		check = 0
+	}
+	return check == digits[9]*10+digits[10]
+}
+
+// validINN проверяет контрольные цифры ИНН (10 цифр - организации, 12 - физические лица)
+func validINN(s string) bool {
+	digits := digitsOnly(s)
+	checksum := func(weights []int) int {
+		sum := 0
+		for i, w := range weights {
+			sum += digits[i] * w
+		}
+		return sum % 11 % 10
+	}
+
+	switch len(digits) {
+	case 10:
+		return checksum([]int{2, 4, 10, 3, 5, 9, 4, 6, 8}) == digits[9]
+	case 12:
+		return checksum([]int{7, 2, 4, 10, 3, 5, 9, 4, 6, 8}) == digits[10] &&
+			checksum([]int{3, 7, 2, 4, 10, 3, 5, 9, 4, 6, 8}) == digits[11]
+	default:
+		return false
+	}
+}
@@ -0,0 +1,365 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestValidCardNumber(t *testing.T) {
+	tests := []struct {
+		number string
+		want   bool
+	}{
+		{"4111 1111 1111 1111", true},
+		{"4111-1111-1111-1111", true},
+		{"5500000000000004", true},
+		{"4111 1111 1111 1112", false},
+		{"1234 5678 9012 3456", false},
+		{"411111111111", false}, // 12 цифр - слишком короткий
+	}
+	for _, tt := range tests {
+		if got := validCardNumber(tt.number); got != tt.want {
+			t.Errorf("validCardNumber(%q) = %v, want %v", tt.number, got, tt.want)
+		}
+	}
+}
+
+func TestValidSNILS(t *testing.T) {
+	tests := []struct {
+		snils string
+		want  bool
+	}{
+		{"112-233-445 95", true},
+		{"11223344595", true},
+		{"112-233-445 96", false},
+		{"112-233-445", false},
+	}
+	for _, tt := range tests {
+		if got := validSNILS(tt.snils); got != tt.want {
+			t.Errorf("validSNILS(%q) = %v, want %v", tt.snils, got, tt.want)
+		}
+	}
+}
+
+func TestValidINN(t *testing.T) {
+	tests := []struct {
+		inn  string
+		want bool
+	}{
+		{"7707083893", true},
+		{"7707083894", false},
+		{"500100732259", true},
+		{"500100732258", false},
+		{"500100732249", false},
+		{"12345678901", false},
+	}
+	for _, tt := range tests {
+		if got := validINN(tt.inn); got != tt.want {
+			t.Errorf("validINN(%q) = %v, want %v", tt.inn, got, tt.want)
+		}
+	}
+}
+
+func TestRedactAndRestore(t *testing.T) {
+	redactor, err := NewRedactor()
+	if err != nil {
+		t.Fatalf("NewRedactor: %v", err)
+	}
+
+	text := "Пишите на ivan@example.com или ivan@example.com, звоните +7 (912) 345-67-89. " +
+		"Карта 4111 1111 1111 1111, СНИЛС 112-233-445 95, ИНН 7707083893. Заказ 1234 5678 9012 3456."
+	redaction := NewRedaction()
+	redacted := redactor.Redact(text, redaction)
+
+	for _, value := range []string{"ivan@example.com", "345-67-89", "4111 1111", "112-233-445", "7707083893"} {
+		if strings.Contains(redacted, value) {
+			t.Errorf("redacted text still contains %q: %s", value, redacted)
+		}
+	}
+	// Номер без корректной контрольной суммы не считается картой
+	if !strings.Contains(redacted, "1234 5678 9012 3456") {
+		t.Errorf("number with invalid checksum should be kept: %s", redacted)
+	}
+	// Одинаковые значения получают один плейсхолдер
+	if strings.Count(redacted, "[EMAIL_1]") != 2 || strings.Contains(redacted, "[EMAIL_2]") {
+		t.Errorf("repeated email should map to one placeholder: %s", redacted)
+	}
+
+	wantCounts := map[string]int{PIIEmail: 1, PIIPhone: 1, PIICard: 1, PIISNILS: 1, PIIINN: 1}
+	counts := redaction.Counts()
+	for entity, want := range wantCounts {
+		if counts[entity] != want {
+			t.Errorf("counts[%s] = %d, want %d", entity, counts[entity], want)
+		}
+	}
+
+	if restored := redaction.Restore(redacted); restored != text {
+		t.Errorf("Restore = %q, want %q", restored, text)
+	}
+}
+
+func TestNewRedactorEntities(t *testing.T) {
+	redactor, err := NewRedactor(PIIEmail)
+	if err != nil {
+		t.Fatalf("NewRedactor: %v", err)
+	}
+
+	redacted := redactor.Redact("ivan@example.com, +7 912 345 67 89", NewRedaction())
+	if redacted != "[EMAIL_1], +7 912 345 67 89" {
+		t.Errorf("Redact = %q", redacted)
+	}
+
+	if _, err := NewRedactor("address"); err == nil {
+		t.Error("NewRedactor should reject unknown entity types")
+	}
+}
+
+// newTestRedaction возвращает соответствие с одним плейсхолдером [EMAIL_1]
+func newTestRedaction(t *testing.T) *Redaction {
+	t.Helper()
+	redactor, err := NewRedactor()
+	if err != nil {
+		t.Fatalf("NewRedactor: %v", err)
+	}
+	redaction := NewRedaction()
+	if got := redactor.Redact("ivan@example.com", redaction); got != "[EMAIL_1]" {
+		t.Fatalf("Redact = %q, want [EMAIL_1]", got)
+	}
+	return redaction
+}
+
+func TestStreamRestorerSplitPlaceholder(t *testing.T) {
+	tests := []struct {
+		name   string
+		chunks []string
+	}{
+		{"whole", []string{"Пишите на ", "[EMAIL_1]", " сегодня"}},
+		{"split in the middle", []string{"Пишите на [EM", "AIL_", "1] сегодня"}},
+		{"split before bracket", []string{"Пишите на ", "[", "EMAIL_1] сегодня"}},
+		{"split before closing bracket", []string{"Пишите на [EMAIL_1", "] сегодня"}},
+		{"one rune per chunk", strings.Split("Пишите на [EMAIL_1] сегодня", "")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			restorer := &streamRestorer{redaction: newTestRedaction(t)}
+
+			var out strings.Builder
+			for _, chunk := range tt.chunks {
+				delta := restorer.Write(chunk)
+				if strings.Contains(delta, "[") || strings.Contains(delta, "]") {
+					t.Errorf("partial placeholder leaked: %q", delta)
+				}
+				out.WriteString(delta)
+			}
+			out.WriteString(restorer.Flush())
+
+			if want := "Пишите на ivan@example.com сегодня"; out.String() != want {
+				t.Errorf("restored = %q, want %q", out.String(), want)
+			}
+		})
+	}
+}
+
+func TestStreamRestorerReleasesPlainBrackets(t *testing.T) {
+	restorer := &streamRestorer{redaction: newTestRedaction(t)}
+
+	// Незакрытая скобка придерживается, пока не станет ясно, что это не плейсхолдер
+	if got := restorer.Write("массив [1, "); got != "массив " {
+		t.Errorf("Write = %q, want held bracket", got)
+	}
+	if got := restorer.Write("2]"); got != "[1, 2]" {
+		t.Errorf("Write = %q, want released text", got)
+	}
+
+	long := "[" + strings.Repeat("x", maxPlaceholderLen)
+	if got := restorer.Write(long); got != long {
+		t.Errorf("long bracket text should not be held: %q", got)
+	}
+
+	// Остаток без закрывающей скобки отдается при завершении потока
+	if got := restorer.Write("конец ["); got != "конец " {
+		t.Errorf("Write = %q", got)
+	}
+	if got := restorer.Flush(); got != "[" {
+		t.Errorf("Flush = %q, want [", got)
+	}
+}
+
+func TestRedactionInterceptor(t *testing.T) {
+	provider := newFakeProvider()
+	provider.reply.Content = "Написал на [EMAIL_1]"
+	provider.chunks = []StreamChunk{{ContentDelta: "Написал на [EMA"}, {ContentDelta: "IL_1]"}}
+
+	redactor, err := NewRedactor()
+	if err != nil {
+		t.Fatalf("NewRedactor: %v", err)
+	}
+	var reports []RedactionReport
+	audit := func(ctx context.Context, report RedactionReport) { reports = append(reports, report) }
+	enabled := func(name string) bool { return name == "fake" }
+	chained := Chain(provider, RedactionInterceptor(redactor, enabled, audit))
+
+	req := ChatRequest{Messages: []Message{{Role: "user", Content: "Напиши на ivan@example.com"}}}
+	resp, err := chained.Chat(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	if got := provider.requests[0].Messages[0].Content; got != "Напиши на [EMAIL_1]" {
+		t.Errorf("provider received %q", got)
+	}
+	if resp.Content != "Написал на ivan@example.com" {
+		t.Errorf("Chat content = %q", resp.Content)
+	}
+	// Исходный запрос вызывающего кода не изменяется
+	if req.Messages[0].Content != "Напиши на ivan@example.com" {
+		t.Errorf("caller request was modified: %q", req.Messages[0].Content)
+	}
+
+	var streamed strings.Builder
+	resp, err = chained.ChatStream(context.Background(), req, func(chunk StreamChunk) error {
+		streamed.WriteString(chunk.ContentDelta)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ChatStream: %v", err)
+	}
+	if streamed.String() != "Написал на ivan@example.com" || resp.Content != streamed.String() {
+		t.Errorf("streamed = %q, content = %q", streamed.String(), resp.Content)
+	}
+
+	if len(reports) != 2 || reports[0].Provider != "fake" || reports[0].Model != "fake-model" || reports[0].Entities[PIIEmail] != 1 {
+		t.Errorf("reports = %+v", reports)
+	}
+
+	// Для провайдеров без обязательного удаления запрос не меняется
+	provider.name = "local"
+	if _, err := chained.Chat(context.Background(), req); err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	if got := provider.requests[len(provider.requests)-1].Messages[0].Content; got != req.Messages[0].Content {
+		t.Errorf("disabled provider received %q", got)
+	}
+	if len(reports) != 2 {
+		t.Errorf("disabled provider must not be audited: %+v", reports)
+	}
+}
+
+// fakeEmbedder записывает тексты, полученные для эмбеддингов
+type fakeEmbedder struct {
+	name   string
+	inputs [][]string
+}
+
+func (e *fakeEmbedder) Embed(ctx context.Context, req EmbeddingRequest) (*EmbeddingResponse, error) {
+	e.inputs = append(e.inputs, req.Input)
+	return &EmbeddingResponse{Vectors: make([][]float32, len(req.Input)), Model: req.Model}, nil
+}
+
+func (e *fakeEmbedder) GetDefaultModel() string { return "embed-model" }
+
+func (e *fakeEmbedder) GetName() string { return e.name }
+
+func TestRedactionEmbedderInterceptor(t *testing.T) {
+	redactor, err := NewRedactor()
+	if err != nil {
+		t.Fatalf("NewRedactor: %v", err)
+	}
+	var reports []RedactionReport
+	audit := func(ctx context.Context, report RedactionReport) { reports = append(reports, report) }
+	enabled := func(name string) bool { return name == "openai" }
+
+	embedder := &fakeEmbedder{name: "openai"}
+	wrapped := RedactionEmbedderInterceptor(redactor, enabled, audit)(embedder)
+
+	input := []string{"письмо от ivan@example.com", "без данных"}
+	if _, err := wrapped.Embed(context.Background(), EmbeddingRequest{Input: input}); err != nil {
+		t.Fatalf("Embed: %v", err)
+	}
+	if got := embedder.inputs[0]; got[0] != "письмо от [EMAIL_1]" || got[1] != "без данных" {
+		t.Errorf("embedder received %q", got)
+	}
+	if input[0] != "письмо от ivan@example.com" {
+		t.Errorf("caller input was modified: %q", input[0])
+	}
+	if len(reports) != 1 || reports[0].Provider != "openai" || reports[0].Model != "embed-model" {
+		t.Errorf("reports = %+v", reports)
+	}
+
+	// Запрос без персональных данных не аудируется
+	if _, err := wrapped.Embed(context.Background(), EmbeddingRequest{Input: []string{"без данных"}}); err != nil {
+		t.Fatalf("Embed: %v", err)
+	}
+	if len(reports) != 1 {
+		t.Errorf("request without PII must not be audited: %+v", reports)
+	}
+
+	local := &fakeEmbedder{name: "local"}
+	if got := RedactionEmbedderInterceptor(redactor, enabled, audit)(local); got != Embedder(local) {
+		t.Error("disabled embedder should not be wrapped")
+	}
+}
+
+// countingProvider реализует TokenCounter и записывает тексты, полученные для подсчета
+type countingProvider struct {
+	*fakeProvider
+	counted [][]string
+}
+
+func (p *countingProvider) CountTokens(ctx context.Context, model string, texts []string) ([]int, error) {
+	p.counted = append(p.counted, texts)
+	counts := make([]int, len(texts))
+	for i, text := range texts {
+		counts[i] = len(text)
+	}
+	return counts, nil
+}
+
+func TestRedactionInterceptorCountsRedactedTokens(t *testing.T) {
+	redactor, err := NewRedactor()
+	if err != nil {
+		t.Fatalf("NewRedactor: %v", err)
+	}
+	enabled := func(name string) bool { return name == "fake" }
+	provider := &countingProvider{fakeProvider: newFakeProvider()}
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+
+	// Счетчик ищется через ai.As от внешней обертки, как в messageTokenCounts
+	chained := Chain(provider, LoggingInterceptor(logger), RedactionInterceptor(redactor, enabled, nil))
+	counter, ok := As[TokenCounter](chained)
+	if !ok {
+		t.Fatal("As[TokenCounter] did not find a counter")
+	}
+
+	texts := []string{"пишите на ivan@example.com", "СНИЛС 112-233-445 95, снова ivan@example.com"}
+	if _, err := counter.CountTokens(context.Background(), "", texts); err != nil {
+		t.Fatalf("CountTokens: %v", err)
+	}
+
+	got := provider.counted[0]
+	want := []string{"пишите на [EMAIL_1]", "СНИЛС [SNILS_1], снова [EMAIL_1]"}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("counted texts = %q, want %q", got, want)
+	}
+	if texts[0] != "пишите на ivan@example.com" {
+		t.Errorf("caller texts were modified: %q", texts[0])
+	}
+
+	// Обертка не добавляет TokenCounter провайдеру, у которого его нет
+	if _, ok := As[TokenCounter](Chain(newFakeProvider(), RedactionInterceptor(redactor, enabled, nil))); ok {
+		t.Error("As[TokenCounter] found a counter for a provider without one")
+	}
+
+	// Для провайдера без обязательного удаления тексты не меняются
+	provider.name = "local"
+	counter, _ = As[TokenCounter](Chain(provider, RedactionInterceptor(redactor, enabled, nil)))
+	if _, err := counter.CountTokens(context.Background(), "", texts); err != nil {
+		t.Fatalf("CountTokens: %v", err)
+	}
+	if got := provider.counted[1]; got[0] != texts[0] {
+		t.Errorf("disabled provider counted %q", got)
+	}
+}
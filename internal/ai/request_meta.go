@@ -0,0 +1,24 @@
+package ai
+
+import "context"
+
+// RequestMeta - сведения о том, от чьего имени выполняется запрос к провайдеру
+// Передаются через context и используются перехватчиками (аудит, модерация)
+type RequestMeta struct {
+	UserID int
+	// ChatID не задан для запросов без чата (completions)
+	ChatID *int
+}
+
+type requestMetaKey struct{}
+
+// WithRequestMeta добавляет сведения о запросе в контекст
+func WithRequestMeta(ctx context.Context, meta RequestMeta) context.Context {
+	return context.WithValue(ctx, requestMetaKey{}, meta)
+}
+
+// RequestMetaFrom возвращает сведения о запросе из контекста
+func RequestMetaFrom(ctx context.Context) (RequestMeta, bool) {
+	meta, ok := ctx.Value(requestMetaKey{}).(RequestMeta)
+	return meta, ok
+}
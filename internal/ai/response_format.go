@@ -0,0 +1,208 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Типы структурированного ответа
+const (
+	ResponseFormatText       = "text"
+	ResponseFormatJSONObject = "json_object"
+	ResponseFormatJSONSchema = "json_schema"
+)
+
+// ResponseFormat задает формат ответа модели (совместим с OpenAI response_format)
+type ResponseFormat struct {
+	Type       string            `json:"type"`
+	JSONSchema *JSONSchemaFormat `json:"json_schema,omitempty"`
+}
+
+// JSONSchemaFormat описывает схему для режима json_schema
+type JSONSchemaFormat struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Schema      json.RawMessage `json:"schema"`
+	Strict      bool            `json:"strict,omitempty"`
+}
+
+// IsJSON сообщает, требует ли формат ответа в виде JSON
+func (f *ResponseFormat) IsJSON() bool {
+	return f != nil && (f.Type == ResponseFormatJSONObject || f.Type == ResponseFormatJSONSchema)
+}
+
+// Validate проверяет корректность формата и схемы
+func (f *ResponseFormat) Validate() error {
+	if f == nil {
+		return nil
+	}
+
+	switch f.Type {
+	case ResponseFormatText, ResponseFormatJSONObject:
+		if f.JSONSchema != nil {
+			return fmt.Errorf("json_schema is only allowed with type %q", ResponseFormatJSONSchema)
+		}
+		return nil
+	case ResponseFormatJSONSchema:
+		if f.JSONSchema == nil {
+			return fmt.Errorf("json_schema is required for type %q", ResponseFormatJSONSchema)
+		}
+		if strings.TrimSpace(f.JSONSchema.Name) == "" {
+			return fmt.Errorf("json_schema.name is required")
+		}
+		if _, err := CompileJSONSchema(f.JSONSchema.Schema); err != nil {
+			return fmt.Errorf("invalid json_schema.schema: %w", err)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported response_format type %q", f.Type)
+	}
+}
+
+// ResponseFormatSupporter реализуют провайдеры, умеющие передавать response_format в API нативно
+type ResponseFormatSupporter interface {
+	SupportsResponseFormat(formatType string) bool
+}
+
+// supportsResponseFormat проверяет нативную поддержку формата провайдером
+func supportsResponseFormat(p Provider, f *ResponseFormat) bool {
+	s, ok := As[ResponseFormatSupporter](p)
+	return ok && f != nil && s.SupportsResponseFormat(f.Type)
+}
+
+// ChatStructured выполняет запрос с учетом req.ResponseFormat
+// Для провайдеров с нативной поддержкой формат передается в API, для остальных эмулируется
+// инструкцией в системном сообщении. Ответ в любом случае проверяется; при ошибке делается
+// одна попытка исправления, после чего возвращается ErrInvalidStructuredOutput.
+// Если ошибка возникла после первого ответа модели, вместе с ошибкой возвращается ответ без содержимого:
+// в нем модель и суммарный расход токенов всех попыток, который вызывающий код должен учесть
+func ChatStructured(ctx context.Context, provider Provider, req ChatRequest) (*ChatResponse, error) {
+	format := req.ResponseFormat
+	if !format.IsJSON() {
+		return provider.Chat(ctx, req)
+	}
+	if err := format.Validate(); err != nil {
+		return nil, err
+	}
+
+	var schema *JSONSchema
+	if format.Type == ResponseFormatJSONSchema {
+		// Схема уже проверена в Validate
+		schema, _ = CompileJSONSchema(format.JSONSchema.Schema)
+	}
+
+	// Инструкция нужна и при нативной поддержке: например, DeepSeek требует упоминания JSON в промпте
+	messages := make([]Message, 0, len(req.Messages)+1)
+	messages = append(messages, Message{Role: "system", Content: structuredOutputInstruction(format)})
+	messages = append(messages, req.Messages...)
+
+	// В кэш попадают только ответы, прошедшие проверку: иначе повторный запрос получил бы
+	// из кэша тот же некорректный ответ без попытки исправления
+	ctx = withCacheValidator(ctx, func(content string) error {
+		_, err := validateStructuredOutput(content, schema)
+		return err
+	})
+
+	attemptReq := req
+	attemptReq.Messages = messages
+	if !supportsResponseFormat(provider, format) {
+		attemptReq.ResponseFormat = nil
+	}
+
+	resp, err := provider.Chat(ctx, attemptReq)
+	if err != nil {
+		return nil, err
+	}
+
+	document, validationErr := validateStructuredOutput(resp.Content, schema)
+	if validationErr == nil {
+		resp.Content = document
+		return resp, nil
+	}
+
+	// Одна попытка исправления: показываем модели ее ответ и ошибку проверки
+	repairReq := attemptReq
+	repairReq.Messages = append(append([]Message{}, messages...),
+		Message{Role: "assistant", Content: resp.Content},
+		Message{Role: "user", Content: fmt.Sprintf(
+			"Your previous reply is not valid: %s. Reply again with only the corrected JSON, without any explanations or markdown.",
+			validationErr)},
+	)
+
+	repaired, err := provider.Chat(ctx, repairReq)
+	if err != nil {
+		resp.Content, resp.ReasoningContent = "", ""
+		return resp, err
+	}
+
+	// Учитываем токены обеих попыток
+	repaired.Usage.PromptTokens += resp.Usage.PromptTokens
+	repaired.Usage.CompletionTokens += resp.Usage.CompletionTokens
+	repaired.Usage.TotalTokens += resp.Usage.TotalTokens
+
+	document, validationErr = validateStructuredOutput(repaired.Content, schema)
+	if validationErr != nil {
+		repaired.Content, repaired.ReasoningContent = "", ""
+		return repaired, fmt.Errorf("%w: %v", ErrInvalidStructuredOutput, validationErr)
+	}
+
+	repaired.Content = document
+	return repaired, nil
+}
+
+// structuredOutputInstruction формирует системную инструкцию для JSON-режима
+func structuredOutputInstruction(format *ResponseFormat) string {
+	var b strings.Builder
+	b.WriteString("Respond only with a single valid JSON value. Do not wrap it in markdown code blocks and do not add any text before or after the JSON.")
+	if format.Type == ResponseFormatJSONSchema {
+		b.WriteString(" The JSON must conform to the following JSON Schema")
+		if format.JSONSchema.Name != "" {
+			fmt.Fprintf(&b, " named %q", format.JSONSchema.Name)
+		}
+		if format.JSONSchema.Description != "" {
+			fmt.Fprintf(&b, " (%s)", format.JSONSchema.Description)
+		}
+		b.WriteString(":\n")
+		b.Write(format.JSONSchema.Schema)
+	} else {
+		b.WriteString(" The JSON must be an object.")
+	}
+	return b.String()
+}
+
+// validateStructuredOutput извлекает JSON из ответа и проверяет его
+// Возвращает компактный JSON без обрамляющего текста
+func validateStructuredOutput(content string, schema *JSONSchema) (string, error) {
+	document := extractJSON(content)
+
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, []byte(document)); err != nil {
+		return "", fmt.Errorf("reply is not valid JSON: %v", err)
+	}
+
+	if schema != nil {
+		if err := schema.Validate(buf.Bytes()); err != nil {
+			return "", fmt.Errorf("reply does not match the schema: %v", err)
+		}
+	} else if !bytes.HasPrefix(buf.Bytes(), []byte("{")) {
+		return "", fmt.Errorf("reply must be a JSON object")
+	}
+
+	return buf.String(), nil
+}
+
+// extractJSON убирает markdown-обрамление ```json ... ```, которое модели часто добавляют
+func extractJSON(content string) string {
+	content = strings.TrimSpace(content)
+	if strings.HasPrefix(content, "```") {
+		content = strings.TrimPrefix(content, "```")
+		if newline := strings.IndexByte(content, '\n'); newline >= 0 {
+			content = content[newline+1:]
+		}
+		content = strings.TrimSuffix(strings.TrimSpace(content), "```")
+	}
+	return strings.TrimSpace(content)
+}
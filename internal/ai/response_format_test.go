@@ -0,0 +1,63 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestChatStructuredReturnsUsageOnFailure(t *testing.T) {
+	format := &ResponseFormat{Type: ResponseFormatJSONObject}
+	req := ChatRequest{Messages: []Message{{Role: "user", Content: "верни JSON"}}, ResponseFormat: format}
+
+	t.Run("invalid after repair", func(t *testing.T) {
+		provider := newFakeProvider()
+		provider.reply.Content = "не JSON"
+
+		resp, err := ChatStructured(context.Background(), provider, req)
+		if !errors.Is(err, ErrInvalidStructuredOutput) {
+			t.Fatalf("err = %v, want ErrInvalidStructuredOutput", err)
+		}
+		if provider.calls != 2 {
+			t.Errorf("provider calls = %d, want 2", provider.calls)
+		}
+		if resp == nil {
+			t.Fatal("response with usage should be returned with the error")
+		}
+		if resp.Usage.PromptTokens != 20 || resp.Usage.CompletionTokens != 10 || resp.Usage.TotalTokens != 30 {
+			t.Errorf("usage = %+v, want both attempts", resp.Usage)
+		}
+		if resp.Content != "" {
+			t.Errorf("content = %q, want empty", resp.Content)
+		}
+	})
+
+	t.Run("repair request failed", func(t *testing.T) {
+		provider := newFakeProvider()
+		provider.reply.Content = "не JSON"
+		upstream := errors.New("upstream failed")
+		provider.onCall = func() {
+			if provider.calls == 2 {
+				provider.err = upstream
+			}
+		}
+
+		resp, err := ChatStructured(context.Background(), provider, req)
+		if !errors.Is(err, upstream) {
+			t.Fatalf("err = %v, want %v", err, upstream)
+		}
+		if resp == nil || resp.Usage.TotalTokens != 15 || resp.Content != "" {
+			t.Errorf("resp = %+v, want first attempt usage without content", resp)
+		}
+	})
+
+	t.Run("first request failed", func(t *testing.T) {
+		provider := newFakeProvider()
+		provider.err = errors.New("upstream failed")
+
+		resp, err := ChatStructured(context.Background(), provider, req)
+		if err == nil || resp != nil {
+			t.Errorf("resp, err = %+v, %v; want nil, error", resp, err)
+		}
+	})
+}
@@ -0,0 +1,102 @@
+package ai
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// StreamChunk представляет очередную порцию потокового ответа
+// Рассуждения (reasoning) и основной ответ приходят раздельно
+type StreamChunk struct {
+	ContentDelta   string `json:"content,omitempty"`
+	ReasoningDelta string `json:"reasoning_content,omitempty"`
+}
+
+// StreamHandler вызывается для каждой порции потокового ответа
+// Возврат ошибки прерывает чтение потока
+type StreamHandler func(chunk StreamChunk) error
+
+// readOpenAIStream читает SSE-поток в OpenAI-совместимом формате (data: {...} / data: [DONE])
+// и собирает итоговый ChatResponse, передавая каждую порцию в onChunk
+func readOpenAIStream(body io.Reader, fallbackModel string, onChunk StreamHandler) (*ChatResponse, error) {
+	var content, reasoning strings.Builder
+	response := &ChatResponse{Model: fallbackModel}
+
+	scanner := bufio.NewScanner(body)
+	// Отдельные события могут быть длинными, увеличиваем буфер
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "[DONE]" {
+			break
+		}
+
+		var event struct {
+			Model   string `json:"model"`
+			Choices []struct {
+				Delta struct {
+					Content          string `json:"content"`
+					ReasoningContent string `json:"reasoning_content"`
+				} `json:"delta"`
+			} `json:"choices"`
+			Usage *struct {
+				PromptTokens     int `json:"prompt_tokens"`
+				CompletionTokens int `json:"completion_tokens"`
+				TotalTokens      int `json:"total_tokens"`
+			} `json:"usage"`
+		}
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			return nil, fmt.Errorf("failed to decode stream event: %w", err)
+		}
+
+		if event.Model != "" {
+			response.Model = event.Model
+		}
+		if event.Usage != nil {
+			response.Usage.PromptTokens = event.Usage.PromptTokens
+			response.Usage.CompletionTokens = event.Usage.CompletionTokens
+			response.Usage.TotalTokens = event.Usage.TotalTokens
+		}
+		if len(event.Choices) == 0 {
+			continue
+		}
+
+		chunk := StreamChunk{
+			ContentDelta:   event.Choices[0].Delta.Content,
+			ReasoningDelta: event.Choices[0].Delta.ReasoningContent,
+		}
+		if chunk.ContentDelta == "" && chunk.ReasoningDelta == "" {
+			continue
+		}
+
+		content.WriteString(chunk.ContentDelta)
+		reasoning.WriteString(chunk.ReasoningDelta)
+
+		if onChunk != nil {
+			if err := onChunk(chunk); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: failed to read stream: %w", ErrAPIRequestFailed, err)
+	}
+
+	response.Content = content.String()
+	response.ReasoningContent = reasoning.String()
+	if response.Content == "" {
+		return nil, fmt.Errorf("%w: empty streamed response", ErrAPIRequestFailed)
+	}
+
+	return response, nil
+}
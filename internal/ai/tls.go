@@ -0,0 +1,109 @@
+package ai
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+)
+
+// TLSOptions - настройки TLS для HTTP клиента провайдера
+type TLSOptions struct {
+	// CAFile - PEM файл с дополнительными корневыми сертификатами (например, НУЦ Минцифры
+	// для GigaChat). Добавляется к системным корневым сертификатам
+	CAFile string
+	// CertFile и KeyFile - клиентский сертификат для mTLS (задаются вместе)
+	CertFile string
+	KeyFile  string
+	// Insecure отключает проверку сертификата сервера. Только для отладки
+	Insecure bool
+}
+
+// NewTLSConfig создает tls.Config с проверкой сертификатов и дополнительными корневыми сертификатами
+// Возвращает nil, если настройки пустые (используется конфигурация по умолчанию)
+func NewTLSConfig(opts TLSOptions) (*tls.Config, error) {
+	if opts == (TLSOptions{}) {
+		return nil, nil
+	}
+
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if opts.CAFile != "" {
+		pem, err := os.ReadFile(opts.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read CA file: %w", err)
+		}
+
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("CA file %s contains no valid PEM certificates", opts.CAFile)
+		}
+		config.RootCAs = pool
+	}
+
+	if opts.CertFile != "" || opts.KeyFile != "" {
+		if opts.CertFile == "" || opts.KeyFile == "" {
+			return nil, fmt.Errorf("client certificate and key must be set together")
+		}
+		cert, err := tls.LoadX509KeyPair(opts.CertFile, opts.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	if opts.Insecure {
+		config.InsecureSkipVerify = true
+	}
+
+	return config, nil
+}
+
+// newHTTPClient создает HTTP клиент провайдера с указанными настройками TLS
+func newHTTPClient(provider string, timeout time.Duration, opts TLSOptions) (*http.Client, error) {
+	tlsConfig, err := NewTLSConfig(opts)
+	if err != nil {
+		return nil, fmt.Errorf("%s TLS: %w", provider, err)
+	}
+
+	client := &http.Client{Timeout: timeout}
+	if tlsConfig != nil {
+		if tlsConfig.InsecureSkipVerify {
+			slog.Warn("TLS certificate verification is disabled for AI provider, do not use in production",
+				"provider", provider)
+		}
+
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = tlsConfig
+		client.Transport = transport
+	}
+
+	return client, nil
+}
+
+// newStreamingClient возвращает копию клиента для потоковых запросов
+// Timeout http.Client ограничивает и чтение тела, поэтому длинный SSE-ответ обрывался бы на нем.
+// Для потоков общий таймаут не задается: длительность ограничивает контекст запроса, а timeout
+// ограничивает только ожидание заголовков ответа
+func newStreamingClient(client *http.Client, timeout time.Duration) *http.Client {
+	streaming := *client
+	streaming.Timeout = 0
+
+	transport, ok := client.Transport.(*http.Transport)
+	if client.Transport == nil {
+		transport, ok = http.DefaultTransport.(*http.Transport)
+	}
+	if ok {
+		transport = transport.Clone()
+		transport.ResponseHeaderTimeout = timeout
+		streaming.Transport = transport
+	}
+
+	return &streaming
+}
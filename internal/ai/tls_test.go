@@ -0,0 +1,64 @@
+package ai
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestStreamingClientHasNoOverallTimeout(t *testing.T) {
+	// Сервер сразу отдает заголовки, а тело - дольше таймаута клиента, как длинный SSE-ответ
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		time.Sleep(300 * time.Millisecond)
+		io.WriteString(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	timeout := 100 * time.Millisecond
+	client, err := newHTTPClient("test", timeout, TLSOptions{})
+	if err != nil {
+		t.Fatalf("newHTTPClient: %v", err)
+	}
+
+	read := func(client *http.Client) error {
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		_, err = io.ReadAll(resp.Body)
+		return err
+	}
+
+	if err := read(client); err == nil {
+		t.Error("regular client should stop reading the body after its timeout")
+	}
+	if err := read(newStreamingClient(client, timeout)); err != nil {
+		t.Errorf("streaming client: %v", err)
+	}
+	if client.Timeout != timeout {
+		t.Errorf("original client timeout changed to %v", client.Timeout)
+	}
+}
+
+func TestStreamingClientWaitsForHeadersWithTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(300 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	client, err := newHTTPClient("test", time.Minute, TLSOptions{})
+	if err != nil {
+		t.Fatalf("newHTTPClient: %v", err)
+	}
+
+	resp, err := newStreamingClient(client, 100*time.Millisecond).Get(server.URL)
+	if err == nil {
+		resp.Body.Close()
+		t.Error("streaming client should time out waiting for response headers")
+	}
+}
@@ -0,0 +1,210 @@
+package chatimport
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"mindforge/internal/export"
+)
+
+// chatGPTConversation - диалог из conversations.json экспорта ChatGPT
+// Сообщения хранятся деревом (mapping): при редактировании и перегенерации появляются ветки,
+// current_node указывает на последнее сообщение ветки, которую пользователь видел последней
+type chatGPTConversation struct {
+	Title       string                 `json:"title"`
+	CreateTime  float64                `json:"create_time"`
+	UpdateTime  float64                `json:"update_time"`
+	CurrentNode string                 `json:"current_node"`
+	Mapping     map[string]chatGPTNode `json:"mapping"`
+}
+
+type chatGPTNode struct {
+	ID       string          `json:"id"`
+	Parent   *string         `json:"parent"`
+	Children []string        `json:"children"`
+	Message  *chatGPTMessage `json:"message"`
+}
+
+type chatGPTMessage struct {
+	Author struct {
+		Role string `json:"role"`
+	} `json:"author"`
+	CreateTime *float64 `json:"create_time"`
+	// Recipient - адресат сообщения; all для видимых сообщений, имя инструмента для вызовов инструментов
+	Recipient string `json:"recipient"`
+	Content   struct {
+		ContentType string            `json:"content_type"`
+		Parts       []json.RawMessage `json:"parts"`
+		Text        string            `json:"text"`
+	} `json:"content"`
+	Metadata struct {
+		ModelSlug                        string `json:"model_slug"`
+		IsVisuallyHiddenFromConversation bool   `json:"is_visually_hidden_from_conversation"`
+	} `json:"metadata"`
+}
+
+func parseChatGPTConversation(data json.RawMessage) Conversation {
+	var conv chatGPTConversation
+	if err := json.Unmarshal(data, &conv); err != nil {
+		return Conversation{Err: fmt.Errorf("invalid conversation: %w", err)}
+	}
+
+	title := strings.TrimSpace(conv.Title)
+	result := Conversation{Title: title}
+
+	branch, err := conv.branch()
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	chat := &export.Chat{
+		Title:     title,
+		CreatedAt: unixTime(conv.CreateTime),
+		UpdatedAt: unixTime(conv.UpdateTime),
+	}
+
+	for _, node := range branch {
+		msg := node.Message
+		if msg == nil || msg.Metadata.IsVisuallyHiddenFromConversation {
+			continue
+		}
+		if msg.Recipient != "" && msg.Recipient != "all" {
+			continue
+		}
+		// Сообщения инструментов (поиск, код, плагины) в историю не переносятся
+		role := msg.Author.Role
+		if role != "user" && role != "assistant" && role != "system" {
+			continue
+		}
+
+		content := msg.text()
+		if content == "" {
+			continue
+		}
+
+		createdAt := chat.CreatedAt
+		if msg.CreateTime != nil {
+			createdAt = unixTime(*msg.CreateTime)
+		}
+
+		chat.Messages = append(chat.Messages, export.Message{
+			Role:      role,
+			Content:   content,
+			Model:     msg.Metadata.ModelSlug,
+			CreatedAt: createdAt,
+		})
+		if msg.Metadata.ModelSlug != "" {
+			chat.AIModel = msg.Metadata.ModelSlug
+		}
+	}
+
+	if len(chat.Messages) == 0 {
+		result.Err = errors.New("conversation has no text messages")
+		return result
+	}
+	if chat.CreatedAt.IsZero() {
+		chat.CreatedAt = chat.Messages[0].CreatedAt
+	}
+	if chat.UpdatedAt.IsZero() {
+		chat.UpdatedAt = chat.Messages[len(chat.Messages)-1].CreatedAt
+	}
+
+	result.Chat = chat
+	return result
+}
+
+// branch возвращает ветку дерева от корня до current_node
+// Если current_node не задан, берется ветка последних потомков
+func (conv *chatGPTConversation) branch() ([]chatGPTNode, error) {
+	if len(conv.Mapping) == 0 {
+		return nil, errors.New("conversation has no messages")
+	}
+
+	current := conv.CurrentNode
+	if _, ok := conv.Mapping[current]; !ok {
+		current = conv.lastLeaf()
+	}
+
+	var branch []chatGPTNode
+	visited := make(map[string]bool)
+	for current != "" {
+		node, ok := conv.Mapping[current]
+		if !ok {
+			return nil, fmt.Errorf("conversation references missing message %s", current)
+		}
+		if visited[current] {
+			return nil, errors.New("conversation message tree has a cycle")
+		}
+		visited[current] = true
+		branch = append(branch, node)
+
+		current = ""
+		if node.Parent != nil {
+			current = *node.Parent
+		}
+	}
+
+	slices.Reverse(branch)
+	return branch, nil
+}
+
+// lastLeaf спускается от корня по последним потомкам
+func (conv *chatGPTConversation) lastLeaf() string {
+	var current string
+	for id, node := range conv.Mapping {
+		if node.Parent == nil || *node.Parent == "" {
+			current = id
+			break
+		}
+	}
+
+	for steps := 0; current != "" && steps < len(conv.Mapping); steps++ {
+		node := conv.Mapping[current]
+		if len(node.Children) == 0 {
+			break
+		}
+		current = node.Children[len(node.Children)-1]
+	}
+	return current
+}
+
+// text собирает текст сообщения из частей; вложения (изображения, файлы) заменяются пометкой
+func (msg *chatGPTMessage) text() string {
+	if msg.Content.Text != "" {
+		return strings.TrimSpace(msg.Content.Text)
+	}
+
+	var parts []string
+	for _, raw := range msg.Content.Parts {
+		var s string
+		if err := json.Unmarshal(raw, &s); err == nil {
+			if s = strings.TrimSpace(s); s != "" {
+				parts = append(parts, s)
+			}
+			continue
+		}
+
+		var part struct {
+			ContentType string `json:"content_type"`
+		}
+		if err := json.Unmarshal(raw, &part); err == nil && part.ContentType != "" {
+			parts = append(parts, "["+part.ContentType+"]")
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// unixTime переводит время ChatGPT (секунды с дробной частью) в time.Time
+func unixTime(seconds float64) time.Time {
+	if seconds <= 0 {
+		return time.Time{}
+	}
+	sec, frac := math.Modf(seconds)
+	return time.Unix(int64(sec), int64(frac*1e9)).UTC()
+}
@@ -0,0 +1,195 @@
+package chatimport
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"mindforge/internal/export"
+)
+
+// Форматы источника импорта
+const (
+	SourceChatGPT   = "chatgpt"
+	SourceMindForge = "mindforge"
+)
+
+// Conversation - разобранный диалог источника
+// Err заполнен, если диалог не удалось разобрать; остальные диалоги импортируются независимо
+type Conversation struct {
+	// Title - название диалога в источнике, используется в отчете об импорте
+	Title string
+	Chat  *export.Chat
+	Err   error
+}
+
+// Limits ограничивает объем импорта
+type Limits struct {
+	// MaxConversations - максимальное количество диалогов в одном импорте
+	MaxConversations int
+	// MaxBytes - максимальный объем распакованных данных ZIP архива
+	MaxBytes int64
+}
+
+var ErrUnknownFormat = errors.New("unsupported import format: expected ChatGPT conversations.json or MindForge JSON export")
+
+// Parse определяет формат данных и разбирает диалоги
+// Поддерживаются conversations.json из экспорта ChatGPT (массив или ZIP архив экспорта),
+// JSON экспорт MindForge (один чат, массив чатов или ZIP архив экспорта в формате json)
+func Parse(data []byte, limits Limits) (string, []Conversation, error) {
+	if bytes.HasPrefix(data, []byte("PK")) {
+		return parseZip(data, limits)
+	}
+
+	source, conversations, err := parseJSON(data)
+	if err != nil {
+		return "", nil, err
+	}
+	if limits.MaxConversations > 0 && len(conversations) > limits.MaxConversations {
+		return "", nil, fmt.Errorf("too many conversations: %d (max %d)", len(conversations), limits.MaxConversations)
+	}
+	return source, conversations, nil
+}
+
+func parseJSON(data []byte) (string, []Conversation, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return "", nil, ErrUnknownFormat
+	}
+
+	switch data[0] {
+	case '{':
+		var doc export.Document
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return "", nil, fmt.Errorf("invalid JSON: %w", err)
+		}
+		if doc.Format != export.DocumentFormat {
+			return "", nil, ErrUnknownFormat
+		}
+		return SourceMindForge, []Conversation{convertDocument(&doc)}, nil
+
+	case '[':
+		var items []json.RawMessage
+		if err := json.Unmarshal(data, &items); err != nil {
+			return "", nil, fmt.Errorf("invalid JSON: %w", err)
+		}
+		if len(items) == 0 {
+			return "", nil, errors.New("no conversations to import")
+		}
+
+		var probe struct {
+			Format  string          `json:"format"`
+			Mapping json.RawMessage `json:"mapping"`
+		}
+		if err := json.Unmarshal(items[0], &probe); err != nil {
+			return "", nil, ErrUnknownFormat
+		}
+
+		conversations := make([]Conversation, len(items))
+		switch {
+		case probe.Mapping != nil:
+			for i, item := range items {
+				conversations[i] = parseChatGPTConversation(item)
+			}
+			return SourceChatGPT, conversations, nil
+		case probe.Format == export.DocumentFormat:
+			for i, item := range items {
+				var doc export.Document
+				if err := json.Unmarshal(item, &doc); err != nil {
+					conversations[i] = Conversation{Err: fmt.Errorf("invalid conversation: %w", err)}
+					continue
+				}
+				conversations[i] = convertDocument(&doc)
+			}
+			return SourceMindForge, conversations, nil
+		}
+	}
+
+	return "", nil, ErrUnknownFormat
+}
+
+// parseZip разбирает ZIP архив: экспорт ChatGPT (conversations.json) или архив JSON экспорта MindForge
+func parseZip(data []byte, limits Limits) (string, []Conversation, error) {
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid ZIP archive: %w", err)
+	}
+
+	var total int64
+	readFile := func(f *zip.File) ([]byte, error) {
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+
+		// Размер в заголовке архива не доверяем: ограничиваем фактически распакованный объем
+		remaining := limits.MaxBytes - total
+		if limits.MaxBytes <= 0 {
+			remaining = 1 << 62
+		}
+		content, err := io.ReadAll(io.LimitReader(rc, remaining+1))
+		if err != nil {
+			return nil, err
+		}
+		total += int64(len(content))
+		if limits.MaxBytes > 0 && total > limits.MaxBytes {
+			return nil, fmt.Errorf("archive content is too large (max %d bytes)", limits.MaxBytes)
+		}
+		return content, nil
+	}
+
+	for _, f := range reader.File {
+		if path.Base(f.Name) == "conversations.json" {
+			content, err := readFile(f)
+			if err != nil {
+				return "", nil, err
+			}
+			return Parse(content, limits)
+		}
+	}
+
+	var conversations []Conversation
+	for _, f := range reader.File {
+		if f.FileInfo().IsDir() || !strings.HasSuffix(strings.ToLower(f.Name), ".json") {
+			continue
+		}
+		if limits.MaxConversations > 0 && len(conversations) >= limits.MaxConversations {
+			return "", nil, fmt.Errorf("too many conversations (max %d)", limits.MaxConversations)
+		}
+
+		content, err := readFile(f)
+		if err != nil {
+			return "", nil, err
+		}
+		var doc export.Document
+		if err := json.Unmarshal(content, &doc); err != nil || doc.Format != export.DocumentFormat {
+			conversations = append(conversations, Conversation{
+				Title: f.Name,
+				Err:   errors.New("not a MindForge JSON export"),
+			})
+			continue
+		}
+		conversations = append(conversations, convertDocument(&doc))
+	}
+
+	if len(conversations) == 0 {
+		return "", nil, ErrUnknownFormat
+	}
+	return SourceMindForge, conversations, nil
+}
+
+func convertDocument(doc *export.Document) Conversation {
+	if doc.Chat == nil {
+		return Conversation{Err: errors.New("conversation has no chat")}
+	}
+	if doc.Version > export.DocumentVersion {
+		return Conversation{Title: doc.Chat.Title, Err: fmt.Errorf("unsupported export version %d", doc.Version)}
+	}
+	return Conversation{Title: doc.Chat.Title, Chat: doc.Chat}
+}
@@ -0,0 +1,176 @@
+package database
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+// APIKeyPrefix - префикс ключей MindForge, по нему ключ легко отличить от JWT
+const APIKeyPrefix = "mf-"
+
+type APIKeyModel struct {
+	DB *sql.DB
+}
+
+type APIKey struct {
+	ID     int    `json:"id"`
+	UserID int    `json:"user_id"`
+	Name   string `json:"name"`
+	// KeyPrefix - начало ключа для отображения в списке ключей
+	KeyPrefix  string     `json:"key_prefix"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// GenerateAPIKey генерирует новый API ключ
+func GenerateAPIKey() (string, error) {
+	token, err := GenerateSafeToken()
+	if err != nil {
+		return "", err
+	}
+	return APIKeyPrefix + token, nil
+}
+
+// HashAPIKey возвращает хеш ключа, который хранится в БД
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// Create сохраняет новый API ключ пользователя (в БД попадает только хеш)
+func (m APIKeyModel) Create(userID int, name, key string) (*APIKey, error) {
+	prefix := key
+	if len(prefix) > 10 {
+		prefix = prefix[:10]
+	}
+
+	query := `
+		INSERT INTO api_keys (user_id, name, key_hash, key_prefix, created_at)
+		VALUES ($1, $2, $3, $4, CURRENT_TIMESTAMP)
+		RETURNING id`
+
+	var id int
+	err := m.DB.QueryRow(query, userID, name, HashAPIKey(key), prefix).Scan(&id)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.GetByID(id)
+}
+
+// GetByID получает API ключ по ID
+func (m APIKeyModel) GetByID(id int) (*APIKey, error) {
+	query := `
+		SELECT id, user_id, name, key_prefix, last_used_at, created_at
+		FROM api_keys
+		WHERE id = $1`
+
+	key, err := scanAPIKey(m.DB.QueryRow(query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("api key not found")
+		}
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// GetByKey находит API ключ по его значению
+func (m APIKeyModel) GetByKey(key string) (*APIKey, error) {
+	query := `
+		SELECT id, user_id, name, key_prefix, last_used_at, created_at
+		FROM api_keys
+		WHERE key_hash = $1`
+
+	apiKey, err := scanAPIKey(m.DB.QueryRow(query, HashAPIKey(key)))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("api key not found")
+		}
+		return nil, err
+	}
+
+	return apiKey, nil
+}
+
+// GetByUserID получает все API ключи пользователя
+func (m APIKeyModel) GetByUserID(userID int) ([]*APIKey, error) {
+	query := `
+		SELECT id, user_id, name, key_prefix, last_used_at, created_at
+		FROM api_keys
+		WHERE user_id = $1
+		ORDER BY created_at DESC`
+
+	rows, err := m.DB.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []*APIKey
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+// Delete удаляет API ключ пользователя
+func (m APIKeyModel) Delete(id, userID int) error {
+	query := `DELETE FROM api_keys WHERE id = $1 AND user_id = $2`
+
+	result, err := m.DB.Exec(query, id, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return errors.New("api key not found")
+	}
+
+	return nil
+}
+
+// TouchLastUsed обновляет время последнего использования ключа
+func (m APIKeyModel) TouchLastUsed(id int) error {
+	query := `UPDATE api_keys SET last_used_at = CURRENT_TIMESTAMP WHERE id = $1`
+	_, err := m.DB.Exec(query, id)
+	return err
+}
+
+func scanAPIKey(row rowScanner) (*APIKey, error) {
+	var key APIKey
+	var lastUsedAt, createdAt sql.NullTime
+	err := row.Scan(
+		&key.ID,
+		&key.UserID,
+		&key.Name,
+		&key.KeyPrefix,
+		&lastUsedAt,
+		&createdAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if lastUsedAt.Valid {
+		key.LastUsedAt = &lastUsedAt.Time
+	}
+	if createdAt.Valid {
+		key.CreatedAt = createdAt.Time
+	}
+
+	return &key, nil
+}
@@ -0,0 +1,191 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// ShareTokenPrefix - префикс токенов публичных ссылок на чаты
+const ShareTokenPrefix = "sh-"
+
+type ChatShareModel struct {
+	DB *sql.DB
+}
+
+// ChatShare - публичная ссылка на снимок чата
+type ChatShare struct {
+	ID     int `json:"id"`
+	ChatID int `json:"chat_id"`
+	UserID int `json:"user_id"`
+	// TokenPrefix - начало токена для отображения в списке ссылок
+	TokenPrefix string `json:"token_prefix"`
+	Title       string `json:"title"`
+	AIModel     string `json:"ai_model"`
+	// LastMessageID - последнее сообщение снимка, nil если на момент публикации чат был пуст
+	LastMessageID *int       `json:"last_message_id,omitempty"`
+	ViewsCount    int        `json:"views_count"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+
+	// MessageIDs - сообщения, входившие в диалог на момент публикации (без невыбранных кандидатов
+	// и заблокированных ответов); выбор кандидата после публикации снимок не меняет
+	MessageIDs []int `json:"-"`
+}
+
+// GenerateShareToken генерирует новый токен публичной ссылки
+func GenerateShareToken() (string, error) {
+	token, err := GenerateSafeToken()
+	if err != nil {
+		return "", err
+	}
+	return ShareTokenPrefix + token, nil
+}
+
+// Expired сообщает, истек ли срок действия ссылки
+func (share *ChatShare) Expired() bool {
+	return share.ExpiresAt != nil && !share.ExpiresAt.After(time.Now())
+}
+
+const chatShareColumns = `id, chat_id, user_id, token_prefix, title, ai_model, last_message_id,
+	message_ids, views_count, expires_at, created_at`
+
+// Create сохраняет снимок чата под токеном (в БД попадает только хеш токена)
+// Снимок включает сообщения диалога на момент публикации; условие отбора совпадает с Message.InContext
+func (m ChatShareModel) Create(chat *Chat, token string, expiresAt *time.Time) (*ChatShare, error) {
+	prefix := token
+	if len(prefix) > 10 {
+		prefix = prefix[:10]
+	}
+
+	query := `
+		INSERT INTO chat_shares (chat_id, user_id, token_hash, token_prefix, title, ai_model,
+		                         last_message_id, message_ids, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6,
+		        (SELECT MAX(id) FROM messages WHERE chat_id = $1),
+		        ARRAY(SELECT id FROM messages
+		              WHERE chat_id = $1 AND moderation_status IS NULL
+		                AND (candidate_status IS NULL OR candidate_status = 'selected')
+		              ORDER BY id),
+		        $7, CURRENT_TIMESTAMP)
+		RETURNING id`
+
+	var id int
+	err := m.DB.QueryRow(query, chat.ID, chat.UserID, HashAPIKey(token), prefix, chat.Title, chat.AIModel, expiresAt).Scan(&id)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.GetByID(id)
+}
+
+// GetByID получает ссылку по ID
+func (m ChatShareModel) GetByID(id int) (*ChatShare, error) {
+	query := `SELECT ` + chatShareColumns + `
+		FROM chat_shares
+		WHERE id = $1`
+
+	share, err := scanChatShare(m.DB.QueryRow(query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("share not found")
+		}
+		return nil, err
+	}
+
+	return share, nil
+}
+
+// GetByToken получает ссылку по токену; ссылки на чаты из корзины не находятся
+func (m ChatShareModel) GetByToken(token string) (*ChatShare, error) {
+	query := `SELECT ` + chatShareColumns + `
+		FROM chat_shares
+		WHERE token_hash = $1
+		  AND EXISTS (SELECT 1 FROM chats c WHERE c.id = chat_id AND c.deleted_at IS NULL)`
+
+	share, err := scanChatShare(m.DB.QueryRow(query, HashAPIKey(token)))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("share not found")
+		}
+		return nil, err
+	}
+
+	return share, nil
+}
+
+// GetByUserID получает ссылки пользователя; chatID ограничивает список ссылками одного чата
+func (m ChatShareModel) GetByUserID(userID int, chatID *int) ([]*ChatShare, error) {
+	query := `SELECT ` + chatShareColumns + `
+		FROM chat_shares
+		WHERE user_id = $1 AND ($2::int IS NULL OR chat_id = $2)
+		ORDER BY created_at DESC`
+
+	rows, err := m.DB.Query(query, userID, chatID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var shares []*ChatShare
+	for rows.Next() {
+		share, err := scanChatShare(rows)
+		if err != nil {
+			return nil, err
+		}
+		shares = append(shares, share)
+	}
+
+	return shares, rows.Err()
+}
+
+// IncrementViews увеличивает счетчик просмотров ссылки
+func (m ChatShareModel) IncrementViews(id int) error {
+	_, err := m.DB.Exec(`UPDATE chat_shares SET views_count = views_count + 1 WHERE id = $1`, id)
+	return err
+}
+
+// Delete отзывает ссылку
+func (m ChatShareModel) Delete(id int) error {
+	_, err := m.DB.Exec(`DELETE FROM chat_shares WHERE id = $1`, id)
+	return err
+}
+
+func scanChatShare(row rowScanner) (*ChatShare, error) {
+	var share ChatShare
+	var lastMessageID sql.NullInt64
+	var messageIDs []int64
+	var expiresAt, createdAt sql.NullTime
+	err := row.Scan(
+		&share.ID,
+		&share.ChatID,
+		&share.UserID,
+		&share.TokenPrefix,
+		&share.Title,
+		&share.AIModel,
+		&lastMessageID,
+		pq.Array(&messageIDs),
+		&share.ViewsCount,
+		&expiresAt,
+		&createdAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	share.LastMessageID = nullIntPtr(lastMessageID)
+	share.MessageIDs = make([]int, len(messageIDs))
+	for i, id := range messageIDs {
+		share.MessageIDs[i] = int(id)
+	}
+	if expiresAt.Valid {
+		share.ExpiresAt = &expiresAt.Time
+	}
+	if createdAt.Valid {
+		share.CreatedAt = createdAt.Time
+	}
+
+	return &share, nil
+}
@@ -2,8 +2,12 @@ package database
 
 import (
 	"database/sql"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"time"
+
+	"github.com/lib/pq"
 )
 
 type ChatModel struct {
@@ -11,23 +15,92 @@ type ChatModel struct {
 }
 
 type Chat struct {
-	ID        int       `json:"id"`
-	UserID    int       `json:"user_id"`
-	AIModel   string    `json:"ai_model"`
-	Title     string    `json:"title"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID      int    `json:"id"`
+	UserID  int    `json:"user_id"`
+	AIModel string `json:"ai_model"`
+	Title   string `json:"title"`
+	// ResponseFormat - структурированный формат ответов чата (ai.ResponseFormat), NULL для обычного текста
+	ResponseFormat json.RawMessage `json:"response_format,omitempty"`
+	// PersonaID - персона чата; ее настройки применяются при каждой генерации ответа
+	PersonaID *int `json:"persona_id,omitempty"`
+	// FolderID - папка чата, nil для чатов в корне
+	FolderID   *int  `json:"folder_id,omitempty"`
+	TagIDs     []int `json:"tag_ids"`
+	IsArchived bool  `json:"is_archived"`
+	IsPinned   bool  `json:"is_pinned"`
+	// ForkedFromChatID и ForkedFromMessageID - исходный чат и сообщение, до которого скопирована история ответвления;
+	// ForkedFromShareID - публичная ссылка, из снимка которой сделано ответвление
+	ForkedFromChatID    *int `json:"forked_from_chat_id,omitempty"`
+	ForkedFromMessageID *int `json:"forked_from_message_id,omitempty"`
+	ForkedFromShareID   *int `json:"forked_from_share_id,omitempty"`
+	// DeletedAt - время перемещения чата в корзину, nil для активных чатов
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// ChatFilter - фильтры списка чатов пользователя
+// Nil-фильтры не применяются; Trashed выбирает чаты из корзины вместо активных
+type ChatFilter struct {
+	Archived *bool
+	Pinned   *bool
+	Trashed  bool
+	// FolderID - чаты папки, 0 - чаты в корне
+	FolderID *int
+	// TagIDs - чаты, отмеченные всеми перечисленными тегами
+	TagIDs []int
+}
+
+// where возвращает условия фильтра, дополняя аргументы запроса
+func (f ChatFilter) where(args []interface{}) (string, []interface{}) {
+	condition := " AND deleted_at IS NULL"
+	if f.Trashed {
+		condition = " AND deleted_at IS NOT NULL"
+	}
+	if f.Archived != nil {
+		args = append(args, *f.Archived)
+		condition += fmt.Sprintf(" AND is_archived = $%d", len(args))
+	}
+	if f.Pinned != nil {
+		args = append(args, *f.Pinned)
+		condition += fmt.Sprintf(" AND is_pinned = $%d", len(args))
+	}
+	if f.FolderID != nil {
+		if *f.FolderID == 0 {
+			condition += " AND folder_id IS NULL"
+		} else {
+			args = append(args, *f.FolderID)
+			condition += fmt.Sprintf(" AND folder_id = $%d", len(args))
+		}
+	}
+	if len(f.TagIDs) > 0 {
+		args = append(args, pq.Array(f.TagIDs), len(f.TagIDs))
+		condition += fmt.Sprintf(` AND id IN (
+			SELECT chat_id FROM chat_tag_links WHERE tag_id = ANY($%d::int[])
+			GROUP BY chat_id HAVING COUNT(*) = $%d)`, len(args)-1, len(args))
+	}
+	return condition, args
 }
 
 // Create создает новый чат
 func (m ChatModel) Create(userID int, aiModel, title string) (*Chat, error) {
+	return m.Insert(&Chat{
+		UserID:  userID,
+		AIModel: aiModel,
+		Title:   title,
+	})
+}
+
+// Insert создает новый чат со всеми заполненными полями
+func (m ChatModel) Insert(chat *Chat) (*Chat, error) {
 	query := `
-		INSERT INTO chats (user_id, ai_model, title, created_at, updated_at)
-		VALUES ($1, $2, $3, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+		INSERT INTO chats (user_id, ai_model, title, response_format, persona_id, folder_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
 		RETURNING id`
 
 	var id int
-	err := m.DB.QueryRow(query, userID, aiModel, title).Scan(&id)
+	err := m.DB.QueryRow(query, chat.UserID, chat.AIModel, chat.Title, nullJSON(chat.ResponseFormat),
+		chat.PersonaID, chat.FolderID).Scan(&id)
 	if err != nil {
 		return nil, err
 	}
@@ -35,23 +108,72 @@ func (m ChatModel) Create(userID int, aiModel, title string) (*Chat, error) {
 	return m.GetByID(id)
 }
 
-// GetByID получает чат по ID
-func (m ChatModel) GetByID(id int) (*Chat, error) {
+// InsertWithHistory создает чат с историей сообщений, сохраняя время создания чата и сообщений
+// Используется для импорта и ответвлений; сообщения вставляются в переданном порядке в одной транзакции
+func (m ChatModel) InsertWithHistory(chat *Chat, messages []*Message) (*Chat, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
 	query := `
-		SELECT id, user_id, ai_model, title, created_at, updated_at
+		INSERT INTO chats (user_id, ai_model, title, response_format, persona_id, folder_id,
+		                   forked_from_chat_id, forked_from_message_id, forked_from_share_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING id`
+
+	var id int
+	err = tx.QueryRow(query,
+		chat.UserID,
+		chat.AIModel,
+		chat.Title,
+		nullJSON(chat.ResponseFormat),
+		chat.PersonaID,
+		chat.FolderID,
+		chat.ForkedFromChatID,
+		chat.ForkedFromMessageID,
+		chat.ForkedFromShareID,
+		chat.CreatedAt,
+		chat.UpdatedAt,
+	).Scan(&id)
+	if err != nil {
+		return nil, err
+	}
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO messages (chat_id, role, content, reasoning_content, metadata, model, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	for _, msg := range messages {
+		if _, err := stmt.Exec(id, msg.Role, msg.Content, msg.ReasoningContent, nullJSON(msg.Metadata), msg.Model, msg.CreatedAt); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return m.GetByID(id)
+}
+
+const chatColumns = `id, user_id, ai_model, title, response_format, persona_id, folder_id,
+	COALESCE((SELECT array_agg(l.tag_id ORDER BY l.tag_id) FROM chat_tag_links l WHERE l.chat_id = chats.id), '{}'),
+	forked_from_chat_id, forked_from_message_id, forked_from_share_id,
+	is_archived, is_pinned, deleted_at, created_at, updated_at`
+
+// GetByID получает чат по ID, в том числе находящийся в корзине
+func (m ChatModel) GetByID(id int) (*Chat, error) {
+	query := `SELECT ` + chatColumns + `
 		FROM chats
 		WHERE id = $1`
 
-	var chat Chat
-	var createdAt, updatedAt sql.NullTime
-	err := m.DB.QueryRow(query, id).Scan(
-		&chat.ID,
-		&chat.UserID,
-		&chat.AIModel,
-		&chat.Title,
-		&createdAt,
-		&updatedAt,
-	)
+	chat, err := scanChat(m.DB.QueryRow(query, id))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, errors.New("chat not found")
@@ -59,57 +181,48 @@ func (m ChatModel) GetByID(id int) (*Chat, error) {
 		return nil, err
 	}
 
-	if createdAt.Valid {
-		chat.CreatedAt = createdAt.Time
-	}
-	if updatedAt.Valid {
-		chat.UpdatedAt = updatedAt.Time
-	}
-
-	return &chat, nil
+	return chat, nil
 }
 
-// GetByUserID получает все чаты пользователя
+// GetByUserID получает все чаты пользователя, кроме находящихся в корзине
 func (m ChatModel) GetByUserID(userID int) ([]*Chat, error) {
-	query := `
-		SELECT id, user_id, ai_model, title, created_at, updated_at
+	query := `SELECT ` + chatColumns + `
 		FROM chats
-		WHERE user_id = $1
+		WHERE user_id = $1 AND deleted_at IS NULL
 		ORDER BY updated_at DESC`
 
-	rows, err := m.DB.Query(query, userID)
+	return m.query(query, userID)
+}
+
+// GetPage получает страницу чатов пользователя от недавно обновленных к старым
+// Возвращает признак наличия следующей страницы в направлении запроса
+func (m ChatModel) GetPage(userID int, filter ChatFilter, page PageQuery) ([]*Chat, bool, error) {
+	condition, args := filter.where([]interface{}{userID})
+	query, args := page.apply(`SELECT `+chatColumns+`
+		FROM chats
+		WHERE user_id = $1`+condition, args, "updated_at", "id")
+
+	chats, err := m.query(query, args...)
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
-	defer rows.Close()
 
-	var chats []*Chat
-	for rows.Next() {
-		var chat Chat
-		var createdAt, updatedAt sql.NullTime
-		err := rows.Scan(
-			&chat.ID,
-			&chat.UserID,
-			&chat.AIModel,
-			&chat.Title,
-			&createdAt,
-			&updatedAt,
-		)
-		if err != nil {
-			return nil, err
-		}
+	chats, hasMore := trimPage(chats, page)
+	return chats, hasMore, nil
+}
 
-		if createdAt.Valid {
-			chat.CreatedAt = createdAt.Time
-		}
-		if updatedAt.Valid {
-			chat.UpdatedAt = updatedAt.Time
-		}
+// CountByUserID возвращает количество чатов пользователя, подходящих под фильтр
+func (m ChatModel) CountByUserID(userID int, filter ChatFilter) (int, error) {
+	condition, args := filter.where([]interface{}{userID})
 
-		chats = append(chats, &chat)
-	}
+	var count int
+	err := m.DB.QueryRow(`SELECT COUNT(*) FROM chats WHERE user_id = $1`+condition, args...).Scan(&count)
+	return count, err
+}
 
-	return chats, rows.Err()
+// PageCursor возвращает курсор чата для keyset-пагинации
+func (chat *Chat) PageCursor() Cursor {
+	return Cursor{Time: chat.UpdatedAt, ID: chat.ID}
 }
 
 // UpdateTitle обновляет заголовок чата
@@ -123,7 +236,84 @@ func (m ChatModel) UpdateTitle(chatID int, title string) error {
 	return err
 }
 
-// Delete удаляет чат
+// UpdateResponseFormat задает структурированный формат ответов чата (nil - обычный текст)
+func (m ChatModel) UpdateResponseFormat(chatID int, responseFormat json.RawMessage) error {
+	query := `
+		UPDATE chats
+		SET response_format = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $2`
+
+	_, err := m.DB.Exec(query, nullJSON(responseFormat), chatID)
+	return err
+}
+
+// ChatUpdate - изменения чата для Update; nil-поля не меняются
+type ChatUpdate struct {
+	Title   *string
+	AIModel *string
+	// SetPersona и SetFolder включают изменение персоны и папки; nil в PersonaID и FolderID отвязывает их
+	SetPersona bool
+	PersonaID  *int
+	SetFolder  bool
+	FolderID   *int
+	Archived   *bool
+	Pinned     *bool
+}
+
+// Update применяет изменения чата одним запросом: либо сохраняются все, либо ни одно
+// Время обновления меняется при смене названия, модели или персоны: перенос в папку,
+// архив и закрепление его не меняют, чтобы чат сохранил свое место в списке
+func (m ChatModel) Update(chatID int, update ChatUpdate) error {
+	query := `
+		UPDATE chats
+		SET title = COALESCE($2::text, title),
+		    ai_model = COALESCE($3::text, ai_model),
+		    persona_id = CASE WHEN $4::boolean THEN $5::integer ELSE persona_id END,
+		    folder_id = CASE WHEN $6::boolean THEN $7::integer ELSE folder_id END,
+		    is_archived = COALESCE($8::boolean, is_archived),
+		    is_pinned = COALESCE($9::boolean, is_pinned),
+		    updated_at = CASE WHEN $2::text IS NOT NULL OR $3::text IS NOT NULL OR $4::boolean
+		                      THEN CURRENT_TIMESTAMP ELSE updated_at END
+		WHERE id = $1`
+
+	_, err := m.DB.Exec(query,
+		chatID,
+		update.Title,
+		update.AIModel,
+		update.SetPersona,
+		update.PersonaID,
+		update.SetFolder,
+		update.FolderID,
+		update.Archived,
+		update.Pinned,
+	)
+	return err
+}
+
+// MoveToTrash перемещает чат в корзину; сообщения сохраняются до окончательного удаления
+func (m ChatModel) MoveToTrash(chatID int) error {
+	query := `UPDATE chats SET deleted_at = CURRENT_TIMESTAMP WHERE id = $1 AND deleted_at IS NULL`
+	_, err := m.DB.Exec(query, chatID)
+	return err
+}
+
+// Restore возвращает чат из корзины
+func (m ChatModel) Restore(chatID int) error {
+	_, err := m.DB.Exec(`UPDATE chats SET deleted_at = NULL WHERE id = $1`, chatID)
+	return err
+}
+
+// PurgeTrashed окончательно удаляет чаты, помещенные в корзину раньше before
+// Возвращает количество удаленных чатов
+func (m ChatModel) PurgeTrashed(before time.Time) (int64, error) {
+	result, err := m.DB.Exec(`DELETE FROM chats WHERE deleted_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// Delete окончательно удаляет чат вместе с сообщениями
 func (m ChatModel) Delete(chatID int) error {
 	query := `DELETE FROM chats WHERE id = $1`
 	_, err := m.DB.Exec(query, chatID)
@@ -136,3 +326,84 @@ func (m ChatModel) UpdateUpdatedAt(chatID int) error {
 	_, err := m.DB.Exec(query, chatID)
 	return err
 }
+
+// nullJSON преобразует пустой JSON в NULL для записи в колонку JSONB
+func nullJSON(data json.RawMessage) interface{} {
+	if len(data) == 0 {
+		return nil
+	}
+	return []byte(data)
+}
+
+func (m ChatModel) query(query string, args ...interface{}) ([]*Chat, error) {
+	rows, err := m.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var chats []*Chat
+	for rows.Next() {
+		chat, err := scanChat(rows)
+		if err != nil {
+			return nil, err
+		}
+		chats = append(chats, chat)
+	}
+
+	return chats, rows.Err()
+}
+
+func scanChat(row rowScanner) (*Chat, error) {
+	var chat Chat
+	var responseFormat []byte
+	var personaID, folderID sql.NullInt64
+	var forkedFromChatID, forkedFromMessageID, forkedFromShareID sql.NullInt64
+	var tagIDs []int64
+	var deletedAt, createdAt, updatedAt sql.NullTime
+	err := row.Scan(
+		&chat.ID,
+		&chat.UserID,
+		&chat.AIModel,
+		&chat.Title,
+		&responseFormat,
+		&personaID,
+		&folderID,
+		pq.Array(&tagIDs),
+		&forkedFromChatID,
+		&forkedFromMessageID,
+		&forkedFromShareID,
+		&chat.IsArchived,
+		&chat.IsPinned,
+		&deletedAt,
+		&createdAt,
+		&updatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if createdAt.Valid {
+		chat.CreatedAt = createdAt.Time
+	}
+	if updatedAt.Valid {
+		chat.UpdatedAt = updatedAt.Time
+	}
+	if len(responseFormat) > 0 {
+		chat.ResponseFormat = responseFormat
+	}
+	chat.PersonaID = nullIntPtr(personaID)
+	chat.FolderID = nullIntPtr(folderID)
+	chat.ForkedFromChatID = nullIntPtr(forkedFromChatID)
+	chat.ForkedFromMessageID = nullIntPtr(forkedFromMessageID)
+	chat.ForkedFromShareID = nullIntPtr(forkedFromShareID)
+	chat.TagIDs = make([]int, len(tagIDs))
+	for i, id := range tagIDs {
+		chat.TagIDs[i] = int(id)
+	}
+	if deletedAt.Valid {
+		chat.DeletedAt = &deletedAt.Time
+	}
+
+	return &chat, nil
+}
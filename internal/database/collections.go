@@ -0,0 +1,217 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type CollectionModel struct {
+	DB *sql.DB
+}
+
+type Collection struct {
+	ID             int       `json:"id"`
+	UserID         int       `json:"user_id"`
+	Name           string    `json:"name"`
+	Description    string    `json:"description"`
+	EmbeddingModel string    `json:"embedding_model"`
+	DocumentsCount int       `json:"documents_count"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// Create создает новую коллекцию знаний
+func (m CollectionModel) Create(userID int, name, description, embeddingModel string) (*Collection, error) {
+	query := `
+		INSERT INTO collections (user_id, name, description, embedding_model, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+		RETURNING id`
+
+	var id int
+	err := m.DB.QueryRow(query, userID, name, description, embeddingModel).Scan(&id)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.GetByID(id)
+}
+
+// GetByID получает коллекцию по ID
+func (m CollectionModel) GetByID(id int) (*Collection, error) {
+	query := `
+		SELECT c.id, c.user_id, c.name, c.description, c.embedding_model,
+		       (SELECT COUNT(*) FROM documents d WHERE d.collection_id = c.id),
+		       c.created_at, c.updated_at
+		FROM collections c
+		WHERE c.id = $1`
+
+	collection, err := scanCollection(m.DB.QueryRow(query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("collection not found")
+		}
+		return nil, err
+	}
+
+	return collection, nil
+}
+
+// GetByUserID получает все коллекции пользователя
+func (m CollectionModel) GetByUserID(userID int) ([]*Collection, error) {
+	query := `
+		SELECT c.id, c.user_id, c.name, c.description, c.embedding_model,
+		       (SELECT COUNT(*) FROM documents d WHERE d.collection_id = c.id),
+		       c.created_at, c.updated_at
+		FROM collections c
+		WHERE c.user_id = $1
+		ORDER BY c.updated_at DESC`
+
+	rows, err := m.DB.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var collections []*Collection
+	for rows.Next() {
+		collection, err := scanCollection(rows)
+		if err != nil {
+			return nil, err
+		}
+		collections = append(collections, collection)
+	}
+
+	return collections, rows.Err()
+}
+
+// Delete удаляет коллекцию вместе с документами и фрагментами
+func (m CollectionModel) Delete(id int) error {
+	query := `DELETE FROM collections WHERE id = $1`
+	_, err := m.DB.Exec(query, id)
+	return err
+}
+
+// Touch обновляет время последнего изменения коллекции
+func (m CollectionModel) Touch(id int) error {
+	query := `UPDATE collections SET updated_at = CURRENT_TIMESTAMP WHERE id = $1`
+	_, err := m.DB.Exec(query, id)
+	return err
+}
+
+// GetByChatID получает коллекции, привязанные к чату
+func (m CollectionModel) GetByChatID(chatID int) ([]*Collection, error) {
+	query := `
+		SELECT c.id, c.user_id, c.name, c.description, c.embedding_model,
+		       (SELECT COUNT(*) FROM documents d WHERE d.collection_id = c.id),
+		       c.created_at, c.updated_at
+		FROM collections c
+		JOIN chat_collections cc ON cc.collection_id = c.id
+		WHERE cc.chat_id = $1
+		ORDER BY c.name ASC`
+
+	rows, err := m.DB.Query(query, chatID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var collections []*Collection
+	for rows.Next() {
+		collection, err := scanCollection(rows)
+		if err != nil {
+			return nil, err
+		}
+		collections = append(collections, collection)
+	}
+
+	return collections, rows.Err()
+}
+
+// GetByPersonaID получает коллекции, привязанные к персоне
+func (m CollectionModel) GetByPersonaID(personaID int) ([]*Collection, error) {
+	query := `
+		SELECT c.id, c.user_id, c.name, c.description, c.embedding_model,
+		       (SELECT COUNT(*) FROM documents d WHERE d.collection_id = c.id),
+		       c.created_at, c.updated_at
+		FROM collections c
+		JOIN persona_collections pc ON pc.collection_id = c.id
+		WHERE pc.persona_id = $1
+		ORDER BY c.name ASC`
+
+	rows, err := m.DB.Query(query, personaID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var collections []*Collection
+	for rows.Next() {
+		collection, err := scanCollection(rows)
+		if err != nil {
+			return nil, err
+		}
+		collections = append(collections, collection)
+	}
+
+	return collections, rows.Err()
+}
+
+// SetChatCollections заменяет набор коллекций, привязанных к чату
+func (m CollectionModel) SetChatCollections(chatID int, collectionIDs []int) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM chat_collections WHERE chat_id = $1`, chatID); err != nil {
+		return err
+	}
+
+	if len(collectionIDs) > 0 {
+		query := `
+			INSERT INTO chat_collections (chat_id, collection_id, created_at)
+			SELECT $1, unnest($2::int[]), CURRENT_TIMESTAMP
+			ON CONFLICT DO NOTHING`
+		if _, err := tx.Exec(query, chatID, pq.Array(collectionIDs)); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// rowScanner объединяет *sql.Row и *sql.Rows для общих функций сканирования
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanCollection(row rowScanner) (*Collection, error) {
+	var collection Collection
+	var createdAt, updatedAt sql.NullTime
+	err := row.Scan(
+		&collection.ID,
+		&collection.UserID,
+		&collection.Name,
+		&collection.Description,
+		&collection.EmbeddingModel,
+		&collection.DocumentsCount,
+		&createdAt,
+		&updatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if createdAt.Valid {
+		collection.CreatedAt = createdAt.Time
+	}
+	if updatedAt.Valid {
+		collection.UpdatedAt = updatedAt.Time
+	}
+
+	return &collection, nil
+}
@@ -0,0 +1,352 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type DocumentModel struct {
+	DB *sql.DB
+}
+
+type Document struct {
+	ID           int       `json:"id"`
+	CollectionID int       `json:"collection_id"`
+	Title        string    `json:"title"`
+	Content      string    `json:"-"`
+	Status       string    `json:"status"` // "processing", "ready" или "failed"
+	Error        string    `json:"error,omitempty"`
+	ChunksCount  int       `json:"chunks_count"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// DocumentChunk представляет фрагмент документа с его эмбеддингом
+type DocumentChunk struct {
+	ID           int       `json:"id"`
+	DocumentID   int       `json:"document_id"`
+	CollectionID int       `json:"collection_id"`
+	ChunkIndex   int       `json:"chunk_index"`
+	Content      string    `json:"content"`
+	Embedding    []float32 `json:"-"`
+}
+
+// ScoredChunk представляет найденный фрагмент с оценкой близости к запросу
+type ScoredChunk struct {
+	DocumentChunk
+	DocumentTitle string  `json:"document_title"`
+	Score         float64 `json:"score"`
+}
+
+// Create создает документ в статусе "processing"
+func (m DocumentModel) Create(collectionID int, title, content string) (*Document, error) {
+	query := `
+		INSERT INTO documents (collection_id, title, content, status, created_at, updated_at)
+		VALUES ($1, $2, $3, 'processing', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+		RETURNING id`
+
+	var id int
+	err := m.DB.QueryRow(query, collectionID, title, content).Scan(&id)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.GetByID(id)
+}
+
+// GetByID получает документ по ID
+func (m DocumentModel) GetByID(id int) (*Document, error) {
+	query := `
+		SELECT id, collection_id, title, content, status, error, chunks_count, created_at, updated_at
+		FROM documents
+		WHERE id = $1`
+
+	document, err := scanDocument(m.DB.QueryRow(query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("document not found")
+		}
+		return nil, err
+	}
+
+	return document, nil
+}
+
+// GetByCollectionID получает документы коллекции (без содержимого)
+func (m DocumentModel) GetByCollectionID(collectionID int) ([]*Document, error) {
+	query := `
+		SELECT id, collection_id, title, '', status, error, chunks_count, created_at, updated_at
+		FROM documents
+		WHERE collection_id = $1
+		ORDER BY created_at DESC`
+
+	rows, err := m.DB.Query(query, collectionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var documents []*Document
+	for rows.Next() {
+		document, err := scanDocument(rows)
+		if err != nil {
+			return nil, err
+		}
+		documents = append(documents, document)
+	}
+
+	return documents, rows.Err()
+}
+
+// Delete удаляет документ вместе с фрагментами и обновляет время изменения коллекции
+// По времени изменения коллекции реплики с MemoryIndex узнают, что кеш фрагментов устарел
+func (m DocumentModel) Delete(id int) error {
+	query := `
+		WITH deleted AS (
+			DELETE FROM documents WHERE id = $1 RETURNING collection_id
+		)
+		UPDATE collections
+		SET updated_at = CURRENT_TIMESTAMP
+		FROM deleted
+		WHERE collections.id = deleted.collection_id`
+	_, err := m.DB.Exec(query, id)
+	return err
+}
+
+// MarkFailed переводит документ в статус "failed"
+func (m DocumentModel) MarkFailed(id int, reason string) error {
+	query := `
+		UPDATE documents
+		SET status = 'failed', error = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $2`
+
+	_, err := m.DB.Exec(query, reason, id)
+	return err
+}
+
+// ReplaceChunks атомарно сохраняет фрагменты документа, переводит его в статус "ready"
+// и обновляет время изменения коллекции
+func (m DocumentModel) ReplaceChunks(documentID, collectionID int, chunks []string, embeddings [][]float32) error {
+	if len(chunks) != len(embeddings) {
+		return errors.New("chunks and embeddings count mismatch")
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM document_chunks WHERE document_id = $1`, documentID); err != nil {
+		return err
+	}
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO document_chunks (document_id, collection_id, chunk_index, content, embedding, created_at)
+		VALUES ($1, $2, $3, $4, $5, CURRENT_TIMESTAMP)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for i, chunk := range chunks {
+		if _, err := stmt.Exec(documentID, collectionID, i, chunk, pq.Float32Array(embeddings[i])); err != nil {
+			return err
+		}
+	}
+
+	query := `
+		UPDATE documents
+		SET status = 'ready', error = '', chunks_count = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $2`
+	if _, err := tx.Exec(query, len(chunks), documentID); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`UPDATE collections SET updated_at = CURRENT_TIMESTAMP WHERE id = $1`, collectionID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetCollectionVersions возвращает время последнего изменения коллекций
+// Удаленные коллекции в результат не попадают
+func (m DocumentModel) GetCollectionVersions(collectionIDs []int) (map[int]time.Time, error) {
+	rows, err := m.DB.Query(`SELECT id, updated_at FROM collections WHERE id = ANY($1)`, pq.Array(collectionIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := make(map[int]time.Time, len(collectionIDs))
+	for rows.Next() {
+		var id int
+		var updatedAt time.Time
+		if err := rows.Scan(&id, &updatedAt); err != nil {
+			return nil, err
+		}
+		versions[id] = updatedAt
+	}
+
+	return versions, rows.Err()
+}
+
+// GetChunksByCollectionID загружает все фрагменты коллекции вместе с эмбеддингами
+func (m DocumentModel) GetChunksByCollectionID(collectionID int) ([]*DocumentChunk, error) {
+	query := `
+		SELECT id, document_id, collection_id, chunk_index, content, embedding
+		FROM document_chunks
+		WHERE collection_id = $1
+		ORDER BY document_id, chunk_index`
+
+	rows, err := m.DB.Query(query, collectionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var chunks []*DocumentChunk
+	for rows.Next() {
+		var chunk DocumentChunk
+		var embedding pq.Float32Array
+		err := rows.Scan(
+			&chunk.ID,
+			&chunk.DocumentID,
+			&chunk.CollectionID,
+			&chunk.ChunkIndex,
+			&chunk.Content,
+			&embedding,
+		)
+		if err != nil {
+			return nil, err
+		}
+		chunk.Embedding = embedding
+		chunks = append(chunks, &chunk)
+	}
+
+	return chunks, rows.Err()
+}
+
+// GetTitles возвращает названия документов по их ID
+func (m DocumentModel) GetTitles(ids []int) (map[int]string, error) {
+	titles := make(map[int]string, len(ids))
+	if len(ids) == 0 {
+		return titles, nil
+	}
+
+	rows, err := m.DB.Query(`SELECT id, title FROM documents WHERE id = ANY($1)`, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		var title string
+		if err := rows.Scan(&id, &title); err != nil {
+			return nil, err
+		}
+		titles[id] = title
+	}
+
+	return titles, rows.Err()
+}
+
+// SearchChunksPgVector ищет ближайшие фрагменты средствами расширения pgvector
+// Требует установленного расширения: CREATE EXTENSION vector
+func (m DocumentModel) SearchChunksPgVector(collectionIDs []int, embedding []float32, limit int) ([]*ScoredChunk, error) {
+	query := `
+		SELECT dc.id, dc.document_id, dc.collection_id, dc.chunk_index, dc.content, d.title,
+		       1 - (dc.embedding::vector <=> $2::vector) AS score
+		FROM document_chunks dc
+		JOIN documents d ON d.id = dc.document_id
+		WHERE dc.collection_id = ANY($1)
+		  AND array_length(dc.embedding, 1) = $4
+		ORDER BY dc.embedding::vector <=> $2::vector
+		LIMIT $3`
+
+	rows, err := m.DB.Query(query, pq.Array(collectionIDs), formatVector(embedding), limit, len(embedding))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var chunks []*ScoredChunk
+	for rows.Next() {
+		var chunk ScoredChunk
+		err := rows.Scan(
+			&chunk.ID,
+			&chunk.DocumentID,
+			&chunk.CollectionID,
+			&chunk.ChunkIndex,
+			&chunk.Content,
+			&chunk.DocumentTitle,
+			&chunk.Score,
+		)
+		if err != nil {
+			return nil, err
+		}
+		chunks = append(chunks, &chunk)
+	}
+
+	return chunks, rows.Err()
+}
+
+// formatVector форматирует вектор в текстовое представление pgvector: [0.1,0.2,...]
+func formatVector(v []float32) string {
+	var b strings.Builder
+	b.WriteByte('[')
+	for i, x := range v {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(strconv.FormatFloat(float64(x), 'g', -1, 32))
+	}
+	b.WriteByte(']')
+	return b.String()
+}
+
+func scanDocument(row rowScanner) (*Document, error) {
+	var document Document
+	var createdAt, updatedAt sql.NullTime
+	err := row.Scan(
+		&document.ID,
+		&document.CollectionID,
+		&document.Title,
+		&document.Content,
+		&document.Status,
+		&document.Error,
+		&document.ChunksCount,
+		&createdAt,
+		&updatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if createdAt.Valid {
+		document.CreatedAt = createdAt.Time
+	}
+	if updatedAt.Valid {
+		document.UpdatedAt = updatedAt.Time
+	}
+
+	return &document, nil
+}
+
+// HasPgVector проверяет, установлено ли в базе расширение pgvector
+func HasPgVector(db *sql.DB) (bool, error) {
+	var exists bool
+	err := db.QueryRow(`SELECT EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'vector')`).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check pgvector extension: %w", err)
+	}
+	return exists, nil
+}
@@ -0,0 +1,217 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Статусы задачи экспорта
+const (
+	ExportStatusPending   = "pending"
+	ExportStatusCompleted = "completed"
+	ExportStatusFailed    = "failed"
+)
+
+// ErrExportInProgress возвращается Create, если у пользователя уже выполняется экспорт
+var ErrExportInProgress = errors.New("export is already in progress")
+
+// exportInterruptedError - ошибка задачи, выполнение которой прервалось (например, при перезапуске сервера)
+const exportInterruptedError = "export was interrupted"
+
+type ExportJobModel struct {
+	DB *sql.DB
+}
+
+// ExportJob - задача фонового экспорта чатов пользователя в ZIP архив
+// Сам архив не загружается вместе с задачей, его возвращает GetArchive
+type ExportJob struct {
+	ID          int        `json:"id"`
+	UserID      int        `json:"user_id"`
+	Format      string     `json:"format"`
+	Status      string     `json:"status"`
+	ChatsCount  int        `json:"chats_count"`
+	SizeBytes   int64      `json:"size_bytes"`
+	Error       string     `json:"error,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+}
+
+const exportJobColumns = `id, user_id, format, status, chats_count, size_bytes, error,
+	created_at, completed_at, expires_at`
+
+// Create создает задачу экспорта; архив будет доступен до expiresAt
+// Прерванная задача пользователя (без обновлений дольше staleAfter) отмечается как failed; если другая задача
+// еще выполняется, возвращается ErrExportInProgress. Уникальный индекс по pending-задачам пользователя
+// исключает гонку между одновременными запросами
+func (m ExportJobModel) Create(userID int, format string, expiresAt time.Time, staleAfter time.Duration) (*ExportJob, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		UPDATE export_jobs
+		SET status = $1, error = $2, completed_at = CURRENT_TIMESTAMP
+		WHERE user_id = $3 AND status = $4 AND updated_at < $5`,
+		ExportStatusFailed, exportInterruptedError, userID, ExportStatusPending, time.Now().Add(-staleAfter))
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		INSERT INTO export_jobs (user_id, format, status, created_at, updated_at, expires_at)
+		VALUES ($1, $2, $3, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP, $4)
+		ON CONFLICT (user_id) WHERE status = 'pending' DO NOTHING
+		RETURNING id`
+
+	var id int
+	if err := tx.QueryRow(query, userID, format, ExportStatusPending, expiresAt).Scan(&id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrExportInProgress
+		}
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return m.GetByID(id)
+}
+
+// GetByID получает задачу экспорта по ID
+func (m ExportJobModel) GetByID(id int) (*ExportJob, error) {
+	query := `SELECT ` + exportJobColumns + `
+		FROM export_jobs
+		WHERE id = $1`
+
+	job, err := scanExportJob(m.DB.QueryRow(query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("export job not found")
+		}
+		return nil, err
+	}
+
+	return job, nil
+}
+
+// GetByUserID получает задачи экспорта пользователя, которые еще не истекли
+func (m ExportJobModel) GetByUserID(userID int) ([]*ExportJob, error) {
+	query := `SELECT ` + exportJobColumns + `
+		FROM export_jobs
+		WHERE user_id = $1 AND expires_at > CURRENT_TIMESTAMP
+		ORDER BY created_at DESC`
+
+	rows, err := m.DB.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []*ExportJob
+	for rows.Next() {
+		job, err := scanExportJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+
+	return jobs, rows.Err()
+}
+
+// FailStale отмечает как failed задачи pending без обновлений дольше staleAfter
+// Возвращает количество прерванных задач
+func (m ExportJobModel) FailStale(staleAfter time.Duration) (int64, error) {
+	query := `
+		UPDATE export_jobs
+		SET status = $1, error = $2, completed_at = CURRENT_TIMESTAMP
+		WHERE status = $3 AND updated_at < $4`
+
+	result, err := m.DB.Exec(query, ExportStatusFailed, exportInterruptedError, ExportStatusPending, time.Now().Add(-staleAfter))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// UpdateProgress сохраняет количество экспортированных чатов и отмечает, что задача выполняется
+func (m ExportJobModel) UpdateProgress(id, chatsCount int) error {
+	query := `UPDATE export_jobs SET chats_count = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2`
+	_, err := m.DB.Exec(query, chatsCount, id)
+	return err
+}
+
+// Complete сохраняет готовый архив
+func (m ExportJobModel) Complete(id, chatsCount int, archive []byte) error {
+	query := `
+		UPDATE export_jobs
+		SET status = $1, chats_count = $2, archive = $3, size_bytes = $4, completed_at = CURRENT_TIMESTAMP
+		WHERE id = $5`
+
+	_, err := m.DB.Exec(query, ExportStatusCompleted, chatsCount, archive, len(archive), id)
+	return err
+}
+
+// Fail отмечает задачу как завершившуюся ошибкой
+func (m ExportJobModel) Fail(id int, errMsg string) error {
+	query := `
+		UPDATE export_jobs
+		SET status = $1, error = $2, completed_at = CURRENT_TIMESTAMP
+		WHERE id = $3`
+
+	_, err := m.DB.Exec(query, ExportStatusFailed, errMsg, id)
+	return err
+}
+
+// GetArchive возвращает содержимое готового архива
+func (m ExportJobModel) GetArchive(id int) ([]byte, error) {
+	var archive []byte
+	err := m.DB.QueryRow(`SELECT archive FROM export_jobs WHERE id = $1`, id).Scan(&archive)
+	return archive, err
+}
+
+// DeleteExpired удаляет истекшие задачи вместе с архивами
+func (m ExportJobModel) DeleteExpired() (int64, error) {
+	result, err := m.DB.Exec(`DELETE FROM export_jobs WHERE expires_at <= CURRENT_TIMESTAMP`)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func scanExportJob(row rowScanner) (*ExportJob, error) {
+	var job ExportJob
+	var createdAt, completedAt, expiresAt sql.NullTime
+	err := row.Scan(
+		&job.ID,
+		&job.UserID,
+		&job.Format,
+		&job.Status,
+		&job.ChatsCount,
+		&job.SizeBytes,
+		&job.Error,
+		&createdAt,
+		&completedAt,
+		&expiresAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if createdAt.Valid {
+		job.CreatedAt = createdAt.Time
+	}
+	if completedAt.Valid {
+		job.CompletedAt = &completedAt.Time
+	}
+	if expiresAt.Valid {
+		job.ExpiresAt = expiresAt.Time
+	}
+
+	return &job, nil
+}
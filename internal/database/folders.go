@@ -0,0 +1,194 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+type FolderModel struct {
+	DB *sql.DB
+}
+
+// Folder - папка чатов пользователя; ParentID nil для папок верхнего уровня
+type Folder struct {
+	ID         int       `json:"id"`
+	UserID     int       `json:"user_id"`
+	ParentID   *int      `json:"parent_id,omitempty"`
+	Name       string    `json:"name"`
+	ChatsCount int       `json:"chats_count"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// В ChatsCount не учитываются чаты из корзины
+const folderColumns = `f.id, f.user_id, f.parent_id, f.name,
+	(SELECT COUNT(*) FROM chats c WHERE c.folder_id = f.id AND c.deleted_at IS NULL),
+	f.created_at, f.updated_at`
+
+// folderSubtree - рекурсивный запрос ID папки $1 и всех ее подпапок
+const folderSubtree = `
+	WITH RECURSIVE subtree AS (
+		SELECT id FROM chat_folders WHERE id = $1
+		UNION ALL
+		SELECT f.id FROM chat_folders f JOIN subtree s ON f.parent_id = s.id
+	)`
+
+// Insert создает папку
+func (m FolderModel) Insert(folder *Folder) (*Folder, error) {
+	query := `
+		INSERT INTO chat_folders (user_id, parent_id, name, created_at, updated_at)
+		VALUES ($1, $2, $3, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+		RETURNING id`
+
+	var id int
+	if err := m.DB.QueryRow(query, folder.UserID, folder.ParentID, folder.Name).Scan(&id); err != nil {
+		return nil, err
+	}
+
+	return m.GetByID(id)
+}
+
+// GetByID получает папку по ID
+func (m FolderModel) GetByID(id int) (*Folder, error) {
+	query := `SELECT ` + folderColumns + `
+		FROM chat_folders f
+		WHERE f.id = $1`
+
+	folder, err := scanFolder(m.DB.QueryRow(query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("folder not found")
+		}
+		return nil, err
+	}
+
+	return folder, nil
+}
+
+// GetByUserID получает все папки пользователя плоским списком; дерево строится по parent_id
+func (m FolderModel) GetByUserID(userID int) ([]*Folder, error) {
+	query := `SELECT ` + folderColumns + `
+		FROM chat_folders f
+		WHERE f.user_id = $1
+		ORDER BY f.name ASC, f.id ASC`
+
+	rows, err := m.DB.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var folders []*Folder
+	for rows.Next() {
+		folder, err := scanFolder(rows)
+		if err != nil {
+			return nil, err
+		}
+		folders = append(folders, folder)
+	}
+
+	return folders, rows.Err()
+}
+
+// Update сохраняет название и родителя папки
+func (m FolderModel) Update(folder *Folder) (*Folder, error) {
+	query := `
+		UPDATE chat_folders
+		SET name = $1, parent_id = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $3`
+
+	if _, err := m.DB.Exec(query, folder.Name, folder.ParentID, folder.ID); err != nil {
+		return nil, err
+	}
+
+	return m.GetByID(folder.ID)
+}
+
+// IsInSubtree проверяет, является ли candidateID самой папкой folderID или одной из ее подпапок
+// Используется, чтобы перенос папки не создал цикл
+func (m FolderModel) IsInSubtree(folderID, candidateID int) (bool, error) {
+	query := folderSubtree + `
+		SELECT EXISTS (SELECT 1 FROM subtree WHERE id = $2)`
+
+	var exists bool
+	err := m.DB.QueryRow(query, folderID, candidateID).Scan(&exists)
+	return exists, err
+}
+
+// Delete удаляет папку вместе с подпапками; их чаты переходят в корень
+// При trashChats чаты папки и подпапок перемещаются в корзину.
+// Возвращает ID затронутых чатов (не считая уже находившихся в корзине)
+func (m FolderModel) Delete(id int, trashChats bool) ([]int, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := folderSubtree + `
+		SELECT id FROM chats
+		WHERE folder_id IN (SELECT id FROM subtree) AND deleted_at IS NULL`
+	if trashChats {
+		query = folderSubtree + `
+			UPDATE chats SET deleted_at = CURRENT_TIMESTAMP
+			WHERE folder_id IN (SELECT id FROM subtree) AND deleted_at IS NULL
+			RETURNING id`
+	}
+
+	rows, err := tx.Query(query, id)
+	if err != nil {
+		return nil, err
+	}
+	var chatIDs []int
+	for rows.Next() {
+		var chatID int
+		if err := rows.Scan(&chatID); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		chatIDs = append(chatIDs, chatID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Подпапки удаляются каскадно, folder_id чатов обнуляется внешним ключом
+	if _, err := tx.Exec(`DELETE FROM chat_folders WHERE id = $1`, id); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return chatIDs, nil
+}
+
+func scanFolder(row rowScanner) (*Folder, error) {
+	var folder Folder
+	var parentID sql.NullInt64
+	var createdAt, updatedAt sql.NullTime
+	err := row.Scan(
+		&folder.ID,
+		&folder.UserID,
+		&parentID,
+		&folder.Name,
+		&folder.ChatsCount,
+		&createdAt,
+		&updatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	folder.ParentID = nullIntPtr(parentID)
+	if createdAt.Valid {
+		folder.CreatedAt = createdAt.Time
+	}
+	if updatedAt.Valid {
+		folder.UpdatedAt = updatedAt.Time
+	}
+
+	return &folder, nil
+}
@@ -0,0 +1,241 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// Статусы задачи импорта
+const (
+	ImportStatusPending   = "pending"
+	ImportStatusCompleted = "completed"
+	ImportStatusFailed    = "failed"
+)
+
+// ErrImportInProgress возвращается Create, если у пользователя уже выполняется импорт
+var ErrImportInProgress = errors.New("import is already in progress")
+
+// importInterruptedError - ошибка задачи, выполнение которой прервалось (например, при перезапуске сервера)
+const importInterruptedError = "import was interrupted"
+
+type ImportJobModel struct {
+	DB *sql.DB
+}
+
+// ImportJob - задача фонового импорта диалогов
+type ImportJob struct {
+	ID       int    `json:"id"`
+	UserID   int    `json:"user_id"`
+	FileName string `json:"file_name"`
+	// Source - распознанный формат источника (chatimport.SourceChatGPT или SourceMindForge)
+	Source   string `json:"source"`
+	Status   string `json:"status"`
+	Total    int    `json:"total"`
+	Imported int    `json:"imported"`
+	Failed   int    `json:"failed"`
+	// Results - отчет по каждому диалогу ([]ImportResult), заполняется по завершении
+	Results     json.RawMessage `json:"results,omitempty"`
+	Error       string          `json:"error,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+	CompletedAt *time.Time      `json:"completed_at,omitempty"`
+}
+
+// ImportResult - результат импорта одного диалога
+type ImportResult struct {
+	Index  int    `json:"index"`
+	Title  string `json:"title"`
+	Status string `json:"status"` // "imported" или "failed"
+	ChatID int    `json:"chat_id,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+const importJobColumns = `id, user_id, file_name, source, status, total, imported, failed,
+	results, error, created_at, completed_at`
+
+// Create создает задачу импорта
+// Прерванная задача пользователя (без обновлений дольше staleAfter) отмечается как failed; если другая задача
+// еще выполняется, возвращается ErrImportInProgress. Уникальный индекс по pending-задачам пользователя
+// исключает гонку между одновременными запросами
+func (m ImportJobModel) Create(userID int, fileName string, staleAfter time.Duration) (*ImportJob, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		UPDATE import_jobs
+		SET status = $1, error = $2, completed_at = CURRENT_TIMESTAMP
+		WHERE user_id = $3 AND status = $4 AND updated_at < $5`,
+		ImportStatusFailed, importInterruptedError, userID, ImportStatusPending, time.Now().Add(-staleAfter))
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		INSERT INTO import_jobs (user_id, file_name, status, created_at, updated_at)
+		VALUES ($1, $2, $3, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+		ON CONFLICT (user_id) WHERE status = 'pending' DO NOTHING
+		RETURNING id`
+
+	var id int
+	if err := tx.QueryRow(query, userID, fileName, ImportStatusPending).Scan(&id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrImportInProgress
+		}
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return m.GetByID(id)
+}
+
+// GetByID получает задачу импорта по ID
+func (m ImportJobModel) GetByID(id int) (*ImportJob, error) {
+	query := `SELECT ` + importJobColumns + `
+		FROM import_jobs
+		WHERE id = $1`
+
+	job, err := scanImportJob(m.DB.QueryRow(query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("import job not found")
+		}
+		return nil, err
+	}
+
+	return job, nil
+}
+
+// GetByUserID получает задачи импорта пользователя без подробных отчетов
+func (m ImportJobModel) GetByUserID(userID int) ([]*ImportJob, error) {
+	query := `
+		SELECT id, user_id, file_name, source, status, total, imported, failed,
+		       NULL, error, created_at, completed_at
+		FROM import_jobs
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+		LIMIT 50`
+
+	rows, err := m.DB.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []*ImportJob
+	for rows.Next() {
+		job, err := scanImportJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+
+	return jobs, rows.Err()
+}
+
+// FailStale отмечает как failed задачи pending без обновлений дольше staleAfter
+// Возвращает количество прерванных задач
+func (m ImportJobModel) FailStale(staleAfter time.Duration) (int64, error) {
+	query := `
+		UPDATE import_jobs
+		SET status = $1, error = $2, completed_at = CURRENT_TIMESTAMP
+		WHERE status = $3 AND updated_at < $4`
+
+	result, err := m.DB.Exec(query, ImportStatusFailed, importInterruptedError, ImportStatusPending, time.Now().Add(-staleAfter))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// Start сохраняет распознанный формат и количество диалогов
+func (m ImportJobModel) Start(id int, source string, total int) error {
+	query := `UPDATE import_jobs SET source = $1, total = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $3`
+	_, err := m.DB.Exec(query, source, total, id)
+	return err
+}
+
+// UpdateProgress сохраняет количество обработанных диалогов и отмечает, что задача выполняется
+func (m ImportJobModel) UpdateProgress(id, imported, failed int) error {
+	query := `UPDATE import_jobs SET imported = $1, failed = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $3`
+	_, err := m.DB.Exec(query, imported, failed, id)
+	return err
+}
+
+// Complete сохраняет итоговый отчет импорта
+func (m ImportJobModel) Complete(id int, results []ImportResult) error {
+	var imported, failed int
+	for _, r := range results {
+		if r.Status == "imported" {
+			imported++
+		} else {
+			failed++
+		}
+	}
+
+	data, err := json.Marshal(results)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE import_jobs
+		SET status = $1, imported = $2, failed = $3, results = $4, completed_at = CURRENT_TIMESTAMP
+		WHERE id = $5`
+
+	_, err = m.DB.Exec(query, ImportStatusCompleted, imported, failed, data, id)
+	return err
+}
+
+// Fail отмечает задачу как завершившуюся ошибкой (например, файл не распознан)
+func (m ImportJobModel) Fail(id int, errMsg string) error {
+	query := `
+		UPDATE import_jobs
+		SET status = $1, error = $2, completed_at = CURRENT_TIMESTAMP
+		WHERE id = $3`
+
+	_, err := m.DB.Exec(query, ImportStatusFailed, errMsg, id)
+	return err
+}
+
+func scanImportJob(row rowScanner) (*ImportJob, error) {
+	var job ImportJob
+	var results []byte
+	var createdAt, completedAt sql.NullTime
+	err := row.Scan(
+		&job.ID,
+		&job.UserID,
+		&job.FileName,
+		&job.Source,
+		&job.Status,
+		&job.Total,
+		&job.Imported,
+		&job.Failed,
+		&results,
+		&job.Error,
+		&createdAt,
+		&completedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(results) > 0 {
+		job.Results = results
+	}
+	if createdAt.Valid {
+		job.CreatedAt = createdAt.Time
+	}
+	if completedAt.Valid {
+		job.CompletedAt = &completedAt.Time
+	}
+
+	return &job, nil
+}
@@ -2,67 +2,186 @@ package database
 
 import (
 	"database/sql"
+	"encoding/json"
 	"errors"
+	"slices"
 	"time"
 )
 
+// Статусы ответов-кандидатов в режиме сравнения моделей
+const (
+	CandidateStatusPending  = "pending"
+	CandidateStatusSelected = "selected"
+	CandidateStatusRejected = "rejected"
+)
+
+// ModerationStatusContentBlocked - ответ модели заблокирован модерацией
+const ModerationStatusContentBlocked = "content_blocked"
+
+// ErrCandidateSelectionRequired возвращается InsertNext, пока в чате не выбран ответ-кандидат
+// или идет сравнение моделей
+var ErrCandidateSelectionRequired = errors.New("candidate selection required")
+
 type MessageModel struct {
 	DB *sql.DB
 }
 
 type Message struct {
-	ID        int       `json:"id"`
-	ChatID    int       `json:"chat_id"`
-	Role      string    `json:"role"` // "user" или "assistant"
-	Content   string    `json:"content"`
-	CreatedAt time.Time `json:"created_at"`
+	ID      int    `json:"id"`
+	ChatID  int    `json:"chat_id"`
+	Role    string `json:"role"` // "user" или "assistant"
+	Content string `json:"content"`
+	// ReasoningContent хранит рассуждения reasoning-модели (только для ответов ассистента)
+	ReasoningContent string `json:"reasoning_content,omitempty"`
+	// Metadata хранит служебные данные ответа (например, цитаты из базы знаний)
+	Metadata json.RawMessage `json:"metadata,omitempty"`
+	// Model - модель, сгенерировавшая ответ ассистента
+	Model string `json:"model,omitempty"`
+	// ParentMessageID и CandidateStatus заполнены только для ответов-кандидатов в режиме сравнения
+	ParentMessageID *int   `json:"parent_message_id,omitempty"`
+	CandidateStatus string `json:"candidate_status,omitempty"`
+	// ModerationStatus заполнен, если ответ заблокирован модерацией
+	ModerationStatus string    `json:"moderation_status,omitempty"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// InContext сообщает, должно ли сообщение попадать в контекст модели
+// Невыбранные ответы-кандидаты и заблокированные модерацией ответы в историю диалога не входят
+func (msg *Message) InContext() bool {
+	if msg.ModerationStatus != "" {
+		return false
+	}
+	return msg.CandidateStatus == "" || msg.CandidateStatus == CandidateStatusSelected
 }
 
+const messageColumns = `id, chat_id, role, content, reasoning_content, metadata,
+	model, parent_message_id, candidate_status, moderation_status, created_at`
+
 // Create создает новое сообщение
 func (m MessageModel) Create(chatID int, role, content string) (*Message, error) {
-	// Валидация роли
-	if role != "user" && role != "assistant" && role != "system" {
-		return nil, errors.New("invalid role: must be 'user', 'assistant', or 'system'")
+	return m.Insert(&Message{
+		ChatID:  chatID,
+		Role:    role,
+		Content: content,
+	})
+}
+
+// Insert создает новое сообщение со всеми заполненными полями (включая метаданные)
+func (m MessageModel) Insert(msg *Message) (*Message, error) {
+	id, err := insertMessage(m.DB, msg)
+	if err != nil {
+		return nil, err
 	}
 
-	// Валидация контента
-	if len(content) == 0 {
-		return nil, errors.New("content cannot be empty")
+	return m.GetByID(id)
+}
+
+// InsertNext добавляет сообщение пользователя, продолжающее диалог
+// Чат блокируется на время проверки, поэтому одновременные отправка и сравнение не проходят оба:
+// если в чате есть ответы-кандидаты, ожидающие выбора, или идет сравнение, возвращается ErrCandidateSelectionRequired.
+// Ненулевой comparingUntil отмечает, что сообщение отправлено на сравнение моделей, которое завершится до этого времени
+func (m MessageModel) InsertNext(msg *Message, comparingUntil *time.Time) (*Message, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return nil, err
 	}
-	if len(content) > 10000 {
-		return nil, errors.New("content too long (max 10000 characters)")
+	defer tx.Rollback()
+
+	var comparing bool
+	err = tx.QueryRow(`
+		SELECT COALESCE(comparing_until > CURRENT_TIMESTAMP, false)
+		FROM chats
+		WHERE id = $1
+		FOR UPDATE`, msg.ChatID).Scan(&comparing)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("chat not found")
+		}
+		return nil, err
 	}
 
-	query := `
-		INSERT INTO messages (chat_id, role, content, created_at)
-		VALUES ($1, $2, $3, CURRENT_TIMESTAMP)
-		RETURNING id`
+	var pending bool
+	err = tx.QueryRow(`SELECT EXISTS(SELECT 1 FROM messages WHERE chat_id = $1 AND candidate_status = $2)`,
+		msg.ChatID, CandidateStatusPending).Scan(&pending)
+	if err != nil {
+		return nil, err
+	}
+	if comparing || pending {
+		return nil, ErrCandidateSelectionRequired
+	}
 
-	var id int
-	err := m.DB.QueryRow(query, chatID, role, content).Scan(&id)
+	id, err := insertMessage(tx, msg)
 	if err != nil {
 		return nil, err
 	}
 
+	if comparingUntil != nil {
+		if _, err := tx.Exec(`UPDATE chats SET comparing_until = $1 WHERE id = $2`, *comparingUntil, msg.ChatID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
 	return m.GetByID(id)
 }
 
+// FinishComparing снимает отметку сравнения моделей после сохранения ответов-кандидатов
+func (m MessageModel) FinishComparing(chatID int) error {
+	_, err := m.DB.Exec(`UPDATE chats SET comparing_until = NULL WHERE id = $1`, chatID)
+	return err
+}
+
+// rowQuerier - *sql.DB или *sql.Tx
+type rowQuerier interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// insertMessage проверяет и сохраняет сообщение, возвращает его ID
+func insertMessage(q rowQuerier, msg *Message) (int, error) {
+	// Валидация роли
+	if msg.Role != "user" && msg.Role != "assistant" && msg.Role != "system" {
+		return 0, errors.New("invalid role: must be 'user', 'assistant', or 'system'")
+	}
+
+	// Валидация контента
+	if len(msg.Content) == 0 {
+		return 0, errors.New("content cannot be empty")
+	}
+	if len(msg.Content) > 10000 {
+		return 0, errors.New("content too long (max 10000 characters)")
+	}
+
+	query := `
+		INSERT INTO messages (chat_id, role, content, reasoning_content, metadata,
+		                      model, parent_message_id, candidate_status, moderation_status, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, CURRENT_TIMESTAMP)
+		RETURNING id`
+
+	var id int
+	err := q.QueryRow(query,
+		msg.ChatID,
+		msg.Role,
+		msg.Content,
+		msg.ReasoningContent,
+		nullJSON(msg.Metadata),
+		msg.Model,
+		msg.ParentMessageID,
+		nullString(msg.CandidateStatus),
+		nullString(msg.ModerationStatus),
+	).Scan(&id)
+	return id, err
+}
+
 // GetByID получает сообщение по ID
 func (m MessageModel) GetByID(id int) (*Message, error) {
-	query := `
-		SELECT id, chat_id, role, content, created_at
+	query := `SELECT ` + messageColumns + `
 		FROM messages
 		WHERE id = $1`
 
-	var msg Message
-	var createdAt sql.NullTime
-	err := m.DB.QueryRow(query, id).Scan(
-		&msg.ID,
-		&msg.ChatID,
-		&msg.Role,
-		&msg.Content,
-		&createdAt,
-	)
+	msg, err := scanMessage(m.DB.QueryRow(query, id))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, errors.New("message not found")
@@ -70,22 +189,138 @@ func (m MessageModel) GetByID(id int) (*Message, error) {
 		return nil, err
 	}
 
-	if createdAt.Valid {
-		msg.CreatedAt = createdAt.Time
-	}
-
-	return &msg, nil
+	return msg, nil
 }
 
 // GetByChatID получает все сообщения чата
 func (m MessageModel) GetByChatID(chatID int) ([]*Message, error) {
-	query := `
-		SELECT id, chat_id, role, content, created_at
+	query := `SELECT ` + messageColumns + `
 		FROM messages
 		WHERE chat_id = $1
 		ORDER BY created_at ASC`
 
-	rows, err := m.DB.Query(query, chatID)
+	return m.query(query, chatID)
+}
+
+// GetUpTo получает сообщения чата с ID не больше lastMessageID (снимок чата на момент этого сообщения)
+func (m MessageModel) GetUpTo(chatID, lastMessageID int) ([]*Message, error) {
+	query := `SELECT ` + messageColumns + `
+		FROM messages
+		WHERE chat_id = $1 AND id <= $2
+		ORDER BY created_at ASC, id ASC`
+
+	return m.query(query, chatID, lastMessageID)
+}
+
+// GetPage получает страницу сообщений чата в хронологическом порядке
+// Без курсора возвращаются последние сообщения; признак hasMore относится к направлению запроса
+func (m MessageModel) GetPage(chatID int, page PageQuery) ([]*Message, bool, error) {
+	query, args := page.apply(`SELECT `+messageColumns+`
+		FROM messages
+		WHERE chat_id = $1`, []interface{}{chatID}, "created_at", "id")
+
+	messages, err := m.query(query, args...)
+	if err != nil {
+		return nil, false, err
+	}
+
+	messages, hasMore := trimPage(messages, page)
+	slices.Reverse(messages)
+	return messages, hasMore, nil
+}
+
+// CountByChatID возвращает количество сообщений чата
+func (m MessageModel) CountByChatID(chatID int) (int, error) {
+	var count int
+	err := m.DB.QueryRow(`SELECT COUNT(*) FROM messages WHERE chat_id = $1`, chatID).Scan(&count)
+	return count, err
+}
+
+// GetContextTail получает последние limit сообщений чата, входящих в контекст модели (см. InContext),
+// в хронологическом порядке. Вся история не загружается
+func (m MessageModel) GetContextTail(chatID, limit int) ([]*Message, error) {
+	query := `SELECT ` + messageColumns + `
+		FROM (
+			SELECT ` + messageColumns + `
+			FROM messages
+			WHERE chat_id = $1
+			  AND moderation_status IS NULL
+			  AND (candidate_status IS NULL OR candidate_status = $2)
+			ORDER BY created_at DESC, id DESC
+			LIMIT $3
+		) tail
+		ORDER BY created_at ASC, id ASC`
+
+	return m.query(query, chatID, CandidateStatusSelected, limit)
+}
+
+// PageCursor возвращает курсор сообщения для keyset-пагинации
+func (msg *Message) PageCursor() Cursor {
+	return Cursor{Time: msg.CreatedAt, ID: msg.ID}
+}
+
+// GetCandidates получает ответы-кандидаты, сгенерированные для сообщения пользователя
+func (m MessageModel) GetCandidates(parentMessageID int) ([]*Message, error) {
+	query := `SELECT ` + messageColumns + `
+		FROM messages
+		WHERE parent_message_id = $1
+		ORDER BY id ASC`
+
+	return m.query(query, parentMessageID)
+}
+
+// HasPendingCandidates проверяет, есть ли в чате ответы-кандидаты, ожидающие выбора, или идет сравнение моделей
+func (m MessageModel) HasPendingCandidates(chatID int) (bool, error) {
+	query := `
+		SELECT EXISTS(SELECT 1 FROM messages WHERE chat_id = $1 AND candidate_status = $2)
+		    OR EXISTS(SELECT 1 FROM chats WHERE id = $1 AND comparing_until > CURRENT_TIMESTAMP)`
+
+	var exists bool
+	err := m.DB.QueryRow(query, chatID, CandidateStatusPending).Scan(&exists)
+	return exists, err
+}
+
+// SelectCandidate отмечает ответ-кандидат выбранным, а остальные ответы на то же сообщение - отклоненными
+func (m MessageModel) SelectCandidate(messageID int) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var parentID sql.NullInt64
+	err = tx.QueryRow(`SELECT parent_message_id FROM messages WHERE id = $1 FOR UPDATE`, messageID).Scan(&parentID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errors.New("message not found")
+		}
+		return err
+	}
+	if !parentID.Valid {
+		return errors.New("message is not a candidate")
+	}
+
+	_, err = tx.Exec(`
+		UPDATE messages
+		SET candidate_status = CASE WHEN id = $1 THEN $2 ELSE $3 END
+		WHERE parent_message_id = $4`,
+		messageID, CandidateStatusSelected, CandidateStatusRejected, parentID.Int64)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DeleteByChatID удаляет все сообщения чата
+func (m MessageModel) DeleteByChatID(chatID int) error {
+	query := `DELETE FROM messages WHERE chat_id = $1`
+	_, err := m.DB.Exec(query, chatID)
+	return err
+}
+
+func (m MessageModel) query(query string, args ...interface{}) ([]*Message, error) {
+	rows, err := m.DB.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -93,32 +328,59 @@ func (m MessageModel) GetByChatID(chatID int) ([]*Message, error) {
 
 	var messages []*Message
 	for rows.Next() {
-		var msg Message
-		var createdAt sql.NullTime
-		err := rows.Scan(
-			&msg.ID,
-			&msg.ChatID,
-			&msg.Role,
-			&msg.Content,
-			&createdAt,
-		)
+		msg, err := scanMessage(rows)
 		if err != nil {
 			return nil, err
 		}
+		messages = append(messages, msg)
+	}
 
-		if createdAt.Valid {
-			msg.CreatedAt = createdAt.Time
-		}
+	return messages, rows.Err()
+}
 
-		messages = append(messages, &msg)
+func scanMessage(row rowScanner) (*Message, error) {
+	var msg Message
+	var metadata []byte
+	var parentID sql.NullInt64
+	var candidateStatus, moderationStatus sql.NullString
+	var createdAt sql.NullTime
+	err := row.Scan(
+		&msg.ID,
+		&msg.ChatID,
+		&msg.Role,
+		&msg.Content,
+		&msg.ReasoningContent,
+		&metadata,
+		&msg.Model,
+		&parentID,
+		&candidateStatus,
+		&moderationStatus,
+		&createdAt,
+	)
+	if err != nil {
+		return nil, err
 	}
 
-	return messages, rows.Err()
+	if createdAt.Valid {
+		msg.CreatedAt = createdAt.Time
+	}
+	if len(metadata) > 0 {
+		msg.Metadata = metadata
+	}
+	if parentID.Valid {
+		id := int(parentID.Int64)
+		msg.ParentMessageID = &id
+	}
+	msg.CandidateStatus = candidateStatus.String
+	msg.ModerationStatus = moderationStatus.String
+
+	return &msg, nil
 }
 
-// DeleteByChatID удаляет все сообщения чата
-func (m MessageModel) DeleteByChatID(chatID int) error {
-	query := `DELETE FROM messages WHERE chat_id = $1`
-	_, err := m.DB.Exec(query, chatID)
-	return err
+// nullString преобразует пустую строку в NULL
+func nullString(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
 }
@@ -7,6 +7,22 @@ type Models struct {
 	RefreshTokens RefreshTokenModel
 	Chats         ChatModel
 	Messages      MessageModel
+	Collections   CollectionModel
+	Documents     DocumentModel
+	Usage         UsageModel
+	APIKeys       APIKeyModel
+	ResponseCache ResponseCacheModel
+	PIIRedactions PIIRedactionModel
+	Moderation    ModerationEventModel
+	Workspaces    WorkspaceModel
+	Prompts       PromptTemplateModel
+	Personas      PersonaModel
+	Search        SearchModel
+	Folders       FolderModel
+	Tags          TagModel
+	Exports       ExportJobModel
+	Imports       ImportJobModel
+	Shares        ChatShareModel
 }
 
 func NewModels(db *sql.DB) Models {
@@ -15,5 +31,21 @@ func NewModels(db *sql.DB) Models {
 		RefreshTokens: RefreshTokenModel{DB: db},
 		Chats:         ChatModel{DB: db},
 		Messages:      MessageModel{DB: db},
+		Collections:   CollectionModel{DB: db},
+		Documents:     DocumentModel{DB: db},
+		Usage:         UsageModel{DB: db},
+		APIKeys:       APIKeyModel{DB: db},
+		ResponseCache: ResponseCacheModel{DB: db},
+		PIIRedactions: PIIRedactionModel{DB: db},
+		Moderation:    ModerationEventModel{DB: db},
+		Workspaces:    WorkspaceModel{DB: db},
+		Prompts:       PromptTemplateModel{DB: db},
+		Personas:      PersonaModel{DB: db},
+		Search:        SearchModel{DB: db},
+		Folders:       FolderModel{DB: db},
+		Tags:          TagModel{DB: db},
+		Exports:       ExportJobModel{DB: db},
+		Imports:       ImportJobModel{DB: db},
+		Shares:        ChatShareModel{DB: db},
 	}
 }
@@ -0,0 +1,118 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Этапы модерации
+const (
+	ModerationStageInput  = "input"
+	ModerationStageOutput = "output"
+)
+
+type ModerationEventModel struct {
+	DB *sql.DB
+}
+
+// ModerationEvent - срабатывание модерации на сообщении пользователя или ответе модели
+type ModerationEvent struct {
+	ID        int    `json:"id"`
+	UserID    *int   `json:"user_id,omitempty"`
+	ChatID    *int   `json:"chat_id,omitempty"`
+	MessageID *int   `json:"message_id,omitempty"`
+	Stage     string `json:"stage"`
+	Moderator string `json:"moderator"`
+	Category  string `json:"category"`
+	Rule      string `json:"rule"`
+	// Excerpt - начало заблокированного текста для разбора администратором
+	Excerpt   string    `json:"excerpt"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Insert сохраняет событие модерации
+func (m ModerationEventModel) Insert(event *ModerationEvent) error {
+	query := `
+		INSERT INTO moderation_events (user_id, chat_id, message_id, stage, moderator, category, rule, excerpt, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, CURRENT_TIMESTAMP)
+		RETURNING id, created_at`
+
+	var createdAt sql.NullTime
+	err := m.DB.QueryRow(query,
+		event.UserID,
+		event.ChatID,
+		event.MessageID,
+		event.Stage,
+		event.Moderator,
+		event.Category,
+		event.Rule,
+		event.Excerpt,
+	).Scan(&event.ID, &createdAt)
+	if err != nil {
+		return err
+	}
+
+	if createdAt.Valid {
+		event.CreatedAt = createdAt.Time
+	}
+
+	return nil
+}
+
+// List возвращает события модерации, начиная с новых
+// stage фильтрует по этапу (пусто - все этапы)
+func (m ModerationEventModel) List(stage string, limit, offset int) ([]*ModerationEvent, error) {
+	query := `
+		SELECT id, user_id, chat_id, message_id, stage, moderator, category, rule, excerpt, created_at
+		FROM moderation_events
+		WHERE $1 = '' OR stage = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2 OFFSET $3`
+
+	rows, err := m.DB.Query(query, stage, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := make([]*ModerationEvent, 0)
+	for rows.Next() {
+		var event ModerationEvent
+		var userID, chatID, messageID sql.NullInt64
+		var createdAt sql.NullTime
+		err := rows.Scan(
+			&event.ID,
+			&userID,
+			&chatID,
+			&messageID,
+			&event.Stage,
+			&event.Moderator,
+			&event.Category,
+			&event.Rule,
+			&event.Excerpt,
+			&createdAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		event.UserID = nullIntPtr(userID)
+		event.ChatID = nullIntPtr(chatID)
+		event.MessageID = nullIntPtr(messageID)
+		if createdAt.Valid {
+			event.CreatedAt = createdAt.Time
+		}
+		events = append(events, &event)
+	}
+
+	return events, rows.Err()
+}
+
+// nullIntPtr преобразует NULL в nil
+func nullIntPtr(v sql.NullInt64) *int {
+	if !v.Valid {
+		return nil
+	}
+	id := int(v.Int64)
+	return &id
+}
@@ -0,0 +1,90 @@
+package database
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cursor - позиция элемента в списке, отсортированном по времени и ID
+// Клиенту передается в закодированном виде (Encode), формат курсора не является частью API
+type Cursor struct {
+	Time time.Time
+	ID   int
+}
+
+// PageQuery - параметры keyset-пагинации
+// Before возвращает элементы старше курсора, After - новее; без курсора возвращаются самые новые элементы
+type PageQuery struct {
+	Limit  int
+	Before *Cursor
+	After  *Cursor
+}
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// Encode кодирует курсор для передачи клиенту
+func (c Cursor) Encode() string {
+	raw := strconv.FormatInt(c.Time.UnixMicro(), 10) + ":" + strconv.Itoa(c.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeCursor разбирает курсор, полученный от клиента
+func DecodeCursor(s string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+
+	micros, id, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return nil, errInvalidCursor
+	}
+	ts, err := strconv.ParseInt(micros, 10, 64)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+	n, err := strconv.Atoi(id)
+	if err != nil || n <= 0 {
+		return nil, errInvalidCursor
+	}
+
+	// PostgreSQL хранит время с точностью до микросекунд, поэтому сравнение точное
+	return &Cursor{Time: time.UnixMicro(ts).UTC(), ID: n}, nil
+}
+
+// apply дополняет запрос (заканчивающийся условием WHERE) условием курсора, сортировкой и лимитом
+// Запрашивается на один элемент больше лимита, чтобы определить, есть ли следующая страница
+func (q PageQuery) apply(query string, args []interface{}, timeColumn, idColumn string) (string, []interface{}) {
+	order := "DESC"
+	switch {
+	case q.After != nil:
+		args = append(args, q.After.Time, q.After.ID)
+		query += fmt.Sprintf(" AND (%s, %s) > ($%d, $%d)", timeColumn, idColumn, len(args)-1, len(args))
+		order = "ASC"
+	case q.Before != nil:
+		args = append(args, q.Before.Time, q.Before.ID)
+		query += fmt.Sprintf(" AND (%s, %s) < ($%d, $%d)", timeColumn, idColumn, len(args)-1, len(args))
+	}
+
+	args = append(args, q.Limit+1)
+	query += fmt.Sprintf(" ORDER BY %s %s, %s %s LIMIT $%d", timeColumn, order, idColumn, order, len(args))
+	return query, args
+}
+
+// trimPage отрезает лишний элемент и приводит страницу к порядку от новых к старым
+// Возвращает признак того, что в направлении запроса есть еще элементы
+func trimPage[T any](items []T, q PageQuery) ([]T, bool) {
+	hasMore := len(items) > q.Limit
+	if hasMore {
+		items = items[:q.Limit]
+	}
+	if q.After != nil {
+		slices.Reverse(items)
+	}
+	return items, hasMore
+}
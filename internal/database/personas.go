@@ -0,0 +1,262 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type PersonaModel struct {
+	DB *sql.DB
+}
+
+// Persona - именованный набор настроек ассистента, который можно назначить чатам
+type Persona struct {
+	ID           int      `json:"id"`
+	UserID       int      `json:"user_id"`
+	Name         string   `json:"name"`
+	Description  string   `json:"description"`
+	AIModel      string   `json:"ai_model"`
+	SystemPrompt string   `json:"system_prompt"`
+	Temperature  *float64 `json:"temperature,omitempty"`
+	TopP         *float64 `json:"top_p,omitempty"`
+	// MaxTokens - ограничение длины ответа, 0 - по умолчанию провайдера
+	MaxTokens     int       `json:"max_tokens"`
+	CollectionIDs []int     `json:"collection_ids"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+const personaColumns = `p.id, p.user_id, p.name, p.description, p.ai_model, p.system_prompt,
+	p.temperature, p.top_p, p.max_tokens,
+	COALESCE((SELECT array_agg(pc.collection_id ORDER BY pc.collection_id)
+	          FROM persona_collections pc WHERE pc.persona_id = p.id), '{}'),
+	p.created_at, p.updated_at`
+
+// Insert создает персону вместе с привязкой коллекций
+func (m PersonaModel) Insert(persona *Persona) (*Persona, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO personas (user_id, name, description, ai_model, system_prompt,
+		                      temperature, top_p, max_tokens, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+		RETURNING id`
+
+	var id int
+	err = tx.QueryRow(query,
+		persona.UserID,
+		persona.Name,
+		persona.Description,
+		persona.AIModel,
+		persona.SystemPrompt,
+		persona.Temperature,
+		persona.TopP,
+		persona.MaxTokens,
+	).Scan(&id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := setPersonaCollections(tx, id, persona.CollectionIDs); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return m.GetByID(id)
+}
+
+// GetByID получает персону по ID
+func (m PersonaModel) GetByID(id int) (*Persona, error) {
+	query := `SELECT ` + personaColumns + `
+		FROM personas p
+		WHERE p.id = $1`
+
+	persona, err := scanPersona(m.DB.QueryRow(query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("persona not found")
+		}
+		return nil, err
+	}
+
+	return persona, nil
+}
+
+// GetByUserID получает все персоны пользователя
+func (m PersonaModel) GetByUserID(userID int) ([]*Persona, error) {
+	query := `SELECT ` + personaColumns + `
+		FROM personas p
+		WHERE p.user_id = $1
+		ORDER BY p.name ASC`
+
+	rows, err := m.DB.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var personas []*Persona
+	for rows.Next() {
+		persona, err := scanPersona(rows)
+		if err != nil {
+			return nil, err
+		}
+		personas = append(personas, persona)
+	}
+
+	return personas, rows.Err()
+}
+
+// Update сохраняет все поля персоны и заменяет набор коллекций
+// Если модель персоны изменилась, ее получают и чаты персоны, которые работали с прежней моделью персоны;
+// чаты, где модель выбрана явно, не меняются. Возвращает ID обновленных чатов
+func (m PersonaModel) Update(persona *Persona) (*Persona, []int, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	var previousModel string
+	err = tx.QueryRow(`SELECT ai_model FROM personas WHERE id = $1 FOR UPDATE`, persona.ID).Scan(&previousModel)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, errors.New("persona not found")
+		}
+		return nil, nil, err
+	}
+
+	query := `
+		UPDATE personas
+		SET name = $1, description = $2, ai_model = $3, system_prompt = $4,
+		    temperature = $5, top_p = $6, max_tokens = $7, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $8`
+
+	_, err = tx.Exec(query,
+		persona.Name,
+		persona.Description,
+		persona.AIModel,
+		persona.SystemPrompt,
+		persona.Temperature,
+		persona.TopP,
+		persona.MaxTokens,
+		persona.ID,
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM persona_collections WHERE persona_id = $1`, persona.ID); err != nil {
+		return nil, nil, err
+	}
+	if err := setPersonaCollections(tx, persona.ID, persona.CollectionIDs); err != nil {
+		return nil, nil, err
+	}
+
+	var chatIDs []int
+	if persona.AIModel != previousModel {
+		rows, err := tx.Query(`
+			UPDATE chats
+			SET ai_model = $1, updated_at = CURRENT_TIMESTAMP
+			WHERE persona_id = $2 AND ai_model = $3
+			RETURNING id`, persona.AIModel, persona.ID, previousModel)
+		if err != nil {
+			return nil, nil, err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var chatID int
+			if err := rows.Scan(&chatID); err != nil {
+				return nil, nil, err
+			}
+			chatIDs = append(chatIDs, chatID)
+		}
+		if err := rows.Err(); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, nil, err
+	}
+
+	updated, err := m.GetByID(persona.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return updated, chatIDs, nil
+}
+
+// Delete удаляет персону; чаты персоны продолжают работать с моделью, сохраненной в чате
+func (m PersonaModel) Delete(id int) error {
+	query := `DELETE FROM personas WHERE id = $1`
+	_, err := m.DB.Exec(query, id)
+	return err
+}
+
+func setPersonaCollections(tx *sql.Tx, personaID int, collectionIDs []int) error {
+	if len(collectionIDs) == 0 {
+		return nil
+	}
+
+	query := `
+		INSERT INTO persona_collections (persona_id, collection_id, created_at)
+		SELECT $1, unnest($2::int[]), CURRENT_TIMESTAMP
+		ON CONFLICT DO NOTHING`
+	_, err := tx.Exec(query, personaID, pq.Array(collectionIDs))
+	return err
+}
+
+func scanPersona(row rowScanner) (*Persona, error) {
+	var persona Persona
+	var temperature, topP sql.NullFloat64
+	var collectionIDs []int64
+	var createdAt, updatedAt sql.NullTime
+	err := row.Scan(
+		&persona.ID,
+		&persona.UserID,
+		&persona.Name,
+		&persona.Description,
+		&persona.AIModel,
+		&persona.SystemPrompt,
+		&temperature,
+		&topP,
+		&persona.MaxTokens,
+		pq.Array(&collectionIDs),
+		&createdAt,
+		&updatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if temperature.Valid {
+		persona.Temperature = &temperature.Float64
+	}
+	if topP.Valid {
+		persona.TopP = &topP.Float64
+	}
+	persona.CollectionIDs = make([]int, len(collectionIDs))
+	for i, id := range collectionIDs {
+		persona.CollectionIDs[i] = int(id)
+	}
+	if createdAt.Valid {
+		persona.CreatedAt = createdAt.Time
+	}
+	if updatedAt.Valid {
+		persona.UpdatedAt = updatedAt.Time
+	}
+
+	return &persona, nil
+}
@@ -0,0 +1,53 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+type PIIRedactionModel struct {
+	DB *sql.DB
+}
+
+// PIIRedactionEvent - запись аудита о персональных данных, удаленных из запроса перед отправкой провайдеру
+type PIIRedactionEvent struct {
+	ID        int            `json:"id"`
+	UserID    *int           `json:"user_id,omitempty"`
+	ChatID    *int           `json:"chat_id,omitempty"`
+	Provider  string         `json:"provider"`
+	Model     string         `json:"model"`
+	Entities  map[string]int `json:"entities"` // тип -> количество значений
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+// Insert сохраняет запись аудита
+func (m PIIRedactionModel) Insert(event *PIIRedactionEvent) error {
+	entities, err := json.Marshal(event.Entities)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO pii_redaction_events (user_id, chat_id, provider, model, entities, created_at)
+		VALUES ($1, $2, $3, $4, $5, CURRENT_TIMESTAMP)
+		RETURNING id, created_at`
+
+	var createdAt sql.NullTime
+	err = m.DB.QueryRow(query,
+		event.UserID,
+		event.ChatID,
+		event.Provider,
+		event.Model,
+		entities,
+	).Scan(&event.ID, &createdAt)
+	if err != nil {
+		return err
+	}
+
+	if createdAt.Valid {
+		event.CreatedAt = createdAt.Time
+	}
+
+	return nil
+}
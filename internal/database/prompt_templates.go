@@ -0,0 +1,328 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Области видимости шаблонов промптов для фильтра списка
+const (
+	PromptScopePersonal  = "personal"
+	PromptScopeWorkspace = "workspace"
+	PromptScopeGlobal    = "global"
+)
+
+type PromptTemplateModel struct {
+	DB *sql.DB
+}
+
+// PromptTemplate - шаблон промпта с текстом и переменными текущей версии
+type PromptTemplate struct {
+	ID     int `json:"id"`
+	UserID int `json:"user_id"`
+	// WorkspaceID задан, если шаблон доступен участникам рабочего пространства
+	WorkspaceID *int   `json:"workspace_id,omitempty"`
+	IsGlobal    bool   `json:"is_global"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Version     int    `json:"version"`
+	Content     string `json:"content"`
+	// Variables - JSON массив описаний переменных (prompts.Variable)
+	Variables json.RawMessage `json:"variables"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+// PromptTemplateVersion - неизменяемая версия текста шаблона
+type PromptTemplateVersion struct {
+	TemplateID int             `json:"template_id"`
+	Version    int             `json:"version"`
+	Content    string          `json:"content"`
+	Variables  json.RawMessage `json:"variables"`
+	CreatedBy  *int            `json:"created_by,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+const promptTemplateColumns = `t.id, t.user_id, t.workspace_id, t.is_global, t.name, t.description,
+	t.current_version, v.content, v.variables, t.created_at, t.updated_at`
+
+// Create создает шаблон и его первую версию
+func (m PromptTemplateModel) Create(template *PromptTemplate) (*PromptTemplate, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var id int
+	query := `
+		INSERT INTO prompt_templates (user_id, workspace_id, is_global, name, description,
+		                              current_version, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, 1, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+		RETURNING id`
+	err = tx.QueryRow(query,
+		template.UserID,
+		template.WorkspaceID,
+		template.IsGlobal,
+		template.Name,
+		template.Description,
+	).Scan(&id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := insertPromptVersion(tx, id, 1, template.Content, template.Variables, template.UserID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return m.GetByID(id)
+}
+
+// GetByID получает шаблон с текущей версией
+func (m PromptTemplateModel) GetByID(id int) (*PromptTemplate, error) {
+	query := `SELECT ` + promptTemplateColumns + `
+		FROM prompt_templates t
+		JOIN prompt_template_versions v ON v.template_id = t.id AND v.version = t.current_version
+		WHERE t.id = $1`
+
+	template, err := scanPromptTemplate(m.DB.QueryRow(query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("prompt template not found")
+		}
+		return nil, err
+	}
+
+	return template, nil
+}
+
+// GetAvailable получает шаблоны, доступные пользователю: личные, глобальные и шаблоны его рабочих пространств
+// scope ограничивает список одной областью видимости, search ищет по названию, описанию и тексту
+func (m PromptTemplateModel) GetAvailable(userID int, scope, search string) ([]*PromptTemplate, error) {
+	memberOf := `t.workspace_id IN (SELECT workspace_id FROM workspace_members WHERE user_id = $1)`
+
+	var conditions []string
+	switch scope {
+	case PromptScopePersonal:
+		conditions = append(conditions, `t.user_id = $1 AND t.workspace_id IS NULL AND NOT t.is_global`)
+	case PromptScopeWorkspace:
+		conditions = append(conditions, memberOf)
+	case PromptScopeGlobal:
+		conditions = append(conditions, `t.is_global`)
+	case "":
+		conditions = append(conditions, `(t.user_id = $1 OR t.is_global OR `+memberOf+`)`)
+	default:
+		return nil, fmt.Errorf("unknown prompt scope: %s", scope)
+	}
+
+	args := []interface{}{userID}
+	if search = strings.TrimSpace(search); search != "" {
+		args = append(args, "%"+escapeLike(search)+"%")
+		conditions = append(conditions, `(t.name ILIKE $2 OR t.description ILIKE $2 OR v.content ILIKE $2)`)
+	}
+
+	query := `SELECT ` + promptTemplateColumns + `
+		FROM prompt_templates t
+		JOIN prompt_template_versions v ON v.template_id = t.id AND v.version = t.current_version
+		WHERE ` + strings.Join(conditions, " AND ") + `
+		ORDER BY t.updated_at DESC`
+
+	rows, err := m.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var templates []*PromptTemplate
+	for rows.Next() {
+		template, err := scanPromptTemplate(rows)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, template)
+	}
+
+	return templates, rows.Err()
+}
+
+// Update сохраняет название, описание и видимость шаблона
+// Если изменились текст или переменные, создается новая версия от имени editorID
+func (m PromptTemplateModel) Update(template *PromptTemplate, newVersion bool, editorID int) (*PromptTemplate, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE prompt_templates
+		SET name = $1, description = $2, workspace_id = $3, is_global = $4,
+		    current_version = current_version + CASE WHEN $5 THEN 1 ELSE 0 END,
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE id = $6
+		RETURNING current_version`
+
+	var version int
+	err = tx.QueryRow(query,
+		template.Name,
+		template.Description,
+		template.WorkspaceID,
+		template.IsGlobal,
+		newVersion,
+		template.ID,
+	).Scan(&version)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("prompt template not found")
+		}
+		return nil, err
+	}
+
+	if newVersion {
+		if err := insertPromptVersion(tx, template.ID, version, template.Content, template.Variables, editorID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return m.GetByID(template.ID)
+}
+
+// Delete удаляет шаблон со всеми версиями
+func (m PromptTemplateModel) Delete(id int) error {
+	query := `DELETE FROM prompt_templates WHERE id = $1`
+	_, err := m.DB.Exec(query, id)
+	return err
+}
+
+// GetVersion получает конкретную версию шаблона
+func (m PromptTemplateModel) GetVersion(templateID, version int) (*PromptTemplateVersion, error) {
+	query := `
+		SELECT template_id, version, content, variables, created_by, created_at
+		FROM prompt_template_versions
+		WHERE template_id = $1 AND version = $2`
+
+	v, err := scanPromptVersion(m.DB.QueryRow(query, templateID, version))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("prompt template version not found")
+		}
+		return nil, err
+	}
+
+	return v, nil
+}
+
+// GetVersions получает историю версий шаблона, начиная с последней
+func (m PromptTemplateModel) GetVersions(templateID int) ([]*PromptTemplateVersion, error) {
+	query := `
+		SELECT template_id, version, content, variables, created_by, created_at
+		FROM prompt_template_versions
+		WHERE template_id = $1
+		ORDER BY version DESC`
+
+	rows, err := m.DB.Query(query, templateID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var versions []*PromptTemplateVersion
+	for rows.Next() {
+		v, err := scanPromptVersion(rows)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, v)
+	}
+
+	return versions, rows.Err()
+}
+
+func insertPromptVersion(tx *sql.Tx, templateID, version int, content string, variables json.RawMessage, createdBy int) error {
+	if len(variables) == 0 {
+		variables = json.RawMessage("[]")
+	}
+
+	query := `
+		INSERT INTO prompt_template_versions (template_id, version, content, variables, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, CURRENT_TIMESTAMP)`
+	_, err := tx.Exec(query, templateID, version, content, []byte(variables), createdBy)
+	return err
+}
+
+// escapeLike экранирует спецсимволы шаблона LIKE
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+func scanPromptTemplate(row rowScanner) (*PromptTemplate, error) {
+	var template PromptTemplate
+	var workspaceID sql.NullInt64
+	var variables []byte
+	var createdAt, updatedAt sql.NullTime
+	err := row.Scan(
+		&template.ID,
+		&template.UserID,
+		&workspaceID,
+		&template.IsGlobal,
+		&template.Name,
+		&template.Description,
+		&template.Version,
+		&template.Content,
+		&variables,
+		&createdAt,
+		&updatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	template.WorkspaceID = nullIntPtr(workspaceID)
+	template.Variables = variables
+	if createdAt.Valid {
+		template.CreatedAt = createdAt.Time
+	}
+	if updatedAt.Valid {
+		template.UpdatedAt = updatedAt.Time
+	}
+
+	return &template, nil
+}
+
+func scanPromptVersion(row rowScanner) (*PromptTemplateVersion, error) {
+	var v PromptTemplateVersion
+	var variables []byte
+	var createdBy sql.NullInt64
+	var createdAt sql.NullTime
+	err := row.Scan(
+		&v.TemplateID,
+		&v.Version,
+		&v.Content,
+		&variables,
+		&createdBy,
+		&createdAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	v.Variables = variables
+	v.CreatedBy = nullIntPtr(createdBy)
+	if createdAt.Valid {
+		v.CreatedAt = createdAt.Time
+	}
+
+	return &v, nil
+}
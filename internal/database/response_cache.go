@@ -0,0 +1,51 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// ResponseCacheModel хранит кэш ответов AI в PostgreSQL, общий для всех реплик
+// Реализует ai.CacheStore
+type ResponseCacheModel struct {
+	DB *sql.DB
+}
+
+// Get возвращает закэшированный ответ, если он есть и не истек
+func (m ResponseCacheModel) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	query := `SELECT response FROM ai_response_cache WHERE cache_key = $1 AND expires_at > CURRENT_TIMESTAMP`
+
+	var value []byte
+	err := m.DB.QueryRowContext(ctx, query, key).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	return value, true, nil
+}
+
+// Set сохраняет ответ в кэш, перезаписывая существующую запись
+func (m ResponseCacheModel) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	query := `
+		INSERT INTO ai_response_cache (cache_key, response, created_at, expires_at)
+		VALUES ($1, $2, CURRENT_TIMESTAMP, $3)
+		ON CONFLICT (cache_key) DO UPDATE
+		SET response = EXCLUDED.response, created_at = EXCLUDED.created_at, expires_at = EXCLUDED.expires_at`
+
+	_, err := m.DB.ExecContext(ctx, query, key, value, time.Now().Add(ttl))
+	return err
+}
+
+// DeleteExpired удаляет истекшие записи и возвращает их количество
+func (m ResponseCacheModel) DeleteExpired() (int64, error) {
+	result, err := m.DB.Exec(`DELETE FROM ai_response_cache WHERE expires_at <= CURRENT_TIMESTAMP`)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,181 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Маркеры подсветки совпадений во фрагментах результатов поиска
+const (
+	SearchHighlightStart = "<mark>"
+	SearchHighlightStop  = "</mark>"
+)
+
+type SearchModel struct {
+	DB *sql.DB
+}
+
+// SearchQuery - параметры полнотекстового поиска по чатам пользователя
+// Пустые фильтры не применяются
+type SearchQuery struct {
+	Text   string
+	Model  string
+	Role   string
+	From   *time.Time
+	To     *time.Time
+	Limit  int
+	Offset int
+}
+
+// ChatSearchResult - чат, название которого совпало с запросом
+type ChatSearchResult struct {
+	ChatID    int       `json:"chat_id"`
+	Title     string    `json:"title"`
+	Snippet   string    `json:"snippet"`
+	AIModel   string    `json:"ai_model"`
+	Rank      float64   `json:"rank"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// MessageSearchResult - сообщение, текст которого совпал с запросом
+type MessageSearchResult struct {
+	ChatID    int       `json:"chat_id"`
+	ChatTitle string    `json:"chat_title"`
+	MessageID int       `json:"message_id"`
+	Role      string    `json:"role"`
+	Model     string    `json:"model,omitempty"`
+	Snippet   string    `json:"snippet"`
+	Rank      float64   `json:"rank"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// searchTSQuery объединяет разбор запроса русской и английской конфигурациями, чтобы находились
+// словоформы обоих языков. Синтаксис запроса как в веб-поиске: "фраза", -исключение, OR
+const searchTSQuery = `(websearch_to_tsquery('russian', $2) || websearch_to_tsquery('english', $2))`
+
+// searchMatch возвращает условие совпадения для текстовой колонки; выражения совпадают с GIN индексами
+func searchMatch(column string) string {
+	return fmt.Sprintf(`(to_tsvector('russian', %[1]s) @@ websearch_to_tsquery('russian', $2)
+		OR to_tsvector('english', %[1]s) @@ websearch_to_tsquery('english', $2))`, column)
+}
+
+// searchRank возвращает выражение релевантности для текстовой колонки
+func searchRank(column string) string {
+	return fmt.Sprintf(`GREATEST(ts_rank(to_tsvector('russian', %[1]s), websearch_to_tsquery('russian', $2)),
+		ts_rank(to_tsvector('english', %[1]s), websearch_to_tsquery('english', $2)))`, column)
+}
+
+// searchHeadline возвращает выражение фрагмента текста с подсвеченными совпадениями
+func searchHeadline(column string) string {
+	return fmt.Sprintf(`ts_headline('russian', %s, %s,
+		'StartSel=%s, StopSel=%s, MaxWords=35, MinWords=15, MaxFragments=2')`,
+		column, searchTSQuery, SearchHighlightStart, SearchHighlightStop)
+}
+
+// SearchChats ищет чаты пользователя по названию; чаты из корзины не ищутся
+// Фильтры: Model - модель чата, From/To - время последнего обновления
+func (m SearchModel) SearchChats(userID int, q SearchQuery) ([]*ChatSearchResult, error) {
+	conditions := []string{`c.user_id = $1`, `c.deleted_at IS NULL`, searchMatch("c.title")}
+	args := []interface{}{userID, q.Text}
+
+	if q.Model != "" {
+		args = append(args, q.Model)
+		conditions = append(conditions, fmt.Sprintf(`c.ai_model = $%d`, len(args)))
+	}
+	if q.From != nil {
+		args = append(args, *q.From)
+		conditions = append(conditions, fmt.Sprintf(`c.updated_at >= $%d`, len(args)))
+	}
+	if q.To != nil {
+		args = append(args, *q.To)
+		conditions = append(conditions, fmt.Sprintf(`c.updated_at < $%d`, len(args)))
+	}
+
+	args = append(args, q.Limit, q.Offset)
+	query := fmt.Sprintf(`
+		SELECT c.id, c.title, %s, c.ai_model, %s AS rank, c.updated_at
+		FROM chats c
+		WHERE %s
+		ORDER BY rank DESC, c.updated_at DESC
+		LIMIT $%d OFFSET $%d`,
+		searchHeadline("c.title"), searchRank("c.title"), strings.Join(conditions, " AND "), len(args)-1, len(args))
+
+	rows, err := m.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []*ChatSearchResult
+	for rows.Next() {
+		var r ChatSearchResult
+		var updatedAt sql.NullTime
+		if err := rows.Scan(&r.ChatID, &r.Title, &r.Snippet, &r.AIModel, &r.Rank, &updatedAt); err != nil {
+			return nil, err
+		}
+		if updatedAt.Valid {
+			r.UpdatedAt = updatedAt.Time
+		}
+		results = append(results, &r)
+	}
+
+	return results, rows.Err()
+}
+
+// SearchMessages ищет сообщения в чатах пользователя
+// Фильтры: Model - модель ответа или чата, Role - роль автора, From/To - время сообщения.
+// Ответы, заблокированные модерацией, и сообщения чатов из корзины не ищутся
+func (m SearchModel) SearchMessages(userID int, q SearchQuery) ([]*MessageSearchResult, error) {
+	conditions := []string{`c.user_id = $1`, `c.deleted_at IS NULL`, `m.moderation_status IS NULL`, searchMatch("m.content")}
+	args := []interface{}{userID, q.Text}
+
+	if q.Model != "" {
+		args = append(args, q.Model)
+		conditions = append(conditions, fmt.Sprintf(`(m.model = $%[1]d OR (m.model = '' AND c.ai_model = $%[1]d))`, len(args)))
+	}
+	if q.Role != "" {
+		args = append(args, q.Role)
+		conditions = append(conditions, fmt.Sprintf(`m.role = $%d`, len(args)))
+	}
+	if q.From != nil {
+		args = append(args, *q.From)
+		conditions = append(conditions, fmt.Sprintf(`m.created_at >= $%d`, len(args)))
+	}
+	if q.To != nil {
+		args = append(args, *q.To)
+		conditions = append(conditions, fmt.Sprintf(`m.created_at < $%d`, len(args)))
+	}
+
+	args = append(args, q.Limit, q.Offset)
+	query := fmt.Sprintf(`
+		SELECT c.id, c.title, m.id, m.role, m.model, %s, %s AS rank, m.created_at
+		FROM messages m
+		JOIN chats c ON c.id = m.chat_id
+		WHERE %s
+		ORDER BY rank DESC, m.created_at DESC
+		LIMIT $%d OFFSET $%d`,
+		searchHeadline("m.content"), searchRank("m.content"), strings.Join(conditions, " AND "), len(args)-1, len(args))
+
+	rows, err := m.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []*MessageSearchResult
+	for rows.Next() {
+		var r MessageSearchResult
+		var createdAt sql.NullTime
+		if err := rows.Scan(&r.ChatID, &r.ChatTitle, &r.MessageID, &r.Role, &r.Model, &r.Snippet, &r.Rank, &createdAt); err != nil {
+			return nil, err
+		}
+		if createdAt.Valid {
+			r.CreatedAt = createdAt.Time
+		}
+		results = append(results, &r)
+	}
+
+	return results, rows.Err()
+}
@@ -0,0 +1,166 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type TagModel struct {
+	DB *sql.DB
+}
+
+// Tag - тег для группировки чатов пользователя
+type Tag struct {
+	ID     int    `json:"id"`
+	UserID int    `json:"user_id"`
+	Name   string `json:"name"`
+	// Color - цвет тега в формате #rrggbb, пустая строка - цвет по умолчанию клиента
+	Color      string    `json:"color"`
+	ChatsCount int       `json:"chats_count"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+const tagColumns = `t.id, t.user_id, t.name, t.color,
+	(SELECT COUNT(*) FROM chat_tag_links l JOIN chats c ON c.id = l.chat_id
+	 WHERE l.tag_id = t.id AND c.deleted_at IS NULL),
+	t.created_at`
+
+// Insert создает тег
+func (m TagModel) Insert(tag *Tag) (*Tag, error) {
+	query := `
+		INSERT INTO chat_tags (user_id, name, color, created_at)
+		VALUES ($1, $2, $3, CURRENT_TIMESTAMP)
+		RETURNING id`
+
+	var id int
+	if err := m.DB.QueryRow(query, tag.UserID, tag.Name, tag.Color).Scan(&id); err != nil {
+		return nil, err
+	}
+
+	return m.GetByID(id)
+}
+
+// GetByID получает тег по ID
+func (m TagModel) GetByID(id int) (*Tag, error) {
+	query := `SELECT ` + tagColumns + `
+		FROM chat_tags t
+		WHERE t.id = $1`
+
+	tag, err := scanTag(m.DB.QueryRow(query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("tag not found")
+		}
+		return nil, err
+	}
+
+	return tag, nil
+}
+
+// GetByName получает тег пользователя по названию
+func (m TagModel) GetByName(userID int, name string) (*Tag, error) {
+	query := `SELECT ` + tagColumns + `
+		FROM chat_tags t
+		WHERE t.user_id = $1 AND t.name = $2`
+
+	tag, err := scanTag(m.DB.QueryRow(query, userID, name))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("tag not found")
+		}
+		return nil, err
+	}
+
+	return tag, nil
+}
+
+// GetByUserID получает все теги пользователя
+func (m TagModel) GetByUserID(userID int) ([]*Tag, error) {
+	query := `SELECT ` + tagColumns + `
+		FROM chat_tags t
+		WHERE t.user_id = $1
+		ORDER BY t.name ASC`
+
+	rows, err := m.DB.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tags []*Tag
+	for rows.Next() {
+		tag, err := scanTag(rows)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+
+	return tags, rows.Err()
+}
+
+// Update сохраняет название и цвет тега
+func (m TagModel) Update(tag *Tag) (*Tag, error) {
+	query := `UPDATE chat_tags SET name = $1, color = $2 WHERE id = $3`
+	if _, err := m.DB.Exec(query, tag.Name, tag.Color, tag.ID); err != nil {
+		return nil, err
+	}
+
+	return m.GetByID(tag.ID)
+}
+
+// Delete удаляет тег и снимает его со всех чатов
+func (m TagModel) Delete(id int) error {
+	_, err := m.DB.Exec(`DELETE FROM chat_tags WHERE id = $1`, id)
+	return err
+}
+
+// SetChatTags заменяет набор тегов чата
+func (m TagModel) SetChatTags(chatID int, tagIDs []int) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM chat_tag_links WHERE chat_id = $1`, chatID); err != nil {
+		return err
+	}
+
+	if len(tagIDs) > 0 {
+		query := `
+			INSERT INTO chat_tag_links (chat_id, tag_id, created_at)
+			SELECT $1, unnest($2::int[]), CURRENT_TIMESTAMP
+			ON CONFLICT DO NOTHING`
+		if _, err := tx.Exec(query, chatID, pq.Array(tagIDs)); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func scanTag(row rowScanner) (*Tag, error) {
+	var tag Tag
+	var createdAt sql.NullTime
+	err := row.Scan(
+		&tag.ID,
+		&tag.UserID,
+		&tag.Name,
+		&tag.Color,
+		&tag.ChatsCount,
+		&createdAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if createdAt.Valid {
+		tag.CreatedAt = createdAt.Time
+	}
+
+	return &tag, nil
+}
@@ -0,0 +1,102 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Источники расхода токенов
+const (
+	UsageSourceChat       = "chat"
+	UsageSourceCompletion = "completion"
+)
+
+type UsageModel struct {
+	DB *sql.DB
+}
+
+type UsageRecord struct {
+	ID     int `json:"id"`
+	UserID int `json:"user_id"`
+	// ChatID не задан для запросов без чата (completions)
+	ChatID           *int   `json:"chat_id,omitempty"`
+	Source           string `json:"source"`
+	Provider         string `json:"provider"`
+	Model            string `json:"model"`
+	PromptTokens     int    `json:"prompt_tokens"`
+	CompletionTokens int    `json:"completion_tokens"`
+	TotalTokens      int    `json:"total_tokens"`
+	// Cached - ответ получен из кэша, провайдер не вызывался
+	Cached    bool      `json:"cached"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// UsageSummary - суммарный расход токенов за период
+// Ответы из кэша не входят в Requests/*Tokens (и в квоту) и считаются отдельно
+type UsageSummary struct {
+	Requests         int `json:"requests"`
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+	CachedRequests   int `json:"cached_requests"`
+	CachedTokens     int `json:"cached_tokens"`
+}
+
+// Insert сохраняет запись о расходе токенов
+func (m UsageModel) Insert(record *UsageRecord) error {
+	query := `
+		INSERT INTO usage_records (user_id, chat_id, source, provider, model,
+		                           prompt_tokens, completion_tokens, total_tokens, cached, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, CURRENT_TIMESTAMP)
+		RETURNING id, created_at`
+
+	var createdAt sql.NullTime
+	err := m.DB.QueryRow(query,
+		record.UserID,
+		record.ChatID,
+		record.Source,
+		record.Provider,
+		record.Model,
+		record.PromptTokens,
+		record.CompletionTokens,
+		record.TotalTokens,
+		record.Cached,
+	).Scan(&record.ID, &createdAt)
+	if err != nil {
+		return err
+	}
+
+	if createdAt.Valid {
+		record.CreatedAt = createdAt.Time
+	}
+
+	return nil
+}
+
+// GetSummary возвращает суммарный расход токенов пользователя начиная с since
+func (m UsageModel) GetSummary(userID int, since time.Time) (*UsageSummary, error) {
+	query := `
+		SELECT COUNT(*) FILTER (WHERE NOT cached),
+		       COALESCE(SUM(prompt_tokens) FILTER (WHERE NOT cached), 0),
+		       COALESCE(SUM(completion_tokens) FILTER (WHERE NOT cached), 0),
+		       COALESCE(SUM(total_tokens) FILTER (WHERE NOT cached), 0),
+		       COUNT(*) FILTER (WHERE cached),
+		       COALESCE(SUM(total_tokens) FILTER (WHERE cached), 0)
+		FROM usage_records
+		WHERE user_id = $1 AND created_at >= $2`
+
+	var summary UsageSummary
+	err := m.DB.QueryRow(query, userID, since).Scan(
+		&summary.Requests,
+		&summary.PromptTokens,
+		&summary.CompletionTokens,
+		&summary.TotalTokens,
+		&summary.CachedRequests,
+		&summary.CachedTokens,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &summary, nil
+}
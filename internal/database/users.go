@@ -46,6 +46,7 @@ type User struct {
 	Username     string    `json:"username"`
 	Email        string    `json:"email"`
 	PasswordHash string    `json:"-"`
+	IsAdmin      bool      `json:"is_admin"`
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
 }
@@ -68,7 +69,7 @@ func (m UserModel) Create(username, email, passwordHash string) (*User, error) {
 
 func (m UserModel) GetByEmail(email string) (*User, error) {
 	query := `
-		SELECT id, username, email, password_hash, created_at, updated_at
+		SELECT id, username, email, password_hash, is_admin, created_at, updated_at
 		FROM users
 		WHERE email = $1`
 
@@ -79,6 +80,7 @@ func (m UserModel) GetByEmail(email string) (*User, error) {
 		&user.Username,
 		&user.Email,
 		&user.PasswordHash,
+		&user.IsAdmin,
 		&createdAt,
 		&updatedAt,
 	)
@@ -103,7 +105,7 @@ func (m UserModel) GetByEmail(email string) (*User, error) {
 // GetByUsername получает пользователя по username
 func (m UserModel) GetByUsername(username string) (*User, error) {
 	query := `
-		SELECT id, username, email, password_hash, created_at, updated_at
+		SELECT id, username, email, password_hash, is_admin, created_at, updated_at
 		FROM users
 		WHERE username = $1`
 
@@ -114,6 +116,7 @@ func (m UserModel) GetByUsername(username string) (*User, error) {
 		&user.Username,
 		&user.Email,
 		&user.PasswordHash,
+		&user.IsAdmin,
 		&createdAt,
 		&updatedAt,
 	)
@@ -137,7 +140,7 @@ func (m UserModel) GetByUsername(username string) (*User, error) {
 
 func (m UserModel) GetByID(id int) (*User, error) {
 	query := `
-		SELECT id, username, email, password_hash, created_at, updated_at
+		SELECT id, username, email, password_hash, is_admin, created_at, updated_at
 		FROM users
 		WHERE id = $1`
 
@@ -148,6 +151,7 @@ func (m UserModel) GetByID(id int) (*User, error) {
 		&user.Username,
 		&user.Email,
 		&user.PasswordHash,
+		&user.IsAdmin,
 		&createdAt,
 		&updatedAt,
 	)
@@ -0,0 +1,197 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Роли участников рабочего пространства
+const (
+	WorkspaceRoleOwner  = "owner"
+	WorkspaceRoleMember = "member"
+)
+
+type WorkspaceModel struct {
+	DB *sql.DB
+}
+
+type Workspace struct {
+	ID        int       `json:"id"`
+	OwnerID   int       `json:"owner_id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type WorkspaceMember struct {
+	UserID    int       `json:"user_id"`
+	Username  string    `json:"username"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Create создает рабочее пространство; владелец сразу становится его участником
+func (m WorkspaceModel) Create(ownerID int, name string) (*Workspace, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var id int
+	query := `
+		INSERT INTO workspaces (owner_id, name, created_at, updated_at)
+		VALUES ($1, $2, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+		RETURNING id`
+	if err := tx.QueryRow(query, ownerID, name).Scan(&id); err != nil {
+		return nil, err
+	}
+
+	query = `
+		INSERT INTO workspace_members (workspace_id, user_id, role, created_at)
+		VALUES ($1, $2, $3, CURRENT_TIMESTAMP)`
+	if _, err := tx.Exec(query, id, ownerID, WorkspaceRoleOwner); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return m.GetByID(id)
+}
+
+// GetByID получает рабочее пространство по ID
+func (m WorkspaceModel) GetByID(id int) (*Workspace, error) {
+	query := `
+		SELECT id, owner_id, name, created_at, updated_at
+		FROM workspaces
+		WHERE id = $1`
+
+	workspace, err := scanWorkspace(m.DB.QueryRow(query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("workspace not found")
+		}
+		return nil, err
+	}
+
+	return workspace, nil
+}
+
+// GetByUserID получает рабочие пространства, в которых состоит пользователь
+func (m WorkspaceModel) GetByUserID(userID int) ([]*Workspace, error) {
+	query := `
+		SELECT w.id, w.owner_id, w.name, w.created_at, w.updated_at
+		FROM workspaces w
+		JOIN workspace_members wm ON wm.workspace_id = w.id
+		WHERE wm.user_id = $1
+		ORDER BY w.name ASC`
+
+	rows, err := m.DB.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var workspaces []*Workspace
+	for rows.Next() {
+		workspace, err := scanWorkspace(rows)
+		if err != nil {
+			return nil, err
+		}
+		workspaces = append(workspaces, workspace)
+	}
+
+	return workspaces, rows.Err()
+}
+
+// Delete удаляет рабочее пространство; общие шаблоны остаются личными шаблонами авторов
+func (m WorkspaceModel) Delete(id int) error {
+	query := `DELETE FROM workspaces WHERE id = $1`
+	_, err := m.DB.Exec(query, id)
+	return err
+}
+
+// IsMember проверяет, состоит ли пользователь в рабочем пространстве
+func (m WorkspaceModel) IsMember(workspaceID, userID int) (bool, error) {
+	query := `SELECT EXISTS(SELECT 1 FROM workspace_members WHERE workspace_id = $1 AND user_id = $2)`
+
+	var exists bool
+	err := m.DB.QueryRow(query, workspaceID, userID).Scan(&exists)
+	return exists, err
+}
+
+// AddMember добавляет пользователя в рабочее пространство (повторное добавление ничего не меняет)
+func (m WorkspaceModel) AddMember(workspaceID, userID int) error {
+	query := `
+		INSERT INTO workspace_members (workspace_id, user_id, role, created_at)
+		VALUES ($1, $2, $3, CURRENT_TIMESTAMP)
+		ON CONFLICT DO NOTHING`
+	_, err := m.DB.Exec(query, workspaceID, userID, WorkspaceRoleMember)
+	return err
+}
+
+// RemoveMember исключает участника; владельца исключить нельзя
+func (m WorkspaceModel) RemoveMember(workspaceID, userID int) error {
+	query := `DELETE FROM workspace_members WHERE workspace_id = $1 AND user_id = $2 AND role <> $3`
+	_, err := m.DB.Exec(query, workspaceID, userID, WorkspaceRoleOwner)
+	return err
+}
+
+// GetMembers получает участников рабочего пространства
+func (m WorkspaceModel) GetMembers(workspaceID int) ([]*WorkspaceMember, error) {
+	query := `
+		SELECT u.id, u.username, u.email, wm.role, wm.created_at
+		FROM workspace_members wm
+		JOIN users u ON u.id = wm.user_id
+		WHERE wm.workspace_id = $1
+		ORDER BY wm.created_at ASC`
+
+	rows, err := m.DB.Query(query, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var members []*WorkspaceMember
+	for rows.Next() {
+		var member WorkspaceMember
+		var createdAt sql.NullTime
+		if err := rows.Scan(&member.UserID, &member.Username, &member.Email, &member.Role, &createdAt); err != nil {
+			return nil, err
+		}
+		if createdAt.Valid {
+			member.CreatedAt = createdAt.Time
+		}
+		members = append(members, &member)
+	}
+
+	return members, rows.Err()
+}
+
+func scanWorkspace(row rowScanner) (*Workspace, error) {
+	var workspace Workspace
+	var createdAt, updatedAt sql.NullTime
+	err := row.Scan(
+		&workspace.ID,
+		&workspace.OwnerID,
+		&workspace.Name,
+		&createdAt,
+		&updatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if createdAt.Valid {
+		workspace.CreatedAt = createdAt.Time
+	}
+	if updatedAt.Valid {
+		workspace.UpdatedAt = updatedAt.Time
+	}
+
+	return &workspace, nil
+}
@@ -1,6 +1,7 @@
 package env
 
 import (
+	"fmt"
 	"os"
 	"strconv"
 )
@@ -21,3 +22,15 @@ func GetEnvInt(key string, defaultValue int) int {
 
 	return defaultValue
 }
+
+func GetEnvBool(key string, defaultValue bool) (bool, error) {
+	if value, exists := os.LookupEnv(key); exists && value != "" {
+		boolValue, err := strconv.ParseBool(value)
+		if err != nil {
+			return defaultValue, fmt.Errorf("%s: invalid boolean %q", key, value)
+		}
+		return boolValue, nil
+	}
+
+	return defaultValue, nil
+}
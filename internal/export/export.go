@@ -0,0 +1,161 @@
+package export
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Format - формат экспорта чата
+type Format string
+
+const (
+	FormatMarkdown Format = "markdown"
+	FormatJSON     Format = "json"
+	FormatHTML     Format = "html"
+)
+
+// Идентификатор и версия собственного JSON формата экспорта; по ним формат распознается при импорте
+const (
+	DocumentFormat  = "mindforge.chat"
+	DocumentVersion = 1
+)
+
+// ParseFormat разбирает название формата; пустая строка означает Markdown
+func ParseFormat(s string) (Format, error) {
+	switch Format(strings.ToLower(s)) {
+	case "", FormatMarkdown, "md":
+		return FormatMarkdown, nil
+	case FormatJSON:
+		return FormatJSON, nil
+	case FormatHTML:
+		return FormatHTML, nil
+	}
+	return "", fmt.Errorf("unsupported export format: %s", s)
+}
+
+// Extension возвращает расширение файла формата
+func (f Format) Extension() string {
+	switch f {
+	case FormatJSON:
+		return "json"
+	case FormatHTML:
+		return "html"
+	default:
+		return "md"
+	}
+}
+
+// ContentType возвращает MIME-тип формата
+func (f Format) ContentType() string {
+	switch f {
+	case FormatJSON:
+		return "application/json; charset=utf-8"
+	case FormatHTML:
+		return "text/html; charset=utf-8"
+	default:
+		return "text/markdown; charset=utf-8"
+	}
+}
+
+// Chat - переписка в виде, не зависящем от хранения в БД
+type Chat struct {
+	Title     string    `json:"title"`
+	AIModel   string    `json:"ai_model"`
+	Persona   string    `json:"persona,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Messages  []Message `json:"messages"`
+}
+
+// Message - сообщение переписки
+type Message struct {
+	Role      string    `json:"role"`
+	Content   string    `json:"content"`
+	Model     string    `json:"model,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	// References - документы базы знаний, на которые ссылается ответ
+	References []Reference `json:"references,omitempty"`
+}
+
+// Reference - ссылка на документ базы знаний
+type Reference struct {
+	Index         int    `json:"index"`
+	CollectionID  int    `json:"collection_id"`
+	DocumentID    int    `json:"document_id"`
+	DocumentTitle string `json:"document_title"`
+}
+
+// Document - корневой объект JSON экспорта
+type Document struct {
+	Format     string    `json:"format"`
+	Version    int       `json:"version"`
+	ExportedAt time.Time `json:"exported_at"`
+	Chat       *Chat     `json:"chat"`
+}
+
+// Write записывает переписку в выбранном формате
+func Write(w io.Writer, format Format, chat *Chat) error {
+	switch format {
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		encoder.SetEscapeHTML(false)
+		return encoder.Encode(Document{
+			Format:     DocumentFormat,
+			Version:    DocumentVersion,
+			ExportedAt: time.Now().UTC(),
+			Chat:       chat,
+		})
+	case FormatHTML:
+		return writeHTML(w, chat)
+	default:
+		return writeMarkdown(w, chat)
+	}
+}
+
+var unsafeFileNameRe = regexp.MustCompile(`[^\p{L}\p{N}._ -]+`)
+
+// FileName формирует имя файла экспорта из ID и названия чата
+func FileName(id int, title string, format Format) string {
+	name := strings.TrimSpace(unsafeFileNameRe.ReplaceAllString(title, ""))
+	if runes := []rune(name); len(runes) > 80 {
+		name = strings.TrimSpace(string(runes[:80]))
+	}
+	if name == "" {
+		return fmt.Sprintf("chat-%d.%s", id, format.Extension())
+	}
+	return fmt.Sprintf("chat-%d %s.%s", id, name, format.Extension())
+}
+
+// Archive - ZIP архив с экспортом нескольких чатов
+type Archive struct {
+	zw *zip.Writer
+}
+
+// NewArchive создает архив, записываемый в w
+func NewArchive(w io.Writer) *Archive {
+	return &Archive{zw: zip.NewWriter(w)}
+}
+
+// Add добавляет в архив файл с перепиской
+func (a *Archive) Add(name string, format Format, chat *Chat) error {
+	f, err := a.zw.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: chat.UpdatedAt,
+	})
+	if err != nil {
+		return err
+	}
+	return Write(f, format, chat)
+}
+
+// Close дописывает оглавление архива
+func (a *Archive) Close() error {
+	return a.zw.Close()
+}
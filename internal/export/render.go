@@ -0,0 +1,100 @@
+package export
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+	"time"
+)
+
+const timeLayout = "2006-01-02 15:04:05 MST"
+
+var roleTitles = map[string]string{
+	"user":      "Пользователь",
+	"assistant": "Ассистент",
+	"system":    "Система",
+}
+
+func roleTitle(role string) string {
+	if title, ok := roleTitles[role]; ok {
+		return title
+	}
+	return role
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(timeLayout)
+}
+
+func writeMarkdown(w io.Writer, chat *Chat) error {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# %s\n\n", chat.Title)
+	fmt.Fprintf(&b, "- Модель: %s\n", chat.AIModel)
+	if chat.Persona != "" {
+		fmt.Fprintf(&b, "- Персона: %s\n", chat.Persona)
+	}
+	fmt.Fprintf(&b, "- Создан: %s\n", formatTime(chat.CreatedAt))
+	fmt.Fprintf(&b, "- Обновлен: %s\n", formatTime(chat.UpdatedAt))
+
+	for _, msg := range chat.Messages {
+		fmt.Fprintf(&b, "\n---\n\n### %s", roleTitle(msg.Role))
+		if msg.Model != "" {
+			fmt.Fprintf(&b, " (%s)", msg.Model)
+		}
+		fmt.Fprintf(&b, "\n\n_%s_\n\n%s\n", formatTime(msg.CreatedAt), msg.Content)
+
+		if len(msg.References) > 0 {
+			b.WriteString("\nИсточники:\n")
+			for _, ref := range msg.References {
+				fmt.Fprintf(&b, "- [%d] %s (документ %d, коллекция %d)\n",
+					ref.Index, ref.DocumentTitle, ref.DocumentID, ref.CollectionID)
+			}
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+var htmlTemplate = template.Must(template.New("chat").Funcs(template.FuncMap{
+	"role": roleTitle,
+	"time": formatTime,
+}).Parse(`<!DOCTYPE html>
+<html lang="ru">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Roboto, sans-serif; max-width: 820px; margin: 2rem auto; padding: 0 1rem; color: #1f2328; }
+header { border-bottom: 1px solid #d0d7de; margin-bottom: 1.5rem; }
+.meta { color: #656d76; font-size: 0.9rem; }
+.message { border: 1px solid #d0d7de; border-radius: 8px; padding: 0.75rem 1rem; margin-bottom: 1rem; }
+.message.user { background: #f6f8fa; }
+.message h3 { margin: 0 0 0.25rem; font-size: 1rem; }
+.content { white-space: pre-wrap; word-wrap: break-word; }
+.references { font-size: 0.9rem; color: #656d76; }
+</style>
+</head>
+<body>
+<header>
+<h1>{{.Title}}</h1>
+<p class="meta">Модель: {{.AIModel}}{{if .Persona}} · Персона: {{.Persona}}{{end}}<br>
+Создан: {{time .CreatedAt}} · Обновлен: {{time .UpdatedAt}}</p>
+</header>
+{{range .Messages}}<section class="message {{.Role}}">
+<h3>{{role .Role}}{{if .Model}} ({{.Model}}){{end}}</h3>
+<p class="meta">{{time .CreatedAt}}</p>
+<div class="content">{{.Content}}</div>
+{{if .References}}<ul class="references">
+{{range .References}}<li>[{{.Index}}] {{.DocumentTitle}} (документ {{.DocumentID}}, коллекция {{.CollectionID}})</li>
+{{end}}</ul>
+{{end}}</section>
+{{end}}</body>
+</html>
+`))
+
+func writeHTML(w io.Writer, chat *Chat) error {
+	return htmlTemplate.Execute(w, chat)
+}
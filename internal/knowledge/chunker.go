@@ -0,0 +1,79 @@
+package knowledge
+
+import (
+	"strings"
+	"unicode"
+)
+
+// ChunkText разбивает текст на фрагменты размером до size символов с перекрытием overlap
+// Границы фрагментов стараемся ставить на абзацах, затем на предложениях и пробелах,
+// чтобы не резать слова посередине
+func ChunkText(text string, size, overlap int) []string {
+	text = strings.TrimSpace(strings.ReplaceAll(text, "\r\n", "\n"))
+	if text == "" || size <= 0 {
+		return nil
+	}
+	if overlap < 0 || overlap >= size {
+		overlap = 0
+	}
+
+	runes := []rune(text)
+	if len(runes) <= size {
+		return []string{text}
+	}
+
+	var chunks []string
+	start := 0
+	for start < len(runes) {
+		end := start + size
+		if end >= len(runes) {
+			end = len(runes)
+		} else {
+			end = findBoundary(runes, start, end)
+		}
+
+		chunk := strings.TrimSpace(string(runes[start:end]))
+		if chunk != "" {
+			chunks = append(chunks, chunk)
+		}
+
+		if end >= len(runes) {
+			break
+		}
+
+		// Следующий фрагмент начинается с перекрытием, но всегда продвигается вперед
+		next := end - overlap
+		if next <= start {
+			next = end
+		}
+		start = next
+	}
+
+	return chunks
+}
+
+// findBoundary ищет наиболее естественную границу фрагмента в его второй половине
+func findBoundary(runes []rune, start, end int) int {
+	min := start + (end-start)/2
+
+	// Абзац
+	for i := end; i > min; i-- {
+		if runes[i-1] == '\n' && i >= 2 && runes[i-2] == '\n' {
+			return i
+		}
+	}
+	// Конец предложения
+	for i := end; i > min; i-- {
+		if (runes[i-1] == '.' || runes[i-1] == '!' || runes[i-1] == '?') && unicode.IsSpace(runes[i]) {
+			return i
+		}
+	}
+	// Пробел
+	for i := end; i > min; i-- {
+		if unicode.IsSpace(runes[i-1]) {
+			return i
+		}
+	}
+
+	return end
+}
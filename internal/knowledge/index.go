@@ -0,0 +1,187 @@
+package knowledge
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"mindforge/internal/database"
+)
+
+// VectorIndex определяет интерфейс поиска ближайших фрагментов по эмбеддингу
+type VectorIndex interface {
+	// Search возвращает до limit фрагментов из указанных коллекций, отсортированных по убыванию близости
+	Search(ctx context.Context, collectionIDs []int, embedding []float32, limit int) ([]*database.ScoredChunk, error)
+
+	// Invalidate сбрасывает закешированные данные коллекции после изменения ее документов
+	Invalidate(collectionID int)
+
+	// Name возвращает название реализации (для логов)
+	Name() string
+}
+
+// PgVectorIndex выполняет поиск в PostgreSQL средствами расширения pgvector
+type PgVectorIndex struct {
+	documents database.DocumentModel
+}
+
+// NewPgVectorIndex создает индекс поверх pgvector
+func NewPgVectorIndex(documents database.DocumentModel) *PgVectorIndex {
+	return &PgVectorIndex{documents: documents}
+}
+
+// Name возвращает название реализации
+func (i *PgVectorIndex) Name() string {
+	return "pgvector"
+}
+
+// Invalidate ничего не делает: данные всегда читаются из БД
+func (i *PgVectorIndex) Invalidate(collectionID int) {}
+
+// Search ищет ближайшие фрагменты по косинусному расстоянию
+func (i *PgVectorIndex) Search(ctx context.Context, collectionIDs []int, embedding []float32, limit int) ([]*database.ScoredChunk, error) {
+	if len(collectionIDs) == 0 || len(embedding) == 0 || limit <= 0 {
+		return nil, nil
+	}
+	return i.documents.SearchChunksPgVector(collectionIDs, embedding, limit)
+}
+
+// MemoryIndex выполняет полный перебор фрагментов в памяти процесса
+// Фрагменты коллекции загружаются из БД при первом обращении и кешируются вместе со временем изменения
+// коллекции. Перед поиском время сверяется с БД, поэтому изменения документов на другой реплике
+// тоже сбрасывают кеш; Invalidate сбрасывает его на текущей реплике сразу
+type MemoryIndex struct {
+	documents database.DocumentModel
+
+	mu     sync.RWMutex
+	chunks map[int]cachedCollection
+}
+
+// cachedCollection - фрагменты коллекции и время ее изменения на момент загрузки
+type cachedCollection struct {
+	version time.Time
+	chunks  []*database.DocumentChunk
+}
+
+// NewMemoryIndex создает in-process индекс
+func NewMemoryIndex(documents database.DocumentModel) *MemoryIndex {
+	return &MemoryIndex{
+		documents: documents,
+		chunks:    make(map[int]cachedCollection),
+	}
+}
+
+// Name возвращает название реализации
+func (i *MemoryIndex) Name() string {
+	return "memory"
+}
+
+// Invalidate удаляет коллекцию из кеша
+func (i *MemoryIndex) Invalidate(collectionID int) {
+	i.mu.Lock()
+	delete(i.chunks, collectionID)
+	i.mu.Unlock()
+}
+
+// Search ищет ближайшие фрагменты по косинусной близости
+func (i *MemoryIndex) Search(ctx context.Context, collectionIDs []int, embedding []float32, limit int) ([]*database.ScoredChunk, error) {
+	if len(collectionIDs) == 0 || len(embedding) == 0 || limit <= 0 {
+		return nil, nil
+	}
+
+	versions, err := i.documents.GetCollectionVersions(collectionIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []*database.ScoredChunk
+	for _, collectionID := range collectionIDs {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		version, ok := versions[collectionID]
+		if !ok {
+			// Коллекция удалена
+			i.Invalidate(collectionID)
+			continue
+		}
+
+		chunks, err := i.load(collectionID, version)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, chunk := range chunks {
+			// Фрагменты другой размерности (например, после смены модели) пропускаем
+			if len(chunk.Embedding) != len(embedding) {
+				continue
+			}
+			results = append(results, &database.ScoredChunk{
+				DocumentChunk: *chunk,
+				Score:         cosineSimilarity(embedding, chunk.Embedding),
+			})
+		}
+	}
+
+	sort.Slice(results, func(a, b int) bool {
+		return results[a].Score > results[b].Score
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+
+	// Подтягиваем названия документов только для попавших в выдачу фрагментов
+	ids := make([]int, 0, len(results))
+	for _, r := range results {
+		ids = append(ids, r.DocumentID)
+	}
+	titles, err := i.documents.GetTitles(ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range results {
+		r.DocumentTitle = titles[r.DocumentID]
+	}
+
+	return results, nil
+}
+
+// load возвращает фрагменты коллекции из кеша, если он не старше version, иначе из БД
+// version прочитана до загрузки фрагментов: изменение во время загрузки даст более новую версию
+// при следующем поиске, и фрагменты загрузятся снова
+func (i *MemoryIndex) load(collectionID int, version time.Time) ([]*database.DocumentChunk, error) {
+	i.mu.RLock()
+	cached, ok := i.chunks[collectionID]
+	i.mu.RUnlock()
+	if ok && !cached.version.Before(version) {
+		return cached.chunks, nil
+	}
+
+	chunks, err := i.documents.GetChunksByCollectionID(collectionID)
+	if err != nil {
+		return nil, err
+	}
+
+	i.mu.Lock()
+	i.chunks[collectionID] = cachedCollection{version: version, chunks: chunks}
+	i.mu.Unlock()
+
+	return chunks, nil
+}
+
+// cosineSimilarity вычисляет косинусную близость двух векторов одинаковой длины
+func cosineSimilarity(a, b []float32) float64 {
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
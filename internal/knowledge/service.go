@@ -0,0 +1,131 @@
+package knowledge
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"mindforge/internal/ai"
+	"mindforge/internal/database"
+)
+
+// embeddingBatchSize ограничивает количество фрагментов в одном запросе к /embeddings
+const embeddingBatchSize = 32
+
+// Service объединяет нарезку, получение эмбеддингов и поиск по коллекциям
+type Service struct {
+	documents database.DocumentModel
+	embedder  ai.Embedder
+	index     VectorIndex
+	chunkSize int
+	overlap   int
+}
+
+// NewService создает сервис базы знаний
+func NewService(documents database.DocumentModel, embedder ai.Embedder, index VectorIndex, chunkSize, overlap int) *Service {
+	return &Service{
+		documents: documents,
+		embedder:  embedder,
+		index:     index,
+		chunkSize: chunkSize,
+		overlap:   overlap,
+	}
+}
+
+// Embedder возвращает используемый провайдер эмбеддингов
+func (s *Service) Embedder() ai.Embedder {
+	return s.embedder
+}
+
+// IndexName возвращает название используемого векторного индекса
+func (s *Service) IndexName() string {
+	return s.index.Name()
+}
+
+// Ingest нарезает документ на фрагменты, получает эмбеддинги и сохраняет их
+// При ошибке документ переводится в статус "failed"
+func (s *Service) Ingest(ctx context.Context, document *database.Document, embeddingModel string) error {
+	err := s.ingest(ctx, document, embeddingModel)
+	if err != nil {
+		if markErr := s.documents.MarkFailed(document.ID, err.Error()); markErr != nil {
+			return fmt.Errorf("%w (also failed to mark document: %v)", err, markErr)
+		}
+		return err
+	}
+
+	s.index.Invalidate(document.CollectionID)
+	return nil
+}
+
+func (s *Service) ingest(ctx context.Context, document *database.Document, embeddingModel string) error {
+	chunks := ChunkText(document.Content, s.chunkSize, s.overlap)
+	if len(chunks) == 0 {
+		return fmt.Errorf("document is empty")
+	}
+
+	embeddings := make([][]float32, 0, len(chunks))
+	for start := 0; start < len(chunks); start += embeddingBatchSize {
+		end := start + embeddingBatchSize
+		if end > len(chunks) {
+			end = len(chunks)
+		}
+
+		resp, err := s.embedder.Embed(ctx, ai.EmbeddingRequest{
+			Model: embeddingModel,
+			Input: chunks[start:end],
+		})
+		if err != nil {
+			return fmt.Errorf("failed to embed chunks: %w", err)
+		}
+		embeddings = append(embeddings, resp.Vectors...)
+	}
+
+	return s.documents.ReplaceChunks(document.ID, document.CollectionID, chunks, embeddings)
+}
+
+// Invalidate сбрасывает кеш индекса для коллекции (после удаления документов)
+func (s *Service) Invalidate(collectionID int) {
+	s.index.Invalidate(collectionID)
+}
+
+// Retrieve находит limit наиболее близких к запросу фрагментов в указанных коллекциях
+// Коллекции с разными моделями эмбеддингов обрабатываются отдельно, результаты объединяются
+func (s *Service) Retrieve(ctx context.Context, collections []*database.Collection, query string, limit int) ([]*database.ScoredChunk, error) {
+	if len(collections) == 0 || query == "" || limit <= 0 {
+		return nil, nil
+	}
+
+	byModel := make(map[string][]int)
+	for _, c := range collections {
+		byModel[c.EmbeddingModel] = append(byModel[c.EmbeddingModel], c.ID)
+	}
+
+	var results []*database.ScoredChunk
+	for model, collectionIDs := range byModel {
+		resp, err := s.embedder.Embed(ctx, ai.EmbeddingRequest{
+			Model: model,
+			Input: []string{query},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to embed query: %w", err)
+		}
+		if len(resp.Vectors) == 0 {
+			continue
+		}
+
+		found, err := s.index.Search(ctx, collectionIDs, resp.Vectors[0], limit)
+		if err != nil {
+			return nil, fmt.Errorf("failed to search chunks: %w", err)
+		}
+		results = append(results, found...)
+	}
+
+	sort.Slice(results, func(a, b int) bool {
+		return results[a].Score > results[b].Score
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+
+	return results, nil
+}
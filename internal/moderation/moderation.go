@@ -0,0 +1,55 @@
+// Package moderation проверяет текст запросов и ответов модели на недопустимое содержимое
+package moderation
+
+import (
+	"context"
+	"unicode"
+)
+
+// Verdict - результат проверки текста
+type Verdict struct {
+	Blocked bool
+	// Category - категория нарушения (например, violence); Rule - сработавшее правило
+	Category string
+	Rule     string
+	// Moderator - имя модератора, вынесшего решение (rules, model)
+	Moderator string
+}
+
+// Moderator проверяет текст на недопустимое содержимое
+type Moderator interface {
+	Moderate(ctx context.Context, text string) (Verdict, error)
+}
+
+// Chain проверяет текст модераторами по порядку и возвращает первое решение о блокировке
+type Chain []Moderator
+
+func (c Chain) Moderate(ctx context.Context, text string) (Verdict, error) {
+	for _, m := range c {
+		verdict, err := m.Moderate(ctx, text)
+		if err != nil {
+			return Verdict{}, err
+		}
+		if verdict.Blocked {
+			return verdict, nil
+		}
+	}
+	return Verdict{}, nil
+}
+
+// detectLanguages определяет языки текста по используемым алфавитам: ru (кириллица) и en (латиница)
+func detectLanguages(text string) map[string]bool {
+	languages := make(map[string]bool, 2)
+	for _, r := range text {
+		switch {
+		case unicode.Is(unicode.Cyrillic, r):
+			languages["ru"] = true
+		case unicode.Is(unicode.Latin, r):
+			languages["en"] = true
+		}
+		if len(languages) == 2 {
+			break
+		}
+	}
+	return languages
+}
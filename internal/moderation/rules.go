@@ -0,0 +1,125 @@
+package moderation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Rule - правило блокировки: списки ключевых слов и регулярных выражений одной категории
+// Language ограничивает правило текстами на языке (ru, en); пусто - для любых текстов
+type Rule struct {
+	Category string   `json:"category"`
+	Language string   `json:"language,omitempty"`
+	Keywords []string `json:"keywords,omitempty"`
+	Patterns []string `json:"patterns,omitempty"`
+}
+
+type compiledRule struct {
+	category string
+	language string
+	keywords []string
+	patterns []*regexp.Regexp
+}
+
+// RuleModerator блокирует текст по спискам ключевых слов и регулярных выражений
+// Ключевые слова ищутся без учета регистра как целые слова или фразы
+type RuleModerator struct {
+	rules []compiledRule
+}
+
+// NewRuleModerator компилирует правила
+func NewRuleModerator(rules []Rule) (*RuleModerator, error) {
+	compiled := make([]compiledRule, 0, len(rules))
+	for i, rule := range rules {
+		if rule.Category == "" {
+			return nil, fmt.Errorf("rule %d: category is required", i)
+		}
+
+		cr := compiledRule{category: rule.Category, language: rule.Language}
+		for _, keyword := range rule.Keywords {
+			if keyword = strings.ToLower(strings.TrimSpace(keyword)); keyword != "" {
+				cr.keywords = append(cr.keywords, keyword)
+			}
+		}
+		for _, pattern := range rule.Patterns {
+			// Шаблоны проверяются без учета регистра
+			re, err := regexp.Compile("(?i)" + pattern)
+			if err != nil {
+				return nil, fmt.Errorf("rule %d (%s): invalid pattern %q: %w", i, rule.Category, pattern, err)
+			}
+			cr.patterns = append(cr.patterns, re)
+		}
+		compiled = append(compiled, cr)
+	}
+
+	return &RuleModerator{rules: compiled}, nil
+}
+
+// LoadRules читает правила из JSON файла: [{"category": "...", "language": "ru", "keywords": [...], "patterns": [...]}]
+func LoadRules(path string) ([]Rule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read moderation rules: %w", err)
+	}
+
+	var rules []Rule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("parse moderation rules %s: %w", path, err)
+	}
+	return rules, nil
+}
+
+func (m *RuleModerator) Moderate(_ context.Context, text string) (Verdict, error) {
+	languages := detectLanguages(text)
+	lower := strings.ToLower(text)
+
+	for _, rule := range m.rules {
+		if rule.language != "" && !languages[rule.language] {
+			continue
+		}
+		for _, keyword := range rule.keywords {
+			if containsWord(lower, keyword) {
+				return Verdict{Blocked: true, Category: rule.category, Rule: "keyword:" + keyword, Moderator: "rules"}, nil
+			}
+		}
+		for _, re := range rule.patterns {
+			if re.MatchString(text) {
+				return Verdict{Blocked: true, Category: rule.category, Rule: "pattern:" + re.String(), Moderator: "rules"}, nil
+			}
+		}
+	}
+
+	return Verdict{}, nil
+}
+
+// containsWord ищет фразу в тексте так, чтобы она не была частью другого слова
+func containsWord(text, phrase string) bool {
+	for start := 0; start < len(text); {
+		i := strings.Index(text[start:], phrase)
+		if i < 0 {
+			return false
+		}
+		i += start
+		end := i + len(phrase)
+
+		before, _ := utf8.DecodeLastRuneInString(text[:i])
+		after, _ := utf8.DecodeRuneInString(text[end:])
+		if !isWordRune(before) && !isWordRune(after) {
+			return true
+		}
+
+		_, size := utf8.DecodeRuneInString(text[i:])
+		start = i + size
+	}
+	return false
+}
+
+func isWordRune(r rune) bool {
+	return r != utf8.RuneError && (unicode.IsLetter(r) || unicode.IsDigit(r))
+}
@@ -0,0 +1,113 @@
+package prompts
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Variable - именованная переменная шаблона промпта
+// В тексте шаблона переменная записывается как {{name}}
+type Variable struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required"`
+	Default     string `json:"default,omitempty"`
+}
+
+var (
+	placeholderRe  = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+	variableNameRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// Placeholders возвращает имена переменных, встречающихся в тексте, в порядке первого появления
+func Placeholders(content string) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, match := range placeholderRe.FindAllStringSubmatch(content, -1) {
+		if !seen[match[1]] {
+			seen[match[1]] = true
+			names = append(names, match[1])
+		}
+	}
+	return names
+}
+
+// Normalize проверяет описания переменных и согласует их с текстом шаблона
+// Переменные из текста без описания добавляются как обязательные; описанная,
+// но не используемая в тексте переменная считается ошибкой
+func Normalize(content string, variables []Variable) ([]Variable, error) {
+	used := Placeholders(content)
+	usedSet := make(map[string]bool, len(used))
+	for _, name := range used {
+		usedSet[name] = true
+	}
+
+	declared := make(map[string]bool, len(variables))
+	result := make([]Variable, 0, len(used))
+	for _, v := range variables {
+		v.Name = strings.TrimSpace(v.Name)
+		if !variableNameRe.MatchString(v.Name) {
+			return nil, fmt.Errorf("invalid variable name %q: use letters, digits and underscore", v.Name)
+		}
+		if declared[v.Name] {
+			return nil, fmt.Errorf("variable %q is declared twice", v.Name)
+		}
+		if !usedSet[v.Name] {
+			return nil, fmt.Errorf("variable %q is not used in the template", v.Name)
+		}
+		declared[v.Name] = true
+		result = append(result, v)
+	}
+
+	for _, name := range used {
+		if !declared[name] {
+			result = append(result, Variable{Name: name, Required: true})
+		}
+	}
+
+	return result, nil
+}
+
+// Render подставляет значения переменных в шаблон
+// Возвращает ошибку, если не передано обязательное значение или передана неизвестная переменная
+func Render(content string, variables []Variable, values map[string]string) (string, error) {
+	known := make(map[string]Variable, len(variables))
+	for _, v := range variables {
+		known[v.Name] = v
+	}
+
+	var unknown []string
+	for name := range values {
+		if _, ok := known[name]; !ok {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return "", fmt.Errorf("unknown variables: %s", strings.Join(unknown, ", "))
+	}
+
+	resolved := make(map[string]string, len(variables))
+	var missing []string
+	for _, v := range variables {
+		value, ok := values[v.Name]
+		if !ok || strings.TrimSpace(value) == "" {
+			value = v.Default
+		}
+		if value == "" && v.Required {
+			missing = append(missing, v.Name)
+		}
+		resolved[v.Name] = value
+	}
+	if len(missing) > 0 {
+		return "", fmt.Errorf("missing required variables: %s", strings.Join(missing, ", "))
+	}
+
+	// Подстановка за один проход: значения переменных повторно не разбираются
+	return placeholderRe.ReplaceAllStringFunc(content, func(match string) string {
+		name := placeholderRe.FindStringSubmatch(match)[1]
+		return resolved[name]
+	}), nil
+}
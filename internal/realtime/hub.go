@@ -0,0 +1,273 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Типы событий, которые получает клиент
+const (
+	// EventReady отправляется сразу после подключения
+	EventReady = "ready"
+	// EventPing - периодическое событие поддержания соединения
+	EventPing = "ping"
+	// EventResync - часть событий могла быть потеряна (переподключение к брокеру), клиенту нужно перечитать данные
+	EventResync = "resync"
+
+	EventChatCreated      = "chat.created"
+	EventChatUpdated      = "chat.updated"
+	EventChatDeleted      = "chat.deleted"
+	EventMessageCreated   = "message.created"
+	EventGenerationStatus = "generation.status"
+	// EventCandidateSelected - выбран ответ-кандидат сравнения, остальные кандидаты отклонены
+	EventCandidateSelected = "message.selected"
+)
+
+// Статусы генерации ответа (данные события generation.status)
+const (
+	GenerationStarted   = "started"
+	GenerationCompleted = "completed"
+	GenerationFailed    = "failed"
+)
+
+// subscriptionBuffer - сколько событий может ждать отправки одному подключению
+// Отстающее подключение закрывается: клиент переподключится и перечитает данные
+const subscriptionBuffer = 64
+
+// ErrPayloadTooLarge возвращается брокером, если событие не помещается в одно сообщение
+var ErrPayloadTooLarge = errors.New("event payload is too large")
+
+// Event - событие, отправляемое подключениям пользователя
+type Event struct {
+	Type   string          `json:"type"`
+	ChatID int             `json:"chat_id,omitempty"`
+	Data   json.RawMessage `json:"data,omitempty"`
+	// Truncated - данные события не поместились в сообщение брокера и были отброшены;
+	// клиент получает их через REST API
+	Truncated bool      `json:"truncated,omitempty"`
+	Time      time.Time `json:"time"`
+}
+
+// envelope - событие вместе с адресатом в том виде, в каком оно передается через брокер
+type envelope struct {
+	UserID int   `json:"user_id"`
+	Event  Event `json:"event"`
+}
+
+// Broker передает события между репликами сервера
+type Broker interface {
+	Name() string
+	// Publish отправляет сообщение всем репликам, включая текущую
+	Publish(ctx context.Context, payload []byte) error
+	// Listen запускает получение сообщений: handler вызывается для каждого сообщения,
+	// resync - когда сообщения могли быть потеряны
+	Listen(handler func(payload []byte), resync func()) error
+	Close() error
+}
+
+// Hub хранит подключения пользователей текущей реплики и раздает им события из брокера
+type Hub struct {
+	broker Broker
+	logger *slog.Logger
+
+	mu          sync.RWMutex
+	subscribers map[int]map[*Subscription]struct{}
+	closed      bool
+}
+
+// NewHub создает хаб и начинает получать события из брокера
+func NewHub(broker Broker, logger *slog.Logger) (*Hub, error) {
+	h := &Hub{
+		broker:      broker,
+		logger:      logger,
+		subscribers: make(map[int]map[*Subscription]struct{}),
+	}
+
+	if err := broker.Listen(h.receive, h.resync); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// BrokerName возвращает название используемого брокера
+func (h *Hub) BrokerName() string {
+	return h.broker.Name()
+}
+
+// Publish отправляет событие всем подключениям пользователя на всех репликах
+// Ошибки доставки только логируются: события - уведомления, данные остаются доступны через REST API
+func (h *Hub) Publish(userID int, eventType string, chatID int, data interface{}) {
+	event := Event{Type: eventType, ChatID: chatID, Time: time.Now().UTC()}
+	if data != nil {
+		encoded, err := json.Marshal(data)
+		if err != nil {
+			h.logger.Error("Error encoding realtime event", "error", err, "type", eventType)
+			return
+		}
+		event.Data = encoded
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := h.publish(ctx, envelope{UserID: userID, Event: event})
+	if errors.Is(err, ErrPayloadTooLarge) {
+		// Крупные данные (длинный ответ, большая схема формата) не передаются через брокер
+		event.Data = nil
+		event.Truncated = true
+		err = h.publish(ctx, envelope{UserID: userID, Event: event})
+	}
+	if err != nil {
+		h.logger.Warn("Error publishing realtime event", "error", err, "type", eventType, "user_id", userID)
+	}
+}
+
+func (h *Hub) publish(ctx context.Context, msg envelope) error {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return h.broker.Publish(ctx, payload)
+}
+
+// receive раздает событие из брокера подключениям адресата на текущей реплике
+func (h *Hub) receive(payload []byte) {
+	var msg envelope
+	if err := json.Unmarshal(payload, &msg); err != nil {
+		h.logger.Warn("Invalid realtime event received", "error", err)
+		return
+	}
+
+	h.mu.RLock()
+	var slow []*Subscription
+	for sub := range h.subscribers[msg.UserID] {
+		select {
+		case sub.events <- msg.Event:
+		default:
+			slow = append(slow, sub)
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, sub := range slow {
+		h.logger.Warn("Realtime subscriber is too slow, closing", "user_id", sub.UserID)
+		sub.Close()
+	}
+}
+
+// resync сообщает всем подключениям реплики, что события могли быть потеряны
+func (h *Hub) resync() {
+	event := Event{Type: EventResync, Time: time.Now().UTC()}
+
+	h.mu.RLock()
+	var slow []*Subscription
+	for _, subs := range h.subscribers {
+		for sub := range subs {
+			select {
+			case sub.events <- event:
+			default:
+				slow = append(slow, sub)
+			}
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, sub := range slow {
+		sub.Close()
+	}
+}
+
+// Subscribe регистрирует подключение пользователя
+// Канал событий закрывается при Close подписки, отставании подключения или остановке хаба
+func (h *Hub) Subscribe(userID int) *Subscription {
+	sub := &Subscription{
+		UserID: userID,
+		events: make(chan Event, subscriptionBuffer),
+		hub:    h,
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		close(sub.events)
+		sub.removed = true
+		return sub
+	}
+	if h.subscribers[userID] == nil {
+		h.subscribers[userID] = make(map[*Subscription]struct{})
+	}
+	h.subscribers[userID][sub] = struct{}{}
+	return sub
+}
+
+// Connections возвращает количество подключений на текущей реплике
+func (h *Hub) Connections() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	count := 0
+	for _, subs := range h.subscribers {
+		count += len(subs)
+	}
+	return count
+}
+
+// Close закрывает все подключения и останавливает получение событий
+func (h *Hub) Close() {
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		return
+	}
+	h.closed = true
+	for userID, subs := range h.subscribers {
+		for sub := range subs {
+			sub.removed = true
+			close(sub.events)
+		}
+		delete(h.subscribers, userID)
+	}
+	h.mu.Unlock()
+
+	if err := h.broker.Close(); err != nil {
+		h.logger.Warn("Error closing realtime broker", "error", err)
+	}
+}
+
+// Subscription - подключение пользователя к хабу
+type Subscription struct {
+	UserID int
+	events chan Event
+	hub    *Hub
+	// removed защищен мьютексом хаба
+	removed bool
+}
+
+// Events возвращает канал событий подключения
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Close отключает подписку от хаба; повторный вызов безопасен
+func (s *Subscription) Close() {
+	h := s.hub
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if s.removed {
+		return
+	}
+	s.removed = true
+	close(s.events)
+
+	subs := h.subscribers[s.UserID]
+	delete(subs, s)
+	if len(subs) == 0 {
+		delete(h.subscribers, s.UserID)
+	}
+}
@@ -0,0 +1,46 @@
+package realtime
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryBroker доставляет события только внутри процесса (одна реплика сервера)
+type MemoryBroker struct {
+	mu      sync.RWMutex
+	handler func(payload []byte)
+}
+
+// NewMemoryBroker создает брокер в памяти процесса
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{}
+}
+
+func (b *MemoryBroker) Name() string {
+	return "memory"
+}
+
+func (b *MemoryBroker) Publish(ctx context.Context, payload []byte) error {
+	b.mu.RLock()
+	handler := b.handler
+	b.mu.RUnlock()
+
+	if handler != nil {
+		handler(payload)
+	}
+	return nil
+}
+
+func (b *MemoryBroker) Listen(handler func(payload []byte), resync func()) error {
+	b.mu.Lock()
+	b.handler = handler
+	b.mu.Unlock()
+	return nil
+}
+
+func (b *MemoryBroker) Close() error {
+	b.mu.Lock()
+	b.handler = nil
+	b.mu.Unlock()
+	return nil
+}
@@ -0,0 +1,97 @@
+package realtime
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// PostgresChannel - канал LISTEN/NOTIFY, через который реплики обмениваются событиями
+const PostgresChannel = "mindforge_events"
+
+// maxNotifyPayload - предел размера уведомления PostgreSQL (8000 байт) с запасом
+const maxNotifyPayload = 7900
+
+// PostgresBroker передает события между репликами через LISTEN/NOTIFY
+// Уведомления отправляются через общий пул соединений, а получаются выделенным соединением pq.Listener
+type PostgresBroker struct {
+	db       *sql.DB
+	listener *pq.Listener
+	logger   *slog.Logger
+	done     chan struct{}
+}
+
+// NewPostgresBroker создает брокер; dsn используется для выделенного соединения LISTEN
+func NewPostgresBroker(db *sql.DB, dsn string, logger *slog.Logger) *PostgresBroker {
+	b := &PostgresBroker{
+		db:     db,
+		logger: logger,
+		done:   make(chan struct{}),
+	}
+	b.listener = pq.NewListener(dsn, 10*time.Second, time.Minute, b.onListenerEvent)
+	return b
+}
+
+func (b *PostgresBroker) Name() string {
+	return "postgres"
+}
+
+func (b *PostgresBroker) Publish(ctx context.Context, payload []byte) error {
+	if len(payload) > maxNotifyPayload {
+		return ErrPayloadTooLarge
+	}
+	_, err := b.db.ExecContext(ctx, `SELECT pg_notify($1, $2)`, PostgresChannel, string(payload))
+	return err
+}
+
+func (b *PostgresBroker) Listen(handler func(payload []byte), resync func()) error {
+	if err := b.listener.Listen(PostgresChannel); err != nil {
+		return err
+	}
+
+	go func() {
+		// Проверка соединения при долгом отсутствии уведомлений: обрыв обнаруживается быстрее
+		ticker := time.NewTicker(90 * time.Second)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case n, ok := <-b.listener.Notify:
+				if !ok {
+					return
+				}
+				// nil приходит после переподключения: уведомления за время обрыва потеряны
+				if n == nil {
+					resync()
+					continue
+				}
+				handler([]byte(n.Extra))
+			case <-ticker.C:
+				go b.listener.Ping()
+			case <-b.done:
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (b *PostgresBroker) Close() error {
+	close(b.done)
+	return b.listener.Close()
+}
+
+func (b *PostgresBroker) onListenerEvent(event pq.ListenerEventType, err error) {
+	switch event {
+	case pq.ListenerEventDisconnected:
+		b.logger.Warn("Realtime listener disconnected from database", "error", err)
+	case pq.ListenerEventReconnected:
+		b.logger.Info("Realtime listener reconnected to database")
+	case pq.ListenerEventConnectionAttemptFailed:
+		b.logger.Warn("Realtime listener connection attempt failed", "error", err)
+	}
+}
@@ -0,0 +1,112 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// EncryptedFileSource читает секреты из локального файла, зашифрованного AES-256-GCM
+// Файл содержит base64(nonce || ciphertext), расшифрованное содержимое - JSON объект {"KEY": "value"}.
+// Ключ шифрования - 32 байта в base64 (SECRETS_KEY). Файл создается командой cmd/secrets
+type EncryptedFileSource struct {
+	path    string
+	secrets map[string]string
+}
+
+// NewEncryptedFileSource расшифровывает файл секретов
+func NewEncryptedFileSource(path, key string) (*EncryptedFileSource, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read secrets file: %w", err)
+	}
+
+	plaintext, err := Decrypt(strings.TrimSpace(string(data)), key)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt secrets file %s: %w", path, err)
+	}
+
+	var secrets map[string]string
+	if err := json.Unmarshal(plaintext, &secrets); err != nil {
+		return nil, fmt.Errorf("secrets file %s must contain a JSON object of strings: %w", path, err)
+	}
+
+	return &EncryptedFileSource{path: path, secrets: secrets}, nil
+}
+
+func (s *EncryptedFileSource) Name() string { return "encrypted:" + s.path }
+
+func (s *EncryptedFileSource) Lookup(key string) (string, bool, error) {
+	value, ok := s.secrets[key]
+	return value, ok, nil
+}
+
+// GenerateKey создает новый ключ шифрования в base64
+func GenerateKey() (string, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// Encrypt шифрует данные ключом в base64 и возвращает base64(nonce || ciphertext)
+func Encrypt(plaintext []byte, key string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := gcm.Seal(nonce, nonce, plaintext, nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt расшифровывает данные, зашифрованные Encrypt
+func Decrypt(encoded, key string) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base64: %w", err)
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, errors.New("ciphertext is too short")
+	}
+
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, errors.New("wrong key or corrupted file")
+	}
+	return plaintext, nil
+}
+
+func newGCM(key string) (cipher.AEAD, error) {
+	raw, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return nil, fmt.Errorf("key must be base64: %w", err)
+	}
+	if len(raw) != 32 {
+		return nil, fmt.Errorf("key must be 32 bytes, got %d", len(raw))
+	}
+
+	block, err := aes.NewCipher(raw)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
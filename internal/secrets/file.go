@@ -0,0 +1,42 @@
+package secrets
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// defaultSecretsDir - каталог, куда Docker Swarm и Compose монтируют секреты
+const defaultSecretsDir = "/run/secrets"
+
+// FileSource читает секреты из файлов каталога: один файл - один секрет
+// Подходит для Docker secrets и Kubernetes secrets, смонтированных как том.
+// Имя файла совпадает с ключом (DEEPSEEK_API_KEY) или записано в нижнем регистре (deepseek_api_key)
+type FileSource struct {
+	dir string
+}
+
+// NewFileSource создает источник секретов из каталога
+func NewFileSource(dir string) *FileSource {
+	return &FileSource{dir: dir}
+}
+
+func (s *FileSource) Name() string { return "files:" + s.dir }
+
+func (s *FileSource) Lookup(key string) (string, bool, error) {
+	for _, name := range []string{key, strings.ToLower(key)} {
+		data, err := os.ReadFile(filepath.Join(s.dir, name))
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return "", false, fmt.Errorf("read secret %s: %w", name, err)
+		}
+		// Файлы секретов часто заканчиваются переводом строки
+		return strings.TrimSpace(string(data)), true, nil
+	}
+	return "", false, nil
+}
@@ -0,0 +1,110 @@
+// Package secrets предоставляет доступ к секретам (API ключам провайдеров и т.п.)
+// из нескольких источников: переменных окружения, файлов (Docker/Kubernetes secrets)
+// и локального зашифрованного файла
+package secrets
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ErrNotFound - секрет не найден ни в одном источнике
+var ErrNotFound = errors.New("secret not found")
+
+// Source - источник секретов
+type Source interface {
+	// Lookup возвращает значение секрета; ok=false, если в этом источнике секрета нет
+	Lookup(key string) (value string, ok bool, err error)
+
+	// Name возвращает имя источника для логов
+	Name() string
+}
+
+// Store ищет секреты в источниках по порядку: побеждает первый источник, в котором секрет найден
+type Store struct {
+	sources []Source
+}
+
+// NewStore создает хранилище с указанными источниками
+func NewStore(sources ...Source) *Store {
+	return &Store{sources: sources}
+}
+
+// Lookup ищет секрет во всех источниках и возвращает имя источника, в котором он найден
+func (s *Store) Lookup(key string) (value, source string, err error) {
+	for _, src := range s.sources {
+		value, ok, err := src.Lookup(key)
+		if err != nil {
+			return "", "", fmt.Errorf("%s: %w", src.Name(), err)
+		}
+		if ok && value != "" {
+			return value, src.Name(), nil
+		}
+	}
+	return "", "", ErrNotFound
+}
+
+// Sources возвращает имена подключенных источников
+func (s *Store) Sources() []string {
+	names := make([]string, len(s.sources))
+	for i, src := range s.sources {
+		names[i] = src.Name()
+	}
+	return names
+}
+
+// FromEnv собирает хранилище по переменным окружения:
+// - переменные окружения (всегда, первыми);
+// - файлы в SECRETS_DIR (по умолчанию /run/secrets, если каталог существует);
+// - зашифрованный файл SECRETS_FILE с ключом SECRETS_KEY
+func FromEnv() (*Store, error) {
+	sources := []Source{EnvSource{}}
+
+	dir := os.Getenv("SECRETS_DIR")
+	if dir == "" {
+		dir = defaultSecretsDir
+	}
+	if info, err := os.Stat(dir); err == nil && info.IsDir() {
+		sources = append(sources, NewFileSource(dir))
+	} else if os.Getenv("SECRETS_DIR") != "" {
+		return nil, fmt.Errorf("SECRETS_DIR %s is not a directory", dir)
+	}
+
+	if path := os.Getenv("SECRETS_FILE"); path != "" {
+		key := os.Getenv("SECRETS_KEY")
+		if key == "" {
+			return nil, errors.New("SECRETS_KEY is required to decrypt SECRETS_FILE")
+		}
+		source, err := NewEncryptedFileSource(path, key)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, source)
+	}
+
+	return NewStore(sources...), nil
+}
+
+// EnvSource читает секреты из переменных окружения
+// Также поддерживается соглашение KEY_FILE: значение читается из файла, путь к которому указан в переменной
+type EnvSource struct{}
+
+func (EnvSource) Name() string { return "env" }
+
+func (EnvSource) Lookup(key string) (string, bool, error) {
+	if value, ok := os.LookupEnv(key); ok && value != "" {
+		return value, true, nil
+	}
+
+	if path, ok := os.LookupEnv(key + "_FILE"); ok && path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", false, fmt.Errorf("read %s_FILE: %w", key, err)
+		}
+		return strings.TrimSpace(string(data)), true, nil
+	}
+
+	return "", false, nil
+}